@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"projekat/auth"
+	"strings"
+)
+
+// newAPIKey je komanda "new-api-key ime [grupa,...]": pravi nasumičan API ključ i ispisuje ga
+// zajedno sa redom za fajl sa ključevima. Sam ključ se ne čuva nigde, pa ga treba odmah predati klijentu.
+func newAPIKey(args []string) error {
+	if len(args) < 1 || len(args) > 2 || args[0] == "" || strings.Contains(args[0], ":") {
+		return errors.New("usage: new-api-key name [group,group]")
+	}
+	key, err := auth.NewAPIKey()
+	if err != nil {
+		return err
+	}
+
+	line := args[0] + ":" + auth.HashAPIKey(key)
+	if len(args) == 2 {
+		line += ":" + args[1]
+	}
+	fmt.Printf("key:  %s\nline: %s\n", key, line)
+	return nil
+}
+
+// loadAuthenticator učitava API ključeve i podešavanja za JWT iz opcija servera
+func loadAuthenticator(opts options) (*auth.Authenticator, error) {
+	var apiKeys *auth.APIKeys
+	if opts.apiKeysFile != "" {
+		keys, err := auth.LoadAPIKeys(opts.apiKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = keys
+	}
+
+	var verifier *auth.JWTVerifier
+	if opts.jwtHMACSecret != "" || opts.jwksFile != "" {
+		var jwks *auth.JWKS
+		if opts.jwksFile != "" {
+			keys, err := auth.LoadJWKS(opts.jwksFile)
+			if err != nil {
+				return nil, err
+			}
+			jwks = keys
+		}
+		verifier = auth.NewJWTVerifier([]byte(opts.jwtHMACSecret), jwks, opts.jwtIssuer, opts.jwtAudience)
+	}
+
+	authenticator := auth.NewAuthenticator(apiKeys, verifier)
+	if !authenticator.Enabled() {
+		log.Printf("No API keys or JWT verification configured; every request is accepted without credentials")
+	}
+	return authenticator, nil
+}
@@ -0,0 +1,162 @@
+// Package archive zapisuje i čita arhivu celog skladišta: sve konfiguracije i grupe sa
+// manifestom koji nosi verziju formata arhive. Arhiva se zapisuje kao JSON lines ili tar.gz.
+package archive
+
+import (
+	"mime"
+	"projekat/model"
+	"sort"
+	"time"
+)
+
+// FormatName označava manifest arhive ovog servisa
+const FormatName = "projekat-archive"
+
+// Version je verzija formata arhive koja se zapisuje. Čitaju se sve verzije do ove.
+// Od verzije 2 broj zapisa nosi završni zapis umesto manifesta, jer se arhiva zapisuje dok se
+// zapisi čitaju iz skladišta.
+const Version = 2
+
+// Manifest opisuje arhivu i uvek je prvi zapis u njoj. Broj zapisa se u manifest zapisuje samo
+// u verziji 1; pri čitanju se popunjava i za novije verzije, iz završnog zapisa.
+type Manifest struct {
+	Format       string    `json:"format"`
+	Version      int       `json:"version"`
+	CreatedAt    time.Time `json:"createdAt"`
+	Configs      int       `json:"configs,omitempty"`
+	ConfigGroups int       `json:"configGroups,omitempty"`
+}
+
+// Trailer je poslednji zapis arhive: broj zapisa, po kome se prepoznaje skraćena arhiva
+type Trailer struct {
+	Configs      int `json:"configs"`
+	ConfigGroups int `json:"configGroups"`
+}
+
+// Archive je sadržaj skladišta u obliku koji se izvozi i uvozi
+type Archive struct {
+	Manifest     Manifest
+	Configs      []model.Config
+	ConfigGroups []model.ConfigGroup
+	// trailer je završni zapis pročitane arhive, ako ga ima
+	trailer *Trailer
+}
+
+// New pravi arhivu sortiranu po imenu i verziji, sa popunjenim manifestom. Iz grupa se uklanjaju
+// konfiguracije razrešene iz referenci, a iz konfiguracija šifrovane vrednosti, jer se u arhivu
+// upisuje samo ono što bi klijent poslao pri kreiranju. Prostor imena se ne zapisuje, pa se arhiva
+// može uvesti u bilo koji prostor imena.
+func New(configs []model.Config, configGroups []model.ConfigGroup, createdAt time.Time) Archive {
+	a := Archive{
+		Configs:      make([]model.Config, 0, len(configs)),
+		ConfigGroups: make([]model.ConfigGroup, 0, len(configGroups)),
+	}
+	for _, config := range configs {
+		a.Configs = append(a.Configs, exportedConfig(config))
+	}
+	for _, configGroup := range configGroups {
+		a.ConfigGroups = append(a.ConfigGroups, exportedConfigGroup(configGroup))
+	}
+	sort.Slice(a.Configs, func(i, j int) bool {
+		return less(a.Configs[i].Name, a.Configs[i].Version, a.Configs[j].Name, a.Configs[j].Version)
+	})
+	sort.Slice(a.ConfigGroups, func(i, j int) bool {
+		return less(a.ConfigGroups[i].Name, a.ConfigGroups[i].Version, a.ConfigGroups[j].Name, a.ConfigGroups[j].Version)
+	})
+
+	a.Manifest = Manifest{
+		Format:       FormatName,
+		Version:      Version,
+		CreatedAt:    createdAt,
+		Configs:      len(a.Configs),
+		ConfigGroups: len(a.ConfigGroups),
+	}
+	return a
+}
+
+func exportedConfig(config model.Config) model.Config {
+	config = config.Clone()
+	config.Encrypted = nil
+	config.Namespace = ""
+	return config
+}
+
+func exportedConfigGroup(configGroup model.ConfigGroup) model.ConfigGroup {
+	configGroup = configGroup.Clone()
+	configGroup.Namespace = ""
+	for i := range configGroup.Configuration {
+		configGroup.Configuration[i] = exportedConfig(configGroup.Configuration[i])
+	}
+	for i := range configGroup.References {
+		configGroup.References[i].Config = nil
+	}
+	return configGroup
+}
+
+func less(name1 string, version1 int, name2 string, version2 int) bool {
+	if name1 != name2 {
+		return name1 < name2
+	}
+	return version1 < version2
+}
+
+// Encoding je način zapisa arhive
+type Encoding string
+
+const (
+	// JSONLines je jedan JSON zapis po redu: prvo manifest, pa konfiguracije, pa grupe, pa završni zapis
+	JSONLines Encoding = "jsonl"
+	// TarGz je tar arhiva komprimovana gzip-om: manifest.json, configs/ime/verzija.json,
+	// configGroups/ime/verzija.json i trailer.json
+	TarGz Encoding = "tar.gz"
+)
+
+var mediaTypes = map[Encoding][]string{
+	JSONLines: {"application/x-ndjson", "application/jsonl", "application/x-jsonlines"},
+	TarGz:     {"application/gzip", "application/x-gzip", "application/x-tar+gzip", "application/x-gtar"},
+}
+
+// ParseEncoding prevodi ime iz ?format= u način zapisa
+func ParseEncoding(name string) (Encoding, bool) {
+	switch name {
+	case "jsonl", "ndjson":
+		return JSONLines, true
+	case "tar.gz", "tgz":
+		return TarGz, true
+	}
+	return "", false
+}
+
+// ContentType vraća media type za odgovor
+func (e Encoding) ContentType() string {
+	return mediaTypes[e][0]
+}
+
+// FileName vraća predloženo ime fajla arhive
+func (e Encoding) FileName(createdAt time.Time) string {
+	return "export-" + createdAt.Format("20060102T150405Z") + "." + string(e)
+}
+
+// FromMediaType pronalazi način zapisa za Content-Type ili jedan tip iz Accept zaglavlja
+func FromMediaType(contentType string) (Encoding, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	for _, encoding := range []Encoding{JSONLines, TarGz} {
+		for _, candidate := range mediaTypes[encoding] {
+			if candidate == mediaType {
+				return encoding, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Sniff prepoznaje način zapisa po prvim bajtovima, za telo bez Content-Type zaglavlja
+func Sniff(prefix []byte) Encoding {
+	if len(prefix) >= 2 && prefix[0] == 0x1f && prefix[1] == 0x8b {
+		return TarGz
+	}
+	return JSONLines
+}
@@ -0,0 +1,228 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"projekat/model"
+	"strings"
+)
+
+// ErrTooLarge znači da je raspakovana arhiva veća od dozvoljene
+var ErrTooLarge = errors.New("archive is too large")
+
+// Read čita arhivu zapisanu sa Writer. Proverava manifest i da broj zapisa odgovara završnom
+// zapisu (u verziji 1 manifestu), tako da se skraćena arhiva odbija umesto da se delimično uveze.
+// Čita najviše maxBytes raspakovanih bajtova, a za veću arhivu vraća ErrTooLarge. Greška čitanja
+// iz r se vraća nepromenjena, a ostale greške su model.ErrInvalid.
+func Read(r io.Reader, encoding Encoding, maxBytes int64) (Archive, error) {
+	var a Archive
+	var err error
+	switch encoding {
+	case JSONLines:
+		limited := newLimitedReader(r, maxBytes)
+		a, err = readJSONLines(limited)
+		if failure := limited.failure(); failure != nil {
+			return Archive{}, failure
+		}
+	case TarGz:
+		a, err = readTarGz(r, maxBytes)
+	default:
+		return Archive{}, model.Invalidf("unknown archive encoding %q", encoding)
+	}
+	if err != nil {
+		return Archive{}, err
+	}
+	if err := a.check(); err != nil {
+		return Archive{}, err
+	}
+	return a, nil
+}
+
+// ReadSniffed čita arhivu čiji način zapisa nije poznat, prepoznajući ga po prvim bajtovima
+func ReadSniffed(r io.Reader, maxBytes int64) (Archive, error) {
+	buffered := bufio.NewReader(r)
+	prefix, _ := buffered.Peek(2)
+	return Read(buffered, Sniff(prefix), maxBytes)
+}
+
+// limitedReader čita najviše limit bajtova; ako iza njih ima još podataka, vraća ErrTooLarge.
+// Pamti i grešku čitanja izvora (npr. preveliko telo zahteva), jer je dekoderi ne prosleđuju
+// uvek, a parseri arhive je pretvaraju u model.ErrInvalid.
+type limitedReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+	exceeded  bool
+	err       error
+}
+
+func newLimitedReader(r io.Reader, limit int64) *limitedReader {
+	return &limitedReader{r: r, limit: limit, remaining: limit}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		var probe [1]byte
+		if n, err := l.r.Read(probe[:]); n == 0 {
+			return 0, l.remember(err)
+		}
+		l.exceeded = true
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, l.remember(err)
+}
+
+func (l *limitedReader) remember(err error) error {
+	if err != nil && !errors.Is(err, io.EOF) && l.err == nil {
+		l.err = err
+	}
+	return err
+}
+
+// failure vraća razlog zbog kog čitanje nije stiglo do kraja arhive, ako nije u samom sadržaju
+func (l *limitedReader) failure() error {
+	if l.exceeded {
+		return fmt.Errorf("%w: more than %d bytes when decompressed", ErrTooLarge, l.limit)
+	}
+	return l.err
+}
+
+func readJSONLines(r io.Reader) (Archive, error) {
+	var a Archive
+	decoder := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var rec record
+		if err := decoder.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return Archive{}, model.Invalidf("archive record %d: %v", line, err)
+		}
+
+		switch {
+		case line == 1:
+			if rec.Kind != kindManifest || rec.Manifest == nil {
+				return Archive{}, model.Invalidf("archive must start with a manifest record")
+			}
+			a.Manifest = *rec.Manifest
+		case a.trailer != nil:
+			return Archive{}, model.Invalidf("archive record %d: unexpected record after the trailer", line)
+		case rec.Kind == kindTrailer && rec.Trailer != nil:
+			a.trailer = rec.Trailer
+		case rec.Kind == kindConfig && rec.Config != nil:
+			a.Configs = append(a.Configs, *rec.Config)
+		case rec.Kind == kindConfigGroup && rec.ConfigGroup != nil:
+			a.ConfigGroups = append(a.ConfigGroups, *rec.ConfigGroup)
+		default:
+			return Archive{}, model.Invalidf("archive record %d: unexpected record of kind %q", line, rec.Kind)
+		}
+	}
+	if a.Manifest.Format == "" {
+		return Archive{}, model.Invalidf("archive is empty")
+	}
+	return a, nil
+}
+
+func readTarGz(r io.Reader, maxBytes int64) (Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Archive{}, model.Invalidf("archive is not gzip compressed: %v", err)
+	}
+	defer gz.Close()
+	limited := newLimitedReader(gz, maxBytes)
+
+	var a Archive
+	hasManifest := false
+	tr := tar.NewReader(limited)
+	for {
+		header, err := tr.Next()
+		if failure := limited.failure(); failure != nil {
+			return Archive{}, gzipFailure(failure)
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Archive{}, model.Invalidf("archive: %v", err)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		name := strings.TrimPrefix(header.Name, "./")
+		var target interface{}
+		switch {
+		case name == manifestFile:
+			target = &a.Manifest
+			hasManifest = true
+		case name == trailerFile:
+			a.trailer = &Trailer{}
+			target = a.trailer
+		case strings.HasPrefix(name, configsDir) && strings.HasSuffix(name, archiveFileSuffix):
+			a.Configs = append(a.Configs, model.Config{})
+			target = &a.Configs[len(a.Configs)-1]
+		case strings.HasPrefix(name, configGroupsDir) && strings.HasSuffix(name, archiveFileSuffix):
+			a.ConfigGroups = append(a.ConfigGroups, model.ConfigGroup{})
+			target = &a.ConfigGroups[len(a.ConfigGroups)-1]
+		default:
+			return Archive{}, model.Invalidf("archive: unexpected entry %s", header.Name)
+		}
+		if err := json.NewDecoder(tr).Decode(target); err != nil {
+			if failure := limited.failure(); failure != nil {
+				return Archive{}, gzipFailure(failure)
+			}
+			return Archive{}, model.Invalidf("archive entry %s: %v", header.Name, err)
+		}
+	}
+	if !hasManifest {
+		return Archive{}, model.Invalidf("archive has no %s", manifestFile)
+	}
+	return a, nil
+}
+
+// gzipFailure razlikuje grešku čitanja izvora, koju gzip prosleđuje nepromenjenu, od skraćenog
+// ili oštećenog gzip toka, koji je neispravna arhiva
+func gzipFailure(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, gzip.ErrChecksum) || errors.Is(err, gzip.ErrHeader) {
+		return model.Invalidf("archive is not a complete gzip stream: %v", err)
+	}
+	return err
+}
+
+// check proverava manifest i završni zapis pročitane arhive i popunjava broj zapisa u manifestu
+func (a *Archive) check() error {
+	m := &a.Manifest
+	if m.Format != FormatName {
+		return model.Invalidf("not a %s archive (format %q)", FormatName, m.Format)
+	}
+	if m.Version < 1 || m.Version > Version {
+		return model.Invalidf("unsupported archive version %d (supported up to %d)", m.Version, Version)
+	}
+	listed := "manifest lists"
+	if m.Version >= 2 {
+		if a.trailer == nil {
+			return model.Invalidf("archive is incomplete: it has no trailer")
+		}
+		m.Configs, m.ConfigGroups = a.trailer.Configs, a.trailer.ConfigGroups
+		listed = "trailer lists"
+	}
+	if m.Configs != len(a.Configs) || m.ConfigGroups != len(a.ConfigGroups) {
+		return model.Invalidf("archive is incomplete: %s %s, archive contains %s",
+			listed, counts(m.Configs, m.ConfigGroups), counts(len(a.Configs), len(a.ConfigGroups)))
+	}
+	return nil
+}
+
+func counts(configs, configGroups int) string {
+	return fmt.Sprintf("%d configs and %d config groups", configs, configGroups)
+}
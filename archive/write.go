@@ -0,0 +1,156 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"projekat/model"
+	"time"
+)
+
+// record je jedan red arhive u JSON lines zapisu; popunjeno je samo polje koje odgovara vrsti
+type record struct {
+	Kind        string             `json:"kind"`
+	Manifest    *Manifest          `json:"manifest,omitempty"`
+	Config      *model.Config      `json:"config,omitempty"`
+	ConfigGroup *model.ConfigGroup `json:"configGroup,omitempty"`
+	Trailer     *Trailer           `json:"trailer,omitempty"`
+}
+
+const (
+	kindManifest    = "manifest"
+	kindConfig      = "config"
+	kindConfigGroup = "configGroup"
+	kindTrailer     = "trailer"
+)
+
+const (
+	manifestFile      = "manifest.json"
+	trailerFile       = "trailer.json"
+	configsDir        = "configs/"
+	configGroupsDir   = "configGroups/"
+	archiveFileSuffix = ".json"
+)
+
+// Writer zapisuje arhivu zapis po zapis, dok se zapisi čitaju iz skladišta, pa se arhiva ne drži
+// cela u memoriji. Manifest se zapisuje pre prvog zapisa, a Close dodaje završni zapis sa brojem
+// zapisa; arhiva prekinuta pre Close nema završni zapis i uvoz je odbija.
+type Writer struct {
+	w        io.Writer
+	encoding Encoding
+	manifest Manifest
+	trailer  Trailer
+	// MaskSecrets maskira tajne parametre, za arhivu izvezenu bez otkrivanja tajni
+	MaskSecrets bool
+	started     bool
+
+	encoder *json.Encoder
+	gz      *gzip.Writer
+	tw      *tar.Writer
+}
+
+// NewWriter pravi Writer za način zapisa; ništa ne zapisuje pre prvog zapisa ili Close
+func NewWriter(w io.Writer, encoding Encoding, createdAt time.Time) (*Writer, error) {
+	if encoding != JSONLines && encoding != TarGz {
+		return nil, fmt.Errorf("unknown archive encoding %q", encoding)
+	}
+	return &Writer{
+		w:        w,
+		encoding: encoding,
+		manifest: Manifest{Format: FormatName, Version: Version, CreatedAt: createdAt},
+	}, nil
+}
+
+// Started proverava da li je nešto već zapisano; do tada greška može da se pošalje umesto arhive
+func (w *Writer) Started() bool {
+	return w.started
+}
+
+// Config zapisuje konfiguraciju bez prostora imena i šifrovanih vrednosti
+func (w *Writer) Config(config model.Config) error {
+	config = exportedConfig(config)
+	if w.MaskSecrets {
+		config = config.Masked()
+	}
+	if err := w.begin(); err != nil {
+		return err
+	}
+	w.trailer.Configs++
+	if w.encoding == JSONLines {
+		return w.encoder.Encode(record{Kind: kindConfig, Config: &config})
+	}
+	name := fmt.Sprintf("%s%s/%d%s", configsDir, config.Name, config.Version, archiveFileSuffix)
+	return w.writeTarEntry(name, config)
+}
+
+// ConfigGroup zapisuje grupu bez prostora imena i bez konfiguracija razrešenih iz referenci
+func (w *Writer) ConfigGroup(configGroup model.ConfigGroup) error {
+	configGroup = exportedConfigGroup(configGroup)
+	if w.MaskSecrets {
+		configGroup = configGroup.Masked()
+	}
+	if err := w.begin(); err != nil {
+		return err
+	}
+	w.trailer.ConfigGroups++
+	if w.encoding == JSONLines {
+		return w.encoder.Encode(record{Kind: kindConfigGroup, ConfigGroup: &configGroup})
+	}
+	name := fmt.Sprintf("%s%s/%d%s", configGroupsDir, configGroup.Name, configGroup.Version, archiveFileSuffix)
+	return w.writeTarEntry(name, configGroup)
+}
+
+// Close zapisuje završni zapis sa brojem zapisa i završava tar.gz
+func (w *Writer) Close() error {
+	if err := w.begin(); err != nil {
+		return err
+	}
+	trailer := w.trailer
+	if w.encoding == JSONLines {
+		return w.encoder.Encode(record{Kind: kindTrailer, Trailer: &trailer})
+	}
+	if err := w.writeTarEntry(trailerFile, trailer); err != nil {
+		return err
+	}
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+// begin zapisuje manifest pre prvog zapisa
+func (w *Writer) begin() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	manifest := w.manifest
+	if w.encoding == JSONLines {
+		// Encoder posle svake vrednosti dodaje novi red
+		w.encoder = json.NewEncoder(w.w)
+		return w.encoder.Encode(record{Kind: kindManifest, Manifest: &manifest})
+	}
+	w.gz = gzip.NewWriter(w.w)
+	w.tw = tar.NewWriter(w.gz)
+	return w.writeTarEntry(manifestFile, manifest)
+}
+
+func (w *Writer) writeTarEntry(name string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: w.manifest.CreatedAt,
+	}
+	if err := w.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = w.tw.Write(data)
+	return err
+}
@@ -0,0 +1,162 @@
+// Package audit vodi audit log izmena konfiguracija i grupa: ko je, kada i u okviru kog zahteva
+// napravio izmenu. Zapisi se samo dodaju i ulančani su SHA-256 hešom, pa se naknadna izmena ili
+// brisanje zapisa u skladištu otkriva proverom lanca.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"projekat/events"
+	"projekat/model"
+	"sync"
+	"time"
+)
+
+// Principal za izmene koje ne dolaze od klijenta i za zahteve kada autentifikacija nije uključena
+const (
+	SystemPrincipal    = "system"
+	AnonymousPrincipal = "anonymous"
+)
+
+// Koliko puta Record pokušava upis kada je druga instanca servera u međuvremenu dodala zapis
+const appendAttempts = 5
+
+// Actor je onaj ko pravi izmenu: proveren klijent i ID zahteva u kom je izmena napravljena
+type Actor struct {
+	Principal string
+	RequestID string
+}
+
+// System je actor za izmene koje server pravi sam, npr. početne podatke pri pokretanju
+var System = Actor{Principal: SystemPrincipal}
+
+// Change opisuje jednu izmenu zapisa. Before i After su zapis pre i posle izmene onako kako ga je
+// repozitorijum sačuvao, sa šifrovanim tajnim parametrima (nil ako nije postojao); u log ulaze
+// samo njihovi heševi.
+type Change struct {
+	Action    string
+	Operation string
+	Kind      events.Kind
+	Namespace string
+	Name      string
+	Version   int
+	Before    json.RawMessage
+	After     json.RawMessage
+}
+
+// Log dodaje zapise u AuditRepository i pamti poslednji zapis, na koji se nadovezuje sledeći
+type Log struct {
+	mu   sync.Mutex
+	repo model.AuditRepository
+	last model.AuditEntry
+	// unrecorded je broj izmena od pokretanja koje nisu upisane jer Record nije uspeo;
+	// lastFailure je poslednja takva greška
+	unrecorded  int64
+	lastFailure string
+}
+
+// NewLog učitava poslednji zapis iz repozitorijuma, pa se lanac nastavlja i posle restarta
+func NewLog(repo model.AuditRepository) (*Log, error) {
+	last, err := repo.Last()
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		return nil, fmt.Errorf("cannot read audit log: %w", err)
+	}
+	return &Log{
+		repo: repo,
+		last: last,
+	}, nil
+}
+
+// Record dodaje izmenu na kraj lanca i vraća upisan zapis
+func (l *Log) Record(actor Actor, change Change) (model.AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	principal := actor.Principal
+	if principal == "" {
+		principal = AnonymousPrincipal
+	}
+	namespace := change.Namespace
+	if namespace == "" {
+		namespace = model.DefaultNamespace
+	}
+	entry := model.AuditEntry{
+		Time:       time.Now().UTC(),
+		Principal:  principal,
+		RequestID:  actor.RequestID,
+		Action:     change.Action,
+		Operation:  change.Operation,
+		Resource:   events.Key(change.Kind, namespace, change.Name, change.Version),
+		Kind:       string(change.Kind),
+		Namespace:  namespace,
+		Name:       change.Name,
+		Version:    change.Version,
+		BeforeHash: StoredHash(change.Before),
+		AfterHash:  StoredHash(change.After),
+	}
+
+	for attempt := 1; ; attempt++ {
+		entry.Sequence = l.last.Sequence + 1
+		entry.PrevHash = l.last.Hash
+		entry.Hash = EntryHash(entry)
+		err := l.repo.Append(entry)
+		if err == nil {
+			l.last = entry
+			return entry, nil
+		}
+		if !errors.Is(err, model.ErrAlreadyExists) || attempt == appendAttempts {
+			return model.AuditEntry{}, fmt.Errorf("cannot append audit entry: %w", err)
+		}
+		// Druga instanca je upisala zapis sa istim rednim brojem: nadovezujemo se na njen
+		last, err := l.repo.Last()
+		if err != nil {
+			return model.AuditEntry{}, fmt.Errorf("cannot read audit log: %w", err)
+		}
+		l.last = last
+	}
+}
+
+// Unrecorded beleži izmenu koja je sačuvana, ali nije upisana u log jer Record nije uspeo.
+// Takva rupa se ne vidi u lancu, pa je Verify prijavljuje dok server radi.
+func (l *Log) Unrecorded(change Change, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.unrecorded++
+	l.lastFailure = fmt.Sprintf("%s %s: %v", change.Operation, events.Key(change.Kind, change.Namespace, change.Name, change.Version), err)
+}
+
+// Entries vraća sve zapise redom
+func (l *Log) Entries() ([]model.AuditEntry, error) {
+	return l.repo.GetAll()
+}
+
+// Hash vraća SHA-256 JSON zapisa, odnosno prazan string ako zapis ne postoji
+func Hash(document interface{}) string {
+	if document == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// StoredHash vraća SHA-256 zapisa u obliku u kom je sačuvan, odnosno prazan string ako zapis ne postoji
+func StoredHash(stored json.RawMessage) string {
+	if stored == nil {
+		return ""
+	}
+	sum := sha256.Sum256(stored)
+	return hex.EncodeToString(sum[:])
+}
+
+// EntryHash računa heš zapisa preko svih polja osim samog Hash-a
+func EntryHash(entry model.AuditEntry) string {
+	entry.Hash = ""
+	return Hash(entry)
+}
@@ -0,0 +1,164 @@
+package audit
+
+import (
+	"errors"
+	"projekat/events"
+	"projekat/model"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryRepository je AuditRepository u memoriji kojem test može da pokvari upis ili zapise
+type memoryRepository struct {
+	mu      sync.Mutex
+	entries []model.AuditEntry
+	fail    error
+}
+
+func (r *memoryRepository) Append(entry model.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail != nil {
+		return r.fail
+	}
+	if n := len(r.entries); n > 0 && r.entries[n-1].Sequence >= entry.Sequence {
+		return model.AlreadyExistsf("audit entry %d already exists", entry.Sequence)
+	}
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *memoryRepository) Last() (model.AuditEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) == 0 {
+		return model.AuditEntry{}, model.NotFoundf("audit log is empty")
+	}
+	return r.entries[len(r.entries)-1], nil
+}
+
+func (r *memoryRepository) GetAll() ([]model.AuditEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]model.AuditEntry(nil), r.entries...), nil
+}
+
+func testChange(version int) Change {
+	return Change{Action: model.AuditCreate, Operation: "create", Kind: events.KindConfig, Name: "db", Version: version, After: []byte(`{}`)}
+}
+
+func recordChanges(t *testing.T, log *Log, n int) {
+	t.Helper()
+	for i := 1; i <= n; i++ {
+		if _, err := log.Record(Actor{Principal: "ci"}, testChange(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	cases := []struct {
+		name     string
+		tamper   func(entries []model.AuditEntry) []model.AuditEntry
+		brokenAt int64
+		reason   string
+	}{
+		{name: "intact", tamper: func(entries []model.AuditEntry) []model.AuditEntry { return entries }},
+		{name: "modified entry", tamper: func(entries []model.AuditEntry) []model.AuditEntry {
+			entries[1].Principal = "admin"
+			return entries
+		}, brokenAt: 2, reason: "hash does not match"},
+		{name: "deleted entry", tamper: func(entries []model.AuditEntry) []model.AuditEntry {
+			return append(entries[:1], entries[2:]...)
+		}, brokenAt: 3, reason: "sequence does not follow"},
+		{name: "truncated tail", tamper: func(entries []model.AuditEntry) []model.AuditEntry {
+			return entries[:2]
+		}, brokenAt: 3, reason: "log ends at entry 2"},
+		{name: "rewritten tail", tamper: func(entries []model.AuditEntry) []model.AuditEntry {
+			// Ponovo izračunat lanac je ispravan, ali poslednji zapis nije onaj koji je server upisao
+			entries[3].Principal = "admin"
+			entries[3].Hash = EntryHash(entries[3])
+			return entries
+		}, brokenAt: 4, reason: "does not match the one this server recorded"},
+		{name: "everything deleted", tamper: func(entries []model.AuditEntry) []model.AuditEntry {
+			return nil
+		}, brokenAt: 1, reason: "log ends at entry 0"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repo := &memoryRepository{}
+			log, err := NewLog(repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			recordChanges(t, log, 4)
+			repo.entries = c.tamper(repo.entries)
+
+			result, err := log.Verify()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Valid != (c.reason == "") || result.BrokenAt != c.brokenAt || !strings.Contains(result.Reason, c.reason) {
+				t.Errorf("got %+v, want brokenAt %d and a reason containing %q", result, c.brokenAt, c.reason)
+			}
+		})
+	}
+}
+
+func TestVerifyAfterRestart(t *testing.T) {
+	repo := &memoryRepository{}
+	log, err := NewLog(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordChanges(t, log, 3)
+
+	// Server posle restarta zatiče poslednji zapis, pa vidi brisanje koje dođe posle toga
+	restarted, err := NewLog(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := restarted.Verify(); err != nil || !result.Valid {
+		t.Fatalf("intact log after a restart: %+v, %v", result, err)
+	}
+	repo.entries = repo.entries[:2]
+	if result, _ := restarted.Verify(); result.Valid || result.BrokenAt != 3 {
+		t.Errorf("truncated log after a restart: %+v", result)
+	}
+
+	// Zapisi obrisani pre pokretanja se ne vide u lancu
+	restarted, err = NewLog(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := restarted.Verify(); !result.Valid || result.Entries != 2 {
+		t.Errorf("log truncated before a restart: %+v", result)
+	}
+}
+
+func TestVerifyReportsUnrecordedChanges(t *testing.T) {
+	repo := &memoryRepository{}
+	log, err := NewLog(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordChanges(t, log, 2)
+
+	repo.fail = errors.New("disk full")
+	change := testChange(3)
+	if _, err := log.Record(Actor{Principal: "ci"}, change); err == nil {
+		t.Fatal("Record succeeded with a failing repository")
+	}
+	log.Unrecorded(change, errors.New("disk full"))
+	repo.fail = nil
+	recordChanges(t, log, 1)
+
+	result, err := log.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.Unrecorded != 1 || result.Entries != 3 || !strings.Contains(result.LastFailure, "configs/db/3: disk full") {
+		t.Errorf("got %+v, want one unrecorded change", result)
+	}
+}
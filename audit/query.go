@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"fmt"
+	"projekat/model"
+	"strings"
+	"time"
+)
+
+// Query bira zapise audit loga. Prazna polja ne ograničavaju izbor.
+type Query struct {
+	Principal string
+	Action    string
+	Kind      string
+	Namespace string
+	// ResourcePrefix zadržava zapise čija putanja počinje prefiksom, npr. configs/db_config/
+	ResourcePrefix string
+	RequestID      string
+	Since          time.Time
+	Until          time.Time
+	// After preskače zapise do tog rednog broja, uključujući i njega (za sledeću stranu)
+	After int64
+	Limit int
+}
+
+// Page je jedna strana zapisa; Next je redni broj od koga se nastavlja, a 0 na poslednjoj strani
+type Page struct {
+	Entries []model.AuditEntry
+	Next    int64
+}
+
+// Matches proverava da li zapis zadovoljava filtere upita
+func (q Query) Matches(entry model.AuditEntry) bool {
+	switch {
+	case entry.Sequence <= q.After:
+		return false
+	case q.Principal != "" && entry.Principal != q.Principal:
+		return false
+	case q.Action != "" && entry.Action != q.Action:
+		return false
+	case q.Kind != "" && entry.Kind != q.Kind:
+		return false
+	case q.Namespace != "" && entry.Namespace != q.Namespace:
+		return false
+	case q.RequestID != "" && entry.RequestID != q.RequestID:
+		return false
+	case !strings.HasPrefix(entry.Resource, strings.TrimPrefix(q.ResourcePrefix, "/")):
+		return false
+	case !q.Since.IsZero() && entry.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !entry.Time.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// Find vraća jednu stranu zapisa koji zadovoljavaju upit, redom kojim su upisani
+func (l *Log) Find(query Query) (Page, error) {
+	entries, err := l.repo.GetAll()
+	if err != nil {
+		return Page{}, err
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = model.DefaultPageLimit
+	}
+
+	page := Page{Entries: make([]model.AuditEntry, 0)}
+	for _, entry := range entries {
+		if !query.Matches(entry) {
+			continue
+		}
+		if len(page.Entries) == limit {
+			page.Next = page.Entries[limit-1].Sequence
+			break
+		}
+		page.Entries = append(page.Entries, entry)
+	}
+	return page, nil
+}
+
+// Verification je rezultat provere lanca. Kod prekinutog lanca BrokenAt je redni broj prvog
+// zapisa koji se ne uklapa, a Reason objašnjava zašto. Unrecorded je broj izmena od pokretanja
+// servera koje nisu upisane u log, a LastFailure poslednja greška upisa.
+type Verification struct {
+	Valid       bool   `json:"valid"`
+	Entries     int    `json:"entries"`
+	LastHash    string `json:"lastHash,omitempty"`
+	BrokenAt    int64  `json:"brokenAt,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	Unrecorded  int64  `json:"unrecorded,omitempty"`
+	LastFailure string `json:"lastFailure,omitempty"`
+}
+
+// Verify ponovo računa heševe svih zapisa i proverava da se svaki nadovezuje na prethodni.
+// Izmenjen zapis ne odgovara svom hešu, a obrisan ili umetnut zapis prekida redne brojeve ili lanac.
+// Sam lanac ne otkriva zapise obrisane sa kraja, pa se kraj poredi i sa poslednjim zapisom koji je
+// upisao ovaj server; posle restarta to poređenje počinje od zapisa zatečenog pri pokretanju.
+func (l *Log) Verify() (Verification, error) {
+	l.mu.Lock()
+	last, unrecorded, lastFailure := l.last, l.unrecorded, l.lastFailure
+	l.mu.Unlock()
+	entries, err := l.repo.GetAll()
+	if err != nil {
+		return Verification{}, err
+	}
+
+	result := Verification{Valid: true, Entries: len(entries), Unrecorded: unrecorded, LastFailure: lastFailure}
+	var previous model.AuditEntry
+	for _, entry := range entries {
+		switch {
+		case entry.Sequence != previous.Sequence+1:
+			result.Reason = "sequence does not follow the previous entry"
+		case entry.PrevHash != previous.Hash:
+			result.Reason = "prevHash does not match the previous entry"
+		case entry.Hash != EntryHash(entry):
+			result.Reason = "hash does not match the entry contents"
+		}
+		if result.Reason != "" {
+			result.Valid = false
+			result.BrokenAt = entry.Sequence
+			return result, nil
+		}
+		if entry.Sequence == last.Sequence && entry.Hash != last.Hash {
+			result.Reason = "entry does not match the one this server recorded"
+			result.Valid = false
+			result.BrokenAt = entry.Sequence
+			return result, nil
+		}
+		previous = entry
+	}
+	result.LastHash = previous.Hash
+	// Druga instanca servera je mogla da doda zapise posle našeg poslednjeg, ali ne i da ih ukloni
+	if previous.Sequence < last.Sequence {
+		result.Valid = false
+		result.BrokenAt = previous.Sequence + 1
+		result.Reason = fmt.Sprintf("log ends at entry %d, but this server recorded entry %d", previous.Sequence, last.Sequence)
+		return result, nil
+	}
+	if unrecorded > 0 {
+		result.Valid = false
+		result.Reason = fmt.Sprintf("%d changes since the server started were not recorded", unrecorded)
+	}
+	return result, nil
+}
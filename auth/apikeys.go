@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// APIKeys su statički ključevi klijenata. Čuvaju se samo SHA-256 otisci, pa ni fajl
+// ni memorija servera ne sadrže same ključeve.
+type APIKeys struct {
+	principals map[string]Principal
+}
+
+// LoadAPIKeys čita fajl sa ključevima. Svaki red ima oblik "ime:sha256-hex[:grupa,grupa]";
+// prazni redovi i redovi koji počinju sa # se preskaču.
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	keys := &APIKeys{principals: make(map[string]Principal)}
+	names := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.Split(text, ":")
+		if len(parts) < 2 || len(parts) > 3 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("%s:%d: expected name:sha256-hex[:group,group]", path, line)
+		}
+		name := strings.TrimSpace(parts[0])
+		hash := strings.ToLower(strings.TrimSpace(parts[1]))
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("%s:%d: hash of key %s must be %d hex characters", path, line, name, 2*sha256.Size)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("%s:%d: duplicate key name %s", path, line, name)
+		}
+		if _, exists := keys.principals[hash]; exists {
+			return nil, fmt.Errorf("%s:%d: key %s has the same hash as another key", path, line, name)
+		}
+
+		principal := Principal{Subject: name, Method: MethodAPIKey}
+		if len(parts) == 3 {
+			principal.Groups = splitList(parts[2])
+		}
+		keys.principals[hash] = principal
+		names[name] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Lookup vraća klijenta kojem pripada ključ
+func (k *APIKeys) Lookup(key string) (Principal, bool) {
+	principal, ok := k.principals[HashAPIKey(key)]
+	return principal, ok
+}
+
+// HashAPIKey vraća otisak ključa u obliku u kojem se upisuje u fajl
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAPIKey pravi nasumičan ključ od 32 bajta
+func NewAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
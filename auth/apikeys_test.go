@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"projekat/model"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeAPIKeys(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "api-keys")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAPIKeysLookup(t *testing.T) {
+	keys, err := LoadAPIKeys(writeAPIKeys(t, "# klijenti\n\nci:"+HashAPIKey("ci-key")+":ops, dev\nadmin:"+strings.ToUpper(HashAPIKey("admin-key"))+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name  string
+		key   string
+		want  Principal
+		found bool
+	}{
+		{name: "key with groups", key: "ci-key", want: Principal{Subject: "ci", Groups: []string{"ops", "dev"}, Method: MethodAPIKey}, found: true},
+		{name: "key with uppercase hash", key: "admin-key", want: Principal{Subject: "admin", Method: MethodAPIKey}, found: true},
+		{name: "unknown key", key: "other-key"},
+		{name: "hash instead of the key", key: HashAPIKey("ci-key")},
+		{name: "empty key", key: ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			principal, found := keys.Lookup(c.key)
+			if found != c.found || !reflect.DeepEqual(principal, c.want) {
+				t.Errorf("got %+v, %v; want %+v, %v", principal, found, c.want, c.found)
+			}
+		})
+	}
+}
+
+func TestLoadAPIKeysRejectsInvalidFiles(t *testing.T) {
+	hash := HashAPIKey("key")
+	cases := map[string]string{
+		"missing hash":   "ci\n",
+		"short hash":     "ci:" + hash[:10] + "\n",
+		"not hex":        "ci:" + strings.Repeat("z", len(hash)) + "\n",
+		"empty name":     ":" + hash + "\n",
+		"too many parts": "ci:" + hash + ":ops:extra\n",
+		"duplicate name": "ci:" + hash + "\nci:" + HashAPIKey("other") + "\n",
+		"duplicate hash": "ci:" + hash + "\nadmin:" + hash + "\n",
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadAPIKeys(writeAPIKeys(t, content)); err == nil {
+				t.Error("file was accepted")
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys, err := LoadAPIKeys(writeAPIKeys(t, "ci:"+HashAPIKey("ci-key")+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := newTestVerifier(testHMACSecret, nil)
+	token := signToken(t, map[string]interface{}{"alg": "HS256"}, claims(nil), hmacSign(testHMACSecret))
+
+	cases := []struct {
+		name          string
+		authenticator *Authenticator
+		header        []string
+		want          Principal
+		wantErr       string
+	}{
+		{name: "X-API-Key", authenticator: NewAuthenticator(keys, verifier), header: []string{APIKeyHeader, "ci-key"}, want: Principal{Subject: "ci", Method: MethodAPIKey}},
+		{name: "API key as bearer", authenticator: NewAuthenticator(keys, verifier), header: []string{"Authorization", "Bearer ci-key"}, want: Principal{Subject: "ci", Method: MethodAPIKey}},
+		{name: "lowercase scheme", authenticator: NewAuthenticator(keys, verifier), header: []string{"Authorization", "bearer ci-key"}, want: Principal{Subject: "ci", Method: MethodAPIKey}},
+		{name: "JWT as bearer", authenticator: NewAuthenticator(keys, verifier), header: []string{"Authorization", "Bearer " + token}, want: Principal{Subject: "ci", Groups: []string{"ops"}, Method: MethodJWT}},
+		// X-API-Key ima prednost, pa neispravan ključ ne zamenjuje ispravan token
+		{name: "invalid X-API-Key with a valid token", authenticator: NewAuthenticator(keys, verifier), header: []string{APIKeyHeader, "wrong", "Authorization", "Bearer " + token}, wantErr: "invalid API key"},
+		{name: "unknown API key", authenticator: NewAuthenticator(keys, verifier), header: []string{"Authorization", "Bearer wrong"}, wantErr: "invalid API key"},
+		{name: "JWT without a verifier", authenticator: NewAuthenticator(keys, nil), header: []string{"Authorization", "Bearer " + token}, wantErr: "invalid API key"},
+		{name: "API key without keys", authenticator: NewAuthenticator(nil, verifier), header: []string{APIKeyHeader, "ci-key"}, wantErr: "API keys are not accepted"},
+		{name: "invalid JWT", authenticator: NewAuthenticator(keys, verifier), header: []string{"Authorization", "Bearer a.b.c"}, wantErr: "malformed token header"},
+		{name: "basic scheme", authenticator: NewAuthenticator(keys, verifier), header: []string{"Authorization", "Basic Y2k6Y2kta2V5"}, wantErr: "missing credentials"},
+		{name: "empty bearer", authenticator: NewAuthenticator(keys, verifier), header: []string{"Authorization", "Bearer "}, wantErr: "missing credentials"},
+		{name: "no credentials", authenticator: NewAuthenticator(keys, verifier), wantErr: "missing credentials"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/configs", nil)
+			for i := 0; i+1 < len(c.header); i += 2 {
+				req.Header.Set(c.header[i], c.header[i+1])
+			}
+			principal, err := c.authenticator.Authenticate(req)
+			if c.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(principal, c.want) {
+					t.Errorf("got %+v, want %+v", principal, c.want)
+				}
+				return
+			}
+			if !errors.Is(err, model.ErrUnauthenticated) || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("got %v, want ErrUnauthenticated containing %q", err, c.wantErr)
+			}
+		})
+	}
+}
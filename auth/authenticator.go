@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"net/http"
+	"projekat/model"
+	"strings"
+)
+
+// APIKeyHeader je zaglavlje u kojem klijent može da pošalje API ključ umesto Authorization
+const APIKeyHeader = "X-API-Key"
+
+// Authenticator proverava akreditive zahteva: API ključ (X-API-Key ili Authorization: Bearer)
+// ili JWT (Authorization: Bearer). Bez ključeva i bez provere tokena autentifikacija je isključena.
+type Authenticator struct {
+	apiKeys *APIKeys
+	jwt     *JWTVerifier
+}
+
+// NewAuthenticator pravi proveru akreditiva; apiKeys i jwt mogu biti nil
+func NewAuthenticator(apiKeys *APIKeys, jwt *JWTVerifier) *Authenticator {
+	return &Authenticator{
+		apiKeys: apiKeys,
+		jwt:     jwt,
+	}
+}
+
+// Enabled govori da li server zahteva akreditive
+func (a *Authenticator) Enabled() bool {
+	return a.apiKeys != nil || a.jwt != nil
+}
+
+// Authenticate vraća klijenta koji je poslao zahtev ili ErrUnauthenticated
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.apiKey(key)
+	}
+
+	scheme, credentials, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	credentials = strings.TrimSpace(credentials)
+	if !strings.EqualFold(scheme, "Bearer") || credentials == "" {
+		return Principal{}, model.Unauthenticatedf("missing credentials: send a bearer token in Authorization or an API key in %s", APIKeyHeader)
+	}
+	// JWT ima tri dela odvojena tačkama, a API ključevi ne sadrže tačke
+	if strings.Count(credentials, ".") == 2 && a.jwt != nil {
+		return a.jwt.Verify(credentials)
+	}
+	return a.apiKey(credentials)
+}
+
+func (a *Authenticator) apiKey(key string) (Principal, error) {
+	if a.apiKeys == nil {
+		return Principal{}, model.Unauthenticatedf("API keys are not accepted")
+	}
+	principal, ok := a.apiKeys.Lookup(key)
+	if !ok {
+		return Principal{}, model.Unauthenticatedf("invalid API key")
+	}
+	return principal, nil
+}
@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"projekat/model"
+	"strings"
+	"time"
+)
+
+// Dozvoljeno odstupanje satova pri proveri exp i nbf
+const clockSkew = time.Minute
+
+// JWTVerifier proverava potpis i rok važenja JWT tokena. HS* algoritmi se proveravaju HMAC
+// tajnom, a RS*, PS* i ES* ključevima iz JWKS-a; "none" i algoritmi bez ključa se odbijaju.
+type JWTVerifier struct {
+	hmacSecret []byte
+	jwks       *JWKS
+	// Ako su zadati, token mora da ih ima u "iss" odnosno "aud"
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// NewJWTVerifier pravi proveru tokena; hmacSecret ili jwks mogu biti prazni, ali ne oba
+func NewJWTVerifier(hmacSecret []byte, jwks *JWKS, issuer, audience string) *JWTVerifier {
+	if jwks == nil {
+		jwks = &JWKS{}
+	}
+	return &JWTVerifier{
+		hmacSecret: hmacSecret,
+		jwks:       jwks,
+		issuer:     issuer,
+		audience:   audience,
+		now:        time.Now,
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+type jwtClaims struct {
+	Subject   string       `json:"sub"`
+	Issuer    string       `json:"iss"`
+	Audience  jwtAudience  `json:"aud"`
+	ExpiresAt *json.Number `json:"exp"`
+	NotBefore *json.Number `json:"nbf"`
+	Groups    []string     `json:"groups"`
+}
+
+// jwtAudience prihvata "aud" kao jedan string ili kao listu
+type jwtAudience []string
+
+func (a *jwtAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = jwtAudience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("aud must be a string or a list of strings")
+	}
+	*a = list
+	return nil
+}
+
+// Verify proverava token i vraća klijenta iz njegovih claim-ova
+func (v *JWTVerifier) Verify(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, model.Unauthenticatedf("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, model.Unauthenticatedf("malformed token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, model.Unauthenticatedf("malformed token signature")
+	}
+	if err := v.verifySignature(header, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return Principal{}, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, model.Unauthenticatedf("malformed token claims")
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return Principal{}, err
+	}
+	return Principal{Subject: claims.Subject, Groups: claims.Groups, Method: MethodJWT}, nil
+}
+
+func (v *JWTVerifier) checkClaims(claims jwtClaims) error {
+	now := v.now()
+	if claims.Subject == "" {
+		return model.Unauthenticatedf("token has no subject")
+	}
+	if claims.ExpiresAt == nil {
+		return model.Unauthenticatedf("token has no expiry")
+	}
+	exp, err := numericDate(*claims.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	if now.After(exp.Add(clockSkew)) {
+		return model.Unauthenticatedf("token expired")
+	}
+	if claims.NotBefore != nil {
+		nbf, err := numericDate(*claims.NotBefore)
+		if err != nil {
+			return err
+		}
+		if now.Add(clockSkew).Before(nbf) {
+			return model.Unauthenticatedf("token is not valid yet")
+		}
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return model.Unauthenticatedf("token issuer is not accepted")
+	}
+	if v.audience != "" {
+		accepted := false
+		for _, audience := range claims.Audience {
+			accepted = accepted || audience == v.audience
+		}
+		if !accepted {
+			return model.Unauthenticatedf("token audience is not accepted")
+		}
+	}
+	return nil
+}
+
+func (v *JWTVerifier) verifySignature(header jwtHeader, signed, signature []byte) error {
+	hash, family, ok := jwtAlgorithm(header.Alg)
+	if !ok {
+		return model.Unauthenticatedf("token algorithm %q is not supported", header.Alg)
+	}
+	digest := hash.New()
+	digest.Write(signed)
+	sum := digest.Sum(nil)
+
+	if family == "HS" {
+		if len(v.hmacSecret) == 0 {
+			return model.Unauthenticatedf("token algorithm %q is not accepted", header.Alg)
+		}
+		mac := hmac.New(hash.New, v.hmacSecret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return model.Unauthenticatedf("invalid token signature")
+		}
+		return nil
+	}
+
+	// Ključ se bira po kid; bez kid se probaju svi ključevi odgovarajućeg tipa
+	for _, candidate := range v.jwks.keys {
+		if header.Kid != "" && candidate.kid != header.Kid {
+			continue
+		}
+		if candidate.alg != "" && candidate.alg != header.Alg {
+			continue
+		}
+		if verifyWithKey(candidate.key, family, hash, sum, signature) {
+			return nil
+		}
+	}
+	return model.Unauthenticatedf("invalid token signature")
+}
+
+func verifyWithKey(key crypto.PublicKey, family string, hash crypto.Hash, sum, signature []byte) bool {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		switch family {
+		case "RS":
+			return rsa.VerifyPKCS1v15(key, hash, sum, signature) == nil
+		case "PS":
+			return rsa.VerifyPSS(key, hash, sum, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case *ecdsa.PublicKey:
+		// JWS ES* potpis je r||s fiksne dužine, ne ASN.1, a kriva mora da odgovara algoritmu
+		bits := key.Curve.Params().BitSize
+		size := (bits + 7) / 8
+		if family != "ES" || len(signature) != 2*size || bits != ecdsaCurveBits[hash] {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(key, sum, r, s)
+	}
+	return false
+}
+
+// ecdsaCurveBits je veličina krive koju zahteva ES256, ES384 odnosno ES512
+var ecdsaCurveBits = map[crypto.Hash]int{
+	crypto.SHA256: 256,
+	crypto.SHA384: 384,
+	crypto.SHA512: 521,
+}
+
+// jwtAlgorithm vraća heš funkciju i porodicu (HS, RS, PS, ES) za ime algoritma iz zaglavlja
+func jwtAlgorithm(alg string) (crypto.Hash, string, bool) {
+	if len(alg) != 5 {
+		return 0, "", false
+	}
+	family := alg[:2]
+	if family != "HS" && family != "RS" && family != "PS" && family != "ES" {
+		return 0, "", false
+	}
+	switch alg[2:] {
+	case "256":
+		return crypto.SHA256, family, true
+	case "384":
+		return crypto.SHA384, family, true
+	case "512":
+		return crypto.SHA512, family, true
+	}
+	return 0, "", false
+}
+
+func decodeSegment(segment string, target interface{}) error {
+	content, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(content)))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+func numericDate(value json.Number) (time.Time, error) {
+	seconds, err := value.Float64()
+	if err != nil {
+		return time.Time{}, model.Unauthenticatedf("token dates must be numbers")
+	}
+	return time.Unix(int64(seconds), 0), nil
+}
@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"projekat/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testNow        = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	testHMACSecret = []byte("0123456789abcdef0123456789abcdef")
+)
+
+// testKeys su ključevi kojima testovi potpisuju tokene; JWKS sadrži njihove javne delove
+type testKeys struct {
+	rsa   *rsa.PrivateKey
+	ec    *ecdsa.PrivateKey
+	other *rsa.PrivateKey
+}
+
+var sharedKeys *testKeys
+
+func keys(t *testing.T) *testKeys {
+	t.Helper()
+	if sharedKeys != nil {
+		return sharedKeys
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharedKeys = &testKeys{rsa: rsaKey, ec: ecKey, other: otherKey}
+	return sharedKeys
+}
+
+func encodeSegment(t *testing.T, value interface{}) string {
+	t.Helper()
+	content, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(content)
+}
+
+// signToken pravi token sa zaglavljem i claim-ovima; sign vraća potpis za "zaglavlje.claim-ovi"
+func signToken(t *testing.T, header, claims map[string]interface{}, sign func(signed []byte) []byte) string {
+	t.Helper()
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func hmacSign(secret []byte) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		mac := hmac.New(crypto.SHA256.New, secret)
+		mac.Write(signed)
+		return mac.Sum(nil)
+	}
+}
+
+func rsaSign(t *testing.T, key *rsa.PrivateKey, pss bool) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		sum := crypto.SHA256.New()
+		sum.Write(signed)
+		var signature []byte
+		var err error
+		if pss {
+			signature, err = rsa.SignPSS(rand.Reader, key, crypto.SHA256, sum.Sum(nil), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum.Sum(nil))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+}
+
+func ecSign(t *testing.T, key *ecdsa.PrivateKey) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		sum := crypto.SHA256.New()
+		sum.Write(signed)
+		r, s, err := ecdsa.Sign(rand.Reader, key, sum.Sum(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature
+	}
+}
+
+func claims(overrides map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"sub":    "ci",
+		"iss":    "https://issuer.example",
+		"aud":    "projekat",
+		"exp":    testNow.Add(time.Hour).Unix(),
+		"groups": []string{"ops"},
+	}
+	for key, value := range overrides {
+		if value == nil {
+			delete(c, key)
+			continue
+		}
+		c[key] = value
+	}
+	return c
+}
+
+func base64URLInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+// writeJWKS upisuje javne ključeve u JWKS fajl i učitava ga kao server
+func writeJWKS(t *testing.T, k *testKeys) *JWKS {
+	t.Helper()
+	set := map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa1", "alg": "RS256", "n": base64URLInt(k.rsa.N), "e": base64URLInt(big.NewInt(int64(k.rsa.E)))},
+		{"kty": "RSA", "kid": "pss1", "alg": "PS256", "n": base64URLInt(k.rsa.N), "e": base64URLInt(big.NewInt(int64(k.rsa.E)))},
+		{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": base64URLInt(k.ec.X), "y": base64URLInt(k.ec.Y)},
+		{"kty": "RSA", "kid": "enc1", "use": "enc", "n": base64URLInt(k.other.N), "e": base64URLInt(big.NewInt(int64(k.other.E)))},
+	}}
+	content, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	jwks, err := LoadJWKS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jwks
+}
+
+func newTestVerifier(hmacSecret []byte, jwks *JWKS) *JWTVerifier {
+	verifier := NewJWTVerifier(hmacSecret, jwks, "https://issuer.example", "projekat")
+	verifier.now = func() time.Time { return testNow }
+	return verifier
+}
+
+func TestJWTVerify(t *testing.T) {
+	k := keys(t)
+	jwks := writeJWKS(t, k)
+	publicDER, err := x509.MarshalPKIXPublicKey(&k.rsa.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	rs := map[string]interface{}{"alg": "RS256", "kid": "rsa1"}
+	valid := signToken(t, hs, claims(nil), hmacSign(testHMACSecret))
+
+	cases := []struct {
+		name string
+		// verifier bez HMAC tajne prihvata samo tokene potpisane ključevima iz JWKS-a
+		withoutHMAC bool
+		token       string
+		wantErr     string
+	}{
+		{name: "HS256", token: valid},
+		{name: "RS256", token: signToken(t, rs, claims(nil), rsaSign(t, k.rsa, false))},
+		{name: "RS256 without kid", token: signToken(t, map[string]interface{}{"alg": "RS256"}, claims(nil), rsaSign(t, k.rsa, false))},
+		{name: "PS256", token: signToken(t, map[string]interface{}{"alg": "PS256", "kid": "pss1"}, claims(nil), rsaSign(t, k.rsa, true))},
+		{name: "ES256", token: signToken(t, map[string]interface{}{"alg": "ES256", "kid": "ec1"}, claims(nil), ecSign(t, k.ec))},
+		{name: "audience list", token: signToken(t, hs, claims(map[string]interface{}{"aud": []string{"other", "projekat"}}), hmacSign(testHMACSecret))},
+
+		{name: "alg none", token: signToken(t, map[string]interface{}{"alg": "none"}, claims(nil), func([]byte) []byte { return nil }), wantErr: `algorithm "none" is not supported`},
+		{name: "alg none without signature", token: strings.TrimSuffix(signToken(t, map[string]interface{}{"alg": "none"}, claims(nil), func([]byte) []byte { return nil }), "."), wantErr: "malformed token"},
+		{name: "HS256 signed with the RSA public key", withoutHMAC: true, token: signToken(t, map[string]interface{}{"alg": "HS256", "kid": "rsa1"}, claims(nil), hmacSign(publicDER)), wantErr: `algorithm "HS256" is not accepted`},
+		{name: "HS256 signed with the RSA public key and an HMAC secret configured", token: signToken(t, map[string]interface{}{"alg": "HS256", "kid": "rsa1"}, claims(nil), hmacSign(publicDER)), wantErr: "invalid token signature"},
+		{name: "RS256 header with an HMAC signature", token: signToken(t, rs, claims(nil), hmacSign(testHMACSecret)), wantErr: "invalid token signature"},
+		{name: "PS256 signature under an RS256 key", token: signToken(t, map[string]interface{}{"alg": "PS256", "kid": "rsa1"}, claims(nil), rsaSign(t, k.rsa, true)), wantErr: "invalid token signature"},
+		{name: "ES256 signature under an RSA kid", token: signToken(t, map[string]interface{}{"alg": "ES256", "kid": "rsa1"}, claims(nil), ecSign(t, k.ec)), wantErr: "invalid token signature"},
+		{name: "unknown kid", token: signToken(t, map[string]interface{}{"alg": "RS256", "kid": "missing"}, claims(nil), rsaSign(t, k.rsa, false)), wantErr: "invalid token signature"},
+		{name: "wrong kid", token: signToken(t, map[string]interface{}{"alg": "RS256", "kid": "ec1"}, claims(nil), rsaSign(t, k.rsa, false)), wantErr: "invalid token signature"},
+		{name: "key only for encryption", token: signToken(t, map[string]interface{}{"alg": "RS256", "kid": "enc1"}, claims(nil), rsaSign(t, k.other, false)), wantErr: "invalid token signature"},
+		{name: "signed by an unknown key", token: signToken(t, rs, claims(nil), rsaSign(t, k.other, false)), wantErr: "invalid token signature"},
+		{name: "wrong HMAC secret", token: signToken(t, hs, claims(nil), hmacSign([]byte("another secret of the same size!"))), wantErr: "invalid token signature"},
+		{name: "modified claims", token: strings.Split(valid, ".")[0] + "." + encodeSegment(t, claims(map[string]interface{}{"sub": "admin"})) + "." + strings.Split(valid, ".")[2], wantErr: "invalid token signature"},
+		{name: "unsupported algorithm", token: signToken(t, map[string]interface{}{"alg": "HS1"}, claims(nil), hmacSign(testHMACSecret)), wantErr: "not supported"},
+
+		{name: "expired", token: signToken(t, hs, claims(map[string]interface{}{"exp": testNow.Add(-2 * clockSkew).Unix()}), hmacSign(testHMACSecret)), wantErr: "token expired"},
+		{name: "expired within clock skew", token: signToken(t, hs, claims(map[string]interface{}{"exp": testNow.Add(-clockSkew / 2).Unix()}), hmacSign(testHMACSecret))},
+		{name: "without expiry", token: signToken(t, hs, claims(map[string]interface{}{"exp": nil}), hmacSign(testHMACSecret)), wantErr: "no expiry"},
+		{name: "not valid yet", token: signToken(t, hs, claims(map[string]interface{}{"nbf": testNow.Add(2 * clockSkew).Unix()}), hmacSign(testHMACSecret)), wantErr: "not valid yet"},
+		{name: "nbf within clock skew", token: signToken(t, hs, claims(map[string]interface{}{"nbf": testNow.Add(clockSkew / 2).Unix()}), hmacSign(testHMACSecret))},
+		{name: "date is not a number", token: signToken(t, hs, claims(map[string]interface{}{"exp": "tomorrow"}), hmacSign(testHMACSecret)), wantErr: "malformed token claims"},
+		{name: "without subject", token: signToken(t, hs, claims(map[string]interface{}{"sub": nil}), hmacSign(testHMACSecret)), wantErr: "no subject"},
+		{name: "wrong issuer", token: signToken(t, hs, claims(map[string]interface{}{"iss": "https://evil.example"}), hmacSign(testHMACSecret)), wantErr: "issuer is not accepted"},
+		{name: "wrong audience", token: signToken(t, hs, claims(map[string]interface{}{"aud": "other"}), hmacSign(testHMACSecret)), wantErr: "audience is not accepted"},
+		{name: "without audience", token: signToken(t, hs, claims(map[string]interface{}{"aud": nil}), hmacSign(testHMACSecret)), wantErr: "audience is not accepted"},
+
+		{name: "two segments", token: strings.Join(strings.Split(valid, ".")[:2], "."), wantErr: "malformed token"},
+		{name: "four segments", token: valid + ".x", wantErr: "malformed token"},
+		{name: "header is not base64url", token: "!!!." + strings.SplitN(valid, ".", 2)[1], wantErr: "malformed token header"},
+		{name: "header is not JSON", token: base64.RawURLEncoding.EncodeToString([]byte("alg")) + "." + strings.SplitN(valid, ".", 2)[1], wantErr: "malformed token header"},
+		{name: "signature is not base64url", token: strings.Join(strings.Split(valid, ".")[:2], ".") + ".a+b/", wantErr: "malformed token signature"},
+		{name: "claims are not JSON", token: signTokenRaw(strings.Split(valid, ".")[0], base64.RawURLEncoding.EncodeToString([]byte("sub")), testHMACSecret), wantErr: "malformed token claims"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			secret := testHMACSecret
+			if c.withoutHMAC {
+				secret = nil
+			}
+			principal, err := newTestVerifier(secret, jwks).Verify(c.token)
+			if c.wantErr == "" {
+				if err != nil {
+					t.Fatalf("valid token rejected: %v", err)
+				}
+				if principal.Subject != "ci" || principal.Method != MethodJWT || len(principal.Groups) != 1 || principal.Groups[0] != "ops" {
+					t.Errorf("unexpected principal %+v", principal)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("token accepted as %+v, want an error containing %q", principal, c.wantErr)
+			}
+			if !errors.Is(err, model.ErrUnauthenticated) {
+				t.Errorf("got %v, want ErrUnauthenticated", err)
+			}
+			if !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("got %q, want an error containing %q", err, c.wantErr)
+			}
+		})
+	}
+}
+
+// signTokenRaw potpisuje već kodirane delove, za tokene čiji sadržaj nije ispravan JSON
+func signTokenRaw(header, claims string, secret []byte) string {
+	signed := header + "." + claims
+	return signed + "." + base64.RawURLEncoding.EncodeToString(hmacSign(secret)([]byte(signed)))
+}
+
+func TestLoadJWKSRejectsWeakAndInvalidKeys(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"short RSA key":    `{"keys":[{"kty":"RSA","n":"` + base64URLInt(weak.N) + `","e":"AQAB"}]}`,
+		"point off curve":  `{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+		"unknown key type": `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`,
+		"no signing keys":  `{"keys":[]}`,
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "jwks.json")
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadJWKS(path); err == nil {
+				t.Error("JWKS was accepted")
+			}
+		})
+	}
+}
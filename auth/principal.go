@@ -0,0 +1,33 @@
+// Package auth proverava ko šalje zahtev: statičkim API ključevima iz fajla ili JWT tokenima
+// potpisanim HMAC tajnom ili ključem iz lokalnog JWKS fajla.
+package auth
+
+import "context"
+
+// Načini na koje se klijent predstavio
+const (
+	MethodAPIKey = "apiKey"
+	MethodJWT    = "jwt"
+)
+
+// Principal je klijent čiji su akreditivi provereni
+type Principal struct {
+	// Subject je ime klijenta: ime API ključa ili "sub" iz tokena
+	Subject string `json:"subject"`
+	// Groups su grupe klijenta: iz fajla sa ključevima ili iz "groups" u tokenu
+	Groups []string `json:"groups,omitempty"`
+	Method string   `json:"method"`
+}
+
+type principalKey struct{}
+
+// WithPrincipal vraća kontekst koji nosi proverenog klijenta
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext vraća klijenta iz konteksta zahteva; ok je false ako autentifikacija nije uključena
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
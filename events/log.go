@@ -0,0 +1,203 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"projekat/model"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Type je vrsta izmene zapisa
+type Type string
+
+const (
+	Created Type = "created"
+	Updated Type = "updated"
+	Deleted Type = "deleted"
+)
+
+// Kind je vrsta zapisa na koji se događaj odnosi
+type Kind string
+
+const (
+	KindConfig      Kind = "config"
+	KindConfigGroup Kind = "configGroup"
+)
+
+// DefaultHistory je podrazumevan broj poslednjih događaja koji se čuvaju za nastavak praćenja
+const DefaultHistory = 1000
+
+// Event opisuje jednu izmenu u repozitorijumu. Događaj ne nosi sadržaj zapisa,
+// pa ne otkriva tajne parametre; klijent ga po potrebi čita preko Key.
+type Event struct {
+	// Revision je globalna revizija skladišta posle ove izmene; raste za svaku izmenu bilo kog zapisa
+	Revision int64 `json:"revision"`
+	Type     Type  `json:"type"`
+	Kind     Kind  `json:"kind"`
+	// Key je putanja zapisa bez početne kose crte, npr. configs/db_config/2 ili, van
+	// podrazumevanog prostora imena, namespaces/payments/configs/db_config/2
+	Key       string `json:"key"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Version   int    `json:"version"`
+	// ResourceRevision je revizija zapisa posle izmene (njegov ETag); izostavlja se za brisanje
+	ResourceRevision int64     `json:"resourceRevision,omitempty"`
+	Time             time.Time `json:"time"`
+}
+
+// Key vraća putanju zapisa po kojoj se događaji filtriraju prefiksom
+func Key(kind Kind, namespace, name string, version int) string {
+	switch kind {
+	case KindConfigGroup:
+		return fmt.Sprintf("%sconfigGroups/%s/%d", model.NamespacePrefix(namespace), name, version)
+	default:
+		return fmt.Sprintf("%sconfigs/%s/%d", model.NamespacePrefix(namespace), name, version)
+	}
+}
+
+// Matches proverava da li putanja događaja počinje prefiksom
+func (e Event) Matches(prefix string) bool {
+	return strings.HasPrefix(e.Key, strings.TrimPrefix(prefix, "/"))
+}
+
+// Log dodeljuje izmenama globalnu reviziju i čuva ograničenu istoriju poslednjih događaja.
+// Revizija se čuva samo u memoriji i posle restarta kreće od nule, pa svaki log ima svoju epohu:
+// klijent nastavlja praćenje tokenom koji nosi i epohu, a token druge epohe (od pre restarta ili
+// sa druge instance) se odbija umesto da se pogrešno protumači kao revizija ovog loga.
+//
+// Log postoji samo u jednom procesu: revizije prate redosled izmena koje su prošle kroz ovaj server,
+// a izmene koje druga instanca upiše u isti Consul se u njemu ne pojavljuju. Praćenje izmena je
+// zato ispravno samo kada svi upisi idu kroz jednu instancu.
+type Log struct {
+	// commits drži upis u repozitorijum zajedno sa objavom njegovog događaja, pa revizije prate
+	// redosled upisa; odvojen je od mu da čitanje istorije ne bi čekalo na spor upis
+	commits  sync.Mutex
+	mu       sync.Mutex
+	epoch    string
+	revision int64
+	capacity int
+	history  []Event
+	// changed se zatvara i zamenjuje novim pri svakom događaju, pa budi sve koji čekaju
+	changed chan struct{}
+}
+
+// NewLog pravi log koji čuva poslednjih capacity događaja (capacity mora biti pozitivan)
+func NewLog(capacity int) *Log {
+	return &Log{
+		epoch:    strconv.FormatInt(time.Now().UnixNano(), 36),
+		capacity: capacity,
+		changed:  make(chan struct{}),
+	}
+}
+
+// Token vraća token za nastavak praćenja posle revizije: epohu loga i reviziju, npr. lq3x8k2f1a.42
+func (l *Log) Token(revision int64) string {
+	return l.epoch + "." + strconv.FormatInt(revision, 10)
+}
+
+// ParseToken vraća reviziju iz tokena. Token druge epohe, kao i revizija bez epohe osim 0 (početak
+// loga), vraća ErrGone, jer se ne zna koje izmene klijent nije video.
+func (l *Log) ParseToken(token string) (int64, error) {
+	epoch, value, hasEpoch := strings.Cut(token, ".")
+	if !hasEpoch {
+		epoch, value = "", token
+	}
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision < 0 {
+		return 0, model.Invalidf("invalid revision token %q", token)
+	}
+	if !hasEpoch && revision == 0 {
+		return 0, nil
+	}
+	if epoch != l.epoch {
+		return 0, model.Gonef("revision token %q is not from this run of the server; read the current state and watch again without since", token)
+	}
+	return revision, nil
+}
+
+// Publish dodeljuje događaju sledeću globalnu reviziju i budi sve koji čekaju na izmene
+func (l *Log) Publish(event Event) Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.revision++
+	event.Revision = l.revision
+	event.Key = Key(event.Kind, event.Namespace, event.Name, event.Version)
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	l.history = append(l.history, event)
+	if len(l.history) > l.capacity {
+		l.history = l.history[len(l.history)-l.capacity:]
+	}
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return event
+}
+
+// Revision vraća reviziju poslednje izmene
+func (l *Log) Revision() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.revision
+}
+
+// Since vraća događaje posle revizije since čija putanja počinje prefiksom, i reviziju do koje su
+// pregledani. Ako su neki događaji posle since već izbačeni iz istorije, ili je since iz budućnosti,
+// vraća ErrGone: klijent tada mora ponovo da pročita stanje.
+func (l *Log) Since(since int64, prefix string) ([]Event, int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	events, err := l.since(since, prefix)
+	return events, l.revision, err
+}
+
+func (l *Log) since(since int64, prefix string) ([]Event, error) {
+	if since > l.revision {
+		return nil, model.Gonef("revision %d is newer than the current revision %d", since, l.revision)
+	}
+	if since == l.revision {
+		return []Event{}, nil
+	}
+	oldest := l.history[0].Revision
+	if since < oldest-1 {
+		return nil, model.Gonef("events after revision %d are no longer kept; the oldest kept revision is %d", since, oldest)
+	}
+
+	// Revizije u istoriji su uzastopne, pa se prvi traženi događaj nalazi direktno
+	matching := make([]Event, 0)
+	for _, event := range l.history[since-oldest+1:] {
+		if event.Matches(prefix) {
+			matching = append(matching, event)
+		}
+	}
+	return matching, nil
+}
+
+// Wait vraća događaje posle revizije since koji odgovaraju prefiksu, a ako ih nema čeka da se
+// pojave ili da ctx istekne. Po isteku vraća praznu listu i trenutnu reviziju, bez greške.
+func (l *Log) Wait(ctx context.Context, since int64, prefix string) ([]Event, int64, error) {
+	for {
+		l.mu.Lock()
+		events, err := l.since(since, prefix)
+		revision, changed := l.revision, l.changed
+		l.mu.Unlock()
+
+		if err != nil || len(events) > 0 {
+			return events, revision, err
+		}
+		// Događaji koji ne odgovaraju prefiksu su pregledani, pa se sledeći put kreće od njih
+		since = revision
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return events, revision, nil
+		}
+	}
+}
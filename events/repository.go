@@ -0,0 +1,275 @@
+package events
+
+import "projekat/model"
+
+// ConfigRepository objavljuje događaj za svaku uspešnu izmenu konfiguracija.
+// Događaj se objavljuje posle upisa, pa ga čitalac uvek vidi zajedno sa izmenom. Revizija zapisa
+// u događaju je ona koju je upis dodelio, iz Change-a koji vraća repozitorijum. Upis i objava se
+// izvršavaju pod redosledom izmena loga, pa globalne revizije prate redosled upisa: dve istovremene
+// izmene istog zapisa ne mogu da se objave obrnuto od onoga kako su upisane.
+type ConfigRepository struct {
+	repo      model.ConfigRepository
+	log       *Log
+	namespace string
+}
+
+func NewConfigRepository(repo model.ConfigRepository, log *Log) model.ConfigRepository {
+	return &ConfigRepository{
+		repo:      repo,
+		log:       log,
+		namespace: model.DefaultNamespace,
+	}
+}
+
+func (r *ConfigRepository) In(namespace string) model.ConfigRepository {
+	return &ConfigRepository{
+		repo:      r.repo.In(namespace),
+		log:       r.log,
+		namespace: namespace,
+	}
+}
+
+func (r *ConfigRepository) publish(eventType Type, name string, version int, revision int64) {
+	r.log.Publish(Event{Type: eventType, Kind: KindConfig, Namespace: r.namespace, Name: name, Version: version, ResourceRevision: revision})
+}
+
+func (r *ConfigRepository) Create(config model.Config) (model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	change, err := r.repo.Create(config)
+	if err != nil {
+		return model.Change{}, err
+	}
+	r.publish(Created, config.Name, config.Version, change.Revision)
+	return change, nil
+}
+
+func (r *ConfigRepository) Update(config model.Config) (model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	change, err := r.repo.Update(config)
+	if err != nil {
+		return model.Change{}, err
+	}
+	r.publish(Updated, config.Name, config.Version, change.Revision)
+	return change, nil
+}
+
+func (r *ConfigRepository) CreateNextVersion(config model.Config) (model.Config, model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	created, change, err := r.repo.CreateNextVersion(config)
+	if err != nil {
+		return model.Config{}, model.Change{}, err
+	}
+	r.publish(Created, created.Name, created.Version, change.Revision)
+	return created, change, nil
+}
+
+func (r *ConfigRepository) Read(name string, version int) (model.Config, error) {
+	return r.repo.Read(name, version)
+}
+
+func (r *ConfigRepository) Delete(name string, version int, expectedRevision int64) (model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	change, err := r.repo.Delete(name, version, expectedRevision)
+	if err != nil {
+		return model.Change{}, err
+	}
+	r.publish(Deleted, name, version, 0)
+	return change, nil
+}
+
+func (r *ConfigRepository) Add(config model.Config) (model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	change, err := r.repo.Add(config)
+	if err != nil {
+		return model.Change{}, err
+	}
+	r.publish(addedType(change), config.Name, config.Version, change.Revision)
+	return change, nil
+}
+
+func (r *ConfigRepository) Get(name string, version int) (model.Config, error) {
+	return r.repo.Get(name, version)
+}
+
+func (r *ConfigRepository) GetAll() ([]model.Config, error) {
+	return r.repo.GetAll()
+}
+
+func (r *ConfigRepository) ListByName(name string) ([]model.Config, error) {
+	return r.repo.ListByName(name)
+}
+
+func (r *ConfigRepository) List(query model.ListQuery) (model.ConfigPage, error) {
+	return r.repo.List(query)
+}
+
+// ConfigGroupRepository objavljuje događaj za svaku uspešnu izmenu grupa. Izmene sadržaja grupe
+// (dodavanje i uklanjanje konfiguracija i referenci) objavljuju se kao updated.
+type ConfigGroupRepository struct {
+	repo      model.ConfigGroupRepository
+	log       *Log
+	namespace string
+}
+
+func NewConfigGroupRepository(repo model.ConfigGroupRepository, log *Log) model.ConfigGroupRepository {
+	return &ConfigGroupRepository{
+		repo:      repo,
+		log:       log,
+		namespace: model.DefaultNamespace,
+	}
+}
+
+func (r *ConfigGroupRepository) In(namespace string) model.ConfigGroupRepository {
+	return &ConfigGroupRepository{
+		repo:      r.repo.In(namespace),
+		log:       r.log,
+		namespace: namespace,
+	}
+}
+
+func (r *ConfigGroupRepository) publish(eventType Type, name string, version int, revision int64) {
+	r.log.Publish(Event{Type: eventType, Kind: KindConfigGroup, Namespace: r.namespace, Name: name, Version: version, ResourceRevision: revision})
+}
+
+func (r *ConfigGroupRepository) Create(configGroup model.ConfigGroup) (model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	change, err := r.repo.Create(configGroup)
+	if err != nil {
+		return model.Change{}, err
+	}
+	r.publish(Created, configGroup.Name, configGroup.Version, change.Revision)
+	return change, nil
+}
+
+func (r *ConfigGroupRepository) CreateNextVersion(configGroup model.ConfigGroup) (model.ConfigGroup, model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	created, change, err := r.repo.CreateNextVersion(configGroup)
+	if err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	r.publish(Created, created.Name, created.Version, change.Revision)
+	return created, change, nil
+}
+
+func (r *ConfigGroupRepository) Read(name string, version int) (model.ConfigGroup, error) {
+	return r.repo.Read(name, version)
+}
+
+func (r *ConfigGroupRepository) Update(configGroup model.ConfigGroup) (model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	change, err := r.repo.Update(configGroup)
+	if err != nil {
+		return model.Change{}, err
+	}
+	r.publish(Updated, configGroup.Name, configGroup.Version, change.Revision)
+	return change, nil
+}
+
+func (r *ConfigGroupRepository) Delete(name string, version int, expectedRevision int64) (model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	change, err := r.repo.Delete(name, version, expectedRevision)
+	if err != nil {
+		return model.Change{}, err
+	}
+	r.publish(Deleted, name, version, 0)
+	return change, nil
+}
+
+func (r *ConfigGroupRepository) GetAll() ([]model.ConfigGroup, error) {
+	return r.repo.GetAll()
+}
+
+func (r *ConfigGroupRepository) ListByName(name string) ([]model.ConfigGroup, error) {
+	return r.repo.ListByName(name)
+}
+
+func (r *ConfigGroupRepository) List(query model.ListQuery) (model.ConfigGroupPage, error) {
+	return r.repo.List(query)
+}
+
+func (r *ConfigGroupRepository) Add(configGroup model.ConfigGroup) (model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	change, err := r.repo.Add(configGroup)
+	if err != nil {
+		return model.Change{}, err
+	}
+	r.publish(addedType(change), configGroup.Name, configGroup.Version, change.Revision)
+	return change, nil
+}
+
+func (r *ConfigGroupRepository) Get(name string, version int) (model.ConfigGroup, error) {
+	return r.repo.Get(name, version)
+}
+
+func (r *ConfigGroupRepository) RemoveConfig(groupName string, groupVersion int, configName string, configVersion int) (model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	change, err := r.repo.RemoveConfig(groupName, groupVersion, configName, configVersion)
+	if err != nil {
+		return model.Change{}, err
+	}
+	r.publish(Updated, groupName, groupVersion, change.Revision)
+	return change, nil
+}
+
+func (r *ConfigGroupRepository) AddConfig(groupName string, groupVersion int, config model.Config) (model.ConfigGroup, model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	configGroup, change, err := r.repo.AddConfig(groupName, groupVersion, config)
+	if err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	r.publish(Updated, groupName, groupVersion, change.Revision)
+	return configGroup, change, nil
+}
+
+func (r *ConfigGroupRepository) AddReference(groupName string, groupVersion int, reference model.ConfigReference) (model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	change, err := r.repo.AddReference(groupName, groupVersion, reference)
+	if err != nil {
+		return model.Change{}, err
+	}
+	r.publish(Updated, groupName, groupVersion, change.Revision)
+	return change, nil
+}
+
+func (r *ConfigGroupRepository) RemoveReference(groupName string, groupVersion int, configName string, configVersion int) (model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	change, err := r.repo.RemoveReference(groupName, groupVersion, configName, configVersion)
+	if err != nil {
+		return model.Change{}, err
+	}
+	r.publish(Updated, groupName, groupVersion, change.Revision)
+	return change, nil
+}
+
+func (r *ConfigGroupRepository) RemoveConfigsByLabels(groupName string, groupVersion int, selector map[string]string) ([]model.Config, model.Change, error) {
+	r.log.commits.Lock()
+	defer r.log.commits.Unlock()
+	removed, change, err := r.repo.RemoveConfigsByLabels(groupName, groupVersion, selector)
+	if err != nil {
+		return nil, model.Change{}, err
+	}
+	r.publish(Updated, groupName, groupVersion, change.Revision)
+	return removed, change, nil
+}
+
+// addedType vraća vrstu događaja za Add, koji zamenjuje postojeći zapis ako ga ima
+func addedType(change model.Change) Type {
+	if change.Before == nil {
+		return Created
+	}
+	return Updated
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"projekat/model"
+	"projekat/repositories"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowConfigRepository posle prve uspešne izmene javi da je upisana i zadrži se pre povratka,
+// kao upis u Consul čiji se odgovor vraća sporo
+type slowConfigRepository struct {
+	model.ConfigRepository
+	slowed    int32
+	committed chan struct{}
+}
+
+func (r *slowConfigRepository) Update(config model.Config) (model.Change, error) {
+	change, err := r.ConfigRepository.Update(config)
+	if err == nil && atomic.CompareAndSwapInt32(&r.slowed, 0, 1) {
+		close(r.committed)
+		time.Sleep(50 * time.Millisecond)
+	}
+	return change, err
+}
+
+// Izmena koja je upisana posle druge, ali se brže vratila, ne sme da dobije raniju reviziju
+func TestPublishFollowsCommitOrder(t *testing.T) {
+	log := NewLog(DefaultHistory)
+	slow := &slowConfigRepository{ConfigRepository: repositories.NewConfigInMemRepository(), committed: make(chan struct{})}
+	repo := NewConfigRepository(slow, log)
+	if _, err := repo.Create(model.NewConfig("db", 1, model.Parameters{"host": "a"})); err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(host string) error {
+		config, err := slow.Get("db", 1)
+		if err != nil {
+			return err
+		}
+		config.Parameters["host"] = host
+		_, err = repo.Update(config)
+		return err
+	}
+
+	first := make(chan error)
+	go func() { first <- update("b") }()
+	<-slow.committed
+	if err := update("c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+
+	found, _, err := log.Since(0, "configs/db/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(found), found)
+	}
+	for i := 1; i < len(found); i++ {
+		if found[i].ResourceRevision <= found[i-1].ResourceRevision {
+			t.Errorf("event %d has resource revision %d after %d; events are not in commit order",
+				found[i].Revision, found[i].ResourceRevision, found[i-1].ResourceRevision)
+		}
+	}
+	stored, err := slow.Get("db", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := found[len(found)-1]; last.ResourceRevision != stored.Revision {
+		t.Errorf("last event has resource revision %d, stored config has %d", last.ResourceRevision, stored.Revision)
+	}
+}
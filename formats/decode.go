@@ -0,0 +1,262 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"projekat/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnsupported znači da se vrednost ne može pročitati iz datog formata
+var ErrUnsupported = errors.New("unsupported format")
+
+// DecodeConfig čita konfiguraciju iz tela zahteva. Iz ravnih formata se čitaju samo parametri
+// (sve vrednosti su stringovi), pa ime, verziju i labele postavlja pozivalac.
+func DecodeConfig(format Format, data []byte) (model.Config, error) {
+	var config model.Config
+	if !format.Flat() {
+		if err := decodeDocument(format, data, &config); err != nil {
+			return model.Config{}, err
+		}
+		return config, nil
+	}
+
+	var values map[string]string
+	var err error
+	switch format {
+	case Env:
+		values, err = parseEnv(data)
+	case Properties:
+		values, err = parseProperties(data)
+	case INI:
+		var sections []iniSection
+		sections, err = parseINI(data)
+		if err == nil {
+			if len(sections) > 1 {
+				return model.Config{}, model.Invalidf("ini: a config must not have sections")
+			}
+			values = sections[0].values
+		}
+	}
+	if err != nil {
+		return model.Config{}, model.Invalidf("%s: %v", format, err)
+	}
+	config.Parameters = stringParameters(values)
+	return config, nil
+}
+
+// DecodeConfigGroup čita grupu iz tela zahteva. Od ravnih formata podržan je samo INI, gde je
+// svaka konfiguracija jedna sekcija [ime@verzija]; ime i verziju grupe postavlja pozivalac.
+func DecodeConfigGroup(format Format, data []byte) (model.ConfigGroup, error) {
+	var configGroup model.ConfigGroup
+	switch format {
+	case Env, Properties:
+		return model.ConfigGroup{}, fmt.Errorf("%w: config groups cannot be read from %s, use ini, json, yaml or toml", ErrUnsupported, format)
+	case INI:
+		sections, err := parseINI(data)
+		if err != nil {
+			return model.ConfigGroup{}, model.Invalidf("ini: %v", err)
+		}
+		if len(sections[0].values) > 0 {
+			return model.ConfigGroup{}, model.Invalidf("ini: parameters must be inside a [name@version] section")
+		}
+		configGroup.Configuration = make([]model.Config, 0, len(sections)-1)
+		for _, section := range sections[1:] {
+			name, version, ok := strings.Cut(section.name, "@")
+			versionInt, err := strconv.Atoi(version)
+			if !ok || err != nil {
+				return model.ConfigGroup{}, model.Invalidf("ini: section [%s] must be named name@version", section.name)
+			}
+			configGroup.Configuration = append(configGroup.Configuration, model.Config{
+				Name:       name,
+				Version:    versionInt,
+				Parameters: stringParameters(section.values),
+			})
+		}
+		return configGroup, nil
+	}
+
+	if err := decodeDocument(format, data, &configGroup); err != nil {
+		return model.ConfigGroup{}, err
+	}
+	return configGroup, nil
+}
+
+func stringParameters(values map[string]string) model.Parameters {
+	parameters := make(model.Parameters, len(values))
+	for key, value := range values {
+		parameters[key] = value
+	}
+	return parameters
+}
+
+// decodeDocument čita strukturni format: dokument se prevede u JSON, pa se dekodira kao i JSON telo
+func decodeDocument(format Format, data []byte, target interface{}) error {
+	var document interface{}
+	switch format {
+	case YAML:
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			// Greške biblioteke već počinju sa "yaml: "
+			return model.Invalidf("%v", err)
+		}
+		value, err := yamlValue(&node)
+		if err != nil {
+			return model.Invalidf("yaml: %v", err)
+		}
+		document = value
+	case TOML:
+		var table map[string]interface{}
+		if _, err := toml.Decode(string(data), &table); err != nil {
+			return model.Invalidf("%v", err)
+		}
+		value, err := fromTOML(table)
+		if err != nil {
+			return model.Invalidf("toml: %v", err)
+		}
+		document = value
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		if err := decoder.Decode(target); err != nil {
+			return model.Invalidf("json: %v", err)
+		}
+		return nil
+	}
+
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(encoded, target); err != nil {
+		return model.Invalidf("%s: %v", format, err)
+	}
+	return nil
+}
+
+// yamlValue prevodi YAML čvor u JSON vrednost; brojevi zadržavaju zapis iz dokumenta
+func yamlValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(node.Content[0])
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.MappingNode:
+		values := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: mapping keys must be scalars", key.Line)
+			}
+			value, err := yamlValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			values[key.Value] = value
+		}
+		return values, nil
+	case yaml.SequenceNode:
+		values := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var value bool
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case "!!int":
+		// Celi brojevi bilo koje veličine, i u zapisima 0x, 0o i 0b, postaju decimalni JSON brojevi
+		value := strings.TrimPrefix(node.Value, "+")
+		base := 0
+		if strings.TrimLeft(strings.TrimPrefix(value, "-"), "0123456789") == "" {
+			base = 10
+		}
+		n, ok := new(big.Int).SetString(value, base)
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid integer %s", node.Line, node.Value)
+		}
+		return json.Number(n.String()), nil
+	case "!!float":
+		if json.Valid([]byte(node.Value)) {
+			return json.Number(node.Value), nil
+		}
+		var value float64
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return nil, fmt.Errorf("line %d: %s cannot be represented in JSON", node.Line, node.Value)
+		}
+		return json.Number(strconv.FormatFloat(value, 'g', -1, 64)), nil
+	}
+	return node.Value, nil
+}
+
+// fromTOML prevodi TOML vrednosti u JSON vrednosti
+func fromTOML(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		values := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted, err := fromTOML(item)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = converted
+		}
+		return values, nil
+	case []map[string]interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			converted, err := fromTOML(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, converted)
+		}
+		return values, nil
+	case []interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			converted, err := fromTOML(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, converted)
+		}
+		return values, nil
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10)), nil
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, fmt.Errorf("%v cannot be represented in JSON", v)
+		}
+		return json.Number(strconv.FormatFloat(v, 'g', -1, 64)), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	}
+	return value, nil
+}
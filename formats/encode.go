@@ -0,0 +1,219 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"projekat/model"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EncodeConfig prikazuje konfiguraciju u datom formatu. Za ravne formate vraća ErrUnsupported
+// ako bi dva parametra bila napisana pod istim ključem.
+func EncodeConfig(format Format, config model.Config) ([]byte, error) {
+	if !format.Flat() {
+		return encodeDocument(format, config)
+	}
+
+	var b strings.Builder
+	var err error
+	header := fmt.Sprintf("config %s/%d", config.Name, config.Version)
+	entries := flatten(config.Parameters)
+	switch format {
+	case Env:
+		fmt.Fprintf(&b, "# %s\n", header)
+		err = writeEnv(&b, keySet{}, nil, entries)
+	case Properties:
+		fmt.Fprintf(&b, "# %s\n", escapeProperties(header, false))
+		err = writeProperties(&b, keySet{}, nil, entries)
+	case INI:
+		fmt.Fprintf(&b, "; %s\n", header)
+		err = writeINI(&b, entries)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+// EncodeConfigGroup prikazuje grupu u datom formatu. Ravni formati sadrže parametre svih
+// konfiguracija grupe, i ugrađenih i onih na koje grupa upućuje. Ključevi svih konfiguracija
+// dele isti fajl (u INI svaka konfiguracija ima svoju sekciju), pa se ErrUnsupported vraća i kada
+// se ključevi dve konfiguracije poklope, npr. u .env za imena db i DB.
+func EncodeConfigGroup(format Format, configGroup model.ConfigGroup) ([]byte, error) {
+	if !format.Flat() {
+		return encodeDocument(format, configGroup)
+	}
+
+	var b strings.Builder
+	header := fmt.Sprintf("config group %s/%d", configGroup.Name, configGroup.Version)
+	configs := configGroup.Configs()
+	labels := configLabels(configs)
+	keys := keySet{}
+	switch format {
+	case Env:
+		fmt.Fprintf(&b, "# %s\n", header)
+		for i, config := range configs {
+			fmt.Fprintf(&b, "\n# %s/%d\n", config.Name, config.Version)
+			if err := writeEnv(&b, keys, []string{labels[i]}, flatten(config.Parameters)); err != nil {
+				return nil, err
+			}
+		}
+	case Properties:
+		fmt.Fprintf(&b, "# %s\n", escapeProperties(header, false))
+		for i, config := range configs {
+			fmt.Fprintf(&b, "\n# %s/%d\n", escapeProperties(config.Name, false), config.Version)
+			if err := writeProperties(&b, keys, []string{labels[i]}, flatten(config.Parameters)); err != nil {
+				return nil, err
+			}
+		}
+	case INI:
+		fmt.Fprintf(&b, "; %s\n", header)
+		for i, config := range configs {
+			fmt.Fprintf(&b, "\n[%s]\n", labels[i])
+			if err := writeINI(&b, flatten(config.Parameters)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// encodeDocument prikazuje vrednost u strukturnom formatu, sa istim poljima kao u JSON-u
+func encodeDocument(format Format, value interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case YAML:
+		node, err := jsonToYAML(json.NewDecoder(bytes.NewReader(encoded)))
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		encoder := yaml.NewEncoder(&b)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(node); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	case TOML:
+		document, err := decodeJSON(encoded)
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		if err := toml.NewEncoder(&b).Encode(tomlValue(document)); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+	return encoded, nil
+}
+
+// jsonToYAML prevodi JSON u YAML čvor čitajući tokene redom, pa polja zadržavaju redosled
+// iz JSON-a, a brojevi tačno onaj zapis koji su imali
+func jsonToYAML(decoder *json.Decoder) (*yaml.Node, error) {
+	decoder.UseNumber()
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '{' {
+			node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := jsonToYAML(decoder)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)}, value)
+			}
+			_, err := decoder.Token()
+			return node, err
+		}
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for decoder.More() {
+			value, err := jsonToYAML(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, value)
+		}
+		_, err := decoder.Token()
+		return node, err
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: t}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(t.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: t.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(t)}, nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return value, nil
+}
+
+// tomlValue prilagođava JSON vrednost TOML-u: TOML nema null, pa se takve vrednosti izostavljaju,
+// a celi brojevi su 64-bitni, pa se veći celi brojevi pišu kao stringovi
+func tomlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		table := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if item != nil {
+				table[key] = tomlValue(item)
+			}
+		}
+		return table
+	case []interface{}:
+		array := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if item != nil {
+				array = append(array, tomlValue(item))
+			}
+		}
+		return array
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if !strings.ContainsAny(v.String(), ".eE") {
+			return v.String()
+		}
+		if f, err := v.Float64(); err == nil && !math.IsInf(f, 0) {
+			return f
+		}
+		return v.String()
+	}
+	return value
+}
@@ -0,0 +1,99 @@
+package formats
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+func writeEnv(b *strings.Builder, keys keySet, prefix []string, entries []entry) error {
+	for _, e := range entries {
+		path := append(append([]string(nil), prefix...), e.path...)
+		key := envKey(path)
+		if err := keys.add(key, path); err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "%s=%s\n", key, quoteEnv(e.value))
+	}
+	return nil
+}
+
+// quoteEnv ostavlja jednostavne vrednosti bez navodnika, ostale stavlja u jednostruke navodnike
+// (bez tumačenja sadržaja), a vrednosti sa ' ili novim redom u dvostruke, sa escape sekvencama
+func quoteEnv(value string) string {
+	if value != "" && strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-.,/:@+%") == "" {
+		return value
+	}
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`", "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// parseEnv čita KEY=vrednost redove; podržava prefiks "export", komentare i vrednosti
+// u jednostrukim ili dvostrukim navodnicima u jednom redu
+func parseEnv(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimSpace(strings.TrimPrefix(text, "export "))
+
+		key, raw, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=value", line)
+		}
+		value, err := unquoteEnv(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
+
+func unquoteEnv(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single-quoted value")
+		}
+		return raw[1 : end+1], nil
+	case strings.HasPrefix(raw, `"`):
+		var value strings.Builder
+		for i := 1; i < len(raw); i++ {
+			c := raw[i]
+			if c == '"' {
+				return value.String(), nil
+			}
+			if c == '\\' && i+1 < len(raw) {
+				i++
+				switch raw[i] {
+				case 'n':
+					value.WriteByte('\n')
+				case 'r':
+					value.WriteByte('\r')
+				case 't':
+					value.WriteByte('\t')
+				default:
+					value.WriteByte(raw[i])
+				}
+				continue
+			}
+			value.WriteByte(c)
+		}
+		return "", fmt.Errorf("unterminated double-quoted value")
+	}
+	// Komentar posle vrednosti bez navodnika počinje sa " #"
+	if i := strings.Index(raw, " #"); i >= 0 {
+		raw = strings.TrimSpace(raw[:i])
+	}
+	return raw, nil
+}
@@ -0,0 +1,138 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"projekat/model"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Pravila razlaganja parametara za ravne formate (.env, properties, INI):
+//
+//   - ugnježdeni objekat se razlaže na ključeve putanje: {"pool": {"max": 10}} postaje pool.max=10
+//   - element niza dobija indeks kao deo putanje: {"hosts": ["a", "b"]} postaje hosts.0=a i hosts.1=b
+//   - prazan objekat i prazan niz ostaju jedna vrednost, {} odnosno []
+//   - string se piše bez navodnika, broj i bool kao u JSON-u, null kao prazna vrednost
+//   - u properties i INI delovi putanje se spajaju tačkom; u .env donjom crtom, velikim slovima,
+//     a svaki znak koji nije slovo, cifra ili _ postaje _ (pool.max → POOL_MAX)
+//   - u grupi se ispred ključeva dodaje ime konfiguracije (properties: config1.pool.max,
+//     .env: CONFIG1_POOL_MAX, INI: sekcija [config1]); ako grupa sadrži više verzija istog imena,
+//     oznaka je ime@verzija
+//   - ako dva parametra daju isti ključ (npr. {"pool": {"max": 1}} i {"pool.max": 2}, ili u .env
+//     pool-max i pool_max), konfiguracija ne može da se prikaže u tom formatu i vraća se ErrUnsupported
+//
+// Ravni formati nemaju tipove, pa se pri čitanju iz tela zahteva ključevi ne razlažu nazad:
+// svaki ključ postaje jedan parametar sa string vrednošću.
+
+// entry je jedan razloženi parametar
+type entry struct {
+	path  []string
+	value string
+}
+
+// flatten razlaže parametre na listu ključ/vrednost, sortiranu po putanji
+func flatten(parameters model.Parameters) []entry {
+	var entries []entry
+	normalized := model.NormalizeValue(map[string]interface{}(parameters))
+	if values, ok := normalized.(map[string]interface{}); ok {
+		for _, key := range sortedMapKeys(values) {
+			flattenValue([]string{key}, values[key], &entries)
+		}
+	}
+	return entries
+}
+
+func flattenValue(path []string, value interface{}, entries *[]entry) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			*entries = append(*entries, entry{path: path, value: "{}"})
+			return
+		}
+		for _, key := range sortedMapKeys(v) {
+			flattenValue(appendPath(path, key), v[key], entries)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			*entries = append(*entries, entry{path: path, value: "[]"})
+			return
+		}
+		for i, item := range v {
+			flattenValue(appendPath(path, strconv.Itoa(i)), item, entries)
+		}
+	case nil:
+		*entries = append(*entries, entry{path: path, value: ""})
+	case string:
+		*entries = append(*entries, entry{path: path, value: v})
+	case json.Number:
+		*entries = append(*entries, entry{path: path, value: v.String()})
+	default:
+		*entries = append(*entries, entry{path: path, value: model.FormatValue(v)})
+	}
+}
+
+// appendPath pravi novu putanju, da grane rekurzije ne bi delile isti niz
+func appendPath(path []string, key string) []string {
+	return append(append(make([]string, 0, len(path)+1), path...), key)
+}
+
+func sortedMapKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// keySet pamti ključeve napisane u jednom fajlu ili INI sekciji i putanju parametra od kog je
+// svaki nastao, da se dva parametra ne bi tiho napisala pod istim ključem
+type keySet map[string]string
+
+func (k keySet) add(key string, path []string) error {
+	source := dottedKey(path)
+	if other, exists := k[key]; exists {
+		return fmt.Errorf("%w: parameters %q and %q would both be written as %s", ErrUnsupported, other, source, key)
+	}
+	k[key] = source
+	return nil
+}
+
+func dottedKey(path []string) string {
+	return strings.Join(path, ".")
+}
+
+// envKey pravi ime promenljive okruženja od putanje
+func envKey(path []string) string {
+	key := strings.Map(func(r rune) rune {
+		r = unicode.ToUpper(r)
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.Join(path, "_"))
+	if key == "" || (key[0] >= '0' && key[0] <= '9') {
+		key = "_" + key
+	}
+	return key
+}
+
+// configLabels vraća oznaku svake konfiguracije grupe: ime, ili ime@verzija
+// kada grupa sadrži više verzija istog imena
+func configLabels(configs []model.Config) []string {
+	count := make(map[string]int)
+	for _, config := range configs {
+		count[config.Name]++
+	}
+	labels := make([]string, len(configs))
+	for i, config := range configs {
+		labels[i] = config.Name
+		if count[config.Name] > 1 {
+			labels[i] = fmt.Sprintf("%s@%d", config.Name, config.Version)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,181 @@
+package formats
+
+import (
+	"encoding/json"
+	"errors"
+	"projekat/model"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parameters čita parametre iz JSON-a, sa brojevima kakve server dobija iz tela zahteva
+func parameters(t *testing.T, document string) model.Parameters {
+	t.Helper()
+	decoder := json.NewDecoder(strings.NewReader(document))
+	decoder.UseNumber()
+	var parameters model.Parameters
+	if err := decoder.Decode(&parameters); err != nil {
+		t.Fatal(err)
+	}
+	return parameters
+}
+
+func TestFlatten(t *testing.T) {
+	cases := []struct {
+		name       string
+		parameters string
+		want       map[string]string
+	}{
+		{"scalars", `{"host":"db","port":5432,"ratio":0.5,"tls":true,"comment":null}`,
+			map[string]string{"host": "db", "port": "5432", "ratio": "0.5", "tls": "true", "comment": ""}},
+		{"nested object", `{"pool":{"max":10,"idle":{"timeout":"30s"}}}`,
+			map[string]string{"pool.max": "10", "pool.idle.timeout": "30s"}},
+		{"array", `{"hosts":["a","b"],"ports":[[1,2]]}`,
+			map[string]string{"hosts.0": "a", "hosts.1": "b", "ports.0.0": "1", "ports.0.1": "2"}},
+		{"array of objects", `{"replicas":[{"host":"a"},{"host":"b","port":1}]}`,
+			map[string]string{"replicas.0.host": "a", "replicas.1.host": "b", "replicas.1.port": "1"}},
+		{"empty containers", `{"labels":{},"hosts":[],"pool":{"extra":{}}}`,
+			map[string]string{"labels": "{}", "hosts": "[]", "pool.extra": "{}"}},
+		{"large number keeps its digits", `{"id":12345678901234567890}`,
+			map[string]string{"id": "12345678901234567890"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			entries := flatten(parameters(t, c.parameters))
+			got := make(map[string]string, len(entries))
+			for _, e := range entries {
+				got[dottedKey(e.path)] = e.value
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+// Prikaz u ravnom formatu se čita nazad kao ravni parametri sa istim vrednostima
+func TestFlatRoundTrip(t *testing.T) {
+	config := model.Config{Name: "app", Version: 3, Parameters: parameters(t, `{
+		"host": "db.internal",
+		"pool": {"max": 10, "idle": "30s"},
+		"hosts": ["a", "b"],
+		"empty": "",
+		"nothing": null,
+		"spaced": "  leading and trailing  ",
+		"quotes": "it's \"quoted\"",
+		"multiline": "line 1\nline 2\ttab",
+		"comment": "value # not a comment; really",
+		"separators": "a=b:c",
+		"unicode": "čćžšđ €",
+		"backslash": "C:\\path\\to",
+		"dollar": "$HOME and `+"`cmd`"+`"
+	}`)}
+
+	flat := map[string]string{
+		"host": "db.internal", "pool.max": "10", "pool.idle": "30s", "hosts.0": "a", "hosts.1": "b",
+		"empty": "", "nothing": "", "spaced": "  leading and trailing  ", "quotes": `it's "quoted"`,
+		"multiline": "line 1\nline 2\ttab", "comment": "value # not a comment; really", "separators": "a=b:c",
+		"unicode": "čćžšđ €", "backslash": `C:\path\to`, "dollar": "$HOME and `cmd`",
+	}
+	cases := []struct {
+		format Format
+		key    func(path string) string
+	}{
+		{Properties, func(path string) string { return path }},
+		{INI, func(path string) string { return path }},
+		{Env, func(path string) string { return envKey(strings.Split(path, ".")) }},
+	}
+	for _, c := range cases {
+		t.Run(string(c.format), func(t *testing.T) {
+			encoded, err := EncodeConfig(c.format, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := DecodeConfig(c.format, encoded)
+			if err != nil {
+				t.Fatalf("cannot read back %s:\n%s\n%v", c.format, encoded, err)
+			}
+			want := make(model.Parameters, len(flat))
+			for path, value := range flat {
+				want[c.key(path)] = value
+			}
+			if !reflect.DeepEqual(decoded.Parameters, want) {
+				t.Errorf("round trip through %s:\n%s\ngot  %v\nwant %v", c.format, encoded, decoded.Parameters, want)
+			}
+		})
+	}
+}
+
+func TestEncodeRejectsKeyCollisions(t *testing.T) {
+	cases := []struct {
+		name       string
+		parameters string
+		collides   []Format
+	}{
+		{"nested and dotted", `{"pool":{"max":1},"pool.max":2}`, []Format{Properties, INI, Env}},
+		{"array index and dotted", `{"hosts":["a"],"hosts.0":"b"}`, []Format{Properties, INI, Env}},
+		{"env separators", `{"pool-max":1,"pool_max":2}`, []Format{Env}},
+		{"env case", `{"Host":"a","host":"b"}`, []Format{Env}},
+		{"env underscore and nesting", `{"pool":{"max":1},"pool_max":2}`, []Format{Env}},
+		{"distinct keys", `{"pool":{"max":1},"pool_min":2,"Host":"a"}`, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := model.Config{Name: "app", Version: 1, Parameters: parameters(t, c.parameters)}
+			for _, format := range []Format{Properties, INI, Env} {
+				want := false
+				for _, collides := range c.collides {
+					want = want || collides == format
+				}
+				_, err := EncodeConfig(format, config)
+				if want && !errors.Is(err, ErrUnsupported) {
+					t.Errorf("%s: got %v, want ErrUnsupported", format, err)
+				}
+				if !want && err != nil {
+					t.Errorf("%s: %v", format, err)
+				}
+			}
+		})
+	}
+}
+
+// U grupi ključevi svih konfiguracija dele fajl, osim u INI gde svaka ima svoju sekciju
+func TestEncodeGroupRejectsKeyCollisions(t *testing.T) {
+	cases := []struct {
+		name     string
+		configs  []model.Config
+		collides []Format
+	}{
+		{"names differing in case", []model.Config{
+			{Name: "db", Version: 1, Parameters: model.Parameters{"host": "a"}},
+			{Name: "DB", Version: 1, Parameters: model.Parameters{"host": "b"}},
+		}, []Format{Env}},
+		{"name prefix and parameter path", []model.Config{
+			{Name: "db", Version: 1, Parameters: model.Parameters{"pool": map[string]interface{}{"max": "1"}}},
+			{Name: "db.pool", Version: 1, Parameters: model.Parameters{"max": "2"}},
+		}, []Format{Properties, Env}},
+		{"versions of one name", []model.Config{
+			{Name: "db", Version: 1, Parameters: model.Parameters{"host": "a"}},
+			{Name: "db", Version: 2, Parameters: model.Parameters{"host": "b"}},
+		}, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			configGroup := model.ConfigGroup{Name: "app", Version: 1, Configuration: c.configs}
+			for _, format := range []Format{Properties, INI, Env} {
+				want := false
+				for _, collides := range c.collides {
+					want = want || collides == format
+				}
+				_, err := EncodeConfigGroup(format, configGroup)
+				if want && !errors.Is(err, ErrUnsupported) {
+					t.Errorf("%s: got %v, want ErrUnsupported", format, err)
+				}
+				if !want && err != nil {
+					t.Errorf("%s: %v", format, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Package formats prikazuje konfiguracije i grupe u formatima koje servisi direktno čitaju
+// (JSON, YAML, TOML, .env, Java properties, INI) i čita iste formate iz tela zahteva.
+//
+// Strukturni formati (JSON, YAML, TOML) sadrže ceo dokument, sa istim poljima kao JSON.
+// Ravni formati (.env, properties, INI) sadrže samo parametre, razložene po pravilima iz flatten.go.
+package formats
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Format string
+
+const (
+	JSON       Format = "json"
+	YAML       Format = "yaml"
+	TOML       Format = "toml"
+	Env        Format = "env"
+	Properties Format = "properties"
+	INI        Format = "ini"
+)
+
+// mediaTypes su tipovi sadržaja koje format prihvata; prvi se šalje u Content-Type odgovora
+var mediaTypes = map[Format][]string{
+	JSON:       {"application/json"},
+	YAML:       {"application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml"},
+	TOML:       {"application/toml"},
+	Env:        {"text/x-env", "application/x-env"},
+	Properties: {"text/x-java-properties"},
+	INI:        {"text/x-ini"},
+}
+
+// order određuje prednost kada Accept zaglavlje podjednako prihvata više formata
+var order = []Format{JSON, YAML, TOML, Env, Properties, INI}
+
+// Names vraća imena svih formata, za poruke o greškama
+func Names() []string {
+	names := make([]string, 0, len(order))
+	for _, format := range order {
+		names = append(names, string(format))
+	}
+	return names
+}
+
+// Parse prepoznaje ime formata iz parametra ?format=
+func Parse(name string) (Format, bool) {
+	format := Format(strings.ToLower(name))
+	if format == "yml" {
+		format = YAML
+	}
+	_, ok := mediaTypes[format]
+	return format, ok
+}
+
+// ContentType vraća Content-Type odgovora u datom formatu
+func (f Format) ContentType() string {
+	contentType := mediaTypes[f][0]
+	if strings.HasPrefix(contentType, "text/") {
+		contentType += "; charset=utf-8"
+	}
+	return contentType
+}
+
+// Flat proverava da li format sadrži samo parametre, bez imena, verzije i labela
+func (f Format) Flat() bool {
+	return f == Env || f == Properties || f == INI
+}
+
+// FromMediaType prepoznaje format iz Content-Type zaglavlja
+func FromMediaType(contentType string) (Format, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	for _, format := range order {
+		for _, candidate := range mediaTypes[format] {
+			if candidate == mediaType {
+				return format, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Negotiate bira format prema Accept zaglavlju (RFC 9110): najveći q, a među jednakima
+// redosled iz order. Prazno zaglavlje i */* znače JSON. Vraća false ako nijedan format nije prihvatljiv.
+func Negotiate(accept string) (Format, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return JSON, true
+	}
+
+	best, bestQ := Format(""), 0.0
+	for _, format := range order {
+		q := acceptQuality(accept, format)
+		if q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// acceptQuality vraća q vrednost najpreciznijeg opsega iz Accept koji obuhvata format
+func acceptQuality(accept string, format Format) float64 {
+	type match struct {
+		specificity int
+		q           float64
+	}
+	var matches []match
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		for _, mediaType := range mediaTypes[format] {
+			switch {
+			case mediaRange == mediaType:
+				matches = append(matches, match{specificity: 2, q: q})
+			case mediaRange == "*/*":
+				matches = append(matches, match{specificity: 0, q: q})
+			case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+				matches = append(matches, match{specificity: 1, q: q})
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return 0
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].specificity > matches[j].specificity })
+	return matches[0].q
+}
@@ -0,0 +1,95 @@
+package formats
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		accept string
+		want   Format
+		ok     bool
+	}{
+		{"", JSON, true},
+		{"*/*", JSON, true},
+		{"application/json", JSON, true},
+		{"application/yaml", YAML, true},
+		{"text/yaml", YAML, true},
+		{"application/x-yaml", YAML, true},
+		{"application/toml", TOML, true},
+		{"text/x-env", Env, true},
+		{"text/x-java-properties", Properties, true},
+		{"text/x-ini", INI, true},
+		{"text/html, application/yaml;q=0.9, */*;q=0.1", YAML, true},
+		{"application/json;q=0.5, application/toml", TOML, true},
+		{"application/json;q=0.5, text/x-ini;q=0.5", JSON, true},
+		{"text/*", YAML, true},
+		{"text/*;q=0.5, text/x-ini", INI, true},
+		{"application/*;q=0.2, text/x-env;q=0.3", Env, true},
+		{"*/*;q=0.1, application/json;q=0", YAML, true},
+		{"application/json;q=0, */*", YAML, true},
+		{" application/yaml ; q=1 ", YAML, true},
+		{"APPLICATION/YAML", YAML, true},
+		{"text/html", "", false},
+		{"application/json;q=0", "", false},
+		{"*/*;q=0", "", false},
+		{"not a media type", "", false},
+		{"application/xml, image/*", "", false},
+	}
+	for _, c := range cases {
+		got, ok := Negotiate(c.accept)
+		if ok != c.ok || (ok && got != c.want) {
+			t.Errorf("Negotiate(%q) = %q, %v; want %q, %v", c.accept, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestFromMediaType(t *testing.T) {
+	cases := []struct {
+		contentType string
+		want        Format
+		ok          bool
+	}{
+		{"application/json", JSON, true},
+		{"application/json; charset=utf-8", JSON, true},
+		{"Application/JSON", JSON, true},
+		{"text/yaml", YAML, true},
+		{"application/toml", TOML, true},
+		{"application/x-env", Env, true},
+		{"text/x-java-properties; charset=utf-8", Properties, true},
+		{"text/x-ini", INI, true},
+		{"application/x-www-form-urlencoded", "", false},
+		{"text/plain", "", false},
+		{"application/*", "", false},
+		{"json", "", false},
+		{"", "", false},
+	}
+	for _, c := range cases {
+		got, ok := FromMediaType(c.contentType)
+		if ok != c.ok || got != c.want {
+			t.Errorf("FromMediaType(%q) = %q, %v; want %q, %v", c.contentType, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name string
+		want Format
+		ok   bool
+	}{
+		{"json", JSON, true},
+		{"YAML", YAML, true},
+		{"yml", YAML, true},
+		{"toml", TOML, true},
+		{"env", Env, true},
+		{"properties", Properties, true},
+		{"ini", INI, true},
+		{"xml", "", false},
+		{"", "", false},
+	}
+	for _, c := range cases {
+		got, ok := Parse(c.name)
+		if ok != c.ok || (ok && got != c.want) {
+			t.Errorf("Parse(%q) = %q, %v; want %q, %v", c.name, got, ok, c.want, c.ok)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package formats
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// iniSection je sekcija INI fajla; sekcija bez imena sadrži ključeve pre prve [sekcije]
+type iniSection struct {
+	name   string
+	values map[string]string
+}
+
+func writeINI(b *strings.Builder, entries []entry) error {
+	keys := keySet{}
+	for _, e := range entries {
+		key := dottedKey(e.path)
+		if err := keys.add(key, e.path); err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "%s = %s\n", key, quoteINI(e.value))
+	}
+	return nil
+}
+
+// quoteINI stavlja vrednost u dvostruke navodnike kada bi se inače pogrešno pročitala
+func quoteINI(value string) string {
+	if value != "" && value == strings.TrimSpace(value) && !strings.ContainsAny(value, "\";#\\\n\r") {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// parseINI čita sekcije i ključ = vrednost redove; komentari počinju sa ; ili #
+func parseINI(data []byte) ([]iniSection, error) {
+	sections := []iniSection{{values: make(map[string]string)}}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == ';' || text[0] == '#' {
+			continue
+		}
+		if text[0] == '[' {
+			if !strings.HasSuffix(text, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section header", line)
+			}
+			sections = append(sections, iniSection{name: strings.TrimSpace(text[1 : len(text)-1]), values: make(map[string]string)})
+			continue
+		}
+
+		key, raw, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected key = value", line)
+		}
+		value, err := unquoteINI(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		sections[len(sections)-1].values[key] = value
+	}
+	return sections, scanner.Err()
+}
+
+func unquoteINI(raw string) (string, error) {
+	if !strings.HasPrefix(raw, `"`) {
+		// Komentar posle vrednosti bez navodnika
+		if i := strings.IndexAny(raw, ";#"); i >= 0 {
+			raw = strings.TrimSpace(raw[:i])
+		}
+		return raw, nil
+	}
+	var value strings.Builder
+	for i := 1; i < len(raw); i++ {
+		c := raw[i]
+		if c == '"' {
+			return value.String(), nil
+		}
+		if c == '\\' && i+1 < len(raw) {
+			i++
+			switch raw[i] {
+			case 'n':
+				value.WriteByte('\n')
+			case 'r':
+				value.WriteByte('\r')
+			case 't':
+				value.WriteByte('\t')
+			default:
+				value.WriteByte(raw[i])
+			}
+			continue
+		}
+		value.WriteByte(c)
+	}
+	return "", fmt.Errorf("unterminated quoted value")
+}
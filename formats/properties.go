@@ -0,0 +1,154 @@
+package formats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+func writeProperties(b *strings.Builder, keys keySet, prefix []string, entries []entry) error {
+	for _, e := range entries {
+		path := append(append([]string(nil), prefix...), e.path...)
+		key := dottedKey(path)
+		if err := keys.add(key, path); err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "%s=%s\n", escapeProperties(key, true), escapeProperties(e.value, false))
+	}
+	return nil
+}
+
+// escapeProperties piše ključ ili vrednost po pravilima java.util.Properties. Znakovi van
+// ASCII opsega se pišu kao \uXXXX, pa fajl može da se učita i kao ISO-8859-1.
+func escapeProperties(value string, isKey bool) string {
+	var b strings.Builder
+	for i, r := range value {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && (isKey || i == 0):
+			b.WriteString(`\ `)
+		case isKey && (r == '=' || r == ':'), (isKey || i == 0) && (r == '#' || r == '!'):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			for _, unit := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, `\u%04x`, unit)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// parseProperties čita format java.util.Properties: komentare (# i !), nastavak reda
+// obrnutom kosom crtom, razdvajanje ključa sa =, : ili razmakom i escape sekvence
+func parseProperties(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		// Red koji se završava neparnim brojem obrnutih kosih crta nastavlja se u sledećem
+		for continues(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+
+		keyEnd := len(line)
+		for j := 0; j < len(line); j++ {
+			if line[j] == '\\' {
+				j++
+				continue
+			}
+			if line[j] == '=' || line[j] == ':' || line[j] == ' ' || line[j] == '\t' || line[j] == '\f' {
+				keyEnd = j
+				break
+			}
+		}
+		rest := strings.TrimLeft(line[keyEnd:], " \t\f")
+		if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+			rest = strings.TrimLeft(rest[1:], " \t\f")
+		}
+
+		key, err := unescapeProperties(line[:keyEnd])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		value, err := unescapeProperties(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+func continues(line string) bool {
+	backslashes := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 1
+}
+
+func unescapeProperties(value string) (string, error) {
+	var units []uint16
+	var b strings.Builder
+	flush := func() {
+		if len(units) > 0 {
+			b.WriteString(string(utf16.Decode(units)))
+			units = nil
+		}
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '\\' || i+1 == len(value) {
+			flush()
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			flush()
+			b.WriteByte('\n')
+		case 'r':
+			flush()
+			b.WriteByte('\r')
+		case 't':
+			flush()
+			b.WriteByte('\t')
+		case 'f':
+			flush()
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(value) {
+				return "", fmt.Errorf("malformed \\u escape")
+			}
+			unit, err := strconv.ParseUint(value[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\u escape")
+			}
+			units = append(units, uint16(unit))
+			i += 4
+		default:
+			flush()
+			b.WriteByte(value[i])
+		}
+	}
+	flush()
+	return b.String(), nil
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"projekat/archive"
+	"projekat/services"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ArchiveHandler struct {
+	service services.ArchiveService
+	secrets SecretAccess
+	// Najveća veličina tela zahteva za uvoz i najveća veličina raspakovane arhive
+	maxRequestBytes int64
+	maxArchiveBytes int64
+}
+
+func NewArchiveHandler(service services.ArchiveService, secrets SecretAccess, maxRequestBytes, maxArchiveBytes int64) ArchiveHandler {
+	return ArchiveHandler{
+		service:         service,
+		secrets:         secrets,
+		maxRequestBytes: maxRequestBytes,
+		maxArchiveBytes: maxArchiveBytes,
+	}
+}
+
+// GET /export?format=jsonl|tar.gz
+func (h ArchiveHandler) Export(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := h.secrets.reveal(w, r, StaticResource("configs/*"), StaticResource("configGroups/*"))
+	if !ok {
+		return
+	}
+	encoding, ok := exportEncoding(w, r)
+	if !ok {
+		return
+	}
+
+	createdAt := time.Now().UTC()
+	out, err := archive.NewWriter(w, encoding, createdAt)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// Arhiva bez otkrivenih tajni služi za pregled; uvoz je odbija jer ne sadrži tajne vrednosti
+	out.MaskSecrets = !reveal
+
+	w.Header().Set("Content-Type", encoding.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", encoding.FileName(createdAt)))
+	if err := h.service.In(requestNamespace(r)).Export(out); err != nil {
+		if !out.Started() {
+			w.Header().Del("Content-Disposition")
+			writeError(w, r, err)
+			return
+		}
+		// Zaglavlja i deo arhive su već poslati, pa greška može samo da se zabeleži; arhiva
+		// ostaje bez završnog zapisa, pa je uvoz odbija
+		log.Printf("export: %v", err)
+	}
+}
+
+// exportEncoding bira način zapisa arhive: ?format= ima prednost nad Accept zaglavljem,
+// a podrazumevano je JSON lines
+func exportEncoding(w http.ResponseWriter, r *http.Request) (archive.Encoding, bool) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		encoding, ok := archive.ParseEncoding(name)
+		if !ok {
+			writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("unknown export format %q (supported: jsonl, tar.gz)", name))
+			return "", false
+		}
+		return encoding, true
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return archive.JSONLines, true
+	}
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaRange = strings.TrimSpace(mediaRange)
+		if encoding, ok := archive.FromMediaType(mediaRange); ok {
+			return encoding, true
+		}
+		if mediaType, _, _ := strings.Cut(mediaRange, ";"); mediaType == "*/*" || mediaType == "application/*" {
+			return archive.JSONLines, true
+		}
+	}
+	writeProblem(w, r, http.StatusNotAcceptable, "export is available as application/x-ndjson or application/gzip")
+	return "", false
+}
+
+// POST /import?mode=skip-existing|overwrite|fail-on-conflict&dryRun=true
+func (h ArchiveHandler) Import(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	mode := services.FailOnConflict
+	if name := query.Get("mode"); name != "" {
+		parsed, err := services.ParseImportMode(name)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		mode = parsed
+	}
+	dryRun := false
+	if value := query.Get("dryRun"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "dryRun must be true or false")
+			return
+		}
+		dryRun = parsed
+	}
+
+	// Telo zahteva se ograničava pre raspakivanja, a raspakovana arhiva posebnim ograničenjem
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxRequestBytes)
+	var a archive.Archive
+	var err error
+	// Bez zaglavlja (ili sa form-urlencoded koji šalje curl --data-binary) način zapisa se prepoznaje po sadržaju
+	if contentType := r.Header.Get("Content-Type"); contentType == "" || isFormContentType(contentType) {
+		a, err = archive.ReadSniffed(r.Body, h.maxArchiveBytes)
+	} else {
+		encoding, ok := archive.FromMediaType(contentType)
+		if !ok {
+			writeProblem(w, r, http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported content type %q (expected application/x-ndjson or application/gzip)", contentType))
+			return
+		}
+		a, err = archive.Read(r.Body, encoding, h.maxArchiveBytes)
+	}
+	if err != nil {
+		switch {
+		case bodyTooLarge(err):
+			writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("archive is larger than %d bytes", h.maxRequestBytes))
+		case errors.Is(err, archive.ErrTooLarge):
+			writeProblem(w, r, http.StatusRequestEntityTooLarge, err.Error())
+		default:
+			writeError(w, r, err)
+		}
+		return
+	}
+
+	report, err := h.service.In(requestNamespace(r)).By(requestActor(r)).Import(a, mode, dryRun)
+	if err != nil {
+		if conflicts := report.Conflicts(); len(conflicts) > 0 {
+			p := newProblem(r, errorStatus(err), err.Error())
+			p.Conflicts = conflicts
+			sendProblem(w, p)
+			return
+		}
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(report)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// exportArchive puni server sa dve konfiguracije i grupom i vraća izvezenu arhivu u JSON lines obliku
+func exportArchive(t *testing.T) string {
+	t.Helper()
+	source := newTestServer(t, "")
+	source.must(http.StatusCreated, "", "POST", "/configs", `{"name":"db","version":1,"parameters":{"host":"db.local"}}`)
+	source.must(http.StatusCreated, "", "POST", "/configs", `{"name":"cache","version":1,"parameters":{"host":"cache.local"}}`)
+	source.must(http.StatusCreated, "", "POST", "/configGroups", `{"name":"app","version":1,"configuration":[],"references":[{"name":"db","version":1}]}`)
+	return source.must(http.StatusOK, "", "GET", "/export", "").Body.String()
+}
+
+func tarGz(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestImportRejectsIncompleteAndOversizedArchives(t *testing.T) {
+	exported := exportArchive(t)
+	lines := strings.SplitAfter(strings.TrimSuffix(exported, "\n"), "\n")
+	ndjson := []string{"Content-Type", "application/x-ndjson"}
+
+	cases := []struct {
+		name   string
+		body   string
+		header []string
+		status int
+	}{
+		{name: "without the trailer", body: strings.Join(lines[:len(lines)-1], ""), header: ndjson, status: http.StatusBadRequest},
+		{name: "without a record", body: strings.Join(append(append([]string{}, lines[:1]...), lines[2:]...), ""), header: ndjson, status: http.StatusBadRequest},
+		{name: "cut in the middle of a record", body: exported[:len(exported)/2], header: ndjson, status: http.StatusBadRequest},
+		{name: "without the manifest", body: strings.Join(lines[1:], ""), header: ndjson, status: http.StatusBadRequest},
+		{name: "empty", body: " ", header: ndjson, status: http.StatusBadRequest},
+		{name: "truncated gzip", body: tarGz(t, "manifest.json", []byte(lines[0]))[:20], header: []string{"Content-Type", "application/gzip"}, status: http.StatusBadRequest},
+		{name: "request body over the limit", body: exported + strings.Repeat(" ", testMaxRequestBytes), header: ndjson, status: http.StatusRequestEntityTooLarge},
+		{name: "decompressed archive over the limit", body: tarGz(t, "manifest.json", bytes.Repeat([]byte(" "), 2*testMaxArchiveBytes)), header: []string{"Content-Type", "application/gzip"}, status: http.StatusRequestEntityTooLarge},
+		{name: "sniffed archive over the limit", body: tarGz(t, "manifest.json", bytes.Repeat([]byte(" "), 2*testMaxArchiveBytes)), header: []string{"Content-Type", "application/x-www-form-urlencoded"}, status: http.StatusRequestEntityTooLarge},
+		{name: "unsupported content type", body: exported, header: []string{"Content-Type", "text/csv"}, status: http.StatusUnsupportedMediaType},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newTestServer(t, "")
+			rec := s.must(c.status, "", "POST", "/import", c.body, c.header...)
+			if rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("error has Content-Type %q", rec.Header().Get("Content-Type"))
+			}
+			// Ništa iz odbijene arhive nije upisano
+			s.must(http.StatusNotFound, "", "GET", "/configs/db/1", "")
+		})
+	}
+
+	// Ista, cela arhiva se uvozi
+	s := newTestServer(t, "")
+	s.must(http.StatusOK, "", "POST", "/import", exported, ndjson...)
+	s.must(http.StatusOK, "", "GET", "/configGroups/app/1", "")
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"projekat/audit"
+	"projekat/auth"
+	"projekat/model"
+	"strconv"
+	"time"
+)
+
+type AuditHandler struct {
+	log *audit.Log
+}
+
+func NewAuditHandler(log *audit.Log) AuditHandler {
+	return AuditHandler{
+		log: log,
+	}
+}
+
+// requestActor vraća onoga ko šalje zahtev, za audit log: proverenog klijenta i ID zahteva
+func requestActor(r *http.Request) audit.Actor {
+	actor := audit.Actor{Principal: audit.AnonymousPrincipal, RequestID: requestID(r)}
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		actor.Principal = principal.Subject
+	}
+	return actor
+}
+
+// GET /audit?principal=&action=&kind=&namespace=&resource=&requestId=&since=&until=&limit=&cursor=
+func (h AuditHandler) Find(w http.ResponseWriter, r *http.Request) {
+	query, err := auditQuery(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	page, err := h.log.Find(query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(page.Entries)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if page.Next > 0 {
+		setNextLink(w, r, strconv.FormatInt(page.Next, 10))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// GET /audit/verify
+func (h AuditHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	result, err := h.log.Verify()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// auditQuery čita filtere audit loga iz upita; since i until su RFC 3339 vremena,
+// a cursor je redni broj poslednjeg zapisa prethodne strane
+func auditQuery(r *http.Request) (audit.Query, error) {
+	values := r.URL.Query()
+	query := audit.Query{
+		Principal:      values.Get("principal"),
+		Action:         values.Get("action"),
+		Kind:           values.Get("kind"),
+		Namespace:      values.Get("namespace"),
+		ResourcePrefix: values.Get("resource"),
+		RequestID:      values.Get("requestId"),
+		Limit:          model.DefaultPageLimit,
+	}
+
+	switch query.Action {
+	case "", model.AuditCreate, model.AuditUpdate, model.AuditDelete:
+	default:
+		return audit.Query{}, model.Invalidf("action must be %s, %s or %s, got %q", model.AuditCreate, model.AuditUpdate, model.AuditDelete, query.Action)
+	}
+	for name, target := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		value := values.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return audit.Query{}, model.Invalidf("%s must be an RFC 3339 time, got %q", name, value)
+		}
+		*target = parsed
+	}
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return audit.Query{}, model.Invalidf("limit must be a positive number, got %q", limit)
+		}
+		if n > model.MaxPageLimit {
+			return audit.Query{}, model.Invalidf("limit must be at most %d, got %d", model.MaxPageLimit, n)
+		}
+		query.Limit = n
+	}
+	if cursor := values.Get("cursor"); cursor != "" {
+		after, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || after < 0 {
+			return audit.Query{}, model.Invalidf("invalid cursor %q", cursor)
+		}
+		query.After = after
+	}
+	return query, nil
+}
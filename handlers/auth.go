@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"projekat/auth"
+)
+
+// Authentication zahteva akreditive za svaki zahtev i stavlja proverenog klijenta u kontekst,
+// odakle ga čitaju handleri i servisi (auth.FromContext)
+type Authentication struct {
+	authenticator *auth.Authenticator
+}
+
+func NewAuthentication(authenticator *auth.Authenticator) Authentication {
+	return Authentication{
+		authenticator: authenticator,
+	}
+}
+
+// Wrap postavlja proveru ispred celog router-a; ako autentifikacija nije uključena, zahtevi prolaze
+func (a Authentication) Wrap(next http.Handler) http.Handler {
+	if !a.authenticator.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := a.authenticator.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="projekat"`)
+			writeError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// GET /whoami
+func (a Authentication) Whoami(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		writeProblem(w, r, http.StatusNotFound, "authentication is not enabled")
+		return
+	}
+
+	resp, err := json.Marshal(principal)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
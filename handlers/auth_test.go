@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"projekat/auth"
+	"testing"
+)
+
+func TestAuthenticationWrap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-keys")
+	if err := os.WriteFile(path, []byte("ci:"+auth.HashAPIKey("ci-key")+":ops\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.LoadAPIKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authentication := NewAuthentication(auth.NewAuthenticator(keys, nil))
+	handler := RequestID(authentication.Wrap(http.HandlerFunc(authentication.Whoami)))
+
+	cases := []struct {
+		name    string
+		header  []string
+		status  int
+		subject string
+	}{
+		{name: "valid key", header: []string{auth.APIKeyHeader, "ci-key"}, status: http.StatusOK, subject: "ci"},
+		{name: "unknown key", header: []string{auth.APIKeyHeader, "wrong"}, status: http.StatusUnauthorized},
+		{name: "unknown bearer", header: []string{"Authorization", "Bearer wrong"}, status: http.StatusUnauthorized},
+		{name: "no credentials", status: http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/whoami", nil)
+			for i := 0; i+1 < len(c.header); i += 2 {
+				req.Header.Set(c.header[i], c.header[i+1])
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != c.status {
+				t.Fatalf("got %d, want %d: %s", rec.Code, c.status, rec.Body.String())
+			}
+			if c.status == http.StatusOK {
+				var principal auth.Principal
+				if err := json.Unmarshal(rec.Body.Bytes(), &principal); err != nil || principal.Subject != c.subject {
+					t.Errorf("whoami returned %s", rec.Body.String())
+				}
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+				t.Errorf("Content-Type is %q, want application/problem+json", got)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != `Bearer realm="projekat"` {
+				t.Errorf("WWW-Authenticate is %q", got)
+			}
+			var body problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Status != http.StatusUnauthorized || body.Detail == "" {
+				t.Errorf("problem is %s", rec.Body.String())
+			}
+		})
+	}
+
+	// Bez ključeva i provere tokena zahtevi prolaze bez akreditiva
+	open := NewAuthentication(auth.NewAuthenticator(nil, nil)).Wrap(http.HandlerFunc(authentication.Whoami))
+	rec := httptest.NewRecorder()
+	open.ServeHTTP(rec, httptest.NewRequest("GET", "/whoami", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("disabled authentication: got %d, want 404 from whoami", rec.Code)
+	}
+}
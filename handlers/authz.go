@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"projekat/auth"
+	"projekat/model"
+	"projekat/rbac"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// ResourceFunc vraća putanju resursa na koji se zahtev odnosi, npr. configs/db_config/2.
+// Putanja koja se završava sa * označava sve resurse sa tim prefiksom. Za zahteve ispod
+// /namespaces/{namespace} putanja počinje sa namespaces/{namespace}/.
+type ResourceFunc func(r *http.Request) string
+
+// VersionResource je jedna verzija zapisa: {prefix}/{ime}/{verzija} iz promenljivih putanje
+func VersionResource(prefix, nameVar, versionVar string) ResourceFunc {
+	return func(r *http.Request) string {
+		return namespacePath(r, "%s/%s/%s", prefix, mux.Vars(r)[nameVar], mux.Vars(r)[versionVar])
+	}
+}
+
+// AllVersionsResource su sve verzije zapisa; koristi se za istoriju, najnoviju i nove verzije
+func AllVersionsResource(prefix, nameVar string) ResourceFunc {
+	return func(r *http.Request) string {
+		return namespacePath(r, "%s/%s/*", prefix, mux.Vars(r)[nameVar])
+	}
+}
+
+// NameResource je zapis bez verzija: {prefix}/{id} iz promenljive putanje
+func NameResource(prefix, nameVar string) ResourceFunc {
+	return func(r *http.Request) string {
+		return namespacePath(r, "%s/%s", prefix, mux.Vars(r)[nameVar])
+	}
+}
+
+// ListResource su svi zapisi čije ime počinje sa ?namePrefix=
+func ListResource(prefix string) ResourceFunc {
+	return func(r *http.Request) string {
+		return namespacePath(r, "%s/%s*", prefix, r.URL.Query().Get("namePrefix"))
+	}
+}
+
+// WatchResource su svi zapisi ispod ?prefix= iz /watch
+func WatchResource(r *http.Request) string {
+	return prefixResource(r.URL.Query().Get("prefix"))
+}
+
+// prefixResource su svi zapisi čija putanja počinje prefiksom događaja, kao u /watch i webhook-ovima
+func prefixResource(prefix string) string {
+	return strings.TrimPrefix(prefix, "/") + "*"
+}
+
+// StaticResource je resurs koji ne zavisi od zahteva, osim od prostora imena
+func StaticResource(resource string) ResourceFunc {
+	return func(r *http.Request) string {
+		return namespacePath(r, "%s", resource)
+	}
+}
+
+// Authorization primenjuje RBAC politiku na zahteve. Bez politike (engine == nil) sve je dozvoljeno.
+type Authorization struct {
+	engine *rbac.Engine
+}
+
+func NewAuthorization(engine *rbac.Engine) Authorization {
+	return Authorization{
+		engine: engine,
+	}
+}
+
+// Require propušta zahtev do handlera samo ako klijent sme da izvrši akciju nad svim resursima
+func (a Authorization) Require(action rbac.Action, next http.HandlerFunc, resources ...ResourceFunc) http.HandlerFunc {
+	if a.engine == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, resource := range resources {
+			if !a.allow(w, r, action, resource(r)) {
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// allow proverava akciju i, ako nije dozvoljena, šalje 403 sa razlogom iz politike.
+// Handleri ga pozivaju za resurse koji se vide tek iz tela zahteva.
+func (a Authorization) allow(w http.ResponseWriter, r *http.Request, action rbac.Action, resource string) bool {
+	if a.engine == nil {
+		return true
+	}
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		writeError(w, r, model.Forbiddenf("request is not authenticated"))
+		return false
+	}
+	decision := a.engine.Decide(principal, action, resource)
+	if !decision.Allowed {
+		writeError(w, r, model.Forbiddenf("%s", decision.Reason))
+		return false
+	}
+	return true
+}
+
+// allowReferences proverava da klijent sme da čita konfiguracije na koje grupa upućuje,
+// jer ih čitaoci grupe vide zajedno sa grupom
+func (a Authorization) allowReferences(w http.ResponseWriter, r *http.Request, references []model.ConfigReference) bool {
+	for _, reference := range references {
+		if !a.allow(w, r, rbac.Read, namespacePath(r, "configs/%s/%d", reference.Name, reference.Version)) {
+			return false
+		}
+	}
+	return true
+}
+
+// GET /authz/check?action=read&resource=configs/db_config/2[&subject=ci&group=ops]
+// Objašnjava odluku za klijenta koji pita; za drugog klijenta (subject, group) potrebno je read nad "authz".
+func (a Authorization) Check(w http.ResponseWriter, r *http.Request) {
+	if a.engine == nil {
+		writeProblem(w, r, http.StatusNotFound, "authorization is not enabled")
+		return
+	}
+
+	query := r.URL.Query()
+	action, err := rbac.ParseAction(query.Get("action"))
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	resource := strings.TrimPrefix(query.Get("resource"), "/")
+	if resource == "" {
+		writeProblem(w, r, http.StatusBadRequest, "resource is required")
+		return
+	}
+
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		writeError(w, r, model.Forbiddenf("request is not authenticated"))
+		return
+	}
+	if subject := query.Get("subject"); subject != "" || len(query["group"]) > 0 {
+		if !a.allow(w, r, rbac.Read, "authz") {
+			return
+		}
+		principal = auth.Principal{Subject: subject, Groups: query["group"]}
+	}
+
+	resp, err := json.Marshal(a.engine.Decide(principal, action, resource))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+const authzPolicy = `
+roles:
+  admin:
+    rules:
+      - actions: ["*"]
+        resources: ["*"]
+  payments-writer:
+    rules:
+      - actions: ["*"]
+        resources: ["configs/payments*"]
+  reader:
+    rules:
+      - actions: [read]
+        resources: ["configs/*"]
+  team:
+    rules:
+      - actions: [read, create]
+        resources: ["namespaces/team/configs/*"]
+bindings:
+  - role: admin
+    subjects: [admin]
+  - role: payments-writer
+    subjects: [payments]
+  - role: reader
+    subjects: [ci]
+  - role: team
+    subjects: [alice]
+`
+
+func TestAuthorizationRequire(t *testing.T) {
+	s := newTestServer(t, authzPolicy)
+	s.must(http.StatusCreated, "admin", "POST", "/namespaces", `{"name":"team"}`)
+	for _, path := range []string{"/configs", "/namespaces/team/configs"} {
+		s.must(http.StatusCreated, "admin", "POST", path, `{"name":"db","version":1,"parameters":{"host":"db"}}`)
+		s.must(http.StatusCreated, "admin", "POST", path, `{"name":"payments_db","version":1,"parameters":{"host":"db"}}`)
+	}
+
+	cases := []struct {
+		name    string
+		subject string
+		method  string
+		path    string
+		body    string
+		status  int
+	}{
+		{"without a principal", "", "GET", "/configs/db/1", "", http.StatusForbidden},
+		{"without a role", "mallory", "GET", "/configs/db/1", "", http.StatusForbidden},
+		{"reader reads a version", "ci", "GET", "/configs/db/1", "", http.StatusOK},
+		{"reader reads all versions", "ci", "GET", "/configs/db/latest", "", http.StatusOK},
+		{"reader lists", "ci", "GET", "/configs", "", http.StatusOK},
+		{"reader cannot delete", "ci", "DELETE", "/configs/db/1", "", http.StatusForbidden},
+		{"reader cannot create", "ci", "POST", "/configs", `{"name":"cache","version":1}`, http.StatusForbidden},
+		{"reader cannot reveal", "ci", "GET", "/configs/db/1?reveal=true", "", http.StatusForbidden},
+		{"reader outside the default namespace", "ci", "GET", "/namespaces/team/configs/db/1", "", http.StatusForbidden},
+		{"name prefix reads", "payments", "GET", "/configs/payments_db/1", "", http.StatusOK},
+		{"name prefix reads other names", "payments", "GET", "/configs/db/1", "", http.StatusForbidden},
+		{"name prefix lists its prefix", "payments", "GET", "/configs?namePrefix=payments", "", http.StatusOK},
+		{"name prefix lists everything", "payments", "GET", "/configs", "", http.StatusForbidden},
+		{"name prefix lists a shorter prefix", "payments", "GET", "/configs?namePrefix=pay", "", http.StatusForbidden},
+		{"name prefix creates versions", "payments", "POST", "/configs/payments_db/versions", `{"parameters":{"host":"db2"}}`, http.StatusCreated},
+		{"name prefix in another namespace", "payments", "GET", "/namespaces/team/configs/payments_db/1", "", http.StatusForbidden},
+		{"namespace role reads", "alice", "GET", "/namespaces/team/configs/db/1", "", http.StatusOK},
+		{"namespace role lists", "alice", "GET", "/namespaces/team/configs", "", http.StatusOK},
+		{"namespace role creates", "alice", "POST", "/namespaces/team/configs", `{"name":"cache","version":1}`, http.StatusCreated},
+		{"namespace role cannot delete", "alice", "DELETE", "/namespaces/team/configs/db/1", "", http.StatusForbidden},
+		{"namespace role in the default namespace", "alice", "GET", "/configs/db/1", "", http.StatusForbidden},
+		{"namespace role cannot export", "alice", "GET", "/namespaces/team/export", "", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := s.do(c.subject, c.method, c.path, c.body)
+			if rec.Code != c.status {
+				t.Errorf("got %d, want %d: %s", rec.Code, c.status, rec.Body.String())
+			}
+			if rec.Code == http.StatusForbidden && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("403 has Content-Type %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestAuthorizationReloadDropsGrant(t *testing.T) {
+	s := newTestServer(t, authzPolicy)
+	s.must(http.StatusCreated, "admin", "POST", "/configs", `{"name":"db","version":1}`)
+	s.must(http.StatusOK, "ci", "GET", "/configs/db/1", "")
+
+	s.writePolicy(`
+roles:
+  admin:
+    rules:
+      - actions: ["*"]
+        resources: ["*"]
+bindings:
+  - role: admin
+    subjects: [admin]
+`)
+	if changed, err := s.engine.Reload(); !changed || err != nil {
+		t.Fatalf("reload: changed=%v, err=%v", changed, err)
+	}
+	s.must(http.StatusForbidden, "ci", "GET", "/configs/db/1", "")
+	s.must(http.StatusOK, "admin", "GET", "/configs/db/1", "")
+}
@@ -3,65 +3,125 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"projekat/formats"
 	"projekat/model"
+	"projekat/rbac"
 	"projekat/services"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
 
 type ConfigHandler struct {
 	service services.ConfigService
+	secrets SecretAccess
+	access  Authorization
 }
 
-func NewConfigHandler(service services.ConfigService) ConfigHandler {
+func NewConfigHandler(service services.ConfigService, secrets SecretAccess, access Authorization) ConfigHandler {
 	return ConfigHandler{
 		service: service,
+		secrets: secrets,
+		access:  access,
 	}
 }
 
+// in vraća servis za prostor imena iz putanje zahteva, koji izmene upisuje u audit log u ime klijenta
+func (c ConfigHandler) in(r *http.Request) services.ConfigService {
+	return c.service.In(requestNamespace(r)).By(requestActor(r))
+}
+
 // POST /configs
 func (c ConfigHandler) Create(w http.ResponseWriter, r *http.Request) {
-	var config model.Config
-	err := json.NewDecoder(r.Body).Decode(&config)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	config, ok := decodeConfig(w, r)
+	if !ok {
+		return
+	}
+	// Ime konfiguracije se vidi tek iz tela, pa se pristup proverava ovde, a ne na ruti
+	if !c.access.allow(w, r, rbac.Create, namespacePath(r, "configs/%s/%d", config.Name, config.Version)) {
 		return
 	}
 
-	err = c.service.CreateConfig(config)
+	err := c.in(r).CreateConfig(config)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 }
 
+// validationResult je odgovor na uspešnu proveru konfiguracije
+type validationResult struct {
+	Valid bool `json:"valid"`
+	// SchemaVersion je verzija šeme po kojoj su provereni parametri (izostavlja se ako šema ne postoji)
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+}
+
+// POST /configs/validate
+func (c ConfigHandler) Validate(w http.ResponseWriter, r *http.Request) {
+	config, ok := decodeConfig(w, r)
+	if !ok {
+		return
+	}
+	if !c.access.allow(w, r, rbac.Create, namespacePath(r, "configs/%s/%d", config.Name, config.Version)) {
+		return
+	}
+
+	// Provera je ista kao pri kreiranju, ali se konfiguracija ne čuva
+	schemaVersion, err := c.in(r).Validate(config)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(validationResult{Valid: true, SchemaVersion: schemaVersion})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
 // GET /configs/{name}/{version}
 func (c ConfigHandler) Get(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := c.secrets.reveal(w, r, VersionResource("configs", "name", "version"))
+	if !ok {
+		return
+	}
+	format, ok := responseFormat(w, r)
+	if !ok {
+		return
+	}
+
 	name := mux.Vars(r)["name"]
 	version := mux.Vars(r)["version"]
 	versionInt, err := strconv.Atoi(version)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	config, err := c.service.Get(name, versionInt)
+	config, err := c.in(r).Get(name, versionInt)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
+	if !reveal {
+		config = config.Masked()
+	}
 
-	resp, err := json.Marshal(config)
+	resp, err := formats.EncodeConfig(format, config)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeEncodeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(resp)
+	setETag(w, config.Revision)
+	writeFormatted(w, format, resp)
 }
 
 // DELETE /configs/{name}/{version}
@@ -70,33 +130,199 @@ func (c ConfigHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	version := mux.Vars(r)["version"]
 	versionInt, err := strconv.Atoi(version)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	err = c.service.Delete(name, versionInt)
+	err = c.in(r).Delete(name, versionInt, ifMatch(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// GET /configs
+// GET /configs?limit=&cursor=&sort=&order=&namePrefix=&parameter=
 func (c ConfigHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	configs, err := c.service.GetAll()
+	reveal, ok := c.secrets.reveal(w, r, ListResource("configs"))
+	if !ok {
+		return
+	}
+
+	query, err := listQuery(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
+	page, err := c.in(r).List(query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	configs := page.Items
+	if !reveal {
+		configs = model.MaskConfigs(configs)
+	}
+
 	resp, err := json.Marshal(configs)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+
+	setNextLink(w, r, page.NextCursor)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// POST /configs/{name}/versions
+func (c ConfigHandler) CreateVersion(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := c.secrets.reveal(w, r, AllVersionsResource("configs", "name"))
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+
+	// Verziju dodeljuje server, pa se eventualna verzija iz tela zanemaruje
+	config, ok := decodeConfig(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := c.in(r).CreateVersion(name, config)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
+	if !reveal {
+		created = created.Masked()
+	}
 
+	resp, err := json.Marshal(created)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setETag(w, created.Revision)
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/"+namespacePath(r, "configs/%s/%d", created.Name, created.Version))
+	w.WriteHeader(http.StatusCreated)
 	w.Write(resp)
 }
+
+// GET /configs/{name}/latest
+func (c ConfigHandler) Latest(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := c.secrets.reveal(w, r, AllVersionsResource("configs", "name"))
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+
+	config, err := c.in(r).Latest(name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !reveal {
+		config = config.Masked()
+	}
+
+	resp, err := json.Marshal(config)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setETag(w, config.Revision)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// GET /configs/{name}
+func (c ConfigHandler) Versions(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	versions, err := c.in(r).Versions(name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(versions)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// POST /configs/{name}/{version}/rollback
+func (c ConfigHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := c.secrets.reveal(w, r, AllVersionsResource("configs", "name"))
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	version := mux.Vars(r)["version"]
+	versionInt, err := strconv.Atoi(version)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Rollback ne menja staru verziju, već pravi novu sa istim sadržajem
+	created, err := c.in(r).Rollback(name, versionInt)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !reveal {
+		created = created.Masked()
+	}
+
+	resp, err := json.Marshal(created)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setETag(w, created.Revision)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/"+namespacePath(r, "configs/%s/%d", created.Name, created.Version))
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+}
+
+// GET /configs/{name}/diff?from={version}&to={version}
+func (c ConfigHandler) Diff(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := c.secrets.reveal(w, r, AllVersionsResource("configs", "name"))
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	from, to, err := diffVersions(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	diff, err := c.in(r).Diff(name, from, to)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !reveal {
+		diff = diff.Masked()
+	}
+
+	writeDiff(w, r, diff, func(b *strings.Builder) { writeConfigDiffText(b, diff) })
+}
@@ -3,35 +3,50 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"projekat/formats"
 	"projekat/model"
+	"projekat/rbac"
 	"projekat/services"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
 
 type ConfigGroupHandler struct {
 	service services.ConfigGroupService
+	secrets SecretAccess
+	access  Authorization
 }
 
-func NewConfigGroupHandler(service services.ConfigGroupService) ConfigGroupHandler {
+func NewConfigGroupHandler(service services.ConfigGroupService, secrets SecretAccess, access Authorization) ConfigGroupHandler {
 	return ConfigGroupHandler{
 		service: service,
+		secrets: secrets,
+		access:  access,
 	}
 }
 
+// in vraća servis za prostor imena iz putanje zahteva, koji izmene upisuje u audit log u ime klijenta
+func (c ConfigGroupHandler) in(r *http.Request) services.ConfigGroupService {
+	return c.service.In(requestNamespace(r)).By(requestActor(r))
+}
+
 // POST /configGroups
 func (c ConfigGroupHandler) Create(w http.ResponseWriter, r *http.Request) {
-	var configGroup model.ConfigGroup
-	err := json.NewDecoder(r.Body).Decode(&configGroup)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	configGroup, ok := decodeConfigGroup(w, r)
+	if !ok {
+		return
+	}
+	// Ime grupe i reference se vide tek iz tela, pa se pristup proverava ovde, a ne na ruti
+	if !c.access.allow(w, r, rbac.Create, namespacePath(r, "configGroups/%s/%d", configGroup.Name, configGroup.Version)) ||
+		!c.access.allowReferences(w, r, configGroup.References) {
 		return
 	}
 
-	err = c.service.Create(configGroup)
+	err := c.in(r).Create(configGroup)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -40,28 +55,43 @@ func (c ConfigGroupHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 // GET /configGroups/{name}/{version}
 func (c ConfigGroupHandler) Get(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := c.secrets.reveal(w, r, VersionResource("configGroups", "name", "version"))
+	if !ok {
+		return
+	}
+	format, ok := responseFormat(w, r)
+	if !ok {
+		return
+	}
+
 	name := mux.Vars(r)["name"]
 	version := mux.Vars(r)["version"]
 	versionInt, err := strconv.Atoi(version)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	configGroup, err := c.service.Get(name, versionInt)
+	configGroup, err := c.in(r).Get(name, versionInt)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
+		return
+	}
+	if reveal && !c.secrets.revealReferences(w, r, configGroup) {
 		return
 	}
+	if !reveal {
+		configGroup = configGroup.Masked()
+	}
 
-	resp, err := json.Marshal(configGroup)
+	resp, err := formats.EncodeConfigGroup(format, configGroup)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeEncodeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(resp)
+	setETag(w, configGroup.Revision)
+	writeFormatted(w, format, resp)
 }
 
 // DELETE /configGroups/{name}/{version}
@@ -70,33 +100,52 @@ func (c ConfigGroupHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	version := mux.Vars(r)["version"]
 	versionInt, err := strconv.Atoi(version)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	err = c.service.Delete(name, versionInt)
+	err = c.in(r).Delete(name, versionInt, ifMatch(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// GET /configGroups
+// GET /configGroups?limit=&cursor=&sort=&order=&namePrefix=&parameter=
 func (c ConfigGroupHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	configGroups, err := c.service.GetAll()
+	reveal, ok := c.secrets.reveal(w, r, ListResource("configGroups"))
+	if !ok {
+		return
+	}
+
+	query, err := listQuery(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
+	page, err := c.in(r).List(query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	configGroups := page.Items
+	if reveal && !c.secrets.revealReferences(w, r, page.Items...) {
+		return
+	}
+	if !reveal {
+		configGroups = model.MaskConfigGroups(configGroups)
+	}
+
 	resp, err := json.Marshal(configGroups)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
+	setNextLink(w, r, page.NextCursor)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(resp)
 }
@@ -112,19 +161,19 @@ func (c ConfigGroupHandler) RemoveConfig(w http.ResponseWriter, r *http.Request)
 	// Konverzija verzije grupe i verzije konfiguracije u integer
 	groupVersionInt, err := strconv.Atoi(groupVersion)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 	configVersionInt, err := strconv.Atoi(configVersion)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Poziv servisa za uklanjanje konfiguracije iz grupe
-	err = c.service.RemoveConfig(groupName, groupVersionInt, configName, configVersionInt)
+	err = c.in(r).RemoveConfig(groupName, groupVersionInt, configName, configVersionInt, ifMatch(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
@@ -133,6 +182,11 @@ func (c ConfigGroupHandler) RemoveConfig(w http.ResponseWriter, r *http.Request)
 
 // PUT /configGroups/{groupName}/{groupVersion}/addConfig
 func (c ConfigGroupHandler) AddConfig(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := c.secrets.reveal(w, r, VersionResource("configGroups", "groupName", "groupVersion"))
+	if !ok {
+		return
+	}
+
 	// Dohvatanje imena grupe i verzije grupe iz putanje rute
 	groupName := mux.Vars(r)["groupName"]
 	groupVersion := mux.Vars(r)["groupVersion"]
@@ -140,23 +194,365 @@ func (c ConfigGroupHandler) AddConfig(w http.ResponseWriter, r *http.Request) {
 	// Konverzija verzije grupe u integer
 	groupVersionInt, err := strconv.Atoi(groupVersion)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Dekodiranje tela zahteva kako bismo dobili objekat konfiguracije
 	config := model.Config{}
 	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Poziv servisa za dodavanje konfiguracije u grupu
-	err = c.service.AddConfigs(groupName, groupVersionInt, config)
+	configGroup, err := c.in(r).AddConfigs(groupName, groupVersionInt, config, ifMatch(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if reveal && !c.secrets.revealReferences(w, r, configGroup) {
+		return
+	}
+	if !reveal {
+		configGroup = configGroup.Masked()
+	}
+
+	// Vraćamo izmenjenu grupu
+	resp, err := json.Marshal(configGroup)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
+	setETag(w, configGroup.Revision)
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+}
+
+// PUT /configGroups/{groupName}/{groupVersion}/addReference
+func (c ConfigGroupHandler) AddReference(w http.ResponseWriter, r *http.Request) {
+	groupName := mux.Vars(r)["groupName"]
+	groupVersion := mux.Vars(r)["groupVersion"]
+
+	groupVersionInt, err := strconv.Atoi(groupVersion)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Telo zahteva sadrži samo ime i verziju konfiguracije na koju grupa upućuje
+	reference := model.ConfigReference{}
+	if err := json.NewDecoder(r.Body).Decode(&reference); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !c.access.allowReferences(w, r, []model.ConfigReference{reference}) {
+		return
+	}
+
+	err = c.in(r).AddReference(groupName, groupVersionInt, reference, ifMatch(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// DELETE /configGroups/{groupName}/{groupVersion}/removeReference/{configName}/{configVersion}
+func (c ConfigGroupHandler) RemoveReference(w http.ResponseWriter, r *http.Request) {
+	groupName := mux.Vars(r)["groupName"]
+	groupVersion := mux.Vars(r)["groupVersion"]
+	configName := mux.Vars(r)["configName"]
+	configVersion := mux.Vars(r)["configVersion"]
+
+	groupVersionInt, err := strconv.Atoi(groupVersion)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	configVersionInt, err := strconv.Atoi(configVersion)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = c.in(r).RemoveReference(groupName, groupVersionInt, configName, configVersionInt, ifMatch(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /configGroups/{name}/{version}/{labels}
+func (c ConfigGroupHandler) GetByLabels(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := c.secrets.reveal(w, r, VersionResource("configGroups", "name", "version"))
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	version := mux.Vars(r)["version"]
+	versionInt, err := strconv.Atoi(version)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Labele se zadaju kao "ključ:vrednost;ključ:vrednost"
+	selector, err := model.ParseLabels(mux.Vars(r)["labels"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reveal && !c.revealStoredReferences(w, r, name, versionInt) {
+		return
+	}
+	configs, err := c.in(r).GetConfigsByLabels(name, versionInt, selector)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !reveal {
+		configs = model.MaskConfigs(configs)
+	}
+
+	resp, err := json.Marshal(configs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// DELETE /configGroups/{name}/{version}/{labels}
+func (c ConfigGroupHandler) DeleteByLabels(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := c.secrets.reveal(w, r, VersionResource("configGroups", "name", "version"))
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	version := mux.Vars(r)["version"]
+	versionInt, err := strconv.Atoi(version)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	selector, err := model.ParseLabels(mux.Vars(r)["labels"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Provera je pre uklanjanja, da odbijeno otkrivanje ne ostavi grupu izmenjenu
+	if reveal && !c.revealStoredReferences(w, r, name, versionInt) {
+		return
+	}
+	removed, err := c.in(r).RemoveConfigsByLabels(name, versionInt, selector, ifMatch(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !reveal {
+		removed = model.MaskConfigs(removed)
+	}
+
+	// Vraćamo uklonjene konfiguracije, da klijent vidi šta je selektor obuhvatio
+	resp, err := json.Marshal(removed)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// POST /configGroups/{name}/versions
+func (c ConfigGroupHandler) CreateVersion(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := c.secrets.reveal(w, r, AllVersionsResource("configGroups", "name"))
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+
+	configGroup, ok := decodeConfigGroup(w, r)
+	if !ok {
+		return
+	}
+	if !c.access.allowReferences(w, r, configGroup.References) {
+		return
+	}
+
+	created, err := c.in(r).CreateVersion(name, configGroup)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if reveal && !c.secrets.revealReferences(w, r, created) {
+		return
+	}
+	if !reveal {
+		created = created.Masked()
+	}
+
+	resp, err := json.Marshal(created)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setETag(w, created.Revision)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/"+namespacePath(r, "configGroups/%s/%d", created.Name, created.Version))
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+}
+
+// GET /configGroups/{name}/latest
+func (c ConfigGroupHandler) Latest(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := c.secrets.reveal(w, r, AllVersionsResource("configGroups", "name"))
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+
+	configGroup, err := c.in(r).Latest(name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if reveal && !c.secrets.revealReferences(w, r, configGroup) {
+		return
+	}
+	if !reveal {
+		configGroup = configGroup.Masked()
+	}
+
+	resp, err := json.Marshal(configGroup)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setETag(w, configGroup.Revision)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// GET /configGroups/{name}
+func (c ConfigGroupHandler) Versions(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	versions, err := c.in(r).Versions(name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(versions)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// POST /configGroups/{name}/{version}/rollback
+func (c ConfigGroupHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := c.secrets.reveal(w, r, AllVersionsResource("configGroups", "name"))
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	version := mux.Vars(r)["version"]
+	versionInt, err := strconv.Atoi(version)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Rollback ne menja staru verziju, već pravi novu sa istim sadržajem
+	created, err := c.in(r).Rollback(name, versionInt)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if reveal && !c.secrets.revealReferences(w, r, created) {
+		return
+	}
+	if !reveal {
+		created = created.Masked()
+	}
+
+	resp, err := json.Marshal(created)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setETag(w, created.Revision)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/"+namespacePath(r, "configGroups/%s/%d", created.Name, created.Version))
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+}
+
+// GET /configGroups/{name}/diff?from={version}&to={version}
+func (c ConfigGroupHandler) Diff(w http.ResponseWriter, r *http.Request) {
+	reveal, ok := c.secrets.reveal(w, r, AllVersionsResource("configGroups", "name"))
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	from, to, err := diffVersions(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reveal && !c.revealStoredReferences(w, r, name, from, to) {
+		return
+	}
+	diff, err := c.in(r).Diff(name, from, to)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !reveal {
+		diff = diff.Masked()
+	}
+
+	writeDiff(w, r, diff, func(b *strings.Builder) { writeConfigGroupDiffText(b, diff) })
+}
+
+// revealStoredReferences čita verzije grupe i proverava da klijent sme da otkrije i konfiguracije na
+// koje upućuju, za odgovore koji vraćaju konfiguracije grupe bez same grupe
+func (c ConfigGroupHandler) revealStoredReferences(w http.ResponseWriter, r *http.Request, name string, versions ...int) bool {
+	for _, version := range versions {
+		configGroup, err := c.in(r).Get(name, version)
+		if err != nil {
+			writeError(w, r, err)
+			return false
+		}
+		if !c.secrets.revealReferences(w, r, configGroup) {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const revealPolicy = `
+roles:
+  admin:
+    rules:
+      - actions: ["*"]
+        resources: ["*"]
+  groupRevealer:
+    rules:
+      - actions: [read, update, reveal]
+        resources: ["configGroups/*"]
+      - actions: [read]
+        resources: ["configs/*"]
+bindings:
+  - role: admin
+    subjects: [admin]
+  - role: groupRevealer
+    subjects: [viewer]
+`
+
+// Grupa upućuje na konfiguraciju sa tajnom; klijent sme da otkrije tajne grupe, ali ne i te konfiguracije
+func TestConfigGroupRevealChecksReferences(t *testing.T) {
+	s := newTestServer(t, revealPolicy)
+	s.must(http.StatusCreated, "admin", "POST", "/configs",
+		`{"name":"db","version":1,"parameters":{"password":"s3cret"},"secrets":["password"],"labels":{"env":"prod"}}`)
+	s.must(http.StatusCreated, "admin", "POST", "/configGroups",
+		`{"name":"app","version":1,"configuration":[],"references":[{"name":"db","version":1}]}`)
+	s.must(http.StatusCreated, "admin", "POST", "/configGroups",
+		`{"name":"app","version":2,"configuration":[]}`)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"get", "GET", "/configGroups/app/1?reveal=true"},
+		{"get by labels", "GET", "/configGroups/app/1/env:prod?reveal=true"},
+		{"delete by labels", "DELETE", "/configGroups/app/1/env:prod?reveal=true"},
+		{"diff", "GET", "/configGroups/app/diff?from=1&to=2&reveal=true"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := s.do("viewer", c.method, c.path, "")
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("got %d, want 403: %s", rec.Code, rec.Body.String())
+			}
+			if strings.Contains(rec.Body.String(), "s3cret") {
+				t.Errorf("response reveals the referenced secret: %s", rec.Body.String())
+			}
+		})
+	}
+
+	// Odbijeno otkrivanje ne sme da ukloni reference iz grupe
+	rec := s.must(http.StatusOK, "admin", "GET", "/configGroups/app/1/env:prod?reveal=true", "")
+	if !strings.Contains(rec.Body.String(), "s3cret") {
+		t.Errorf("admin does not see the referenced secret: %s", rec.Body.String())
+	}
+	rec = s.must(http.StatusOK, "admin", "GET", "/configGroups/app/diff?from=1&to=2&reveal=true", "")
+	if !strings.Contains(rec.Body.String(), "s3cret") {
+		t.Errorf("admin diff does not reveal the removed reference: %s", rec.Body.String())
+	}
+
+	// Bez otkrivanja klijent vidi iste odgovore sa maskiranim tajnama
+	rec = s.must(http.StatusOK, "viewer", "GET", "/configGroups/app/1/env:prod", "")
+	if strings.Contains(rec.Body.String(), "s3cret") || !strings.Contains(rec.Body.String(), `"db"`) {
+		t.Errorf("masked response is %s", rec.Body.String())
+	}
+}
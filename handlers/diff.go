@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"projekat/model"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// diffVersions čita verzije iz ?from=&to= parametara
+func diffVersions(r *http.Request) (int, int, error) {
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("query parameter from must be a version number")
+	}
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("query parameter to must be a version number")
+	}
+	return from, to, nil
+}
+
+// writeDiff šalje razliku kao JSON, ili kao tekst u unified stilu za ?format=text
+func writeDiff(w http.ResponseWriter, r *http.Request, diff interface{}, text func(*strings.Builder)) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		resp, err := json.Marshal(diff)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	case "text":
+		var b strings.Builder
+		text(&b)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(b.String()))
+	default:
+		writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("unknown diff format %q (expected json or text)", format))
+	}
+}
+
+// writeConfigDiffText ispisuje razliku konfiguracije:
+//
+//	--- configs/db_config/1
+//	+++ configs/db_config/2
+//	@@ parameters @@
+//	-password=old
+//	+password=new
+func writeConfigDiffText(b *strings.Builder, diff model.ConfigDiff) {
+	fmt.Fprintf(b, "--- configs/%s/%d\n", diff.Name, diff.FromVersion)
+	fmt.Fprintf(b, "+++ configs/%s/%d\n", diff.Name, diff.ToVersion)
+	writeMapDiffText(b, "parameters", diff.Parameters)
+	writeMapDiffText(b, "labels", diff.Labels)
+}
+
+// writeConfigGroupDiffText ispisuje dodate (+) i uklonjene (-) konfiguracije,
+// pa za svaku izmenjenu konfiguraciju razliku parametara i labela
+func writeConfigGroupDiffText(b *strings.Builder, diff model.ConfigGroupDiff) {
+	fmt.Fprintf(b, "--- configGroups/%s/%d\n", diff.Name, diff.FromVersion)
+	fmt.Fprintf(b, "+++ configGroups/%s/%d\n", diff.Name, diff.ToVersion)
+	for _, config := range diff.Added {
+		fmt.Fprintf(b, "+config %s/%d\n", config.Name, config.Version)
+	}
+	for _, config := range diff.Removed {
+		fmt.Fprintf(b, "-config %s/%d\n", config.Name, config.Version)
+	}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(b, "@@ config %s/%d -> %s/%d @@\n", change.Name, change.FromVersion, change.Name, change.ToVersion)
+		writeMapDiffText(b, "parameters", change.Parameters)
+		writeMapDiffText(b, "labels", change.Labels)
+	}
+}
+
+func writeMapDiffText(b *strings.Builder, section string, diff model.MapDiff) {
+	if diff.Empty() {
+		return
+	}
+
+	keys := make([]string, 0, len(diff.Added)+len(diff.Removed)+len(diff.Modified))
+	for key := range diff.Added {
+		keys = append(keys, key)
+	}
+	for key := range diff.Removed {
+		keys = append(keys, key)
+	}
+	for key := range diff.Modified {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(b, "@@ %s @@\n", section)
+	for _, key := range keys {
+		if value, ok := diff.Removed[key]; ok {
+			fmt.Fprintf(b, "-%s=%s\n", key, model.FormatValue(value))
+		}
+		if change, ok := diff.Modified[key]; ok {
+			fmt.Fprintf(b, "-%s=%s\n", key, model.FormatValue(change.From))
+			fmt.Fprintf(b, "+%s=%s\n", key, model.FormatValue(change.To))
+		}
+		if value, ok := diff.Added[key]; ok {
+			fmt.Fprintf(b, "+%s=%s\n", key, model.FormatValue(value))
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"projekat/model"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func diffServer(t *testing.T) *testServer {
+	s := newTestServer(t, revealPolicy)
+	for _, config := range []string{
+		`{"name":"db","version":1,"parameters":{"host":"a","port":"5432","debug":"true","password":"old"},"labels":{"env":"dev","team":"core"},"secrets":["password"]}`,
+		`{"name":"db","version":2,"parameters":{"host":"b","port":"5432","tls":"on","password":"new"},"labels":{"env":"prod","tier":"1"},"secrets":["password"]}`,
+		`{"name":"cache","version":1,"parameters":{"size":"1"}}`,
+	} {
+		s.must(http.StatusCreated, "admin", "POST", "/configs", config)
+	}
+	s.must(http.StatusCreated, "admin", "POST", "/configGroups",
+		`{"name":"app","version":1,"configuration":[{"name":"web","version":1,"parameters":{"port":"80"}},{"name":"old","version":1,"parameters":{"x":"1"}}],"references":[{"name":"db","version":1}]}`)
+	s.must(http.StatusCreated, "admin", "POST", "/configGroups",
+		`{"name":"app","version":2,"configuration":[{"name":"web","version":1,"parameters":{"port":"8080"}}],"references":[{"name":"db","version":2},{"name":"cache","version":1}]}`)
+	return s
+}
+
+func TestConfigDiffHandler(t *testing.T) {
+	s := diffServer(t)
+
+	rec := s.must(http.StatusOK, "admin", "GET", "/configs/db/diff?from=1&to=2", "")
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("got Content-Type %q", rec.Header().Get("Content-Type"))
+	}
+	var diff model.ConfigDiff
+	if err := json.Unmarshal(rec.Body.Bytes(), &diff); err != nil {
+		t.Fatal(err)
+	}
+	want := model.ConfigDiff{
+		Name:        "db",
+		FromVersion: 1,
+		ToVersion:   2,
+		Parameters: model.MapDiff{
+			Added:   map[string]interface{}{"tls": "on"},
+			Removed: map[string]interface{}{"debug": "true"},
+			Modified: map[string]model.ValueChange{
+				"host":     {From: "a", To: "b"},
+				"password": {From: model.MaskedValue, To: model.MaskedValue},
+			},
+		},
+		Labels: model.MapDiff{
+			Added:    map[string]interface{}{"tier": "1"},
+			Removed:  map[string]interface{}{"team": "core"},
+			Modified: map[string]model.ValueChange{"env": {From: "dev", To: "prod"}},
+		},
+		Secrets: []string{"password"},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("got %+v, want %+v", diff, want)
+	}
+
+	// Tajne se otkrivaju samo uz ?reveal=true
+	rec = s.must(http.StatusOK, "admin", "GET", "/configs/db/diff?from=1&to=2&reveal=true", "")
+	if !strings.Contains(rec.Body.String(), `"password":{"from":"old","to":"new"}`) {
+		t.Errorf("revealed diff is %s", rec.Body.String())
+	}
+
+	rec = s.must(http.StatusOK, "admin", "GET", "/configs/db/diff?from=1&to=2&format=text", "")
+	wantText := "--- configs/db/1\n" +
+		"+++ configs/db/2\n" +
+		"@@ parameters @@\n" +
+		"-debug=true\n" +
+		"-host=a\n" +
+		"+host=b\n" +
+		"-password=" + model.FormatValue(model.MaskedValue) + "\n" +
+		"+password=" + model.FormatValue(model.MaskedValue) + "\n" +
+		"+tls=on\n" +
+		"@@ labels @@\n" +
+		"-env=dev\n" +
+		"+env=prod\n" +
+		"-team=core\n" +
+		"+tier=1\n"
+	if rec.Body.String() != wantText {
+		t.Errorf("text diff is\n%s\nwant\n%s", rec.Body.String(), wantText)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("got Content-Type %q", rec.Header().Get("Content-Type"))
+	}
+
+	// Verzija sa sobom nema razlike, pa tekst ima samo zaglavlje
+	rec = s.must(http.StatusOK, "admin", "GET", "/configs/cache/diff?from=1&to=1&format=text", "")
+	if rec.Body.String() != "--- configs/cache/1\n+++ configs/cache/1\n" {
+		t.Errorf("text diff of a version with itself is %q", rec.Body.String())
+	}
+}
+
+func TestConfigGroupDiffHandler(t *testing.T) {
+	s := diffServer(t)
+
+	rec := s.must(http.StatusOK, "admin", "GET", "/configGroups/app/diff?from=1&to=2", "")
+	var diff model.ConfigGroupDiff
+	if err := json.Unmarshal(rec.Body.Bytes(), &diff); err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Name != "cache" || diff.Added[0].Parameters["size"] != "1" {
+		t.Errorf("added %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Name != "old" {
+		t.Errorf("removed %+v", diff.Removed)
+	}
+	if len(diff.Changed) != 2 || diff.Changed[0].Name != "db" || diff.Changed[1].Name != "web" {
+		t.Fatalf("changed %+v", diff.Changed)
+	}
+	if change := diff.Changed[0].Parameters.Modified["password"]; change.From != model.MaskedValue || change.To != model.MaskedValue {
+		t.Errorf("secret of a referenced config is not masked: %s", rec.Body.String())
+	}
+
+	rec = s.must(http.StatusOK, "admin", "GET", "/configGroups/app/diff?from=1&to=2&format=text", "")
+	wantText := "--- configGroups/app/1\n" +
+		"+++ configGroups/app/2\n" +
+		"+config cache/1\n" +
+		"-config old/1\n" +
+		"@@ config db/1 -> db/2 @@\n" +
+		"@@ parameters @@\n" +
+		"-debug=true\n" +
+		"-host=a\n" +
+		"+host=b\n" +
+		"-password=" + model.FormatValue(model.MaskedValue) + "\n" +
+		"+password=" + model.FormatValue(model.MaskedValue) + "\n" +
+		"+tls=on\n" +
+		"@@ labels @@\n" +
+		"-env=dev\n" +
+		"+env=prod\n" +
+		"-team=core\n" +
+		"+tier=1\n" +
+		"@@ config web/1 -> web/1 @@\n" +
+		"@@ parameters @@\n" +
+		"-port=80\n" +
+		"+port=8080\n"
+	if rec.Body.String() != wantText {
+		t.Errorf("text diff is\n%s\nwant\n%s", rec.Body.String(), wantText)
+	}
+}
+
+func TestDiffHandlerErrors(t *testing.T) {
+	s := diffServer(t)
+	s.must(http.StatusCreated, "admin", "POST", "/namespaces", `{"name":"team"}`)
+
+	cases := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"missing to version", "/configs/db/diff?from=1&to=3", http.StatusNotFound},
+		{"missing from version", "/configs/db/diff?from=3&to=1", http.StatusNotFound},
+		{"missing config", "/configs/queue/diff?from=1&to=2", http.StatusNotFound},
+		{"config in another namespace", "/namespaces/team/configs/db/diff?from=1&to=2", http.StatusNotFound},
+		{"missing group version", "/configGroups/app/diff?from=1&to=3", http.StatusNotFound},
+		{"missing group", "/configGroups/web/diff?from=1&to=2", http.StatusNotFound},
+		{"from not a number", "/configs/db/diff?from=one&to=2", http.StatusBadRequest},
+		{"to missing", "/configs/db/diff?from=1", http.StatusBadRequest},
+		{"group to not a number", "/configGroups/app/diff?from=1&to=latest", http.StatusBadRequest},
+		{"unknown format", "/configs/db/diff?from=1&to=2&format=yaml", http.StatusBadRequest},
+		{"unknown group format", "/configGroups/app/diff?from=1&to=2&format=html", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := s.do("admin", "GET", c.path, "")
+			if rec.Code != c.status {
+				t.Fatalf("got %d, want %d: %s", rec.Code, c.status, rec.Body.String())
+			}
+			if rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("got Content-Type %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"projekat/model"
+	"strconv"
+	"strings"
+)
+
+// ifMatch čita If-Match zaglavlje (RFC 9110) u uslov za servis. ETag je revizija zapisa pod
+// navodnicima; slabi ETag-ovi (W/) i ETag-ovi koji nisu revizija nikad ne odgovaraju.
+func ifMatch(r *http.Request) model.Precondition {
+	values := r.Header.Values("If-Match")
+	if len(values) == 0 {
+		return model.Precondition{}
+	}
+
+	precondition := model.Precondition{Set: true}
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" {
+				precondition.Any = true
+				continue
+			}
+			unquoted, err := strconv.Unquote(tag)
+			if err != nil || !strings.HasPrefix(tag, `"`) {
+				continue
+			}
+			if revision, err := strconv.ParseInt(unquoted, 10, 64); err == nil {
+				precondition.Revisions = append(precondition.Revisions, revision)
+			}
+		}
+	}
+	return precondition
+}
+
+// setETag postavlja ETag odgovora na reviziju zapisa
+func setETag(w http.ResponseWriter, revision int64) {
+	w.Header().Set("ETag", model.ETag(revision))
+}
+
+// Preconditions određuje da li izmene postojećih zapisa moraju da pošalju If-Match
+type Preconditions struct {
+	required bool
+}
+
+func NewPreconditions(required bool) Preconditions {
+	return Preconditions{
+		required: required,
+	}
+}
+
+// Wrap odbija zahtev bez If-Match sa 428 kada je uslov obavezan
+func (p Preconditions) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p.required && r.Header.Get("If-Match") == "" {
+			writeProblem(w, r, http.StatusPreconditionRequired, "this request must be conditional: send If-Match with the ETag from GET")
+			return
+		}
+		next(w, r)
+	}
+}
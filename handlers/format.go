@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"projekat/formats"
+	"projekat/model"
+	"strconv"
+	"strings"
+)
+
+// responseFormat bira format odgovora: ?format= ima prednost nad Accept zaglavljem.
+// Ako format nije podržan, šalje grešku i vraća ok == false.
+func responseFormat(w http.ResponseWriter, r *http.Request) (formats.Format, bool) {
+	w.Header().Add("Vary", "Accept")
+	if name := r.URL.Query().Get("format"); name != "" {
+		format, ok := formats.Parse(name)
+		if !ok {
+			writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("unknown format %q (supported: %s)", name, strings.Join(formats.Names(), ", ")))
+			return "", false
+		}
+		return format, true
+	}
+
+	format, ok := formats.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		writeProblem(w, r, http.StatusNotAcceptable, fmt.Sprintf("none of the accepted media types is supported (formats: %s)", strings.Join(formats.Names(), ", ")))
+		return "", false
+	}
+	return format, true
+}
+
+// writeFormatted šalje već prikazan odgovor sa Content-Type-om formata
+func writeFormatted(w http.ResponseWriter, format formats.Format, body []byte) {
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Write(body)
+}
+
+// writeEncodeError šalje grešku prikaza; zapis koji ne može da se prikaže u traženom formatu daje 406
+func writeEncodeError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, formats.ErrUnsupported) {
+		writeProblem(w, r, http.StatusNotAcceptable, err.Error())
+		return
+	}
+	writeError(w, r, err)
+}
+
+// requestFormat čita format tela iz Content-Type zaglavlja; telo bez zaglavlja je JSON.
+// Forma (application/x-www-form-urlencoded, podrazumevana za curl -d) nije podržan format i daje
+// 415, umesto da se telo pogađa; uvoz arhive, koji prepoznaje sadržaj tela, to radi zasebno.
+func requestFormat(w http.ResponseWriter, r *http.Request) (formats.Format, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return formats.JSON, true
+	}
+	if isFormContentType(contentType) {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "form bodies are not supported; send the body with a Content-Type such as application/json (curl: -H 'Content-Type: application/json')")
+		return "", false
+	}
+	format, ok := formats.FromMediaType(contentType)
+	if !ok {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported content type %q", contentType))
+		return "", false
+	}
+	return format, true
+}
+
+// decodeConfig čita konfiguraciju iz tela zahteva. Ravni formati sadrže samo parametre,
+// pa se ime, verzija, labele i tajni parametri zadaju u upitu:
+// ?name=db_config&version=2&labels=env:prod;region:eu&secrets=password,token
+func decodeConfig(w http.ResponseWriter, r *http.Request) (model.Config, bool) {
+	format, ok := requestFormat(w, r)
+	if !ok {
+		return model.Config{}, false
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return model.Config{}, false
+	}
+
+	config, err := formats.DecodeConfig(format, body)
+	if err != nil {
+		writeDecodeError(w, r, err)
+		return model.Config{}, false
+	}
+	if !format.Flat() {
+		return config, true
+	}
+
+	query := r.URL.Query()
+	config.Name = query.Get("name")
+	if config.Version, ok = queryVersion(w, r); !ok {
+		return model.Config{}, false
+	}
+	if selector := query.Get("labels"); selector != "" {
+		labels, err := model.ParseLabels(selector)
+		if err != nil {
+			writeError(w, r, err)
+			return model.Config{}, false
+		}
+		config.Labels = labels
+	}
+	if secrets := query.Get("secrets"); secrets != "" {
+		for _, name := range strings.Split(secrets, ",") {
+			config.Secrets = append(config.Secrets, strings.TrimSpace(name))
+		}
+	}
+	return config, true
+}
+
+// decodeConfigGroup čita grupu iz tela zahteva. Za INI se ime i verzija grupe zadaju u upitu
+// (?name=...&version=...), a svaka konfiguracija je sekcija [ime@verzija].
+func decodeConfigGroup(w http.ResponseWriter, r *http.Request) (model.ConfigGroup, bool) {
+	format, ok := requestFormat(w, r)
+	if !ok {
+		return model.ConfigGroup{}, false
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return model.ConfigGroup{}, false
+	}
+
+	configGroup, err := formats.DecodeConfigGroup(format, body)
+	if err != nil {
+		writeDecodeError(w, r, err)
+		return model.ConfigGroup{}, false
+	}
+	if format.Flat() {
+		configGroup.Name = r.URL.Query().Get("name")
+		if configGroup.Version, ok = queryVersion(w, r); !ok {
+			return model.ConfigGroup{}, false
+		}
+	}
+	return configGroup, true
+}
+
+func queryVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("version")
+	if value == "" {
+		return 0, true
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("version must be a number, got %q", value))
+		return 0, false
+	}
+	return version, true
+}
+
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, formats.ErrUnsupported) {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+	writeError(w, r, err)
+}
+
+// isFormContentType prepoznaje podrazumevani Content-Type koji curl -d i --data-binary šalju i kada telo nije forma
+func isFormContentType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/x-www-form-urlencoded"
+}
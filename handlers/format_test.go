@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDecodeConfigContentTypes(t *testing.T) {
+	cases := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"json", "/configs", "application/json", `{"name":"c1","version":1,"parameters":{"host":"a"}}`, http.StatusCreated},
+		{"json with charset", "/configs", "application/json; charset=utf-8", `{"name":"c2","version":1,"parameters":{"host":"a"}}`, http.StatusCreated},
+		{"no content type", "/configs", "", `{"name":"c3","version":1,"parameters":{"host":"a"}}`, http.StatusCreated},
+		{"yaml", "/configs", "application/yaml", "name: c4\nversion: 1\nparameters:\n  host: a\n", http.StatusCreated},
+		{"toml", "/configs", "application/toml", "name = \"c5\"\nversion = 1\n[parameters]\nhost = \"a\"\n", http.StatusCreated},
+		{"env with query", "/configs?name=c6&version=1&labels=env:prod&secrets=DB_PASS", "text/x-env", "DB_HOST=a\nDB_PASS=s3cret\n", http.StatusCreated},
+		{"properties", "/configs?name=c7&version=1", "text/x-java-properties", "db.host=a\n", http.StatusCreated},
+		{"ini", "/configs?name=c8&version=1", "text/x-ini", "host = a\n", http.StatusCreated},
+		{"form", "/configs", "application/x-www-form-urlencoded", `{"name":"c9","version":1,"parameters":{"host":"a"}}`, http.StatusUnsupportedMediaType},
+		{"form with charset", "/configs", "application/x-www-form-urlencoded; charset=utf-8", `{"name":"c9","version":1,"parameters":{"host":"a"}}`, http.StatusUnsupportedMediaType},
+		{"plain text", "/configs", "text/plain", `{"name":"c9","version":1,"parameters":{"host":"a"}}`, http.StatusUnsupportedMediaType},
+		{"malformed content type", "/configs", "application/", `{"name":"c9","version":1,"parameters":{"host":"a"}}`, http.StatusUnsupportedMediaType},
+		{"invalid json", "/configs", "application/json", `{"name":`, http.StatusBadRequest},
+		{"ini with sections", "/configs?name=c9&version=1", "text/x-ini", "[db]\nhost = a\n", http.StatusBadRequest},
+		{"flat version not a number", "/configs?name=c9&version=x", "text/x-env", "DB_HOST=a\n", http.StatusBadRequest},
+		{"new version as form", "/configs/c1/versions", "application/x-www-form-urlencoded", `{"parameters":{"host":"b"}}`, http.StatusUnsupportedMediaType},
+		{"group as form", "/configGroups", "application/x-www-form-urlencoded", `{"name":"g1","version":1,"configuration":[]}`, http.StatusUnsupportedMediaType},
+		{"group as env", "/configGroups?name=g1&version=1", "text/x-env", "DB_HOST=a\n", http.StatusUnsupportedMediaType},
+		{"group as ini", "/configGroups?name=g1&version=1", "text/x-ini", "[db@1]\nhost = a\n", http.StatusCreated},
+	}
+
+	s := newTestServer(t, "")
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := s.do("", "POST", c.path, c.body, "Content-Type", c.contentType)
+			if rec.Code != c.status {
+				t.Fatalf("got %d, want %d: %s", rec.Code, c.status, rec.Body.String())
+			}
+			if c.status >= 400 && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("got Content-Type %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	// Ravni formati daju string parametre, a ime, verzija, labele i tajne dolaze iz upita
+	rec := s.must(http.StatusOK, "", "GET", "/configs/c6/1", "")
+	var config struct {
+		Parameters map[string]interface{} `json:"parameters"`
+		Labels     map[string]string      `json:"labels"`
+		Secrets    []string               `json:"secrets"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Parameters["DB_HOST"] != "a" || config.Labels["env"] != "prod" || len(config.Secrets) != 1 || config.Secrets[0] != "DB_PASS" {
+		t.Errorf("config read from .env is %s", rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "s3cret") {
+		t.Errorf("secret parameter is not masked: %s", rec.Body.String())
+	}
+
+	// Odbijeno telo forme nije ništa upisalo
+	s.must(http.StatusNotFound, "", "GET", "/configs/c9/1", "")
+}
+
+// Parametri koji bi u ravnom formatu dobili isti ključ daju 406 umesto fajla sa pregaženom vrednošću
+func TestEncodeConfigKeyCollision(t *testing.T) {
+	s := newTestServer(t, "")
+	s.must(http.StatusCreated, "", "POST", "/configs", `{"name":"db","version":1,"parameters":{"pool":{"max":1},"pool.max":2,"pool_min":3}}`)
+
+	cases := []struct {
+		format string
+		status int
+	}{
+		{"json", http.StatusOK},
+		{"yaml", http.StatusOK},
+		{"properties", http.StatusNotAcceptable},
+		{"ini", http.StatusNotAcceptable},
+		{"env", http.StatusNotAcceptable},
+	}
+	for _, c := range cases {
+		t.Run(c.format, func(t *testing.T) {
+			rec := s.do("", "GET", "/configs/db/1?format="+c.format, "")
+			if rec.Code != c.status {
+				t.Fatalf("got %d, want %d: %s", rec.Code, c.status, rec.Body.String())
+			}
+			if c.status == http.StatusNotAcceptable && !strings.Contains(rec.Body.String(), "pool.max") {
+				t.Errorf("problem does not name the colliding parameters: %s", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"projekat/auth"
+	"sync"
+	"time"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// Koliko dugo ključ zahteva u obradi blokira ponovljene zahteve. Posle toga se smatra da je
+// obrada prekinuta, pa klijent može ponovo da pošalje zahtev sa istim ključem.
+const idempotencyInFlightTTL = 5 * time.Minute
+
+// IdempotencyStore pamti prvi odgovor za svaki Idempotency-Key i ponavlja ga za ponovljene zahteve.
+// Ključ važi samo za istu metodu i putanju (i istog klijenta, ako je autentifikacija uključena); ponovljen zahtev sa istim ključem a drugačijim telom
+// dobija 422, a zahtev koji stigne dok je prvi još u obradi dobija 409. Odbijeni zahtevi (401, 403)
+// i serverske greške se ne pamte.
+type IdempotencyStore struct {
+	mu  sync.Mutex
+	ttl time.Duration
+	// maxBodyBytes ograničava telo koje se čita u memoriju da bi se uporedilo sa ponovljenim zahtevom
+	maxBodyBytes int64
+	entries      map[string]*idempotencyEntry
+}
+
+type idempotencyEntry struct {
+	requestHash [sha256.Size]byte
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+func NewIdempotencyStore(ttl time.Duration, maxBodyBytes int64) *IdempotencyStore {
+	return &IdempotencyStore{
+		ttl:          ttl,
+		maxBodyBytes: maxBodyBytes,
+		entries:      make(map[string]*idempotencyEntry),
+	}
+}
+
+// Wrap dodaje podršku za Idempotency-Key zaglavlje handleru
+func (s *IdempotencyStore) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBodyBytes))
+		if err != nil {
+			if bodyTooLarge(err) {
+				writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is larger than %d bytes", s.maxBodyBytes))
+				return
+			}
+			writeProblem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := sha256.Sum256(body)
+		scopedKey := r.Method + " " + r.URL.Path + " " + key
+		// Ključevi različitih klijenata se ne mešaju
+		if principal, ok := auth.FromContext(r.Context()); ok {
+			scopedKey = principal.Method + ":" + principal.Subject + " " + scopedKey
+		}
+
+		s.mu.Lock()
+		s.purgeExpired(time.Now())
+		if entry, exists := s.entries[scopedKey]; exists {
+			// Odgovor se ne menja kada je zahtev završen, pa se posle otključavanja čita bez brave
+			done := entry.done
+			s.mu.Unlock()
+			switch {
+			case entry.requestHash != requestHash:
+				writeProblem(w, r, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request body")
+			case !done:
+				writeProblem(w, r, http.StatusConflict, "a request with this Idempotency-Key is still being processed")
+			default:
+				entry.replay(w)
+			}
+			return
+		}
+		entry := &idempotencyEntry{requestHash: requestHash, expires: time.Now().Add(idempotencyInFlightTTL)}
+		s.entries[scopedKey] = entry
+		s.mu.Unlock()
+
+		// Nedovršen ključ se briše i kada handler panikuje, da ponovljeni zahtevi ne bi zauvek dobijali 409
+		finished := false
+		defer func() {
+			if !finished {
+				s.forget(scopedKey, entry)
+			}
+		}()
+
+		recorder := &responseRecorder{header: w.Header(), status: http.StatusOK}
+		next(recorder, r)
+
+		if !rememberStatus(recorder.status) {
+			s.forget(scopedKey, entry)
+		} else {
+			s.mu.Lock()
+			entry.done = true
+			entry.status = recorder.status
+			entry.header = w.Header().Clone()
+			entry.body = recorder.body.Bytes()
+			entry.expires = time.Now().Add(s.ttl)
+			s.mu.Unlock()
+		}
+		finished = true
+
+		w.WriteHeader(recorder.status)
+		w.Write(recorder.body.Bytes())
+	}
+}
+
+// rememberStatus govori da li se odgovor pamti za ponovljene zahteve. Serverske greške se ne
+// pamte, da bi klijent mogao ponovo da pokuša, a ni 401 i 403: handleri kao Create proveravaju
+// ovlašćenja tek kad pročitaju telo, a odbijen zahtev ne sme da zauzme ključ pa da i posle
+// dodele prava ponovljeni zahtev dobija isti odgovor.
+func rememberStatus(status int) bool {
+	return status < http.StatusInternalServerError && status != http.StatusUnauthorized && status != http.StatusForbidden
+}
+
+// forget briše ključ, ali samo ako i dalje pripada ovom zahtevu: posle isteka ključ je mogao
+// da preuzme novi zahtev
+func (s *IdempotencyStore) forget(key string, entry *idempotencyEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries[key] == entry {
+		delete(s.entries, key)
+	}
+}
+
+// purgeExpired briše istekle odgovore i ključeve zahteva čija je obrada predugo trajala;
+// poziva se pod bravom
+func (s *IdempotencyStore) purgeExpired(now time.Time) {
+	for key, entry := range s.entries {
+		if now.After(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+func (e *idempotencyEntry) replay(w http.ResponseWriter) {
+	for name, values := range e.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(e.status)
+	w.Write(e.body)
+}
+
+// responseRecorder zadržava odgovor handlera dok ga ne zapamtimo
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status = status
+	r.wroteHeader = true
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(b)
+}
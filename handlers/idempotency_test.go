@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+const idempotencyPolicy = `
+roles:
+  reader:
+    rules:
+      - actions: [read]
+        resources: ["*"]
+  writer:
+    rules:
+      - actions: ["*"]
+        resources: ["*"]
+bindings:
+  - role: reader
+    subjects: [ci]
+  - role: writer
+    subjects: [admin]
+`
+
+// Create proverava ovlašćenja tek kad pročita telo, unutar Idempotency-Key provere
+func TestIdempotencyDoesNotRememberDeniedRequests(t *testing.T) {
+	s := newTestServer(t, idempotencyPolicy)
+	cases := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"config", "/configs", `{"name":"db","version":1}`},
+		{"config group", "/configGroups", `{"name":"app","version":1,"configuration":[]}`},
+		{"namespace", "/namespaces", `{"name":"team"}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			key := []string{idempotencyKeyHeader, "key-" + c.name}
+			s.must(http.StatusForbidden, "ci", "POST", c.path, c.body, key...)
+			rec := s.must(http.StatusForbidden, "ci", "POST", c.path, c.body, key...)
+			if rec.Header().Get("Idempotent-Replayed") != "" {
+				t.Error("403 was replayed")
+			}
+		})
+	}
+
+	// Kada klijent dobije pravo, ponovljen zahtev sa istim ključem se izvršava
+	s.writePolicy(`
+roles:
+  writer:
+    rules:
+      - actions: ["*"]
+        resources: ["*"]
+bindings:
+  - role: writer
+    subjects: [admin, ci]
+`)
+	if _, err := s.engine.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		key := []string{idempotencyKeyHeader, "key-" + c.name}
+		s.must(http.StatusCreated, "ci", "POST", c.path, c.body, key...)
+		rec := s.must(http.StatusCreated, "ci", "POST", c.path, c.body, key...)
+		if rec.Header().Get("Idempotent-Replayed") != "true" {
+			t.Errorf("%s: repeated request was not replayed", c.name)
+		}
+	}
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	s := newTestServer(t, "")
+	key := []string{idempotencyKeyHeader, "create-db"}
+	first := s.must(http.StatusCreated, "", "POST", "/configs", `{"name":"db","version":1}`, key...)
+	replayed := s.must(http.StatusCreated, "", "POST", "/configs", `{"name":"db","version":1}`, key...)
+	if replayed.Header().Get("Idempotent-Replayed") != "true" || replayed.Body.String() != first.Body.String() {
+		t.Errorf("replayed response is %s", replayed.Body.String())
+	}
+	s.must(http.StatusUnprocessableEntity, "", "POST", "/configs", `{"name":"db","version":2}`, key...)
+	// Isti ključ drugog klijenta je drugi ključ
+	s.must(http.StatusConflict, "other", "POST", "/configs", `{"name":"db","version":1}`, key...)
+	// Greške klijenta se pamte kao i uspešni odgovori
+	key = []string{idempotencyKeyHeader, "invalid"}
+	s.must(http.StatusBadRequest, "", "POST", "/configs", `{"name":`, key...)
+	if rec := s.must(http.StatusBadRequest, "", "POST", "/configs", `{"name":`, key...); rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("400 was not replayed")
+	}
+}
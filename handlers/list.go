@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"projekat/model"
+	"strconv"
+)
+
+// listQuery čita parametre liste iz upita:
+// ?limit=50&cursor=...&sort=name|version|createdAt&order=asc|desc&namePrefix=db_&parameter=port
+func listQuery(r *http.Request) (model.ListQuery, error) {
+	values := r.URL.Query()
+	query := model.ListQuery{
+		NamePrefix:   values.Get("namePrefix"),
+		ParameterKey: values.Get("parameter"),
+		Cursor:       values.Get("cursor"),
+		Scope:        r.URL.Path,
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		field, err := model.ParseSortField(sort)
+		if err != nil {
+			return model.ListQuery{}, err
+		}
+		query.Sort = field
+	}
+	switch order := values.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return model.ListQuery{}, model.Invalidf("order must be asc or desc, got %q", order)
+	}
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return model.ListQuery{}, model.Invalidf("limit must be a positive number, got %q", limit)
+		}
+		query.Limit = n
+	}
+
+	if err := query.Validate(); err != nil {
+		return model.ListQuery{}, err
+	}
+	return query, nil
+}
+
+// setNextLink dodaje Link zaglavlje (RFC 8288) sa adresom sledeće strane, ako ona postoji
+func setNextLink(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	if nextCursor == "" {
+		return
+	}
+	values := r.URL.Query()
+	values.Set("cursor", nextCursor)
+	w.Header().Set("Link", fmt.Sprintf("<%s?%s>; rel=\"next\"", r.URL.Path, values.Encode()))
+}
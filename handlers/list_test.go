@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+var nextLink = regexp.MustCompile(`^<([^>]+)>; rel="next"$`)
+
+type listedRecord struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+// listPage čita jednu stranu liste i adresu sledeće iz Link zaglavlja
+func (s *testServer) listPage(path string) ([]string, string) {
+	s.t.Helper()
+	rec := s.must(http.StatusOK, "", "GET", path, "")
+	var records []listedRecord
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+		s.t.Fatal(err)
+	}
+	keys := make([]string, 0, len(records))
+	for _, record := range records {
+		keys = append(keys, fmt.Sprintf("%s/%d", record.Name, record.Version))
+	}
+
+	link := rec.Header().Get("Link")
+	if link == "" {
+		return keys, ""
+	}
+	match := nextLink.FindStringSubmatch(link)
+	if match == nil {
+		s.t.Fatalf("malformed Link header %q", link)
+	}
+	return keys, match[1]
+}
+
+// listAll prati Link zaglavlja od prve strane do poslednje
+func (s *testServer) listAll(path string) []string {
+	s.t.Helper()
+	var all []string
+	for pages := 0; path != ""; pages++ {
+		if pages > 20 {
+			s.t.Fatal("paging does not end")
+		}
+		var keys []string
+		keys, path = s.listPage(path)
+		all = append(all, keys...)
+	}
+	return all
+}
+
+func listServer(t *testing.T) *testServer {
+	s := newTestServer(t, "")
+	for _, config := range []string{
+		`{"name":"web","version":1,"parameters":{"host":"a","port":80}}`,
+		`{"name":"db","version":1,"parameters":{"host":"a"}}`,
+		`{"name":"api","version":1,"parameters":{"host":"a","port":8080}}`,
+		`{"name":"db_replica","version":1,"parameters":{"host":"a","port":5432}}`,
+		`{"name":"db","version":2,"parameters":{"host":"a","port":5432}}`,
+		`{"name":"cache","version":1,"parameters":{"host":"a"}}`,
+		`{"name":"db","version":3,"parameters":{"password":"s3cret"},"secrets":["password"]}`,
+	} {
+		s.must(http.StatusCreated, "", "POST", "/configs", config)
+	}
+	s.must(http.StatusCreated, "", "POST", "/configGroups", `{"name":"app","version":1,"configuration":[]}`)
+	s.must(http.StatusCreated, "", "POST", "/configGroups", `{"name":"app","version":2,"configuration":[]}`)
+	s.must(http.StatusCreated, "", "POST", "/configGroups", `{"name":"db_group","version":1,"configuration":[]}`)
+	return s
+}
+
+func TestListPagesFollowLinkHeader(t *testing.T) {
+	s := listServer(t)
+	cases := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"name", "", []string{"api/1", "cache/1", "db/1", "db/2", "db/3", "db_replica/1", "web/1"}},
+		{"name descending", "order=desc", []string{"web/1", "db_replica/1", "db/3", "db/2", "db/1", "cache/1", "api/1"}},
+		{"version", "sort=version", []string{"api/1", "cache/1", "db/1", "db_replica/1", "web/1", "db/2", "db/3"}},
+		{"version descending", "sort=version&order=desc", []string{"db/3", "db/2", "web/1", "db_replica/1", "db/1", "cache/1", "api/1"}},
+		{"name prefix", "namePrefix=db", []string{"db/1", "db/2", "db/3", "db_replica/1"}},
+		{"name prefix with underscore", "namePrefix=db_", []string{"db_replica/1"}},
+		{"parameter", "parameter=port", []string{"api/1", "db/2", "db_replica/1", "web/1"}},
+		{"secret parameter", "parameter=password", []string{"db/3"}},
+		{"name prefix and parameter", "namePrefix=db&parameter=port&order=desc", []string{"db_replica/1", "db/2"}},
+		{"no match", "namePrefix=zzz", []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			whole, next := s.listPage("/configs?" + c.query)
+			if next != "" || !reflect.DeepEqual(whole, c.want) {
+				t.Fatalf("whole list is %v with next %q, want %v", whole, next, c.want)
+			}
+			// Sve veličine strana daju istu listu, bez ponovljenih i preskočenih zapisa
+			for limit := 1; limit <= len(c.want)+1; limit++ {
+				got := s.listAll(fmt.Sprintf("/configs?%s&limit=%d", c.query, limit))
+				if len(got) == 0 {
+					got = []string{}
+				}
+				if !reflect.DeepEqual(got, c.want) {
+					t.Errorf("limit %d: got %v, want %v", limit, got, c.want)
+				}
+			}
+		})
+	}
+
+	if got := s.listAll("/configGroups?limit=1&order=desc"); !reflect.DeepEqual(got, []string{"db_group/1", "app/2", "app/1"}) {
+		t.Errorf("groups are %v", got)
+	}
+}
+
+// Link zadržava putanju i sve parametre upita, a menja samo cursor
+func TestSetNextLink(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/namespaces/team/configs?limit=2&namePrefix=db&cursor=old&sort=version", nil)
+	setNextLink(rec, req, "next.sum")
+
+	match := nextLink.FindStringSubmatch(rec.Header().Get("Link"))
+	if match == nil {
+		t.Fatalf("Link header is %q", rec.Header().Get("Link"))
+	}
+	next, err := url.Parse(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"limit": {"2"}, "namePrefix": {"db"}, "cursor": {"next.sum"}, "sort": {"version"}}
+	if next.Path != "/namespaces/team/configs" || !reflect.DeepEqual(next.Query(), want) {
+		t.Errorf("next page is %s", match[1])
+	}
+
+	rec = httptest.NewRecorder()
+	setNextLink(rec, req, "")
+	if link := rec.Header().Get("Link"); link != "" {
+		t.Errorf("last page has Link %q", link)
+	}
+}
+
+func TestListQueryRejectsInvalidParameters(t *testing.T) {
+	s := listServer(t)
+	s.must(http.StatusCreated, "", "POST", "/namespaces", `{"name":"team"}`)
+	s.must(http.StatusCreated, "", "POST", "/namespaces/team/configs", `{"name":"db","version":1,"parameters":{"host":"a"}}`)
+	s.must(http.StatusCreated, "", "POST", "/namespaces/team/configs", `{"name":"db","version":2,"parameters":{"host":"a"}}`)
+
+	_, next := s.listPage("/configs?limit=2&namePrefix=db")
+	if next == "" {
+		t.Fatal("first page has no next link")
+	}
+	cursor, err := url.Parse(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := cursor.Query().Get("cursor")
+	tampered := []byte(valid)
+	tampered[2] ^= 1
+
+	cases := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"next page", next, http.StatusOK},
+		{"next page with another limit", "/configs?limit=5&namePrefix=db&cursor=" + valid, http.StatusOK},
+		{"unknown sort", "/configs?sort=size", http.StatusBadRequest},
+		{"unknown order", "/configs?order=up", http.StatusBadRequest},
+		{"zero limit", "/configs?limit=0", http.StatusBadRequest},
+		{"negative limit", "/configs?limit=-1", http.StatusBadRequest},
+		{"limit not a number", "/configs?limit=ten", http.StatusBadRequest},
+		{"limit above maximum", "/configs?limit=1001", http.StatusBadRequest},
+		{"malformed cursor", "/configs?cursor=garbage", http.StatusBadRequest},
+		{"tampered cursor", "/configs?limit=2&namePrefix=db&cursor=" + url.QueryEscape(string(tampered)), http.StatusBadRequest},
+		{"cursor with another sort", "/configs?limit=2&namePrefix=db&sort=version&cursor=" + valid, http.StatusBadRequest},
+		{"cursor with another order", "/configs?limit=2&namePrefix=db&order=desc&cursor=" + valid, http.StatusBadRequest},
+		{"cursor with another name prefix", "/configs?limit=2&namePrefix=d&cursor=" + valid, http.StatusBadRequest},
+		{"cursor with a parameter filter", "/configs?limit=2&namePrefix=db&parameter=host&cursor=" + valid, http.StatusBadRequest},
+		{"cursor of configs for groups", "/configGroups?limit=2&namePrefix=db&cursor=" + valid, http.StatusBadRequest},
+		{"cursor of another namespace", "/namespaces/team/configs?limit=2&namePrefix=db&cursor=" + valid, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := s.do("", "GET", c.path, "")
+			if rec.Code != c.status {
+				t.Fatalf("got %d, want %d: %s", rec.Code, c.status, rec.Body.String())
+			}
+			if c.status == http.StatusBadRequest && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("got Content-Type %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"projekat/model"
+	"projekat/rbac"
+	"projekat/services"
+
+	"github.com/gorilla/mux"
+)
+
+type NamespaceHandler struct {
+	service services.NamespaceService
+	access  Authorization
+}
+
+func NewNamespaceHandler(service services.NamespaceService, access Authorization) NamespaceHandler {
+	return NamespaceHandler{
+		service: service,
+		access:  access,
+	}
+}
+
+// requestNamespace vraća prostor imena iz putanje zahteva. Putanje bez /namespaces/{namespace}
+// pripadaju podrazumevanom prostoru imena.
+func requestNamespace(r *http.Request) string {
+	if namespace := mux.Vars(r)["namespace"]; namespace != "" {
+		return namespace
+	}
+	return model.DefaultNamespace
+}
+
+// namespacePath vraća putanju zapisa u prostoru imena zahteva, npr. configs/db_config/2
+// ili namespaces/payments/configs/db_config/2
+func namespacePath(r *http.Request, format string, args ...interface{}) string {
+	return model.NamespacePrefix(requestNamespace(r)) + fmt.Sprintf(format, args...)
+}
+
+// Exists propušta zahteve za /namespaces/{namespace}/... samo ako prostor imena postoji
+func (h NamespaceHandler) Exists(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := h.service.Get(requestNamespace(r)); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// POST /namespaces
+func (h NamespaceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var namespace model.Namespace
+	if err := json.NewDecoder(r.Body).Decode(&namespace); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.access.allow(w, r, rbac.Create, "namespaces/"+namespace.Name) {
+		return
+	}
+
+	created, err := h.service.Create(namespace)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(created)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/namespaces/%s", created.Name))
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+}
+
+// GET /namespaces
+func (h NamespaceHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	all, err := h.service.GetAll()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(all)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// GET /namespaces/{name}
+func (h NamespaceHandler) Get(w http.ResponseWriter, r *http.Request) {
+	namespace, err := h.service.Get(mux.Vars(r)["name"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(namespace)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// PUT /namespaces/{name}
+// Menja opis i labele; ime iz tela se zanemaruje.
+func (h NamespaceHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var namespace model.Namespace
+	if err := json.NewDecoder(r.Body).Decode(&namespace); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	namespace.Name = mux.Vars(r)["name"]
+
+	updated, err := h.service.Update(namespace)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(updated)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// DELETE /namespaces/{name}
+func (h NamespaceHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Delete(mux.Vars(r)["name"]); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
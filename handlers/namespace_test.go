@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"projekat/events"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// namespaceServer upisuje db/1 i u default i u prostor imena a, a samo u a konfiguraciju only_a/1
+// i grupu app/1 koja upućuje na db/1 iz a; prostor imena b ostaje prazan
+func namespaceServer(t *testing.T, policy, subject string) *testServer {
+	s := newTestServer(t, policy)
+	s.must(http.StatusCreated, subject, "POST", "/namespaces", `{"name":"a"}`)
+	s.must(http.StatusCreated, subject, "POST", "/namespaces", `{"name":"b"}`)
+	s.must(http.StatusCreated, subject, "POST", "/configs", `{"name":"db","version":1,"parameters":{"host":"in-default"}}`)
+	s.must(http.StatusCreated, subject, "POST", "/namespaces/a/configs", `{"name":"db","version":1,"parameters":{"host":"in-a"}}`)
+	s.must(http.StatusCreated, subject, "POST", "/namespaces/a/configs", `{"name":"only_a","version":1,"parameters":{"host":"only-in-a"}}`)
+	s.must(http.StatusCreated, subject, "POST", "/namespaces/a/configGroups",
+		`{"name":"app","version":1,"configuration":[],"references":[{"name":"db","version":1}]}`)
+	return s
+}
+
+func TestNamespaceIsolation(t *testing.T) {
+	s := namespaceServer(t, "", "")
+
+	rec := s.must(http.StatusOK, "", "GET", "/namespaces/a/configs/db/1", "")
+	if !strings.Contains(rec.Body.String(), "in-a") {
+		t.Errorf("config in a is %s", rec.Body.String())
+	}
+	rec = s.must(http.StatusOK, "", "GET", "/configs/db/1", "")
+	if strings.Contains(rec.Body.String(), "in-a") {
+		t.Errorf("default namespace reads the config from a: %s", rec.Body.String())
+	}
+	// Grupa razrešava referencu u svom prostoru imena
+	rec = s.must(http.StatusOK, "", "GET", "/namespaces/a/configGroups/app/1", "")
+	if !strings.Contains(rec.Body.String(), "in-a") || strings.Contains(rec.Body.String(), "in-default") {
+		t.Errorf("group in a resolves its reference as %s", rec.Body.String())
+	}
+
+	for _, path := range []string{
+		"/configs/only_a/1",
+		"/configs/only_a/latest",
+		"/configGroups/app/1",
+		"/configGroups/app/diff?from=1&to=1",
+		"/namespaces/b/configs/db/1",
+		"/namespaces/b/configs/db/latest",
+		"/namespaces/b/configs/db/diff?from=1&to=1",
+		"/namespaces/b/configs/only_a/1",
+		"/namespaces/b/configGroups/app/1",
+		"/namespaces/c/configs/db/1",
+	} {
+		s.must(http.StatusNotFound, "", "GET", path, "")
+	}
+
+	lists := []struct {
+		path string
+		want []string
+	}{
+		{"/configs", []string{"db/1"}},
+		{"/configGroups", []string{}},
+		{"/namespaces/a/configs", []string{"db/1", "only_a/1"}},
+		{"/namespaces/a/configGroups", []string{"app/1"}},
+		{"/namespaces/b/configs", []string{}},
+		{"/namespaces/b/configGroups", []string{}},
+	}
+	for _, list := range lists {
+		got := s.listAll(list.path + "?limit=1")
+		if len(got) == 0 {
+			got = []string{}
+		}
+		if !reflect.DeepEqual(got, list.want) {
+			t.Errorf("%s lists %v, want %v", list.path, got, list.want)
+		}
+	}
+
+	// Arhiva sadrži samo zapise svog prostora imena
+	exported := s.must(http.StatusOK, "", "GET", "/export", "").Body.String()
+	if !strings.Contains(exported, "in-default") || strings.Contains(exported, "in-a") || strings.Contains(exported, "only_a") {
+		t.Errorf("export of default is %s", exported)
+	}
+	exported = s.must(http.StatusOK, "", "GET", "/namespaces/b/export", "").Body.String()
+	if strings.Contains(exported, "db") || strings.Contains(exported, "only_a") || strings.Contains(exported, "app") {
+		t.Errorf("export of b is %s", exported)
+	}
+	exported = s.must(http.StatusOK, "", "GET", "/namespaces/a/export", "").Body.String()
+	if !strings.Contains(exported, "only_a") || strings.Contains(exported, "in-default") {
+		t.Errorf("export of a is %s", exported)
+	}
+
+	// Grupa ne može da uputi na konfiguraciju iz drugog prostora imena
+	s.must(http.StatusCreated, "", "POST", "/namespaces/b/configGroups", `{"name":"app","version":1,"configuration":[]}`)
+	if rec := s.do("", "PUT", "/namespaces/b/configGroups/app/1/addReference", `{"name":"only_a","version":1}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("group in b references a config from a: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := s.do("", "POST", "/configGroups", `{"name":"app","version":1,"configuration":[],"references":[{"name":"only_a","version":1}]}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("group in default references a config from a: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Brisanje u a ne dira zapis sa istim imenom u default, a brisanje u b ne nalazi nijedan
+	s.must(http.StatusNotFound, "", "DELETE", "/namespaces/b/configs/db/1", "")
+	s.must(http.StatusCreated, "", "POST", "/configs", `{"name":"cache","version":1,"parameters":{"size":"1"}}`)
+	s.must(http.StatusCreated, "", "POST", "/namespaces/a/configs", `{"name":"cache","version":1,"parameters":{"size":"1"}}`)
+	s.must(http.StatusNoContent, "", "DELETE", "/namespaces/a/configs/cache/1", "")
+	s.must(http.StatusOK, "", "GET", "/configs/cache/1", "")
+	s.must(http.StatusNotFound, "", "GET", "/namespaces/a/configs/cache/1", "")
+}
+
+// Ključevi događaja nose prostor imena, pa prefiks configs/ ne vidi izmene iz drugih prostora imena
+func TestNamespaceIsolationWatch(t *testing.T) {
+	s := namespaceServer(t, "", "")
+
+	cases := []struct {
+		prefix string
+		want   []string
+	}{
+		{"configs/", []string{"created configs/db/1"}},
+		{"configs/db/", []string{"created configs/db/1"}},
+		{"configGroups/", []string{}},
+		{"namespaces/b/", []string{}},
+		{"namespaces/a/", []string{
+			"created namespaces/a/configs/db/1",
+			"created namespaces/a/configs/only_a/1",
+			"created namespaces/a/configGroups/app/1",
+		}},
+		{"namespaces/a/configs/", []string{
+			"created namespaces/a/configs/db/1",
+			"created namespaces/a/configs/only_a/1",
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.prefix, func(t *testing.T) {
+			if got := eventKeys(s.watch(c.prefix, "0").Events); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+const namespacePolicy = `
+roles:
+  admin:
+    rules:
+      - actions: ["*"]
+        resources: ["*"]
+  team-a:
+    rules:
+      - actions: ["*"]
+        resources: ["namespaces/a/*"]
+  default-reader:
+    rules:
+      - actions: [read, reveal]
+        resources: ["configs/*", "configGroups/*"]
+bindings:
+  - role: admin
+    subjects: [admin]
+  - role: team-a
+    subjects: [alice]
+  - role: default-reader
+    subjects: [bob]
+`
+
+// Pravo nad namespaces/a/* ne važi ni za default ni za b, a pravo nad configs/* ne važi za a
+func TestNamespaceIsolationAuthorization(t *testing.T) {
+	s := namespaceServer(t, namespacePolicy, "admin")
+
+	cases := []struct {
+		name    string
+		subject string
+		method  string
+		path    string
+		body    string
+		status  int
+	}{
+		{"team reads its namespace", "alice", "GET", "/namespaces/a/configs/db/1", "", http.StatusOK},
+		{"team lists its namespace", "alice", "GET", "/namespaces/a/configs", "", http.StatusOK},
+		{"team writes its namespace", "alice", "POST", "/namespaces/a/configs", `{"name":"queue","version":1}`, http.StatusCreated},
+		{"team exports its namespace", "alice", "GET", "/namespaces/a/export", "", http.StatusOK},
+		{"team reads default", "alice", "GET", "/configs/db/1", "", http.StatusForbidden},
+		{"team lists default", "alice", "GET", "/configs", "", http.StatusForbidden},
+		{"team writes default", "alice", "POST", "/configs", `{"name":"queue","version":1}`, http.StatusForbidden},
+		{"team exports default", "alice", "GET", "/export", "", http.StatusForbidden},
+		{"team reads b", "alice", "GET", "/namespaces/b/configs/db/1", "", http.StatusForbidden},
+		{"team lists b", "alice", "GET", "/namespaces/b/configs", "", http.StatusForbidden},
+		{"team writes b", "alice", "POST", "/namespaces/b/configs", `{"name":"queue","version":1}`, http.StatusForbidden},
+		{"team exports b", "alice", "GET", "/namespaces/b/export", "", http.StatusForbidden},
+		{"team imports into b", "alice", "POST", "/namespaces/b/import", "", http.StatusForbidden},
+		{"default reader reads default", "bob", "GET", "/configs/db/1", "", http.StatusOK},
+		{"default reader exports default", "bob", "GET", "/export", "", http.StatusOK},
+		{"default reader reads a", "bob", "GET", "/namespaces/a/configs/db/1", "", http.StatusForbidden},
+		{"default reader lists a", "bob", "GET", "/namespaces/a/configs", "", http.StatusForbidden},
+		{"default reader reads group in a", "bob", "GET", "/namespaces/a/configGroups/app/1", "", http.StatusForbidden},
+		{"default reader diffs in a", "bob", "GET", "/namespaces/a/configs/db/diff?from=1&to=1", "", http.StatusForbidden},
+		{"default reader exports a", "bob", "GET", "/namespaces/a/export", "", http.StatusForbidden},
+		{"team watches its namespace", "alice", "GET", "/watch?timeout=10ms&prefix=namespaces/a/", "", http.StatusOK},
+		{"team watches default", "alice", "GET", "/watch?timeout=10ms&prefix=configs/", "", http.StatusForbidden},
+		{"team watches everything", "alice", "GET", "/watch?timeout=10ms", "", http.StatusForbidden},
+		{"team watches b", "alice", "GET", "/watch?timeout=10ms&prefix=namespaces/b/", "", http.StatusForbidden},
+		{"default reader watches default", "bob", "GET", "/watch?timeout=10ms&prefix=configs/", "", http.StatusOK},
+		{"default reader watches a", "bob", "GET", "/watch?timeout=10ms&prefix=namespaces/a/", "", http.StatusForbidden},
+		{"default reader watches everything", "bob", "GET", "/watch?timeout=10ms", "", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := s.do(c.subject, c.method, c.path, c.body)
+			if rec.Code != c.status {
+				t.Fatalf("got %d, want %d: %s", rec.Code, c.status, rec.Body.String())
+			}
+		})
+	}
+
+	// Dozvoljeni izvoz i watch i dalje vide samo zapise svog prostora imena
+	exported := s.must(http.StatusOK, "bob", "GET", "/export?reveal=true", "").Body.String()
+	if strings.Contains(exported, "in-a") || strings.Contains(exported, "only_a") {
+		t.Errorf("export of default for bob is %s", exported)
+	}
+	rec := s.must(http.StatusOK, "bob", "GET", "/watch?timeout=10ms&since=0&prefix="+url.QueryEscape("configs/"), "")
+	var result struct {
+		Events []events.Event `json:"events"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if got := eventKeys(result.Events); !reflect.DeepEqual(got, []string{"created configs/db/1"}) {
+		t.Errorf("bob watches %v", got)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"projekat/model"
+	"projekat/services"
+)
+
+// problem je telo odgovora sa greškom po RFC 7807 (application/problem+json)
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Violations je proširenje za greške validacije po šemi: sva odstupanja, ne samo prvo
+	Violations []model.Violation `json:"violations,omitempty"`
+	// Conflicts je proširenje za uvoz: zapisi koji se razlikuju od postojećih ili su napravljeni
+	// za vreme uvoza
+	Conflicts []services.ImportItem `json:"conflicts,omitempty"`
+}
+
+func newProblem(r *http.Request, status int, detail string) problem {
+	return problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	}
+}
+
+// writeProblem šalje grešku sa datim statusom kao application/problem+json
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	sendProblem(w, newProblem(r, status, detail))
+}
+
+// writeError mapira vrstu greške iz model paketa na HTTP status
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var violationErr *model.SchemaViolationError
+	if errors.As(err, &violationErr) {
+		p := newProblem(r, http.StatusUnprocessableEntity, fmt.Sprintf("config %s does not match schema %s/%d", violationErr.Config, violationErr.Config, violationErr.SchemaVersion))
+		p.Violations = violationErr.Violations
+		sendProblem(w, p)
+		return
+	}
+	writeProblem(w, r, errorStatus(err), err.Error())
+}
+
+func sendProblem(w http.ResponseWriter, p problem) {
+	resp, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, p.Detail, p.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	w.Write(resp)
+}
+
+// bodyTooLarge proverava da li je čitanje tela prekinuto jer je telo veće od http.MaxBytesReader ograničenja
+func bodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, model.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, model.ErrConflict), errors.Is(err, model.ErrSchemaViolation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, model.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, model.ErrGone):
+		return http.StatusGone
+	case errors.Is(err, model.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, model.ErrForbidden):
+		return http.StatusForbidden
+	}
+	log.Printf("%v", err)
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader nosi ID zahteva: klijent može da ga zada, a server ga uvek vraća u odgovoru
+const RequestIDHeader = "X-Request-Id"
+
+// Najduži ID zahteva koji se prihvata od klijenta
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestID dodeljuje svakom zahtevu ID i vraća ga u zaglavlju odgovora. ID iz zahteva se
+// zadržava ako je kratak i sadrži samo vidljive ASCII znakove, inače server pravi nov.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestID vraća ID zahteva koji je dodelio RequestID
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"projekat/model"
+	"projekat/services"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type SchemaHandler struct {
+	service services.SchemaService
+}
+
+func NewSchemaHandler(service services.SchemaService) SchemaHandler {
+	return SchemaHandler{
+		service: service,
+	}
+}
+
+// POST /schemas
+func (h SchemaHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var schema model.Schema
+	if err := json.NewDecoder(r.Body).Decode(&schema); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.Create(schema); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/schemas/%s/%d", schema.Name, schema.Version))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// POST /schemas/{name}/versions
+func (h SchemaHandler) CreateVersion(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	// Verziju dodeljuje server, pa se eventualna verzija iz tela zanemaruje
+	var schema model.Schema
+	if err := json.NewDecoder(r.Body).Decode(&schema); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	created, err := h.service.CreateVersion(name, schema)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(created)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/schemas/%s/%d", created.Name, created.Version))
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+}
+
+// GET /schemas/{name}/{version}
+func (h SchemaHandler) Get(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	version, err := strconv.Atoi(mux.Vars(r)["version"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	schema, err := h.service.Get(name, version)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(schema)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// GET /schemas/{name}/latest
+func (h SchemaHandler) Latest(w http.ResponseWriter, r *http.Request) {
+	schema, err := h.service.Latest(mux.Vars(r)["name"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(schema)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// GET /schemas/{name}
+func (h SchemaHandler) Versions(w http.ResponseWriter, r *http.Request) {
+	schemas, err := h.service.Versions(mux.Vars(r)["name"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(schemas)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// GET /schemas
+func (h SchemaHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	schemas, err := h.service.GetAll()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(schemas)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// DELETE /schemas/{name}/{version}
+func (h SchemaHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	version, err := strconv.Atoi(mux.Vars(r)["version"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.Delete(name, version); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
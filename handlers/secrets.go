@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"projekat/model"
+	"projekat/rbac"
+	"strconv"
+)
+
+// revealTokenHeader nosi token kojim klijent dokazuje da sme da vidi tajne parametre
+const revealTokenHeader = "X-Reveal-Token"
+
+// SecretAccess odlučuje da li odgovor sme da sadrži vrednosti tajnih parametara.
+// Tajne se podrazumevano maskiraju; otkrivaju se samo na zahtev (?reveal=true). Sa RBAC politikom
+// za to je potrebna akcija reveal nad resursom, a bez politike ispravan token u X-Reveal-Token.
+type SecretAccess struct {
+	revealToken string
+	access      Authorization
+}
+
+// NewSecretAccess prima token za otkrivanje tajni, koji važi samo dok autorizacija nije uključena;
+// prazan token tada znači da otkrivanje nije dozvoljeno
+func NewSecretAccess(revealToken string, access Authorization) SecretAccess {
+	return SecretAccess{
+		revealToken: revealToken,
+		access:      access,
+	}
+}
+
+// reveal vraća da li treba otkriti tajne resursa. Ako zahtev traži otkrivanje koje nije dozvoljeno,
+// šalje grešku i vraća ok == false, pa handler treba da prekine obradu.
+func (a SecretAccess) reveal(w http.ResponseWriter, r *http.Request, resources ...ResourceFunc) (reveal bool, ok bool) {
+	value := r.URL.Query().Get("reveal")
+	if value == "" {
+		return false, true
+	}
+	reveal, err := strconv.ParseBool(value)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "reveal must be true or false")
+		return false, false
+	}
+	if !reveal {
+		return false, true
+	}
+
+	if a.access.engine != nil {
+		for _, resource := range resources {
+			if !a.access.allow(w, r, rbac.Reveal, resource(r)) {
+				return false, false
+			}
+		}
+		return true, true
+	}
+	if a.revealToken == "" {
+		writeProblem(w, r, http.StatusForbidden, "revealing secret parameters is disabled")
+		return false, false
+	}
+	token := r.Header.Get(revealTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.revealToken)) != 1 {
+		writeProblem(w, r, http.StatusForbidden, "not allowed to reveal secret parameters")
+		return false, false
+	}
+	return true, true
+}
+
+// revealReferences proverava da klijent sme da otkrije i tajne konfiguracija na koje grupe upućuju,
+// jer se razrešene reference vraćaju zajedno sa grupom
+func (a SecretAccess) revealReferences(w http.ResponseWriter, r *http.Request, configGroups ...model.ConfigGroup) bool {
+	if a.access.engine == nil {
+		return true
+	}
+	for _, configGroup := range configGroups {
+		for _, reference := range configGroup.References {
+			if !a.access.allow(w, r, rbac.Reveal, namespacePath(r, "configs/%s/%d", reference.Name, reference.Version)) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"projekat/audit"
+	"projekat/auth"
+	"projekat/events"
+	"projekat/rbac"
+	"projekat/repositories"
+	"projekat/secrets"
+	"projekat/services"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// subjectHeader zamenjuje autentifikaciju u testovima: zahtev sa njim stiže kao klijent tog imena
+const subjectHeader = "X-Test-Subject"
+
+// Ograničenja tela zahteva i raspakovane arhive za uvoz
+const (
+	testMaxRequestBytes = 1 << 16
+	testMaxArchiveBytes = 1 << 17
+)
+
+// testServer je server sa rutama kao u main.go, nad repozitorijumima u memoriji
+type testServer struct {
+	t          *testing.T
+	handler    http.Handler
+	changes    *events.Log
+	policyFile string
+	engine     *rbac.Engine
+}
+
+// newTestServer pravi server; prazna politika znači da autorizacija nije uključena
+func newTestServer(t *testing.T, policy string) *testServer {
+	t.Helper()
+	s := &testServer{t: t}
+
+	storage, err := repositories.NewStorage("memory", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := secrets.EphemeralKeyring()
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.Configs = secrets.NewConfigRepository(storage.Configs, keyring)
+	storage.ConfigGroups = secrets.NewConfigGroupRepository(storage.ConfigGroups, keyring)
+	s.changes = events.NewLog(100)
+	storage.Configs = events.NewConfigRepository(storage.Configs, s.changes)
+	storage.ConfigGroups = events.NewConfigGroupRepository(storage.ConfigGroups, s.changes)
+
+	if policy != "" {
+		s.policyFile = filepath.Join(t.TempDir(), "policy.yaml")
+		s.writePolicy(policy)
+		s.engine, err = rbac.NewEngine(s.policyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	authz := NewAuthorization(s.engine)
+
+	auditLog, err := audit.NewLog(storage.Audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serviceSchema := services.NewSchemaService(storage.Schemas)
+	service := services.NewConfigService(storage.Configs, storage.ConfigGroups, serviceSchema, services.RestrictReferences, auditLog)
+	serviceGroup := services.NewConfigGroupService(storage.ConfigGroups, storage.Configs, serviceSchema, auditLog)
+	secretAccess := NewSecretAccess("", authz)
+	handler := NewConfigHandler(service, secretAccess, authz)
+	handlerGroup := NewConfigGroupHandler(serviceGroup, secretAccess, authz)
+	handlerArchive := NewArchiveHandler(services.NewArchiveService(storage.Configs, storage.ConfigGroups, serviceSchema, auditLog), secretAccess, testMaxRequestBytes, testMaxArchiveBytes)
+	idempotency := NewIdempotencyStore(time.Hour, 1<<20)
+	preconditions := NewPreconditions(false)
+	serviceNamespace := services.NewNamespaceService(storage.Namespaces, storage.Configs, storage.ConfigGroups)
+	handlerNamespace := NewNamespaceHandler(serviceNamespace, authz)
+	if err := serviceNamespace.EnsureDefault(); err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	configVersion := VersionResource("configs", "name", "version")
+	configAll := AllVersionsResource("configs", "name")
+	groupVersion := VersionResource("configGroups", "name", "version")
+	groupAll := AllVersionsResource("configGroups", "name")
+	groupContent := VersionResource("configGroups", "groupName", "groupVersion")
+	allConfigs := StaticResource("configs/*")
+	allGroups := StaticResource("configGroups/*")
+	configRoutes := func(r *mux.Router) {
+		r.HandleFunc("/configs/{name}/{version:[0-9]+}", authz.Require(rbac.Read, handler.Get, configVersion)).Methods("GET")
+		r.HandleFunc("/configGroups/{name}/{version:[0-9]+}", authz.Require(rbac.Read, handlerGroup.Get, groupVersion)).Methods("GET")
+		r.HandleFunc("/configs/{name}/latest", authz.Require(rbac.Read, handler.Latest, configAll)).Methods("GET")
+		r.HandleFunc("/configs/{name}/diff", authz.Require(rbac.Read, handler.Diff, configAll)).Methods("GET")
+		r.HandleFunc("/configGroups/{name}/diff", authz.Require(rbac.Read, handlerGroup.Diff, groupAll)).Methods("GET")
+		r.HandleFunc("/configs", authz.Require(rbac.Read, handler.GetAll, ListResource("configs"))).Methods("GET")
+		r.HandleFunc("/configGroups", authz.Require(rbac.Read, handlerGroup.GetAll, ListResource("configGroups"))).Methods("GET")
+		r.HandleFunc("/configs", idempotency.Wrap(handler.Create)).Methods("POST")
+		r.HandleFunc("/configGroups", idempotency.Wrap(handlerGroup.Create)).Methods("POST")
+		r.HandleFunc("/configs/{name}/versions", authz.Require(rbac.Create, idempotency.Wrap(handler.CreateVersion), configAll)).Methods("POST")
+		r.HandleFunc("/configGroups/{name}/versions", authz.Require(rbac.Create, idempotency.Wrap(handlerGroup.CreateVersion), groupAll)).Methods("POST")
+		r.HandleFunc("/configGroups/{name}/{version:[0-9]+}/{labels}", authz.Require(rbac.Read, handlerGroup.GetByLabels, groupVersion)).Methods("GET")
+		r.HandleFunc("/configGroups/{name}/{version:[0-9]+}/{labels}", authz.Require(rbac.Update, preconditions.Wrap(handlerGroup.DeleteByLabels), groupVersion)).Methods("DELETE")
+		r.HandleFunc("/configs/{name}/{version:[0-9]+}", authz.Require(rbac.Delete, preconditions.Wrap(handler.Delete), configVersion)).Methods("DELETE")
+		r.HandleFunc("/configGroups/{groupName}/{groupVersion:[0-9]+}/addReference", authz.Require(rbac.Update, preconditions.Wrap(handlerGroup.AddReference), groupContent)).Methods("PUT")
+		r.HandleFunc("/export", authz.Require(rbac.Read, handlerArchive.Export, allConfigs, allGroups)).Methods("GET")
+		r.HandleFunc("/import", authz.Require(rbac.Create, authz.Require(rbac.Update, idempotency.Wrap(handlerArchive.Import), allConfigs, allGroups), allConfigs, allGroups)).Methods("POST")
+	}
+	configRoutes(router)
+	router.HandleFunc("/namespaces", idempotency.Wrap(handlerNamespace.Create)).Methods("POST")
+	namespaced := router.PathPrefix("/namespaces/{namespace}").Subrouter()
+	namespaced.Use(handlerNamespace.Exists)
+	configRoutes(namespaced)
+	router.HandleFunc("/watch", authz.Require(rbac.Read, NewWatchHandler(s.changes).Watch, WatchResource)).Methods("GET")
+
+	s.handler = RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subject := r.Header.Get(subjectHeader); subject != "" {
+			r = r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{Subject: subject, Method: auth.MethodAPIKey}))
+		}
+		router.ServeHTTP(w, r)
+	}))
+	return s
+}
+
+// writePolicy menja fajl sa politikom; engine ga učitava tek na Reload
+func (s *testServer) writePolicy(policy string) {
+	s.t.Helper()
+	if err := os.WriteFile(s.policyFile, []byte(policy), 0o644); err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+// do šalje zahtev kao klijent subject (prazan znači bez klijenta); header su parovi ime, vrednost
+func (s *testServer) do(subject, method, path, body string, header ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	if subject != "" {
+		req.Header.Set(subjectHeader, subject)
+	}
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// must šalje zahtev i prekida test ako odgovor nema očekivani status
+func (s *testServer) must(status int, subject, method, path, body string, header ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	rec := s.do(subject, method, path, body, header...)
+	if rec.Code != status {
+		s.t.Fatalf("%s %s: got %d, want %d: %s", method, path, rec.Code, status, rec.Body.String())
+	}
+	return rec
+}
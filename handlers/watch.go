@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"projekat/events"
+	"projekat/model"
+	"strings"
+	"time"
+)
+
+const (
+	// Koliko dugo long-poll čeka na izmene ako klijent ne zada timeout, i najduže dozvoljeno čekanje
+	defaultWatchTimeout = 30 * time.Second
+	maxWatchTimeout     = 5 * time.Minute
+	// Koliko često SSE tok šalje komentar da veza ne bi bila prekinuta zbog neaktivnosti
+	watchHeartbeat = 15 * time.Second
+)
+
+type WatchHandler struct {
+	log *events.Log
+}
+
+func NewWatchHandler(log *events.Log) WatchHandler {
+	return WatchHandler{
+		log: log,
+	}
+}
+
+// watchResult je odgovor na long-poll: događaji i token revizije od koje klijent nastavlja (?since=)
+type watchResult struct {
+	Revision string         `json:"revision"`
+	Events   []events.Event `json:"events"`
+}
+
+// GET /watch?prefix=&since=&timeout=
+// Sa Accept: text/event-stream odgovor je SSE tok, inače long-poll.
+func (h WatchHandler) Watch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept")
+	if acceptsEventStream(r.Header.Get("Accept")) {
+		h.stream(w, r)
+		return
+	}
+
+	prefix := r.URL.Query().Get("prefix")
+	since, err := h.watchSince(r, "")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	timeout, err := watchTimeout(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if since < 0 {
+		since = h.log.Revision()
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	found, revision, err := h.log.Wait(ctx, since, prefix)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if r.Context().Err() != nil {
+		return
+	}
+
+	resp, err := json.Marshal(watchResult{Revision: h.log.Token(revision), Events: found})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// stream šalje događaje kao Server-Sent Events dok klijent ne zatvori vezu. Svaki događaj ima id
+// jednak tokenu svoje revizije, pa se posle prekida nastavlja sa Last-Event-ID.
+func (h WatchHandler) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeProblem(w, r, http.StatusNotAcceptable, "streaming is not supported by this connection")
+		return
+	}
+
+	prefix := r.URL.Query().Get("prefix")
+	since, err := h.watchSince(r, r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if since < 0 {
+		since = h.log.Revision()
+	}
+	// Prekinuta istorija se prijavljuje statusom pre početka toka
+	if _, _, err := h.log.Since(since, prefix); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", watchHeartbeat.Milliseconds())
+	flusher.Flush()
+
+	for {
+		ctx, cancel := context.WithTimeout(r.Context(), watchHeartbeat)
+		found, revision, err := h.log.Wait(ctx, since, prefix)
+		cancel()
+		if r.Context().Err() != nil {
+			return
+		}
+		if err != nil {
+			// Klijent je zaostao više od čuvane istorije: šalje se greška i tok se zatvara
+			data, _ := json.Marshal(newProblem(r, errorStatus(err), err.Error()))
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+			flusher.Flush()
+			return
+		}
+
+		if len(found) == 0 {
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		for _, event := range found {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", h.log.Token(event.Revision), event.Type, data)
+		}
+		flusher.Flush()
+		since = revision
+	}
+}
+
+// watchSince čita token revizije od koje se prate izmene; -1 znači samo buduće izmene.
+// lastEventID (SSE nastavak) ima prednost nad ?since=.
+func (h WatchHandler) watchSince(r *http.Request, lastEventID string) (int64, error) {
+	value := r.URL.Query().Get("since")
+	if lastEventID != "" {
+		value = lastEventID
+	}
+	if value == "" {
+		return -1, nil
+	}
+	return h.log.ParseToken(value)
+}
+
+func watchTimeout(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get("timeout")
+	if value == "" {
+		return defaultWatchTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, model.Invalidf("timeout must be a positive duration such as 30s, got %q", value)
+	}
+	if timeout > maxWatchTimeout {
+		return 0, model.Invalidf("timeout must be at most %s", maxWatchTimeout)
+	}
+	return timeout, nil
+}
+
+// acceptsEventStream proverava da li klijent eksplicitno traži text/event-stream
+func acceptsEventStream(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == "text/event-stream" {
+			return true
+		}
+	}
+	return false
+}
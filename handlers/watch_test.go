@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"projekat/events"
+	"strings"
+	"testing"
+	"time"
+)
+
+// watch čita long-poll odgovor; timeout je kratak jer se čekanje na nove izmene ne testira
+func (s *testServer) watch(prefix, since string) watchResult {
+	s.t.Helper()
+	path := "/watch?timeout=10ms&prefix=" + url.QueryEscape(prefix)
+	if since != "" {
+		path += "&since=" + url.QueryEscape(since)
+	}
+	rec := s.must(http.StatusOK, "", "GET", path, "")
+	var result watchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		s.t.Fatal(err)
+	}
+	return result
+}
+
+func eventKeys(found []events.Event) []string {
+	keys := make([]string, 0, len(found))
+	for _, event := range found {
+		keys = append(keys, string(event.Type)+" "+event.Key)
+	}
+	return keys
+}
+
+func TestWatchResumesFromRevision(t *testing.T) {
+	s := newTestServer(t, "")
+	s.must(http.StatusCreated, "", "POST", "/configs", `{"name":"db","version":1,"parameters":{"host":"a"}}`)
+	s.must(http.StatusCreated, "", "POST", "/configGroups", `{"name":"app","version":1,"configuration":[]}`)
+	s.must(http.StatusCreated, "", "POST", "/configs", `{"name":"db","version":2,"parameters":{"host":"b"}}`)
+	s.must(http.StatusNoContent, "", "DELETE", "/configs/db/1", "")
+
+	all := s.watch("", "0")
+	want := []string{"created configs/db/1", "created configGroups/app/1", "created configs/db/2", "deleted configs/db/1"}
+	if got := eventKeys(all.Events); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got events %v, want %v", got, want)
+	}
+	for i, event := range all.Events {
+		if event.Revision != int64(i+1) {
+			t.Errorf("event %d has revision %d", i, event.Revision)
+		}
+	}
+	if all.Revision != s.changes.Token(4) {
+		t.Errorf("got revision %q, want %q", all.Revision, s.changes.Token(4))
+	}
+
+	cases := []struct {
+		name   string
+		prefix string
+		since  int64
+		want   []string
+	}{
+		{"after first", "", 1, want[1:]},
+		{"after first with prefix", "configs/", 1, []string{"created configs/db/2", "deleted configs/db/1"}},
+		{"after last", "", 4, []string{}},
+		{"other version", "configs/db/2", 0, []string{"created configs/db/2"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := s.watch(c.prefix, s.changes.Token(c.since))
+			if got := eventKeys(result.Events); strings.Join(got, ",") != strings.Join(c.want, ",") {
+				t.Errorf("got events %v, want %v", got, c.want)
+			}
+			if result.Revision != s.changes.Token(4) {
+				t.Errorf("got revision %q, want %q", result.Revision, s.changes.Token(4))
+			}
+		})
+	}
+
+	// Klijent nastavlja od vraćene revizije i dobija samo kasnije izmene
+	s.must(http.StatusCreated, "", "POST", "/configs", `{"name":"db","version":3,"parameters":{"host":"c"}}`)
+	next := s.watch("configs/", all.Revision)
+	if got := eventKeys(next.Events); len(got) != 1 || got[0] != "created configs/db/3" || next.Events[0].Revision != 5 {
+		t.Errorf("got events %+v after %s", next.Events, all.Revision)
+	}
+}
+
+// SSE tok posle prekida nastavlja od Last-Event-ID
+func TestWatchStreamResumesFromLastEventID(t *testing.T) {
+	s := newTestServer(t, "")
+	for _, body := range []string{
+		`{"name":"db","version":1,"parameters":{"host":"a"}}`,
+		`{"name":"db","version":2,"parameters":{"host":"b"}}`,
+		`{"name":"db","version":3,"parameters":{"host":"c"}}`,
+	} {
+		s.must(http.StatusCreated, "", "POST", "/configs", body)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("GET", "/watch?prefix=configs/", nil).WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Last-Event-ID", s.changes.Token(1))
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "id: "+s.changes.Token(1)+"\n") {
+		t.Errorf("stream repeats the event of Last-Event-ID: %s", body)
+	}
+	second := strings.Index(body, "id: "+s.changes.Token(2)+"\n")
+	third := strings.Index(body, "id: "+s.changes.Token(3)+"\n")
+	if second < 0 || third < second {
+		t.Errorf("stream does not continue with revisions 2 and 3 in order: %s", body)
+	}
+}
+
+// Token servera pre restarta, ili revizija koju ovaj server još nije dodelio, dobija 410 Gone
+func TestWatchRejectsTokensFromAnotherEpoch(t *testing.T) {
+	before := newTestServer(t, "")
+	before.must(http.StatusCreated, "", "POST", "/configs", `{"name":"db","version":1,"parameters":{"host":"a"}}`)
+	oldToken := before.changes.Token(1)
+
+	s := newTestServer(t, "")
+	s.must(http.StatusCreated, "", "POST", "/configs", `{"name":"db","version":1,"parameters":{"host":"a"}}`)
+	if s.changes.Token(1) == oldToken {
+		t.Fatal("restarted server has the same epoch")
+	}
+
+	cases := []struct {
+		name   string
+		since  string
+		accept string
+		status int
+	}{
+		{"previous epoch", oldToken, "", http.StatusGone},
+		{"previous epoch stream", oldToken, "text/event-stream", http.StatusGone},
+		{"revision without epoch", "1", "", http.StatusGone},
+		{"future revision", s.changes.Token(2), "", http.StatusGone},
+		{"invalid token", "x.y", "", http.StatusBadRequest},
+		{"start of log", "0", "", http.StatusOK},
+		{"current epoch", s.changes.Token(1), "", http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := s.do("", "GET", "/watch?timeout=10ms&since="+url.QueryEscape(c.since), "", "Accept", c.accept)
+			if rec.Code != c.status {
+				t.Fatalf("got %d, want %d: %s", rec.Code, c.status, rec.Body.String())
+			}
+			if c.status != http.StatusOK && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("got Content-Type %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"projekat/model"
+	"projekat/rbac"
+	"projekat/services"
+	"projekat/webhooks"
+
+	"github.com/gorilla/mux"
+)
+
+type WebhookHandler struct {
+	service services.WebhookService
+	access  Authorization
+}
+
+func NewWebhookHandler(service services.WebhookService, access Authorization) WebhookHandler {
+	return WebhookHandler{
+		service: service,
+		access:  access,
+	}
+}
+
+// POST /webhooks
+func (h WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var webhook model.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Pretplata dobija iste događaje kao /watch sa tim prefiksom, pa traži isto ovlašćenje
+	if !h.access.allow(w, r, rbac.Read, prefixResource(webhook.Prefix)) {
+		return
+	}
+
+	created, err := h.service.Create(webhook)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Ključ za potpis se vraća samo u ovom odgovoru
+	resp, err := json.Marshal(created)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/webhooks/%s", created.ID))
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+}
+
+// GET /webhooks
+func (h WebhookHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	all, err := h.service.GetAll()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(all)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// GET /webhooks/{id}
+func (h WebhookHandler) Get(w http.ResponseWriter, r *http.Request) {
+	webhook, err := h.service.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(webhook)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// DELETE /webhooks/{id}
+func (h WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Delete(mux.Vars(r)["id"]); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /webhooks/deadLetters?webhook={id}
+func (h WebhookHandler) DeadLetters(w http.ResponseWriter, r *http.Request) {
+	letters, err := h.service.DeadLetters(r.URL.Query().Get("webhook"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(letters)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// GET /webhooks/deadLetters/{id}
+func (h WebhookHandler) DeadLetter(w http.ResponseWriter, r *http.Request) {
+	letter, err := h.service.DeadLetter(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(letter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// DELETE /webhooks/deadLetters/{id}
+func (h WebhookHandler) DeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteDeadLetter(mux.Vars(r)["id"]); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /webhooks/deadLetters/{id}/replay
+func (h WebhookHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Replay(r.Context(), mux.Vars(r)["id"])
+	var deliveryErr *webhooks.DeliveryError
+	if errors.As(err, &deliveryErr) {
+		// Neuspeh je na strani primaoca, a isporuka ostaje u dead-letter listi
+		writeProblem(w, r, http.StatusBadGateway, deliveryErr.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
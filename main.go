@@ -6,10 +6,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"projekat/archive"
+	"projekat/audit"
+	"projekat/events"
 	"projekat/handlers"
 	"projekat/model"
+	"projekat/rbac"
 	"projekat/repositories"
+	"projekat/secrets"
 	"projekat/services"
+	"projekat/webhooks"
 	"syscall"
 	"time"
 
@@ -17,6 +23,19 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "rotate-keys" {
+		if err := rotateKeys(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "new-api-key" {
+		if err := newAPIKey(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Kanal za prekid signala
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
@@ -24,51 +43,229 @@ func main() {
 	// Startovanje HTTP servera
 	srv := &http.Server{Addr: ":8000"}
 
-	repo := repositories.NewConfigInMemRepository()
-	repoGroup := repositories.NewConfigGroupInMemRepository()
-	service := services.NewConfigService(repo)
-	serviceGroup := services.NewConfigGroupService(repoGroup)
-	handler := handlers.NewConfigHandler(service)
-	handlerGroup := handlers.NewConfigGroupHandler(serviceGroup)
+	opts, err := parseOptions(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	storage, err := repositories.NewStorage(opts.storage, opts.storageOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Using %s storage backend", opts.storage)
+
+	// Tajni parametri se šifruju pre upisa u backend i dešifruju pri čitanju
+	keyring, err := loadKeyring(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	storage.Configs = secrets.NewConfigRepository(storage.Configs, keyring)
+	storage.ConfigGroups = secrets.NewConfigGroupRepository(storage.ConfigGroups, keyring)
+	storage.Webhooks = secrets.NewWebhookRepository(storage.Webhooks, keyring)
+
+	if opts.maxRequestBytes < 1 || opts.importMaxBytes < 1 {
+		log.Fatalf("request and import size limits must be positive, got %d and %d", opts.maxRequestBytes, opts.importMaxBytes)
+	}
+
+	// Svaka izmena repozitorijuma dobija globalnu reviziju i objavljuje se za /watch
+	if opts.watchHistory < 1 {
+		log.Fatalf("watch history must be a positive number, got %d", opts.watchHistory)
+	}
+	changes := events.NewLog(opts.watchHistory)
+	storage.Configs = events.NewConfigRepository(storage.Configs, changes)
+	storage.ConfigGroups = events.NewConfigGroupRepository(storage.ConfigGroups, changes)
+
+	authenticator, err := loadAuthenticator(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Ovlašćenja se proveravaju po RBAC politici iz fajla, koja se ponovo učitava kad se fajl izmeni
+	var policy *rbac.Engine
+	if opts.authzPolicyFile != "" {
+		if !authenticator.Enabled() {
+			log.Fatal("an authorization policy requires authentication: set -api-keys-file, -jwt-hmac-secret or -jwks-file")
+		}
+		if opts.authzReloadInterval <= 0 {
+			log.Fatalf("authz reload interval must be positive, got %s", opts.authzReloadInterval)
+		}
+		policy, err = rbac.NewEngine(opts.authzPolicyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Using authorization policy %s", opts.authzPolicyFile)
+		if opts.revealToken != "" {
+			log.Print("Ignoring the reveal token: revealing secret parameters requires the reveal action in the authorization policy")
+		}
+	}
+	authz := handlers.NewAuthorization(policy)
+
+	referencePolicy, err := services.ParseReferencePolicy(opts.referencePolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Izmene kroz servise se upisuju u ulančan audit log, koji se nastavlja posle restarta
+	auditLog, err := audit.NewLog(storage.Audit)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	serviceSchema := services.NewSchemaService(storage.Schemas)
+	service := services.NewConfigService(storage.Configs, storage.ConfigGroups, serviceSchema, referencePolicy, auditLog)
+	serviceGroup := services.NewConfigGroupService(storage.ConfigGroups, storage.Configs, serviceSchema, auditLog)
+	secretAccess := handlers.NewSecretAccess(opts.revealToken, authz)
+	handler := handlers.NewConfigHandler(service, secretAccess, authz)
+	handlerGroup := handlers.NewConfigGroupHandler(serviceGroup, secretAccess, authz)
+	handlerSchema := handlers.NewSchemaHandler(serviceSchema)
+	serviceArchive := services.NewArchiveService(storage.Configs, storage.ConfigGroups, serviceSchema, auditLog)
+	handlerArchive := handlers.NewArchiveHandler(serviceArchive, secretAccess, int64(opts.maxRequestBytes), int64(opts.importMaxBytes))
+	idempotency := handlers.NewIdempotencyStore(opts.idempotencyTTL, int64(opts.maxRequestBytes))
+	preconditions := handlers.NewPreconditions(opts.requireIfMatch)
+	handlerWatch := handlers.NewWatchHandler(changes)
+	authentication := handlers.NewAuthentication(authenticator)
+	if opts.webhookAttempts < 1 {
+		log.Fatalf("webhook attempts must be a positive number, got %d", opts.webhookAttempts)
+	}
+	dispatcher := webhooks.NewDispatcher(storage.Webhooks, webhooks.NewClient(opts.webhookTimeout, opts.webhookAllowPrivate), webhooks.RetryPolicy{
+		Attempts:   opts.webhookAttempts,
+		Backoff:    opts.webhookBackoff,
+		MaxBackoff: opts.webhookMaxBackoff,
+	})
+	serviceWebhook := services.NewWebhookService(storage.Webhooks, dispatcher, opts.webhookAllowPrivate)
+	handlerWebhook := handlers.NewWebhookHandler(serviceWebhook, authz)
+	serviceNamespace := services.NewNamespaceService(storage.Namespaces, storage.Configs, storage.ConfigGroups)
+	handlerNamespace := handlers.NewNamespaceHandler(serviceNamespace, authz)
+	handlerAudit := handlers.NewAuditHandler(auditLog)
+	if err := serviceNamespace.EnsureDefault(); err != nil {
+		log.Fatal(err)
+	}
 
 	configs := []model.Config{}
 
 	// Dodavanje pojedinačnih konfiguracija u listu
-	params1 := map[string]string{"username": "pera", "password": "pera123"}
-	config1 := model.Config{Name: "config1", Version: 1, Parameters: params1}
+	params1 := model.Parameters{"username": "pera", "password": "pera123"}
+	config1 := model.Config{Name: "config1", Version: 1, Parameters: params1, Secrets: []string{"password"}, Labels: map[string]string{"env": "prod", "region": "eu"}}
 	configs = append(configs, config1)
 
-	params2 := map[string]string{"username": "mika", "password": "mika123"}
-	config2 := model.Config{Name: "config2", Version: 1, Parameters: params2}
+	params2 := model.Parameters{"username": "mika", "password": "mika123"}
+	config2 := model.Config{Name: "config2", Version: 1, Parameters: params2, Secrets: []string{"password"}, Labels: map[string]string{"env": "dev", "region": "eu"}}
 	configs = append(configs, config2)
 
-	params := map[string]string{"username": "pera", "password": "pera123"}
-	config := model.Config{Name: "db_config", Version: 2, Parameters: params}
+	params := model.Parameters{"username": "pera", "password": "pera123"}
+	config := model.Config{Name: "db_config", Version: 2, Parameters: params, Secrets: []string{"password"}}
 
-	service.Add(config)
 	// Pravljenje konfiguracione grupe sa dodatom listom konfiguracija
 	configGroup := model.ConfigGroup{Name: "configGroup", Version: 9, Configuration: configs}
 	configGroup2 := model.ConfigGroup{Name: "configGroup2", Version: 2, Configuration: configs}
 
-	serviceGroup.Add(configGroup)
-	serviceGroup.Add(configGroup2)
+	// Početni podaci se uvoze bez pregaženja, pa trajni backend posle restarta zadržava izmene
+	seed := archive.New([]model.Config{config}, []model.ConfigGroup{configGroup, configGroup2}, time.Now().UTC())
+	if _, err := serviceArchive.Import(seed, services.SkipExisting, false); err != nil {
+		log.Printf("Seeding failed: %v", err)
+	}
+
+	// Webhook-ovi dobijaju izmene od ovog trenutka, preko istog loga kao /watch
+	dispatchCtx, stopDispatch := context.WithCancel(context.Background())
+	defer stopDispatch()
+	go dispatcher.Run(dispatchCtx, changes)
+	if policy != nil {
+		go policy.Watch(dispatchCtx, opts.authzReloadInterval)
+	}
 
 	router := mux.NewRouter()
-	router.HandleFunc("/configs/{name}/{version}", handler.Get).Methods("GET")
-	router.HandleFunc("/configGroups/{name}/{version}", handlerGroup.Get).Methods("GET")
-	router.HandleFunc("/configs", handler.GetAll).Methods("GET")
-	router.HandleFunc("/configGroups", handlerGroup.GetAll).Methods("GET")
-	router.HandleFunc("/configs", handler.Create).Methods("POST")
-	router.HandleFunc("/configGroups", handlerGroup.Create).Methods("POST")
-	router.HandleFunc("/configGroups/{name}/{version}", handlerGroup.Delete).Methods("DELETE")
-	router.HandleFunc("/configs/{name}/{version}", handler.Delete).Methods("DELETE")
-	router.HandleFunc("/configGroups/{groupName}/{groupVersion}/removeConfig/{configName}/{configVersion}", handlerGroup.RemoveConfig).Methods("DELETE")
-	router.HandleFunc("/configGroups/{groupName}/{groupVersion}/addConfig", handlerGroup.AddConfig).Methods("PUT")
+	configVersion := handlers.VersionResource("configs", "name", "version")
+	configAll := handlers.AllVersionsResource("configs", "name")
+	groupVersion := handlers.VersionResource("configGroups", "name", "version")
+	groupAll := handlers.AllVersionsResource("configGroups", "name")
+	groupContent := handlers.VersionResource("configGroups", "groupName", "groupVersion")
+	schemaVersion := handlers.VersionResource("schemas", "name", "version")
+	schemaAll := handlers.AllVersionsResource("schemas", "name")
+	allSchemas := handlers.StaticResource("schemas/*")
+	allConfigs := handlers.StaticResource("configs/*")
+	allGroups := handlers.StaticResource("configGroups/*")
+	allWebhooks := handlers.StaticResource("webhooks/*")
+	webhook := handlers.NameResource("webhooks", "id")
+	deadLetter := handlers.NameResource("webhooks/deadLetters", "id")
+
+	// Provera ovlašćenja je spolja, pa odbijen zahtev ne zauzima Idempotency-Key i ne proverava If-Match.
+	// Create i Validate proveravaju ime iz tela u samom handleru, unutar Idempotency-Key provere, pa
+	// IdempotencyStore ne pamti odgovore 401 i 403. Rute konfiguracija i grupa važe za
+	// podrazumevani prostor imena i, ispod /namespaces/{namespace}, za ostale prostore imena.
+	configRoutes := func(r *mux.Router) {
+		r.HandleFunc("/configs/{name}/{version:[0-9]+}", authz.Require(rbac.Read, handler.Get, configVersion)).Methods("GET")
+		r.HandleFunc("/configGroups/{name}/{version:[0-9]+}", authz.Require(rbac.Read, handlerGroup.Get, groupVersion)).Methods("GET")
+		r.HandleFunc("/configs/{name}/latest", authz.Require(rbac.Read, handler.Latest, configAll)).Methods("GET")
+		r.HandleFunc("/configs/{name}/diff", authz.Require(rbac.Read, handler.Diff, configAll)).Methods("GET")
+		r.HandleFunc("/configGroups/{name}/diff", authz.Require(rbac.Read, handlerGroup.Diff, groupAll)).Methods("GET")
+		r.HandleFunc("/configGroups/{name}/latest", authz.Require(rbac.Read, handlerGroup.Latest, groupAll)).Methods("GET")
+		r.HandleFunc("/configs/{name}", authz.Require(rbac.Read, handler.Versions, configAll)).Methods("GET")
+		r.HandleFunc("/configGroups/{name}", authz.Require(rbac.Read, handlerGroup.Versions, groupAll)).Methods("GET")
+		r.HandleFunc("/configs", authz.Require(rbac.Read, handler.GetAll, handlers.ListResource("configs"))).Methods("GET")
+		r.HandleFunc("/configGroups", authz.Require(rbac.Read, handlerGroup.GetAll, handlers.ListResource("configGroups"))).Methods("GET")
+		r.HandleFunc("/configs", idempotency.Wrap(handler.Create)).Methods("POST")
+		r.HandleFunc("/configGroups", idempotency.Wrap(handlerGroup.Create)).Methods("POST")
+		r.HandleFunc("/configs/{name}/versions", authz.Require(rbac.Create, idempotency.Wrap(handler.CreateVersion), configAll)).Methods("POST")
+		r.HandleFunc("/configGroups/{name}/versions", authz.Require(rbac.Create, idempotency.Wrap(handlerGroup.CreateVersion), groupAll)).Methods("POST")
+		r.HandleFunc("/configs/{name}/{version:[0-9]+}/rollback", authz.Require(rbac.Create, idempotency.Wrap(handler.Rollback), configAll)).Methods("POST")
+		r.HandleFunc("/configGroups/{name}/{version:[0-9]+}/rollback", authz.Require(rbac.Create, idempotency.Wrap(handlerGroup.Rollback), groupAll)).Methods("POST")
+		r.HandleFunc("/configGroups/{name}/{version:[0-9]+}", authz.Require(rbac.Delete, preconditions.Wrap(handlerGroup.Delete), groupVersion)).Methods("DELETE")
+		r.HandleFunc("/configGroups/{name}/{version:[0-9]+}/{labels}", authz.Require(rbac.Read, handlerGroup.GetByLabels, groupVersion)).Methods("GET")
+		r.HandleFunc("/configGroups/{name}/{version:[0-9]+}/{labels}", authz.Require(rbac.Update, preconditions.Wrap(handlerGroup.DeleteByLabels), groupVersion)).Methods("DELETE")
+		r.HandleFunc("/configs/{name}/{version:[0-9]+}", authz.Require(rbac.Delete, preconditions.Wrap(handler.Delete), configVersion)).Methods("DELETE")
+		r.HandleFunc("/configGroups/{groupName}/{groupVersion:[0-9]+}/removeConfig/{configName}/{configVersion:[0-9]+}", authz.Require(rbac.Update, preconditions.Wrap(handlerGroup.RemoveConfig), groupContent)).Methods("DELETE")
+		r.HandleFunc("/configGroups/{groupName}/{groupVersion:[0-9]+}/addConfig", authz.Require(rbac.Update, preconditions.Wrap(handlerGroup.AddConfig), groupContent)).Methods("PUT")
+		r.HandleFunc("/configGroups/{groupName}/{groupVersion:[0-9]+}/addReference", authz.Require(rbac.Update, preconditions.Wrap(handlerGroup.AddReference), groupContent)).Methods("PUT")
+		r.HandleFunc("/configGroups/{groupName}/{groupVersion:[0-9]+}/removeReference/{configName}/{configVersion:[0-9]+}", authz.Require(rbac.Update, preconditions.Wrap(handlerGroup.RemoveReference), groupContent)).Methods("DELETE")
+		r.HandleFunc("/configs/validate", handler.Validate).Methods("POST")
+
+		r.HandleFunc("/export", authz.Require(rbac.Read, handlerArchive.Export, allConfigs, allGroups)).Methods("GET")
+		r.HandleFunc("/import", authz.Require(rbac.Create, authz.Require(rbac.Update, idempotency.Wrap(handlerArchive.Import), allConfigs, allGroups), allConfigs, allGroups)).Methods("POST")
+	}
+	configRoutes(router)
+
+	namespaceRecord := handlers.NameResource("namespaces", "name")
+	router.HandleFunc("/namespaces", authz.Require(rbac.Read, handlerNamespace.GetAll, handlers.StaticResource("namespaces/*"))).Methods("GET")
+	router.HandleFunc("/namespaces", idempotency.Wrap(handlerNamespace.Create)).Methods("POST")
+	router.HandleFunc("/namespaces/{name}", authz.Require(rbac.Read, handlerNamespace.Get, namespaceRecord)).Methods("GET")
+	router.HandleFunc("/namespaces/{name}", authz.Require(rbac.Update, handlerNamespace.Update, namespaceRecord)).Methods("PUT")
+	router.HandleFunc("/namespaces/{name}", authz.Require(rbac.Delete, handlerNamespace.Delete, namespaceRecord)).Methods("DELETE")
+	namespaced := router.PathPrefix("/namespaces/{namespace}").Subrouter()
+	namespaced.Use(handlerNamespace.Exists)
+	configRoutes(namespaced)
+
+	router.HandleFunc("/schemas", authz.Require(rbac.Read, handlerSchema.GetAll, allSchemas)).Methods("GET")
+	router.HandleFunc("/schemas/{name}", authz.Require(rbac.Read, handlerSchema.Versions, schemaAll)).Methods("GET")
+	router.HandleFunc("/schemas/{name}/latest", authz.Require(rbac.Read, handlerSchema.Latest, schemaAll)).Methods("GET")
+	router.HandleFunc("/schemas/{name}/{version:[0-9]+}", authz.Require(rbac.Read, handlerSchema.Get, schemaVersion)).Methods("GET")
+	router.HandleFunc("/schemas", authz.Require(rbac.Create, idempotency.Wrap(handlerSchema.Create), allSchemas)).Methods("POST")
+	router.HandleFunc("/schemas/{name}/versions", authz.Require(rbac.Create, idempotency.Wrap(handlerSchema.CreateVersion), schemaAll)).Methods("POST")
+	router.HandleFunc("/schemas/{name}/{version:[0-9]+}", authz.Require(rbac.Delete, handlerSchema.Delete, schemaVersion)).Methods("DELETE")
+
+	router.HandleFunc("/watch", authz.Require(rbac.Read, handlerWatch.Watch, handlers.WatchResource)).Methods("GET")
+
+	auditResource := handlers.StaticResource("audit")
+	router.HandleFunc("/audit", authz.Require(rbac.Read, handlerAudit.Find, auditResource)).Methods("GET")
+	router.HandleFunc("/audit/verify", authz.Require(rbac.Read, handlerAudit.Verify, auditResource)).Methods("GET")
+
+	router.HandleFunc("/whoami", authentication.Whoami).Methods("GET")
+	router.HandleFunc("/authz/check", authz.Check).Methods("GET")
+
+	router.HandleFunc("/webhooks", authz.Require(rbac.Read, handlerWebhook.GetAll, allWebhooks)).Methods("GET")
+	router.HandleFunc("/webhooks", authz.Require(rbac.Create, idempotency.Wrap(handlerWebhook.Create), allWebhooks)).Methods("POST")
+	router.HandleFunc("/webhooks/deadLetters", authz.Require(rbac.Read, handlerWebhook.DeadLetters, handlers.StaticResource("webhooks/deadLetters/*"))).Methods("GET")
+	router.HandleFunc("/webhooks/deadLetters/{id}", authz.Require(rbac.Read, handlerWebhook.DeadLetter, deadLetter)).Methods("GET")
+	router.HandleFunc("/webhooks/deadLetters/{id}", authz.Require(rbac.Delete, handlerWebhook.DeleteDeadLetter, deadLetter)).Methods("DELETE")
+	router.HandleFunc("/webhooks/deadLetters/{id}/replay", authz.Require(rbac.Update, handlerWebhook.Replay, deadLetter)).Methods("POST")
+	router.HandleFunc("/webhooks/{id}", authz.Require(rbac.Read, handlerWebhook.Get, webhook)).Methods("GET")
+	router.HandleFunc("/webhooks/{id}", authz.Require(rbac.Delete, handlerWebhook.Delete, webhook)).Methods("DELETE")
 
 	// Pokretanje servera u zasebnoj gorutini
 	go func() {
 		log.Println("Starting server...")
-		if err := http.ListenAndServe(":8000", router); err != nil {
+		// Autentifikacija je ispred router-a, pa važi i za putanje koje router ne poznaje.
+		// ID zahteva se dodeljuje pre nje, pa ga imaju i odbijeni zahtevi.
+		if err := http.ListenAndServe(":8000", handlers.RequestID(authentication.Wrap(router))); err != nil {
 			log.Fatal(err)
 		}
 	}()
@@ -76,6 +273,7 @@ func main() {
 	// Čekanje na prekid signala za graceful shutdown
 	<-interrupt
 	log.Println("Received SIGINT or SIGTERM. Shutting down...")
+	stopDispatch()
 
 	// Shutdown servera
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
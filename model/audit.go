@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// Akcije u audit logu
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditEntry je jedan zapis audit loga: ko je, kada i u okviru kog zahteva izmenio koji zapis.
+// Zapisi su ulančani: Hash pokriva sva ostala polja, uključujući PrevHash prethodnog zapisa,
+// pa izmena ili brisanje bilo kog zapisa kvari lanac od tog mesta nadalje.
+type AuditEntry struct {
+	Sequence  int64     `json:"sequence"`
+	Time      time.Time `json:"time"`
+	Principal string    `json:"principal"`
+	RequestID string    `json:"requestId,omitempty"`
+	// Action je create, update ili delete, a Operation poziv koji je izmenu napravio
+	// (npr. createVersion, rollback, addReference, import)
+	Action    string `json:"action"`
+	Operation string `json:"operation"`
+	// Resource je putanja zapisa, ista kao ključ događaja u /watch
+	Resource  string `json:"resource"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Version   int    `json:"version"`
+	// BeforeHash i AfterHash su SHA-256 zapisa pre i posle izmene; prazni su kada zapis nije postojao
+	BeforeHash string `json:"beforeHash,omitempty"`
+	AfterHash  string `json:"afterHash,omitempty"`
+	PrevHash   string `json:"prevHash"`
+	Hash       string `json:"hash"`
+}
+
+// AuditRepository čuva audit log. Zapisi se samo dodaju: nema izmene ni brisanja.
+type AuditRepository interface {
+	// Append dodaje zapis; ako zapis sa istim rednim brojem već postoji vraća ErrAlreadyExists
+	Append(entry AuditEntry) error
+	// Last vraća poslednji zapis, odnosno ErrNotFound za prazan log
+	Last() (AuditEntry, error)
+	// GetAll vraća sve zapise sortirane po rednom broju
+	GetAll() ([]AuditEntry, error)
+}
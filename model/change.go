@@ -0,0 +1,17 @@
+package model
+
+import "encoding/json"
+
+// Change je izmena jednog zapisa onako kako ju je repozitorijum upisao: zapis pre i posle izmene
+// tačno u obliku u kom je sačuvan (JSON, sa šifrovanim tajnim parametrima) i revizija posle izmene.
+// Before je nil za novi zapis, a After za obrisan; Revision je 0 za obrisan zapis.
+type Change struct {
+	Before   json.RawMessage
+	After    json.RawMessage
+	Revision int64
+}
+
+// Changed proverava da li se sačuvan zapis izmenio
+func (c Change) Changed() bool {
+	return string(c.Before) != string(c.After)
+}
@@ -1,12 +1,36 @@
 package model
 
+import (
+	"strings"
+	"time"
+)
+
 type Config struct {
+	// Namespace popunjava repozitorijum; vrednost koju pošalje klijent se ne koristi
+	Namespace  string            `json:"namespace,omitempty"`
 	Name       string            `json:"name"`
 	Version    int               `json:"version"`
-	Parameters map[string]string `json:"parameters"`
+	Parameters Parameters        `json:"parameters"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	// Secrets su imena tajnih parametara: u repozitorijumu se čuvaju šifrovani, a pri čitanju se maskiraju
+	Secrets []string `json:"secrets,omitempty"`
+	// Encrypted sadrži šifrovane vrednosti tajnih parametara. Popunjava se samo u repozitorijumu;
+	// servisi i klijenti uvek vide vrednosti u Parameters.
+	Encrypted map[string]EncryptedValue `json:"encrypted,omitempty"`
+	CreatedAt time.Time                 `json:"createdAt"`
+	// Revision se povećava pri svakoj izmeni sačuvanog zapisa; služi kao ETag
+	Revision int64 `json:"revision,omitempty"`
+}
+
+// ConfigVersion opisuje jednu verziju konfiguracije u istoriji verzija
+type ConfigVersion struct {
+	Version    int               `json:"version"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Parameters int               `json:"parameters"`
 }
 
-func NewConfig(name string, version int, parameters map[string]string) Config {
+func NewConfig(name string, version int, parameters Parameters) Config {
 	return Config{
 		Name:       name,
 		Version:    version,
@@ -22,11 +46,86 @@ func NewConfig2(name string, version int) Config {
 }
 
 type ConfigRepository interface {
-	Create(config Config) error
+	// In vraća isti repozitorijum ograničen na prostor imena. Svi ostali metodi rade samo sa
+	// zapisima svog prostora imena; repozitorijum koji pravi backend radi sa podrazumevanim.
+	In(namespace string) ConfigRepository
+	// Izmene vraćaju Change sa zapisom pre i posle izmene, onakvim kakav je sačuvan
+	Create(config Config) (Change, error)
+	// Update zamenjuje konfiguraciju samo ako je sačuvana u reviziji config.Revision (compare-and-swap),
+	// inače vraća ErrPreconditionFailed. Sačuvana konfiguracija dobija sledeću reviziju.
+	Update(config Config) (Change, error)
+	// CreateNextVersion atomski dodeljuje konfiguraciji sledeću slobodnu verziju i čuva je
+	CreateNextVersion(config Config) (Config, Change, error)
 	Read(name string, version int) (Config, error)
-	Update(config Config) error
-	Delete(name string, version int) error
-	Add(Config Config)
+	// Delete briše konfiguraciju. Ako expectedRevision nije 0, briše je samo ako je sačuvana
+	// u toj reviziji (compare-and-swap), inače vraća ErrPreconditionFailed.
+	Delete(name string, version int, expectedRevision int64) (Change, error)
+	// Add čuva konfiguraciju i zamenjuje postojeću sa istim imenom i verzijom
+	Add(config Config) (Change, error)
 	Get(name string, version int) (Config, error)
 	GetAll() ([]Config, error)
+	// ListByName vraća sve verzije konfiguracije sortirane po verziji
+	ListByName(name string) ([]Config, error)
+	// List vraća jednu stranu konfiguracija koje odgovaraju upitu
+	List(query ListQuery) (ConfigPage, error)
+}
+
+// Clone vraća kopiju konfiguracije koja ne deli mape parametara i labela sa originalom
+func (c Config) Clone() Config {
+	c.Parameters = c.Parameters.Clone()
+	if c.Labels != nil {
+		labels := make(map[string]string, len(c.Labels))
+		for key, value := range c.Labels {
+			labels[key] = value
+		}
+		c.Labels = labels
+	}
+	if c.Secrets != nil {
+		c.Secrets = append([]string(nil), c.Secrets...)
+	}
+	if c.Encrypted != nil {
+		encrypted := make(map[string]EncryptedValue, len(c.Encrypted))
+		for key, value := range c.Encrypted {
+			encrypted[key] = value
+		}
+		c.Encrypted = encrypted
+	}
+	return c
+}
+
+// Validate proverava da se konfiguracija može sačuvati pod ključem ime/verzija
+func (c Config) Validate() error {
+	if err := ValidateName("config", c.Name); err != nil {
+		return err
+	}
+	if c.Version < 1 {
+		return Invalidf("config version must be a positive number")
+	}
+	for _, name := range c.Secrets {
+		if _, ok := c.Parameters[name]; !ok {
+			return Invalidf("secret parameter %q of config %s/%d is not set", name, c.Name, c.Version)
+		}
+	}
+	return nil
+}
+
+// ValidateName proverava ime konfiguracije ili grupe, koje je deo ključa u repozitorijumu
+func ValidateName(kind, name string) error {
+	if name == "" {
+		return Invalidf("%s name is required", kind)
+	}
+	if strings.Contains(name, "/") {
+		return Invalidf("%s name %q must not contain '/'", kind, name)
+	}
+	return nil
+}
+
+// VersionInfo vraća metapodatke o verziji konfiguracije
+func (c Config) VersionInfo() ConfigVersion {
+	return ConfigVersion{
+		Version:    c.Version,
+		CreatedAt:  c.CreatedAt,
+		Labels:     c.Labels,
+		Parameters: len(c.Parameters),
+	}
 }
@@ -1,9 +1,33 @@
 package model
 
+import "time"
+
 type ConfigGroup struct {
-	Name          string   `json:"name"`
-	Version       int      `json:"version"`
-	Configuration []Config `json:"configuration"`
+	// Namespace popunjava repozitorijum; važi i za ugrađene konfiguracije
+	Namespace     string            `json:"namespace,omitempty"`
+	Name          string            `json:"name"`
+	Version       int               `json:"version"`
+	Configuration []Config          `json:"configuration"`
+	References    []ConfigReference `json:"references,omitempty"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	// Revision se povećava pri svakoj izmeni grupe; služi kao ETag
+	Revision int64 `json:"revision,omitempty"`
+}
+
+// ConfigGroupVersion opisuje jednu verziju grupe u istoriji verzija
+type ConfigGroupVersion struct {
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Configs    int       `json:"configs"`
+	References int       `json:"references"`
+}
+
+// ConfigReference upućuje na konfiguraciju iz ConfigRepository-ja umesto da čuva njenu kopiju.
+// Config se popunjava tek pri čitanju grupe i nikad se ne čuva u repozitorijumu.
+type ConfigReference struct {
+	Name    string  `json:"name"`
+	Version int     `json:"version"`
+	Config  *Config `json:"config,omitempty"`
 }
 
 func NewConfigGroup(name string, version int, configuration []Config) ConfigGroup {
@@ -22,13 +46,174 @@ func NewConfigGroup2(name string, version int) ConfigGroup {
 }
 
 type ConfigGroupRepository interface {
-	Create(configGroup ConfigGroup) error
+	// In vraća isti repozitorijum ograničen na prostor imena, kao ConfigRepository.In
+	In(namespace string) ConfigGroupRepository
+	// Izmene vraćaju Change kao izmene ConfigRepository-ja
+	Create(configGroup ConfigGroup) (Change, error)
+	// CreateNextVersion atomski dodeljuje grupi sledeću slobodnu verziju i čuva je
+	CreateNextVersion(configGroup ConfigGroup) (ConfigGroup, Change, error)
 	Read(name string, version int) (ConfigGroup, error)
-	Update(configGroup ConfigGroup) error
-	Delete(name string, version int) error
+	// Update zamenjuje grupu samo ako je sačuvana u reviziji configGroup.Revision (compare-and-swap),
+	// inače vraća ErrPreconditionFailed. Update i sve ostale izmene grupi dodeljuju sledeću reviziju.
+	Update(configGroup ConfigGroup) (Change, error)
+	// Delete briše grupu, uz proveru revizije kao ConfigRepository.Delete
+	Delete(name string, version int, expectedRevision int64) (Change, error)
 	GetAll() ([]ConfigGroup, error)
-	Add(ConfigGroup ConfigGroup)
+	// ListByName vraća sve verzije grupe sortirane po verziji
+	ListByName(name string) ([]ConfigGroup, error)
+	// List vraća jednu stranu grupa koje odgovaraju upitu
+	List(query ListQuery) (ConfigGroupPage, error)
+	// Add čuva grupu i zamenjuje postojeću sa istim imenom i verzijom
+	Add(configGroup ConfigGroup) (Change, error)
 	Get(name string, version int) (ConfigGroup, error)
-	RemoveConfig(groupName string, groupVersion int, configName string, configVersion int) error
-	AddConfig(groupName string, groupVersion int, config Config) error
+	RemoveConfig(groupName string, groupVersion int, configName string, configVersion int) (Change, error)
+	// AddConfig atomski dodaje konfiguraciju u grupu i vraća izmenjenu grupu
+	AddConfig(groupName string, groupVersion int, config Config) (ConfigGroup, Change, error)
+	AddReference(groupName string, groupVersion int, reference ConfigReference) (Change, error)
+	RemoveReference(groupName string, groupVersion int, configName string, configVersion int) (Change, error)
+	RemoveConfigsByLabels(groupName string, groupVersion int, selector map[string]string) ([]Config, Change, error)
+}
+
+// Clone vraća duboku kopiju grupe, tako da izmene kopije ne utiču na original
+func (g ConfigGroup) Clone() ConfigGroup {
+	if g.Configuration != nil {
+		configuration := make([]Config, len(g.Configuration))
+		for i, config := range g.Configuration {
+			configuration[i] = config.Clone()
+		}
+		g.Configuration = configuration
+	}
+	if g.References != nil {
+		references := make([]ConfigReference, len(g.References))
+		for i, reference := range g.References {
+			if reference.Config != nil {
+				config := reference.Config.Clone()
+				reference.Config = &config
+			}
+			references[i] = reference
+		}
+		g.References = references
+	}
+	return g
+}
+
+// InNamespace vraća grupu smeštenu u prostor imena, zajedno sa ugrađenim konfiguracijama.
+// Lista ugrađenih konfiguracija se kopira, pa original ostaje nepromenjen.
+func (g ConfigGroup) InNamespace(namespace string) ConfigGroup {
+	g.Namespace = namespace
+	if g.Configuration != nil {
+		configuration := make([]Config, len(g.Configuration))
+		for i, config := range g.Configuration {
+			config.Namespace = namespace
+			configuration[i] = config
+		}
+		g.Configuration = configuration
+	}
+	return g
+}
+
+// Configs vraća sve konfiguracije grupe: ugrađene kopije i razrešene reference
+func (g ConfigGroup) Configs() []Config {
+	configs := make([]Config, 0, len(g.Configuration)+len(g.References))
+	configs = append(configs, g.Configuration...)
+	for _, reference := range g.References {
+		if reference.Config != nil {
+			configs = append(configs, *reference.Config)
+		}
+	}
+	return configs
+}
+
+// AddConfig dodaje kopiju konfiguracije, osim ako grupa već sadrži isto ime i verziju
+func (g *ConfigGroup) AddConfig(config Config) error {
+	for _, existing := range g.Configuration {
+		if existing.Name == config.Name && existing.Version == config.Version {
+			return AlreadyExistsf("config group already contains config with name %s and version %d", config.Name, config.Version)
+		}
+	}
+	g.Configuration = append(g.Configuration, config.Clone())
+	return nil
+}
+
+// RemoveConfig uklanja ugrađenu konfiguraciju sa datim imenom i verzijom
+func (g *ConfigGroup) RemoveConfig(name string, version int) error {
+	for i, config := range g.Configuration {
+		if config.Name == name && config.Version == version {
+			g.Configuration = append(g.Configuration[:i:i], g.Configuration[i+1:]...)
+			return nil
+		}
+	}
+	return NotFoundf("config with name %s and version %d not found in group", name, version)
+}
+
+// HasReference proverava da li grupa upućuje na konfiguraciju sa datim imenom i verzijom
+func (g ConfigGroup) HasReference(name string, version int) bool {
+	for _, reference := range g.References {
+		if reference.Name == name && reference.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// AddReference dodaje referencu, osim ako grupa već upućuje na istu konfiguraciju
+func (g *ConfigGroup) AddReference(reference ConfigReference) error {
+	if g.HasReference(reference.Name, reference.Version) {
+		return AlreadyExistsf("config group already references config with name %s and version %d", reference.Name, reference.Version)
+	}
+	reference.Config = nil
+	g.References = append(g.References, reference)
+	return nil
+}
+
+// RemoveReference uklanja referencu na konfiguraciju sa datim imenom i verzijom
+func (g *ConfigGroup) RemoveReference(name string, version int) error {
+	for i, reference := range g.References {
+		if reference.Name == name && reference.Version == version {
+			g.References = append(g.References[:i:i], g.References[i+1:]...)
+			return nil
+		}
+	}
+	return NotFoundf("config with name %s and version %d is not referenced by group", name, version)
+}
+
+// RemoveConfigsByLabels uklanja ugrađene konfiguracije koje odgovaraju selektoru i vraća uklonjene
+func (g *ConfigGroup) RemoveConfigsByLabels(selector map[string]string) []Config {
+	kept := make([]Config, 0, len(g.Configuration))
+	removed := make([]Config, 0)
+	for _, config := range g.Configuration {
+		if config.MatchesLabels(selector) {
+			removed = append(removed, config)
+		} else {
+			kept = append(kept, config)
+		}
+	}
+	g.Configuration = kept
+	return removed
+}
+
+// Validate proverava grupu i sve ugrađene konfiguracije
+func (g ConfigGroup) Validate() error {
+	if err := ValidateName("config group", g.Name); err != nil {
+		return err
+	}
+	if g.Version < 1 {
+		return Invalidf("config group version must be a positive number")
+	}
+	for _, config := range g.Configuration {
+		if err := config.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VersionInfo vraća metapodatke o verziji grupe
+func (g ConfigGroup) VersionInfo() ConfigGroupVersion {
+	return ConfigGroupVersion{
+		Version:    g.Version,
+		CreatedAt:  g.CreatedAt,
+		Configs:    len(g.Configuration),
+		References: len(g.References),
+	}
 }
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ValueChange je stara i nova vrednost izmenjenog ključa
+type ValueChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// MapDiff opisuje razliku između dve mape (parametara ili labela)
+type MapDiff struct {
+	Added    map[string]interface{} `json:"added,omitempty"`
+	Removed  map[string]interface{} `json:"removed,omitempty"`
+	Modified map[string]ValueChange `json:"modified,omitempty"`
+}
+
+// Empty proverava da li između mapa nema razlike
+func (d MapDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// ConfigDiff je razlika između dve verzije konfiguracije
+type ConfigDiff struct {
+	Name        string  `json:"name"`
+	FromVersion int     `json:"fromVersion"`
+	ToVersion   int     `json:"toVersion"`
+	Parameters  MapDiff `json:"parameters"`
+	Labels      MapDiff `json:"labels"`
+	// Secrets su imena parametara koji su tajni u bar jednoj od verzija
+	Secrets []string `json:"secrets,omitempty"`
+}
+
+// Empty proverava da li se konfiguracije razlikuju po parametrima ili labelama
+func (d ConfigDiff) Empty() bool {
+	return d.Parameters.Empty() && d.Labels.Empty()
+}
+
+// ConfigGroupDiff je razlika između dve verzije grupe. Konfiguracije se u grupama
+// uparuju po imenu, pa je promena verzije konfiguracije izmena, a ne brisanje i dodavanje.
+type ConfigGroupDiff struct {
+	Name        string       `json:"name"`
+	FromVersion int          `json:"fromVersion"`
+	ToVersion   int          `json:"toVersion"`
+	Added       []Config     `json:"added"`
+	Removed     []Config     `json:"removed"`
+	Changed     []ConfigDiff `json:"changed"`
+}
+
+// diffMaps poredi vrednosti na najvišem nivou; izmena ugnježdenog objekta prikazuje se
+// kao izmena celog ključa, sa starom i novom vrednošću
+func diffMaps(from, to map[string]interface{}) MapDiff {
+	diff := MapDiff{}
+	for key, value := range to {
+		old, exists := from[key]
+		switch {
+		case !exists:
+			if diff.Added == nil {
+				diff.Added = make(map[string]interface{})
+			}
+			diff.Added[key] = value
+		case !ValuesEqual(old, value):
+			if diff.Modified == nil {
+				diff.Modified = make(map[string]ValueChange)
+			}
+			diff.Modified[key] = ValueChange{From: old, To: value}
+		}
+	}
+	for key, value := range from {
+		if _, exists := to[key]; !exists {
+			if diff.Removed == nil {
+				diff.Removed = make(map[string]interface{})
+			}
+			diff.Removed[key] = value
+		}
+	}
+	return diff
+}
+
+// DiffConfigs poredi dve konfiguracije
+func DiffConfigs(from, to Config) ConfigDiff {
+	return ConfigDiff{
+		Name:        to.Name,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Parameters:  diffMaps(from.Parameters, to.Parameters),
+		Labels:      diffMaps(labelValues(from.Labels), labelValues(to.Labels)),
+		Secrets:     secretsUnion(from.Secrets, to.Secrets),
+	}
+}
+
+func secretsUnion(from, to []string) []string {
+	var secrets []string
+	for _, name := range append(append([]string(nil), from...), to...) {
+		if !containsString(secrets, name) {
+			secrets = append(secrets, name)
+		}
+	}
+	sort.Strings(secrets)
+	return secrets
+}
+
+// DiffConfigGroups poredi konfiguracije dve grupe (ugrađene i razrešene reference)
+func DiffConfigGroups(from, to ConfigGroup) ConfigGroupDiff {
+	diff := ConfigGroupDiff{
+		Name:        to.Name,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Added:       []Config{},
+		Removed:     []Config{},
+		Changed:     []ConfigDiff{},
+	}
+
+	fromConfigs := configsByName(from.Configs())
+	toConfigs := configsByName(to.Configs())
+
+	for _, key := range sortedKeys(toConfigs) {
+		newConfig := toConfigs[key]
+		oldConfig, exists := fromConfigs[key]
+		if !exists {
+			diff.Added = append(diff.Added, newConfig)
+			continue
+		}
+		configDiff := DiffConfigs(oldConfig, newConfig)
+		if oldConfig.Version != newConfig.Version || !configDiff.Empty() {
+			diff.Changed = append(diff.Changed, configDiff)
+		}
+	}
+	for _, key := range sortedKeys(fromConfigs) {
+		if _, exists := toConfigs[key]; !exists {
+			diff.Removed = append(diff.Removed, fromConfigs[key])
+		}
+	}
+	return diff
+}
+
+// labelValues prilagođava labele za diffMaps
+func labelValues(labels map[string]string) map[string]interface{} {
+	values := make(map[string]interface{}, len(labels))
+	for key, value := range labels {
+		values[key] = value
+	}
+	return values
+}
+
+// configsByName indeksira konfiguracije po imenu; ime koje se u grupi javlja više puta
+// indeksira se kao ime/verzija, da se nijedna konfiguracija ne izgubi
+func configsByName(configs []Config) map[string]Config {
+	count := make(map[string]int)
+	for _, config := range configs {
+		count[config.Name]++
+	}
+
+	indexed := make(map[string]Config, len(configs))
+	for _, config := range configs {
+		key := config.Name
+		if count[config.Name] > 1 {
+			key = fmt.Sprintf("%s/%d", config.Name, config.Version)
+		}
+		indexed[key] = config
+	}
+	return indexed
+}
+
+func sortedKeys(configs map[string]Config) []string {
+	keys := make([]string, 0, len(configs))
+	for key := range configs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
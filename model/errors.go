@@ -0,0 +1,71 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Vrste grešaka koje vraćaju repozitorijumi i servisi. Proveravaju se sa errors.Is,
+// a handleri ih mapiraju na HTTP statuse.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	ErrInvalid       = errors.New("invalid")
+	ErrConflict      = errors.New("conflict")
+	// Greška ove vrste je uvek *SchemaViolationError sa spiskom odstupanja
+	ErrSchemaViolation = errors.New("schema violation")
+	// ErrPreconditionFailed znači da zapis više nije u reviziji koju je klijent očekivao
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrGone znači da tražena istorija više ne postoji, npr. događaji starijih revizija
+	ErrGone = errors.New("gone")
+	// ErrUnauthenticated znači da zahtev nema ispravne akreditive (API ključ ili token)
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden znači da politika pristupa ne dozvoljava akciju proverenom klijentu
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Error nosi poruku za korisnika i vrstu greške
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func NotFoundf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func AlreadyExistsf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrAlreadyExists, Message: fmt.Sprintf(format, args...)}
+}
+
+func Invalidf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrInvalid, Message: fmt.Sprintf(format, args...)}
+}
+
+func Conflictf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+func PreconditionFailedf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrPreconditionFailed, Message: fmt.Sprintf(format, args...)}
+}
+
+func Gonef(format string, args ...interface{}) error {
+	return &Error{Kind: ErrGone, Message: fmt.Sprintf(format, args...)}
+}
+
+func Unauthenticatedf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrUnauthenticated, Message: fmt.Sprintf(format, args...)}
+}
+
+func Forbiddenf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrForbidden, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,34 @@
+package model
+
+import "strings"
+
+// ParseLabels pretvara selektor oblika "env:prod;region:eu" u mapu labela
+func ParseLabels(selector string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(selector, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, Invalidf("invalid label %q, expected key:value", pair)
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	if len(labels) == 0 {
+		return nil, Invalidf("empty label selector")
+	}
+	return labels, nil
+}
+
+// MatchesLabels proverava da li konfiguracija ima sve labele iz selektora sa istim vrednostima
+func (c Config) MatchesLabels(selector map[string]string) bool {
+	for key, value := range selector {
+		if actual, ok := c.Labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"regexp"
+	"time"
+)
+
+// DefaultNamespace je prostor imena u koji idu zahtevi sa putanja bez /namespaces/{ns}.
+// Uvek postoji i ne može se obrisati.
+const DefaultNamespace = "default"
+
+// Ime prostora imena je deo ključeva i putanja, pa sme da sadrži samo mala slova, cifre i crtice
+var namespaceName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Namespace odvaja konfiguracije i grupe jednog tima od ostalih: ista imena u različitim
+// prostorima imena su različiti zapisi
+type Namespace struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+}
+
+type NamespaceRepository interface {
+	Create(namespace Namespace) error
+	Update(namespace Namespace) error
+	Get(name string) (Namespace, error)
+	Delete(name string) error
+	GetAll() ([]Namespace, error)
+}
+
+// ValidateNamespace proverava ime prostora imena
+func ValidateNamespace(name string) error {
+	if !namespaceName.MatchString(name) {
+		return Invalidf("namespace name %q must be 1-63 lowercase letters, digits or '-', starting and ending with a letter or digit", name)
+	}
+	return nil
+}
+
+// NamespacePrefix vraća prefiks ključeva i putanja zapisa iz prostora imena. Za podrazumevani
+// prostor imena prefiks je prazan, pa ključevi u skladištu, putanje događaja i resursi u politici
+// pristupa ostaju isti kao pre uvođenja prostora imena.
+func NamespacePrefix(namespace string) string {
+	if namespace == "" || namespace == DefaultNamespace {
+		return ""
+	}
+	return "namespaces/" + namespace + "/"
+}
+
+// Validate proverava prostor imena pre čuvanja
+func (n Namespace) Validate() error {
+	return ValidateNamespace(n.Name)
+}
+
+// Clone vraća kopiju prostora imena koja ne deli labele sa originalom
+func (n Namespace) Clone() Namespace {
+	if n.Labels != nil {
+		labels := make(map[string]string, len(n.Labels))
+		for key, value := range n.Labels {
+			labels[key] = value
+		}
+		n.Labels = labels
+	}
+	return n
+}
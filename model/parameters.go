@@ -0,0 +1,93 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// Parameters su parametri konfiguracije sa očuvanim JSON tipovima: stringovi, brojevi,
+// bool vrednosti, nizovi i ugnježdeni objekti. Brojevi se čuvaju kao json.Number,
+// da bi se veliki celi brojevi vratili tačno onako kako su poslati.
+type Parameters map[string]interface{}
+
+// UnmarshalJSON dekodira parametre tako da brojevi ostanu json.Number, a ne float64
+func (p *Parameters) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var values map[string]interface{}
+	if err := decoder.Decode(&values); err != nil {
+		return err
+	}
+	*p = values
+	return nil
+}
+
+// Clone vraća duboku kopiju parametara
+func (p Parameters) Clone() Parameters {
+	if p == nil {
+		return nil
+	}
+	cloned := make(Parameters, len(p))
+	for key, value := range p {
+		cloned[key] = cloneValue(value)
+	}
+	return cloned
+}
+
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		cloned := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			cloned[key] = cloneValue(item)
+		}
+		return cloned
+	case []interface{}:
+		cloned := make([]interface{}, len(v))
+		for i, item := range v {
+			cloned[i] = cloneValue(item)
+		}
+		return cloned
+	}
+	return value
+}
+
+// NormalizeValue svodi vrednost na oblik koji daje JSON dekoder (json.Number za brojeve,
+// map[string]interface{} i []interface{} za složene vrednosti), da bi se vrednosti
+// zadate u Go kodu mogle porediti sa onima pročitanim iz repozitorijuma
+func NormalizeValue(value interface{}) interface{} {
+	switch value.(type) {
+	case nil, string, bool, json.Number:
+		return value
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var normalized interface{}
+	if err := decoder.Decode(&normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+// ValuesEqual poredi dve vrednosti parametara po JSON sadržaju
+func ValuesEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(NormalizeValue(a), NormalizeValue(b))
+}
+
+// FormatValue prikazuje vrednost parametra u jednom redu: string bez navodnika, ostalo kao JSON
+func FormatValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
@@ -0,0 +1,266 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SortField je polje po kome se sortira lista konfiguracija ili grupa
+type SortField string
+
+const (
+	SortByName      SortField = "name"
+	SortByVersion   SortField = "version"
+	SortByCreatedAt SortField = "createdAt"
+)
+
+// ParseSortField proverava ime polja za sortiranje
+func ParseSortField(name string) (SortField, error) {
+	switch field := SortField(name); field {
+	case SortByName, SortByVersion, SortByCreatedAt:
+		return field, nil
+	}
+	return "", Invalidf("cannot sort by %q (expected %q, %q or %q)", name, SortByName, SortByVersion, SortByCreatedAt)
+}
+
+const (
+	// DefaultPageLimit je veličina strane kada klijent ne zada limit
+	DefaultPageLimit = 100
+	// MaxPageLimit je najveća dozvoljena veličina strane
+	MaxPageLimit = 1000
+)
+
+// ListQuery opisuje jednu stranu liste: filtere, sortiranje i poziciju. Repozitorijumi je
+// primenjuju koliko backend dozvoljava (npr. prefiks imena kao prefiks ključa), a ostatak
+// preko PageConfigs i PageConfigGroups, tako da svi backend-i vraćaju iste strane.
+type ListQuery struct {
+	// NamePrefix zadržava samo zapise čije ime počinje prefiksom
+	NamePrefix string
+	// ParameterKey zadržava konfiguracije koje imaju parametar sa tim ključem,
+	// odnosno grupe u kojima ga ima bar jedna ugrađena konfiguracija
+	ParameterKey string
+	Sort         SortField
+	Descending   bool
+	Limit        int
+	// Cursor je NextCursor prethodne strane; prazan znači prvu stranu
+	Cursor string
+	// Scope označava listu za koju se izdaju kursori (handler-i zadaju putanju zahteva, npr.
+	// /namespaces/payments/configs). Kursor važi samo uz isti Scope i iste filtere.
+	Scope string
+}
+
+// ConfigPage je jedna strana konfiguracija; NextCursor je prazan na poslednjoj strani
+type ConfigPage struct {
+	Items      []Config
+	NextCursor string
+}
+
+// ConfigGroupPage je jedna strana grupa; NextCursor je prazan na poslednjoj strani
+type ConfigGroupPage struct {
+	Items      []ConfigGroup
+	NextCursor string
+}
+
+// cursor pamti ključ poslednjeg zapisa strane i sortiranje za koje važi. Klijentu se predaje kao
+// neproziran string: base64 JSON-a i kontrolne sume nad njim, opsegom i filterima upita, pa se
+// izmenjen kursor ili kursor druge liste odbija. Suma nije potpis: ko je namerno izračuna može
+// samo da zada drugačiji početak liste koju ionako sme da čita.
+type cursor struct {
+	Sort       SortField `json:"s"`
+	Descending bool      `json:"d,omitempty"`
+	Name       string    `json:"n"`
+	Version    int       `json:"v"`
+	CreatedAt  time.Time `json:"c"`
+}
+
+// recordKey su polja zapisa po kojima se sortira
+type recordKey struct {
+	Name      string
+	Version   int
+	CreatedAt time.Time
+}
+
+// Validate dopunjuje podrazumevane vrednosti i proverava upit
+func (q *ListQuery) Validate() error {
+	if q.Sort == "" {
+		q.Sort = SortByName
+	}
+	if _, err := ParseSortField(string(q.Sort)); err != nil {
+		return err
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultPageLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxPageLimit {
+		return Invalidf("limit must be between 1 and %d", MaxPageLimit)
+	}
+	_, err := q.decodeCursor()
+	return err
+}
+
+func (q ListQuery) decodeCursor() (*cursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	encoded, encodedSum, ok := strings.Cut(q.Cursor, ".")
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if !ok || err != nil {
+		return nil, Invalidf("invalid cursor")
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(encodedSum)
+	if err != nil || !hmac.Equal(sum, q.cursorSum(data)) {
+		return nil, Invalidf("invalid cursor: it was modified or issued for a different list or filters")
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, Invalidf("invalid cursor")
+	}
+	if c.Sort != q.Sort || c.Descending != q.Descending {
+		return nil, Invalidf("cursor was issued for a different sort order")
+	}
+	return &c, nil
+}
+
+func (q ListQuery) encodeCursor(key recordKey) string {
+	data, _ := json.Marshal(cursor{Sort: q.Sort, Descending: q.Descending, Name: key.Name, Version: key.Version, CreatedAt: key.CreatedAt})
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(q.cursorSum(data))
+}
+
+// cursorSum je kontrolna suma kursora: prvih 12 bajtova SHA-256 nad opsegom, filterima i sadržajem
+func (q ListQuery) cursorSum(data []byte) []byte {
+	h := sha256.New()
+	for _, part := range []string{q.Scope, q.NamePrefix, q.ParameterKey} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(data)
+	return h.Sum(nil)[:12]
+}
+
+// compare poredi ključeve po polju za sortiranje, a jednake po imenu i verziji,
+// tako da je redosled potpun i stabilan između strana
+func (q ListQuery) compare(a, b recordKey) int {
+	result := 0
+	switch q.Sort {
+	case SortByVersion:
+		result = compareInts(a.Version, b.Version)
+	case SortByCreatedAt:
+		switch {
+		case a.CreatedAt.Before(b.CreatedAt):
+			result = -1
+		case a.CreatedAt.After(b.CreatedAt):
+			result = 1
+		}
+	}
+	if result == 0 {
+		result = strings.Compare(a.Name, b.Name)
+	}
+	if result == 0 {
+		result = compareInts(a.Version, b.Version)
+	}
+	if q.Descending {
+		return -result
+	}
+	return result
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// MatchesConfig proverava filtere upita. Tajni parametri u repozitorijumu su u Encrypted,
+// pa se i tamo traži ključ parametra.
+func (q ListQuery) MatchesConfig(config Config) bool {
+	if !strings.HasPrefix(config.Name, q.NamePrefix) {
+		return false
+	}
+	if q.ParameterKey == "" {
+		return true
+	}
+	return config.hasParameter(q.ParameterKey)
+}
+
+// MatchesConfigGroup proverava filtere upita za grupu
+func (q ListQuery) MatchesConfigGroup(configGroup ConfigGroup) bool {
+	if !strings.HasPrefix(configGroup.Name, q.NamePrefix) {
+		return false
+	}
+	if q.ParameterKey == "" {
+		return true
+	}
+	for _, config := range configGroup.Configuration {
+		if config.hasParameter(q.ParameterKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Config) hasParameter(key string) bool {
+	if _, ok := c.Parameters[key]; ok {
+		return true
+	}
+	_, ok := c.Encrypted[key]
+	return ok
+}
+
+// PageConfigs filtrira, sortira i seče konfiguracije na stranu upita. Ne kopira zapise.
+func PageConfigs(configs []Config, query ListQuery) (ConfigPage, error) {
+	items, next, err := page(configs, query, query.MatchesConfig, func(c Config) recordKey {
+		return recordKey{Name: c.Name, Version: c.Version, CreatedAt: c.CreatedAt}
+	})
+	if err != nil {
+		return ConfigPage{}, err
+	}
+	return ConfigPage{Items: items, NextCursor: next}, nil
+}
+
+// PageConfigGroups filtrira, sortira i seče grupe na stranu upita. Ne kopira zapise.
+func PageConfigGroups(configGroups []ConfigGroup, query ListQuery) (ConfigGroupPage, error) {
+	items, next, err := page(configGroups, query, query.MatchesConfigGroup, func(g ConfigGroup) recordKey {
+		return recordKey{Name: g.Name, Version: g.Version, CreatedAt: g.CreatedAt}
+	})
+	if err != nil {
+		return ConfigGroupPage{}, err
+	}
+	return ConfigGroupPage{Items: items, NextCursor: next}, nil
+}
+
+func page[T any](records []T, query ListQuery, matches func(T) bool, key func(T) recordKey) ([]T, string, error) {
+	if err := query.Validate(); err != nil {
+		return nil, "", err
+	}
+	after, err := query.decodeCursor()
+	if err != nil {
+		return nil, "", err
+	}
+
+	selected := make([]T, 0)
+	for _, record := range records {
+		if !matches(record) {
+			continue
+		}
+		if after != nil && query.compare(key(record), recordKey{Name: after.Name, Version: after.Version, CreatedAt: after.CreatedAt}) <= 0 {
+			continue
+		}
+		selected = append(selected, record)
+	}
+	sort.Slice(selected, func(i, j int) bool { return query.compare(key(selected[i]), key(selected[j])) < 0 })
+
+	if len(selected) <= query.Limit {
+		return selected, "", nil
+	}
+	selected = selected[:query.Limit]
+	return selected, query.encodeCursor(key(selected[len(selected)-1])), nil
+}
@@ -0,0 +1,213 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listedConfigs vraća konfiguracije sa ponovljenim imenima, verzijama i vremenima kreiranja,
+// da bi redosled zavisio i od drugog i trećeg ključa sortiranja
+func listedConfigs() []Config {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var configs []Config
+	for i, name := range []string{"web", "db", "api", "db_replica", "cache", "db", "queue", "api", "db", "auth"} {
+		version := 1
+		for _, config := range configs {
+			if config.Name == name {
+				version++
+			}
+		}
+		config := NewConfig(name, version, Parameters{"host": "localhost"})
+		if i%3 == 0 {
+			config.Parameters["port"] = 80
+		}
+		config.CreatedAt = created.Add(time.Duration(i%4) * time.Hour)
+		configs = append(configs, config)
+	}
+	return configs
+}
+
+func configKeys(configs []Config) []string {
+	keys := make([]string, 0, len(configs))
+	for _, config := range configs {
+		keys = append(keys, fmt.Sprintf("%s/%d", config.Name, config.Version))
+	}
+	return keys
+}
+
+// pageAll prolazi kroz sve strane upita i vraća zapise redom kojim su stigli
+func pageAll(t *testing.T, configs []Config, query ListQuery) []string {
+	t.Helper()
+	var listed []string
+	for pages := 0; ; pages++ {
+		if pages > len(configs)+1 {
+			t.Fatal("paging does not end")
+		}
+		page, err := PageConfigs(configs, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Items) > query.Limit || (page.NextCursor != "" && len(page.Items) != query.Limit) {
+			t.Fatalf("page has %d items with limit %d and next cursor %q", len(page.Items), query.Limit, page.NextCursor)
+		}
+		listed = append(listed, configKeys(page.Items)...)
+		if page.NextCursor == "" {
+			return listed
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+// Strane bilo koje veličine, za svako sortiranje, daju isti redosled kao cela lista: bez
+// ponovljenih i bez preskočenih zapisa
+func TestPageConfigsCoversListWithoutGapsOrDuplicates(t *testing.T) {
+	configs := listedConfigs()
+	for _, sort := range []SortField{SortByName, SortByVersion, SortByCreatedAt} {
+		for _, descending := range []bool{false, true} {
+			whole, err := PageConfigs(configs, ListQuery{Sort: sort, Descending: descending, Limit: MaxPageLimit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := configKeys(whole.Items)
+			if len(want) != len(configs) || whole.NextCursor != "" {
+				t.Fatalf("%s: whole list has %d items and cursor %q", sort, len(want), whole.NextCursor)
+			}
+			for limit := 1; limit <= len(configs)+1; limit++ {
+				got := pageAll(t, configs, ListQuery{Sort: sort, Descending: descending, Limit: limit})
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("sort %s, descending %v, limit %d: got %v, want %v", sort, descending, limit, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestPageConfigsOrder(t *testing.T) {
+	configs := listedConfigs()
+	cases := []struct {
+		name  string
+		query ListQuery
+		want  []string
+	}{
+		{"name", ListQuery{},
+			[]string{"api/1", "api/2", "auth/1", "cache/1", "db/1", "db/2", "db/3", "db_replica/1", "queue/1", "web/1"}},
+		{"name descending", ListQuery{Descending: true},
+			[]string{"web/1", "queue/1", "db_replica/1", "db/3", "db/2", "db/1", "cache/1", "auth/1", "api/2", "api/1"}},
+		{"version, then name", ListQuery{Sort: SortByVersion},
+			[]string{"api/1", "auth/1", "cache/1", "db/1", "db_replica/1", "queue/1", "web/1", "api/2", "db/2", "db/3"}},
+		{"created, then name and version", ListQuery{Sort: SortByCreatedAt},
+			[]string{"cache/1", "db/3", "web/1", "auth/1", "db/1", "db/2", "api/1", "queue/1", "api/2", "db_replica/1"}},
+		{"name prefix", ListQuery{NamePrefix: "db"},
+			[]string{"db/1", "db/2", "db/3", "db_replica/1"}},
+		{"parameter", ListQuery{ParameterKey: "port"},
+			[]string{"auth/1", "db_replica/1", "queue/1", "web/1"}},
+		{"name prefix and parameter", ListQuery{NamePrefix: "db", ParameterKey: "port"},
+			[]string{"db_replica/1"}},
+		{"no match", ListQuery{NamePrefix: "zzz"}, []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			page, err := PageConfigs(configs, c.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := configKeys(page.Items); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+// Tajni parametar se u repozitorijumu čuva šifrovan, a filter po ključu ga i dalje nalazi
+func TestPageConfigsFindsEncryptedParameter(t *testing.T) {
+	secret := NewConfig("vault", 1, Parameters{})
+	secret.Encrypted = map[string]EncryptedValue{"password": {}}
+	page, err := PageConfigs([]Config{secret, NewConfig("plain", 1, Parameters{"host": "a"})}, ListQuery{ParameterKey: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := configKeys(page.Items); !reflect.DeepEqual(got, []string{"vault/1"}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestCursorIsRejectedOutsideItsList(t *testing.T) {
+	configs := listedConfigs()
+	query := ListQuery{Scope: "/configs", NamePrefix: "db", Limit: 2}
+	first, err := PageConfigs(configs, query)
+	if err != nil || first.NextCursor == "" {
+		t.Fatalf("first page: %v, cursor %q", err, first.NextCursor)
+	}
+	cursor := first.NextCursor
+
+	payload, sum, _ := strings.Cut(cursor, ".")
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Kursor koji upućuje dalje u listu, sa nepromenjenom sumom
+	edited := base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(data), `"n":"db"`, `"n":"db_replica"`, 1))) + "." + sum
+	if edited == cursor {
+		t.Fatalf("cursor payload %s does not name db", data)
+	}
+
+	cases := []struct {
+		name   string
+		modify func(q *ListQuery)
+		ok     bool
+	}{
+		{"same list", func(q *ListQuery) {}, true},
+		{"other page size", func(q *ListQuery) { q.Limit = 5 }, true},
+		{"other scope", func(q *ListQuery) { q.Scope = "/namespaces/payments/configs" }, false},
+		{"other list", func(q *ListQuery) { q.Scope = "/configGroups" }, false},
+		{"other name prefix", func(q *ListQuery) { q.NamePrefix = "d" }, false},
+		{"other parameter", func(q *ListQuery) { q.ParameterKey = "port" }, false},
+		{"other order", func(q *ListQuery) { q.Descending = true }, false},
+		{"other sort", func(q *ListQuery) { q.Sort = SortByVersion }, false},
+		{"edited payload", func(q *ListQuery) { q.Cursor = edited }, false},
+		{"without sum", func(q *ListQuery) { q.Cursor = payload }, false},
+		{"other sum", func(q *ListQuery) { q.Cursor = payload + "." + base64.RawURLEncoding.EncodeToString(make([]byte, 12)) }, false},
+		{"not base64", func(q *ListQuery) { q.Cursor = "%%%." + sum }, false},
+		{"not json", func(q *ListQuery) { q.Cursor = base64.RawURLEncoding.EncodeToString([]byte("x")) + "." + sum }, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			next := query
+			next.Cursor = cursor
+			c.modify(&next)
+			_, err := PageConfigs(configs, next)
+			if c.ok && err != nil {
+				t.Errorf("got %v", err)
+			}
+			if !c.ok && !errors.Is(err, ErrInvalid) {
+				t.Errorf("got %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestListQueryValidate(t *testing.T) {
+	query := ListQuery{}
+	if err := query.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if query.Sort != SortByName || query.Limit != DefaultPageLimit {
+		t.Errorf("defaults are sort %q and limit %d", query.Sort, query.Limit)
+	}
+
+	for _, invalid := range []ListQuery{
+		{Limit: MaxPageLimit + 1},
+		{Limit: -1},
+		{Sort: "size"},
+		{Cursor: "garbage"},
+	} {
+		if err := invalid.Validate(); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%+v: got %v, want ErrInvalid", invalid, err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package model
+
+import "fmt"
+
+// FirstRevision je revizija novog zapisa
+const FirstRevision = 1
+
+// CheckRevision proverava da je sačuvani zapis i dalje u očekivanoj reviziji
+func CheckRevision(kind, name string, version int, stored, expected int64) error {
+	if stored != expected {
+		return PreconditionFailedf("%s %s/%d was modified: revision is %d, expected %d", kind, name, version, stored, expected)
+	}
+	return nil
+}
+
+// Precondition je uslov iz If-Match zaglavlja. Nulta vrednost ne postavlja uslov.
+type Precondition struct {
+	// Set je true kada je klijent poslao If-Match
+	Set bool
+	// Any odgovara "If-Match: *", koji traži samo da zapis postoji
+	Any bool
+	// Revisions su revizije iz navedenih ETag-ova
+	Revisions []int64
+}
+
+// Check vraća ErrPreconditionFailed ako uslov ne odgovara trenutnoj reviziji zapisa
+func (p Precondition) Check(kind, name string, version int, revision int64) error {
+	if !p.Set || p.Any {
+		return nil
+	}
+	for _, expected := range p.Revisions {
+		if expected == revision {
+			return nil
+		}
+	}
+	return PreconditionFailedf("%s %s/%d is at revision %d, which does not match If-Match", kind, name, version, revision)
+}
+
+// ETag vraća jaki ETag za reviziju zapisa
+func ETag(revision int64) string {
+	return fmt.Sprintf("%q", fmt.Sprint(revision))
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schema je JSON Schema za parametre konfiguracija sa istim imenom. Šeme su verzionisane
+// kao i konfiguracije; konfiguracija se proverava po najnovijoj verziji šeme.
+type Schema struct {
+	Name      string          `json:"name"`
+	Version   int             `json:"version"`
+	Schema    json.RawMessage `json:"schema"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type SchemaRepository interface {
+	Create(schema Schema) error
+	// CreateNextVersion atomski dodeljuje šemi sledeću slobodnu verziju i čuva je
+	CreateNextVersion(schema Schema) (Schema, error)
+	Get(name string, version int) (Schema, error)
+	Delete(name string, version int) error
+	GetAll() ([]Schema, error)
+	// ListByName vraća sve verzije šeme sortirane po verziji
+	ListByName(name string) ([]Schema, error)
+}
+
+// Clone vraća kopiju šeme koja ne deli sadržaj sa originalom
+func (s Schema) Clone() Schema {
+	if s.Schema != nil {
+		s.Schema = append(json.RawMessage(nil), s.Schema...)
+	}
+	return s
+}
+
+// Validate proverava da se šema može sačuvati pod ključem ime/verzija
+func (s Schema) Validate() error {
+	if err := ValidateName("schema", s.Name); err != nil {
+		return err
+	}
+	if s.Version < 1 {
+		return Invalidf("schema version must be a positive number")
+	}
+	if len(s.Schema) == 0 {
+		return Invalidf("schema %s/%d has no schema document", s.Name, s.Version)
+	}
+	return nil
+}
+
+// Violation je jedno odstupanje parametara od šeme. Path je JSON pointer do vrednosti
+// unutar parametara ("" za same parametre).
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// SchemaViolationError nosi sva odstupanja konfiguracije od njene šeme
+type SchemaViolationError struct {
+	Config        string
+	SchemaVersion int
+	Violations    []Violation
+}
+
+func (e *SchemaViolationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		path := violation.Path
+		if path == "" {
+			path = "/"
+		}
+		messages = append(messages, fmt.Sprintf("%s: %s", path, violation.Message))
+	}
+	return fmt.Sprintf("config %s does not match schema %s/%d: %s", e.Config, e.Config, e.SchemaVersion, strings.Join(messages, "; "))
+}
+
+func (e *SchemaViolationError) Unwrap() error {
+	return ErrSchemaViolation
+}
@@ -0,0 +1,116 @@
+package model
+
+// MaskedValue zamenjuje vrednost tajnog parametra u odgovorima
+const MaskedValue = "******"
+
+// EncryptedValue je vrednost tajnog parametra šifrovana envelope šemom: vrednost je
+// šifrovana ključem podataka, a ključ podataka glavnim ključem sa oznakom KeyID
+type EncryptedValue struct {
+	KeyID      string `json:"keyId"`
+	DataKey    []byte `json:"dataKey"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Masked vraća kopiju konfiguracije u kojoj su vrednosti tajnih parametara zamenjene sa MaskedValue
+func (c Config) Masked() Config {
+	c = c.Clone()
+	for _, name := range c.Secrets {
+		if _, ok := c.Parameters[name]; ok {
+			c.Parameters[name] = MaskedValue
+		}
+	}
+	return c
+}
+
+// Masked vraća kopiju grupe sa maskiranim tajnim parametrima ugrađenih i razrešenih konfiguracija
+func (g ConfigGroup) Masked() ConfigGroup {
+	g = g.Clone()
+	for i := range g.Configuration {
+		g.Configuration[i] = g.Configuration[i].Masked()
+	}
+	for i := range g.References {
+		if g.References[i].Config != nil {
+			masked := g.References[i].Config.Masked()
+			g.References[i].Config = &masked
+		}
+	}
+	return g
+}
+
+// MaskConfigs maskira tajne parametre u listi konfiguracija
+func MaskConfigs(configs []Config) []Config {
+	masked := make([]Config, len(configs))
+	for i, config := range configs {
+		masked[i] = config.Masked()
+	}
+	return masked
+}
+
+// MaskConfigGroups maskira tajne parametre u listi grupa
+func MaskConfigGroups(configGroups []ConfigGroup) []ConfigGroup {
+	masked := make([]ConfigGroup, len(configGroups))
+	for i, configGroup := range configGroups {
+		masked[i] = configGroup.Masked()
+	}
+	return masked
+}
+
+// Masked vraća razliku u kojoj su stare i nove vrednosti tajnih parametara maskirane;
+// vidi se samo da je tajni parametar dodat, uklonjen ili izmenjen
+func (d ConfigDiff) Masked() ConfigDiff {
+	d.Parameters = d.Parameters.masked(d.Secrets)
+	return d
+}
+
+// Masked vraća razliku grupa sa maskiranim tajnim parametrima
+func (d ConfigGroupDiff) Masked() ConfigGroupDiff {
+	d.Added = MaskConfigs(d.Added)
+	d.Removed = MaskConfigs(d.Removed)
+	changed := make([]ConfigDiff, len(d.Changed))
+	for i, configDiff := range d.Changed {
+		changed[i] = configDiff.Masked()
+	}
+	d.Changed = changed
+	return d
+}
+
+func (d MapDiff) masked(secrets []string) MapDiff {
+	masked := MapDiff{}
+	for key, value := range d.Added {
+		if containsString(secrets, key) {
+			value = MaskedValue
+		}
+		if masked.Added == nil {
+			masked.Added = make(map[string]interface{})
+		}
+		masked.Added[key] = value
+	}
+	for key, value := range d.Removed {
+		if containsString(secrets, key) {
+			value = MaskedValue
+		}
+		if masked.Removed == nil {
+			masked.Removed = make(map[string]interface{})
+		}
+		masked.Removed[key] = value
+	}
+	for key, change := range d.Modified {
+		if containsString(secrets, key) {
+			change = ValueChange{From: MaskedValue, To: MaskedValue}
+		}
+		if masked.Modified == nil {
+			masked.Modified = make(map[string]ValueChange)
+		}
+		masked.Modified[key] = change
+	}
+	return masked
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
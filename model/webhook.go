@@ -0,0 +1,98 @@
+package model
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// Vrste izmena na koje webhook može da se pretplati; iste su kao vrste događaja u /watch
+var WebhookEventTypes = []string{"created", "updated", "deleted"}
+
+// MinWebhookSecretLength je najkraći ključ za potpis koji klijent sme da zada
+const MinWebhookSecretLength = 16
+
+// Webhook je pretplata na izmene: za svaku izmenu zapisa čija putanja počinje sa Prefix
+// (npr. configGroups/configGroup/) server šalje potpisan POST na URL.
+type Webhook struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Secret je ključ HMAC potpisa isporuka. Klijentu se vraća samo pri kreiranju;
+	// u repozitorijumu se čuva šifrovan u EncryptedSecret.
+	Secret          string          `json:"secret,omitempty"`
+	EncryptedSecret *EncryptedValue `json:"encryptedSecret,omitempty"`
+	Prefix          string          `json:"prefix,omitempty"`
+	// Types ograničava pretplatu na vrste izmena; prazno znači sve
+	Types     []string  `json:"types,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DeadLetter je isporuka koja nije uspela ni posle svih pokušaja. Čuva tačan sadržaj zahteva,
+// pa ponovno slanje šalje isti događaj.
+type DeadLetter struct {
+	ID            string          `json:"id"`
+	WebhookID     string          `json:"webhookId"`
+	URL           string          `json:"url"`
+	EventRevision int64           `json:"eventRevision"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"lastError"`
+	FailedAt      time.Time       `json:"failedAt"`
+}
+
+type WebhookRepository interface {
+	Create(webhook Webhook) error
+	// Update zamenjuje postojeću pretplatu (koristi se pri rotaciji ključeva)
+	Update(webhook Webhook) error
+	Get(id string) (Webhook, error)
+	Delete(id string) error
+	GetAll() ([]Webhook, error)
+	AddDeadLetter(letter DeadLetter) error
+	GetDeadLetter(id string) (DeadLetter, error)
+	DeleteDeadLetter(id string) error
+	GetAllDeadLetters() ([]DeadLetter, error)
+}
+
+// Validate proverava pretplatu pre čuvanja
+func (w Webhook) Validate() error {
+	target, err := url.Parse(w.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return Invalidf("webhook url must be an absolute http or https URL, got %q", w.URL)
+	}
+	if w.Secret != "" && len(w.Secret) < MinWebhookSecretLength {
+		return Invalidf("webhook secret must have at least %d characters", MinWebhookSecretLength)
+	}
+	for _, eventType := range w.Types {
+		if !containsString(WebhookEventTypes, eventType) {
+			return Invalidf("unknown webhook event type %q (expected created, updated or deleted)", eventType)
+		}
+	}
+	return nil
+}
+
+// WithoutSecret vraća pretplatu bez ključa za potpis, za odgovore posle kreiranja
+func (w Webhook) WithoutSecret() Webhook {
+	w.Secret = ""
+	w.EncryptedSecret = nil
+	return w
+}
+
+// Clone vraća kopiju pretplate koja ne deli liste sa originalom
+func (w Webhook) Clone() Webhook {
+	if w.Types != nil {
+		w.Types = append([]string(nil), w.Types...)
+	}
+	if w.EncryptedSecret != nil {
+		encrypted := *w.EncryptedSecret
+		w.EncryptedSecret = &encrypted
+	}
+	return w
+}
+
+// Clone vraća kopiju isporuke koja ne deli sadržaj sa originalom
+func (d DeadLetter) Clone() DeadLetter {
+	if d.Payload != nil {
+		d.Payload = append(json.RawMessage(nil), d.Payload...)
+	}
+	return d
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"projekat/events"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// options su podešavanja servera; svaka opcija može da se zada flag-om ili promenljivom okruženja
+type options struct {
+	storage        string
+	storageOptions keyValueFlag
+	// Šta se dešava sa referencama grupa kada se obriše konfiguracija: restrict ili cascade
+	referencePolicy string
+	// Koliko dugo se pamte odgovori za Idempotency-Key
+	idempotencyTTL time.Duration
+	// Najveće telo zahteva koje se čita u memoriju (zahtevi sa Idempotency-Key i uvoz arhive)
+	// i najveća raspakovana arhiva koju uvoz prihvata
+	maxRequestBytes int
+	importMaxBytes  int
+	// Fajl sa glavnim ključevima za šifrovanje tajnih parametara
+	masterKeyFile string
+	// Token kojim klijent sme da otkrije tajne parametre (?reveal=true) dok autorizacija nije uključena;
+	// prazan isključuje otkrivanje
+	revealToken string
+	// Da li izmene postojećih konfiguracija i grupa moraju da pošalju If-Match
+	requireIfMatch bool
+	// Koliko poslednjih događaja se čuva za nastavak praćenja izmena (/watch?since=)
+	watchHistory int
+	// Isporuka webhook-ova: broj pokušaja, čekanje posle prvog neuspeha (udvostručava se do najviše
+	// webhookMaxBackoff) i najduže trajanje jednog zahteva
+	webhookAttempts   int
+	webhookBackoff    time.Duration
+	webhookMaxBackoff time.Duration
+	webhookTimeout    time.Duration
+	// Da li webhook sme da šalje na loopback, privatne, link-local i rezervisane adrese
+	webhookAllowPrivate bool
+	// Autentifikacija: fajl sa otiscima API ključeva, HMAC tajna i/ili JWKS fajl za JWT.
+	// Ako ništa nije zadato, server ne traži akreditive.
+	apiKeysFile   string
+	jwtHMACSecret string
+	jwksFile      string
+	jwtIssuer     string
+	jwtAudience   string
+	// RBAC politika (YAML fajl) i koliko često se proverava da li je fajl izmenjen.
+	// Bez politike svaki autentifikovan klijent sme sve.
+	authzPolicyFile     string
+	authzReloadInterval time.Duration
+}
+
+// keyValueFlag skuplja ponovljene "-flag ključ=vrednost" argumente
+type keyValueFlag map[string]string
+
+func (f keyValueFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for key, value := range f {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f keyValueFlag) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		if pair == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("expected key=value, got %q", pair)
+		}
+		f[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return nil
+}
+
+func parseOptions(args []string) (options, error) {
+	opts := options{storageOptions: keyValueFlag{}}
+
+	fs := flag.NewFlagSet("projekat", flag.ContinueOnError)
+	fs.StringVar(&opts.storage, "storage", envOr("STORAGE", "memory"), "storage backend: memory, file or consul (env STORAGE)")
+	fs.Var(opts.storageOptions, "storage-opt", "backend option as key=value, may be repeated (env STORAGE_OPTIONS=key=value,...)")
+
+	fs.StringVar(&opts.referencePolicy, "reference-policy", envOr("REFERENCE_POLICY", "restrict"), "deleting a config referenced by groups: restrict or cascade (env REFERENCE_POLICY)")
+
+	idempotencyTTL, err := durationEnv("IDEMPOTENCY_TTL", 24*time.Hour)
+	if err != nil {
+		return options{}, err
+	}
+	fs.DurationVar(&opts.idempotencyTTL, "idempotency-ttl", idempotencyTTL, "how long responses are kept for Idempotency-Key replays (env IDEMPOTENCY_TTL)")
+
+	maxRequestBytes, err := intEnv("MAX_REQUEST_BYTES", 32<<20)
+	if err != nil {
+		return options{}, err
+	}
+	fs.IntVar(&opts.maxRequestBytes, "max-request-bytes", maxRequestBytes, "largest request body read into memory, for Idempotency-Key requests and imports (env MAX_REQUEST_BYTES)")
+	importMaxBytes, err := intEnv("IMPORT_MAX_BYTES", 256<<20)
+	if err != nil {
+		return options{}, err
+	}
+	fs.IntVar(&opts.importMaxBytes, "import-max-bytes", importMaxBytes, "largest archive accepted by /import after decompression (env IMPORT_MAX_BYTES)")
+
+	fs.StringVar(&opts.masterKeyFile, "master-key-file", os.Getenv("MASTER_KEY_FILE"), "file with master keys for secret parameters, one id:base64-key per line, first is active (env MASTER_KEY_FILE)")
+	fs.StringVar(&opts.revealToken, "reveal-token", os.Getenv("REVEAL_TOKEN"), "token clients send in X-Reveal-Token to reveal secret parameters when authorization is disabled; empty disables revealing (env REVEAL_TOKEN)")
+
+	requireIfMatch, err := boolEnv("REQUIRE_IF_MATCH", false)
+	if err != nil {
+		return options{}, err
+	}
+	fs.BoolVar(&opts.requireIfMatch, "require-if-match", requireIfMatch, "reject changes to existing configs and groups without If-Match with 428 (env REQUIRE_IF_MATCH)")
+
+	watchHistory, err := intEnv("WATCH_HISTORY", events.DefaultHistory)
+	if err != nil {
+		return options{}, err
+	}
+	fs.IntVar(&opts.watchHistory, "watch-history", watchHistory, "how many recent change events are kept for resuming /watch (env WATCH_HISTORY)")
+
+	webhookAttempts, err := intEnv("WEBHOOK_ATTEMPTS", 5)
+	if err != nil {
+		return options{}, err
+	}
+	fs.IntVar(&opts.webhookAttempts, "webhook-attempts", webhookAttempts, "delivery attempts before a webhook delivery goes to the dead-letter list (env WEBHOOK_ATTEMPTS)")
+	webhookBackoff, err := durationEnv("WEBHOOK_BACKOFF", time.Second)
+	if err != nil {
+		return options{}, err
+	}
+	fs.DurationVar(&opts.webhookBackoff, "webhook-backoff", webhookBackoff, "wait after the first failed webhook delivery, doubled after each further failure (env WEBHOOK_BACKOFF)")
+	webhookMaxBackoff, err := durationEnv("WEBHOOK_MAX_BACKOFF", 5*time.Minute)
+	if err != nil {
+		return options{}, err
+	}
+	fs.DurationVar(&opts.webhookMaxBackoff, "webhook-max-backoff", webhookMaxBackoff, "longest wait between webhook delivery attempts (env WEBHOOK_MAX_BACKOFF)")
+	webhookTimeout, err := durationEnv("WEBHOOK_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return options{}, err
+	}
+	fs.DurationVar(&opts.webhookTimeout, "webhook-timeout", webhookTimeout, "timeout of a single webhook delivery request (env WEBHOOK_TIMEOUT)")
+	webhookAllowPrivate, err := boolEnv("WEBHOOK_ALLOW_PRIVATE", false)
+	if err != nil {
+		return options{}, err
+	}
+	fs.BoolVar(&opts.webhookAllowPrivate, "webhook-allow-private", webhookAllowPrivate, "allow webhooks to loopback, private, link-local and reserved addresses (env WEBHOOK_ALLOW_PRIVATE)")
+
+	fs.StringVar(&opts.apiKeysFile, "api-keys-file", os.Getenv("API_KEYS_FILE"), "file with API keys, one name:sha256-hex[:group,...] per line (env API_KEYS_FILE)")
+	fs.StringVar(&opts.jwtHMACSecret, "jwt-hmac-secret", os.Getenv("JWT_HMAC_SECRET"), "secret for verifying HS256/384/512 bearer tokens (env JWT_HMAC_SECRET)")
+	fs.StringVar(&opts.jwksFile, "jwks-file", os.Getenv("JWKS_FILE"), "local JWKS file with public keys for verifying RS/PS/ES bearer tokens (env JWKS_FILE)")
+	fs.StringVar(&opts.jwtIssuer, "jwt-issuer", os.Getenv("JWT_ISSUER"), "required iss claim of bearer tokens; empty accepts any (env JWT_ISSUER)")
+	fs.StringVar(&opts.jwtAudience, "jwt-audience", os.Getenv("JWT_AUDIENCE"), "required aud claim of bearer tokens; empty accepts any (env JWT_AUDIENCE)")
+
+	fs.StringVar(&opts.authzPolicyFile, "authz-policy-file", os.Getenv("AUTHZ_POLICY_FILE"), "YAML file with RBAC roles and bindings; empty disables authorization (env AUTHZ_POLICY_FILE)")
+	authzReloadInterval, err := durationEnv("AUTHZ_RELOAD_INTERVAL", 5*time.Second)
+	if err != nil {
+		return options{}, err
+	}
+	fs.DurationVar(&opts.authzReloadInterval, "authz-reload-interval", authzReloadInterval, "how often the RBAC policy file is checked for changes (env AUTHZ_RELOAD_INTERVAL)")
+
+	// Opcije iz okruženja se primenjuju prve, tako da ih flag-ovi mogu pregaziti
+	if env := os.Getenv("STORAGE_OPTIONS"); env != "" {
+		if err := opts.storageOptions.Set(env); err != nil {
+			return options{}, fmt.Errorf("STORAGE_OPTIONS: %w", err)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return options{}, err
+	}
+	return opts, nil
+}
+
+func envOr(name, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func durationEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return fallback, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return duration, nil
+}
+
+func boolEnv(name string, fallback bool) (bool, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", name, err)
+	}
+	return parsed, nil
+}
+
+func intEnv(name string, fallback int) (int, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,97 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"projekat/auth"
+	"sync"
+	"time"
+)
+
+// Engine primenjuje politiku iz fajla i ponovo je učitava kada se fajl promeni.
+// Neispravna nova politika se odbija i ostaje prethodna, pa greška u fajlu ne otvara pristup.
+type Engine struct {
+	path string
+
+	mu      sync.RWMutex
+	policy  *Policy
+	content []byte
+}
+
+// NewEngine učitava politiku; za razliku od kasnijih učitavanja, greška ovde prekida pokretanje
+func NewEngine(path string) (*Engine, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := ParsePolicy(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &Engine{path: path, policy: policy, content: content}, nil
+}
+
+// Decide odlučuje po trenutno važećoj politici
+func (e *Engine) Decide(principal auth.Principal, action Action, resource string) Decision {
+	e.mu.RLock()
+	policy := e.policy
+	e.mu.RUnlock()
+	return policy.Decide(principal, action, resource)
+}
+
+// Reload ponovo čita fajl i menja politiku ako se sadržaj promenio; vraća da li je promenjena
+func (e *Engine) Reload() (bool, error) {
+	content, err := os.ReadFile(e.path)
+	if err != nil {
+		return false, err
+	}
+
+	e.mu.RLock()
+	unchanged := bytes.Equal(content, e.content)
+	e.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	policy, err := ParsePolicy(content)
+	if err != nil {
+		return false, err
+	}
+	e.mu.Lock()
+	e.policy = policy
+	e.content = content
+	e.mu.Unlock()
+	return true, nil
+}
+
+// Watch proverava fajl na svakih interval dok ctx ne istekne
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Ista greška se prijavljuje jednom, a ne na svakoj proveri
+	lastErr := ""
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		changed, err := e.Reload()
+		if err != nil {
+			if err.Error() != lastErr {
+				log.Printf("rbac: keeping the previous policy: %s: %v", e.path, err)
+				lastErr = err.Error()
+			}
+			continue
+		}
+		lastErr = ""
+		if changed {
+			log.Printf("rbac: reloaded policy from %s", e.path)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"projekat/auth"
+	"testing"
+)
+
+func TestEngineReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(testPolicy)
+	engine, err := NewEngine(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci := auth.Principal{Subject: "ci"}
+	if !engine.Decide(ci, Read, "configs/db/1").Allowed {
+		t.Fatal("ci cannot read before the reload")
+	}
+
+	if changed, err := engine.Reload(); changed || err != nil {
+		t.Errorf("reloading an unchanged file: changed=%v, err=%v", changed, err)
+	}
+
+	// Nova politika uklanja pravo čitanja konfiguracija
+	write("roles:\n  reader:\n    rules:\n      - actions: [read]\n        resources: [\"configGroups/*\"]\nbindings:\n  - role: reader\n    subjects: [ci]\n")
+	if changed, err := engine.Reload(); !changed || err != nil {
+		t.Fatalf("reload: changed=%v, err=%v", changed, err)
+	}
+	if engine.Decide(ci, Read, "configs/db/1").Allowed {
+		t.Error("ci can still read configs after the grant was removed")
+	}
+	if !engine.Decide(ci, Read, "configGroups/app/1").Allowed {
+		t.Error("ci lost the remaining grant")
+	}
+
+	// Neispravan fajl ne menja važeću politiku
+	write("roles:\n  reader:\n    rules:\n      - actions: [write]\n        resources: [\"*\"]\n")
+	if changed, err := engine.Reload(); changed || err == nil {
+		t.Errorf("reloading an invalid policy: changed=%v, err=%v", changed, err)
+	}
+	if engine.Decide(ci, Read, "configs/db/1").Allowed || !engine.Decide(ci, Read, "configGroups/app/1").Allowed {
+		t.Error("invalid policy replaced the previous one")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Reload(); err == nil {
+		t.Error("reloading a missing file succeeded")
+	}
+	if !engine.Decide(ci, Read, "configGroups/app/1").Allowed {
+		t.Error("missing file replaced the previous policy")
+	}
+}
@@ -0,0 +1,243 @@
+// Package rbac odlučuje šta provereni klijent sme da radi. Politika ima uloge (skupove pravila
+// akcija nad šablonima resursa) i vezivanja uloga za klijente i grupe. Sve što nijedno pravilo
+// ne dozvoljava je zabranjeno.
+package rbac
+
+import (
+	"fmt"
+	"projekat/auth"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Action je operacija nad resursom
+type Action string
+
+const (
+	Read   Action = "read"
+	Create Action = "create"
+	Update Action = "update"
+	Delete Action = "delete"
+	// Reveal je čitanje vrednosti tajnih parametara (?reveal=true)
+	Reveal Action = "reveal"
+	// AllActions u pravilu obuhvata sve akcije
+	AllActions Action = "*"
+)
+
+// ParseAction proverava ime akcije iz upita ili politike
+func ParseAction(name string) (Action, error) {
+	switch action := Action(name); action {
+	case Read, Create, Update, Delete, Reveal:
+		return action, nil
+	}
+	return "", fmt.Errorf("unknown action %q (expected read, create, update, delete or reveal)", name)
+}
+
+// Rule dozvoljava akcije nad resursima čija putanja odgovara nekom od šablona.
+// U šablonu * zamenjuje bilo koji niz znakova, uključujući /.
+type Rule struct {
+	Actions   []Action `yaml:"actions" json:"actions"`
+	Resources []string `yaml:"resources" json:"resources"`
+}
+
+type Role struct {
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Rules       []Rule `yaml:"rules" json:"rules"`
+}
+
+// Binding dodeljuje ulogu klijentima po imenu (Subjects) i po grupi (Groups)
+type Binding struct {
+	Role     string   `yaml:"role" json:"role"`
+	Subjects []string `yaml:"subjects,omitempty" json:"subjects,omitempty"`
+	Groups   []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+}
+
+type Policy struct {
+	Roles    map[string]Role `yaml:"roles" json:"roles"`
+	Bindings []Binding       `yaml:"bindings" json:"bindings"`
+}
+
+// ParsePolicy čita politiku iz YAML-a (ili JSON-a, koji je njegov podskup) i proverava je
+func ParsePolicy(content []byte) (*Policy, error) {
+	var policy Policy
+	decoder := yaml.NewDecoder(strings.NewReader(string(content)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, err
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Validate proverava da su akcije poznate, šabloni zadati i da vezivanja upućuju na postojeće uloge
+func (p *Policy) Validate() error {
+	for name, role := range p.Roles {
+		for i, rule := range role.Rules {
+			if len(rule.Actions) == 0 || len(rule.Resources) == 0 {
+				return fmt.Errorf("role %s, rule %d: actions and resources are required", name, i+1)
+			}
+			for _, action := range rule.Actions {
+				if action == AllActions {
+					continue
+				}
+				if _, err := ParseAction(string(action)); err != nil {
+					return fmt.Errorf("role %s, rule %d: %w", name, i+1, err)
+				}
+			}
+			for _, pattern := range rule.Resources {
+				if strings.TrimSpace(pattern) == "" {
+					return fmt.Errorf("role %s, rule %d: empty resource pattern", name, i+1)
+				}
+			}
+		}
+	}
+	for i, binding := range p.Bindings {
+		if _, ok := p.Roles[binding.Role]; !ok {
+			return fmt.Errorf("binding %d: unknown role %q", i+1, binding.Role)
+		}
+		if len(binding.Subjects) == 0 && len(binding.Groups) == 0 {
+			return fmt.Errorf("binding %d (role %s): subjects or groups are required", i+1, binding.Role)
+		}
+	}
+	return nil
+}
+
+// Decision je odluka sa objašnjenjem, za odgovor na /authz/check i poruku uz 403
+type Decision struct {
+	Allowed  bool     `json:"allowed"`
+	Subject  string   `json:"subject"`
+	Groups   []string `json:"groups,omitempty"`
+	Action   Action   `json:"action"`
+	Resource string   `json:"resource"`
+	// Roles su sve uloge vezane za klijenta
+	Roles []string `json:"roles"`
+	// Role, Binding i Pattern opisuju pravilo koje je dozvolilo zahtev
+	Role    string `json:"role,omitempty"`
+	Binding string `json:"binding,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// Decide odlučuje da li klijent sme da izvrši akciju nad resursom. Resurs koji se završava sa *
+// označava sve resurse sa tim prefiksom (liste, praćenje, nove verzije) i dozvoljen je samo
+// ako ga jedno pravilo pokriva celog.
+func (p *Policy) Decide(principal auth.Principal, action Action, resource string) Decision {
+	decision := Decision{
+		Subject:  principal.Subject,
+		Groups:   principal.Groups,
+		Action:   action,
+		Resource: resource,
+		Roles:    []string{},
+	}
+
+	type boundRole struct {
+		name, binding string
+	}
+	var bound []boundRole
+	seen := make(map[string]bool)
+	for _, binding := range p.Bindings {
+		via, ok := bindingMatches(binding, principal)
+		if !ok {
+			continue
+		}
+		bound = append(bound, boundRole{name: binding.Role, binding: via})
+		if !seen[binding.Role] {
+			seen[binding.Role] = true
+			decision.Roles = append(decision.Roles, binding.Role)
+		}
+	}
+	sort.Strings(decision.Roles)
+
+	if len(bound) == 0 {
+		decision.Reason = fmt.Sprintf("no role is bound to subject %q or its groups", principal.Subject)
+		return decision
+	}
+
+	for _, role := range bound {
+		for _, rule := range p.Roles[role.name].Rules {
+			if !ruleHasAction(rule, action) {
+				continue
+			}
+			for _, pattern := range rule.Resources {
+				if covers(pattern, resource) {
+					decision.Allowed = true
+					decision.Role = role.name
+					decision.Binding = role.binding
+					decision.Pattern = pattern
+					decision.Reason = fmt.Sprintf("role %s (bound via %s) allows %s on %s", role.name, role.binding, action, pattern)
+					return decision
+				}
+			}
+		}
+	}
+	decision.Reason = fmt.Sprintf("none of the roles %s allows %s on %s", strings.Join(decision.Roles, ", "), action, resource)
+	return decision
+}
+
+// bindingMatches vraća opis po čemu se vezivanje odnosi na klijenta
+func bindingMatches(binding Binding, principal auth.Principal) (string, bool) {
+	for _, subject := range binding.Subjects {
+		if subject == principal.Subject {
+			return "subject " + subject, true
+		}
+	}
+	for _, group := range binding.Groups {
+		for _, principalGroup := range principal.Groups {
+			if group == principalGroup {
+				return "group " + group, true
+			}
+		}
+	}
+	return "", false
+}
+
+func ruleHasAction(rule Rule, action Action) bool {
+	for _, allowed := range rule.Actions {
+		if allowed == AllActions || allowed == action {
+			return true
+		}
+	}
+	return false
+}
+
+// covers proverava da li šablon obuhvata resurs. Za skup resursa ("prefiks*") šablon mora da bude
+// prefiks sa * na kraju, jer se samo tada sigurno zna da obuhvata svaki resurs iz skupa.
+func covers(pattern, resource string) bool {
+	if prefix, isSet := cutSuffix(resource, "*"); isSet {
+		patternPrefix, ok := cutSuffix(pattern, "*")
+		return ok && !strings.Contains(patternPrefix, "*") && strings.HasPrefix(prefix, patternPrefix)
+	}
+	return match(pattern, resource)
+}
+
+// match poredi putanju sa šablonom u kojem * zamenjuje bilo koji niz znakova
+func match(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(value, part)
+		if index < 0 {
+			return false
+		}
+		value = value[index+len(part):]
+	}
+	return strings.HasSuffix(value, last)
+}
+
+func cutSuffix(value, suffix string) (string, bool) {
+	if !strings.HasSuffix(value, suffix) {
+		return value, false
+	}
+	return value[:len(value)-len(suffix)], true
+}
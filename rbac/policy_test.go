@@ -0,0 +1,177 @@
+package rbac
+
+import (
+	"projekat/auth"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"configs/db/1", "configs/db/1", true},
+		{"configs/db/1", "configs/db/10", false},
+		{"configs/db/1", "configs/db", false},
+		{"*", "configs/db/1", true},
+		{"*", "", true},
+		// * prelazi preko /
+		{"configs/*", "configs/db/1", true},
+		{"configs/*", "configs/", true},
+		{"configs/*", "configs", false},
+		{"configs/*", "configGroups/app/1", false},
+		{"configs/payments*", "configs/payments_db/1", true},
+		{"configs/payments*", "configs/payments/1", true},
+		{"configs/payments*", "configs/pay/1", false},
+		{"configs/*/1", "configs/db/1", true},
+		{"configs/*/1", "configs/db/11", false},
+		{"configs/*/1", "configs/db/2/1", true},
+		{"*/db/*", "configs/db/1", true},
+		{"*/db/*", "namespaces/team/configs/db/1", true},
+		{"*/db/*", "configs/db", false},
+		{"configs/*_db/*", "configs/payments_db/3", true},
+		{"configs/*_db/*", "configs/payments_db", false},
+		{"configs/a*a", "configs/a", false},
+		{"configs/a*a", "configs/aa", true},
+		{"namespaces/*/configs/*", "namespaces/team/configs/db/1", true},
+		{"namespaces/*/configs/*", "configs/db/1", false},
+	}
+	for _, c := range cases {
+		if got := match(c.pattern, c.value); got != c.want {
+			t.Errorf("match(%q, %q) = %v, want %v", c.pattern, c.value, got, c.want)
+		}
+	}
+}
+
+func TestCovers(t *testing.T) {
+	cases := []struct {
+		pattern, resource string
+		want              bool
+	}{
+		// Jedan resurs se poredi kao u match
+		{"configs/*", "configs/db/1", true},
+		{"configs/db/*", "configs/db/1", true},
+		// Skup resursa pokriva samo šablon koji je njegov prefiks sa * na kraju
+		{"configs/*", "configs/*", true},
+		{"*", "configs/*", true},
+		{"configs/*", "configs/db/*", true},
+		{"configs/*", "configs/payments*", true},
+		{"configs/payments*", "configs/payments*", true},
+		{"configs/payments*", "configs/payments_db/*", true},
+		{"configs/payments*", "configs/*", false},
+		{"configs/payments*", "configs/pay*", false},
+		{"configs/db/*", "configs/*", false},
+		{"configs/db/*", "configs/db*", false},
+		{"configs/db/1", "configs/db/*", false},
+		// * u sredini šablona ne pokriva skup, iako bi match prihvatio samu putanju skupa
+		{"configs/*/1", "configs/*", false},
+		{"*/db/*", "configs/db/*", false},
+		{"configs/*", "namespaces/team/configs/*", false},
+		{"namespaces/team/*", "namespaces/team/configs/*", true},
+		{"namespaces/team/configs/*", "namespaces/team/*", false},
+		{"namespaces/team/configs/*", "namespaces/other/configs/*", false},
+	}
+	for _, c := range cases {
+		if got := covers(c.pattern, c.resource); got != c.want {
+			t.Errorf("covers(%q, %q) = %v, want %v", c.pattern, c.resource, got, c.want)
+		}
+	}
+}
+
+const testPolicy = `
+roles:
+  payments-writer:
+    rules:
+      - actions: ["*"]
+        resources: ["configs/payments*"]
+      - actions: [read]
+        resources: ["configGroups/configGroup/*"]
+  reader:
+    rules:
+      - actions: [read]
+        resources: ["configs/*", "configGroups/*"]
+  team:
+    rules:
+      - actions: [read, create]
+        resources: ["namespaces/team/*"]
+bindings:
+  - role: payments-writer
+    groups: [team-payments]
+  - role: reader
+    subjects: [ci]
+  - role: team
+    subjects: [alice]
+`
+
+func TestDecide(t *testing.T) {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci := auth.Principal{Subject: "ci"}
+	payments := auth.Principal{Subject: "bob", Groups: []string{"dev", "team-payments"}}
+	alice := auth.Principal{Subject: "alice"}
+
+	cases := []struct {
+		name      string
+		principal auth.Principal
+		action    Action
+		resource  string
+		allowed   bool
+		role      string
+	}{
+		{name: "no bindings", principal: auth.Principal{Subject: "mallory"}, action: Read, resource: "configs/db/1"},
+		{name: "group does not match subject", principal: auth.Principal{Subject: "team-payments"}, action: Read, resource: "configs/payments_db/1"},
+		{name: "reader reads", principal: ci, action: Read, resource: "configs/db/1", allowed: true, role: "reader"},
+		{name: "reader lists", principal: ci, action: Read, resource: "configs/*", allowed: true, role: "reader"},
+		{name: "reader cannot create", principal: ci, action: Create, resource: "configs/db/*"},
+		{name: "reader cannot reveal", principal: ci, action: Reveal, resource: "configs/db/1"},
+		{name: "reader outside the default namespace", principal: ci, action: Read, resource: "namespaces/team/configs/db/1"},
+		{name: "group writes by name prefix", principal: payments, action: Delete, resource: "configs/payments_db/2", allowed: true, role: "payments-writer"},
+		{name: "group creates versions by name prefix", principal: payments, action: Create, resource: "configs/payments_db/*", allowed: true, role: "payments-writer"},
+		{name: "group lists by name prefix", principal: payments, action: Read, resource: "configs/payments_*", allowed: true, role: "payments-writer"},
+		{name: "group cannot list all configs", principal: payments, action: Read, resource: "configs/*"},
+		{name: "group outside the name prefix", principal: payments, action: Update, resource: "configs/db/1"},
+		{name: "group reads one group", principal: payments, action: Read, resource: "configGroups/configGroup/3", allowed: true, role: "payments-writer"},
+		{name: "group cannot update the group", principal: payments, action: Update, resource: "configGroups/configGroup/3"},
+		{name: "namespace role", principal: alice, action: Create, resource: "namespaces/team/configs/db/*", allowed: true, role: "team"},
+		{name: "namespace role lists", principal: alice, action: Read, resource: "namespaces/team/configGroups/*", allowed: true, role: "team"},
+		{name: "namespace role in another namespace", principal: alice, action: Read, resource: "namespaces/other/configs/db/1"},
+		{name: "namespace role in the default namespace", principal: alice, action: Read, resource: "configs/db/1"},
+		{name: "namespace role action mismatch", principal: alice, action: Delete, resource: "namespaces/team/configs/db/1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			decision := policy.Decide(c.principal, c.action, c.resource)
+			if decision.Allowed != c.allowed || decision.Role != c.role {
+				t.Errorf("got allowed=%v role=%q (%s), want allowed=%v role=%q", decision.Allowed, decision.Role, decision.Reason, c.allowed, c.role)
+			}
+			if decision.Reason == "" {
+				t.Error("decision has no reason")
+			}
+		})
+	}
+
+	decision := policy.Decide(payments, Delete, "configs/payments_db/2")
+	if decision.Binding != "group team-payments" || decision.Pattern != "configs/payments*" {
+		t.Errorf("decision is explained by binding %q and pattern %q", decision.Binding, decision.Pattern)
+	}
+}
+
+func TestParsePolicyRejectsInvalidPolicies(t *testing.T) {
+	cases := map[string]string{
+		"unknown action":      "roles:\n  r:\n    rules:\n      - actions: [write]\n        resources: ['*']\n",
+		"no resources":        "roles:\n  r:\n    rules:\n      - actions: [read]\n",
+		"empty resource":      "roles:\n  r:\n    rules:\n      - actions: [read]\n        resources: [' ']\n",
+		"unknown role":        "roles: {}\nbindings:\n  - role: r\n    subjects: [ci]\n",
+		"binding without who": "roles:\n  r:\n    rules:\n      - actions: [read]\n        resources: ['*']\nbindings:\n  - role: r\n",
+		"unknown field":       "roles:\n  r:\n    rule: []\n",
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParsePolicy([]byte(content)); err == nil {
+				t.Error("policy was accepted")
+			}
+		})
+	}
+}
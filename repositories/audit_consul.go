@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"projekat/model"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Prefiks pod kojim se u Consul KV čuva audit log
+const auditPrefix = "audit/"
+
+// auditKey dopunjuje redni broj nulama, pa je redosled ključeva isti kao redosled zapisa
+func auditKey(sequence int64) string {
+	return fmt.Sprintf("%s%020d", auditPrefix, sequence)
+}
+
+type AuditConsulRepository struct {
+	kv *api.KV
+}
+
+func NewAuditConsulRepository(client *api.Client) model.AuditRepository {
+	return &AuditConsulRepository{
+		kv: client.KV(),
+	}
+}
+
+func (repo *AuditConsulRepository) Append(entry model.AuditEntry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// ModifyIndex 0 upisuje ključ samo ako još ne postoji, pa dve instance ne mogu da upišu isti redni broj
+	ok, _, err := repo.kv.CAS(&api.KVPair{Key: auditKey(entry.Sequence), Value: value, ModifyIndex: 0}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return model.AlreadyExistsf("audit entry %d already exists", entry.Sequence)
+	}
+	return nil
+}
+
+func (repo *AuditConsulRepository) Last() (model.AuditEntry, error) {
+	keys, _, err := repo.kv.Keys(auditPrefix, "", nil)
+	if err != nil {
+		return model.AuditEntry{}, err
+	}
+	last := ""
+	for _, key := range keys {
+		if !strings.HasSuffix(key, "/") && key > last {
+			last = key
+		}
+	}
+	if last == "" {
+		return model.AuditEntry{}, model.NotFoundf("audit log is empty")
+	}
+
+	pair, _, err := repo.kv.Get(last, nil)
+	if err != nil {
+		return model.AuditEntry{}, err
+	}
+	if pair == nil {
+		return model.AuditEntry{}, model.NotFoundf("audit log is empty")
+	}
+	var entry model.AuditEntry
+	if err := json.Unmarshal(pair.Value, &entry); err != nil {
+		return model.AuditEntry{}, fmt.Errorf("cannot decode %s: %w", pair.Key, err)
+	}
+	return entry, nil
+}
+
+func (repo *AuditConsulRepository) GetAll() ([]model.AuditEntry, error) {
+	pairs, _, err := repo.kv.List(auditPrefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]model.AuditEntry, 0, len(pairs))
+	for _, pair := range pairs {
+		if strings.HasSuffix(pair.Key, "/") {
+			continue
+		}
+		var entry model.AuditEntry
+		if err := json.Unmarshal(pair.Value, &entry); err != nil {
+			return nil, fmt.Errorf("cannot decode %s: %w", pair.Key, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
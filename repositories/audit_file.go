@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"projekat/model"
+)
+
+// AuditFileRepository čuva audit log u fileStore-u na lokalnom disku
+type AuditFileRepository struct {
+	store *fileStore
+}
+
+func NewAuditFileRepository(store *fileStore) model.AuditRepository {
+	return &AuditFileRepository{
+		store: store,
+	}
+}
+
+func (repo *AuditFileRepository) Append(entry model.AuditEntry) error {
+	key := auditKey(entry.Sequence)
+	return repo.store.update(func(tx *fileTx) error {
+		if _, exists := tx.get(key); exists {
+			return model.AlreadyExistsf("audit entry %d already exists", entry.Sequence)
+		}
+		return tx.put(key, entry)
+	})
+}
+
+func (repo *AuditFileRepository) Last() (model.AuditEntry, error) {
+	values := repo.store.list(auditPrefix)
+	if len(values) == 0 {
+		return model.AuditEntry{}, model.NotFoundf("audit log is empty")
+	}
+	var entry model.AuditEntry
+	if err := json.Unmarshal(values[len(values)-1], &entry); err != nil {
+		return model.AuditEntry{}, fmt.Errorf("cannot decode audit entry: %w", err)
+	}
+	return entry, nil
+}
+
+func (repo *AuditFileRepository) GetAll() ([]model.AuditEntry, error) {
+	values := repo.store.list(auditPrefix)
+	entries := make([]model.AuditEntry, 0, len(values))
+	for _, value := range values {
+		var entry model.AuditEntry
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return nil, fmt.Errorf("cannot decode audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
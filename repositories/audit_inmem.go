@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"projekat/model"
+	"sync"
+)
+
+// AuditInMemRepository je bezbedan za istovremeno korišćenje iz više gorutina
+type AuditInMemRepository struct {
+	mu      sync.RWMutex
+	entries []model.AuditEntry
+}
+
+func NewAuditInMemRepository() model.AuditRepository {
+	return &AuditInMemRepository{
+		entries: make([]model.AuditEntry, 0),
+	}
+}
+
+func (repo *AuditInMemRepository) Append(entry model.AuditEntry) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if n := len(repo.entries); n > 0 && repo.entries[n-1].Sequence >= entry.Sequence {
+		return model.AlreadyExistsf("audit entry %d already exists", entry.Sequence)
+	}
+	repo.entries = append(repo.entries, entry)
+	return nil
+}
+
+func (repo *AuditInMemRepository) Last() (model.AuditEntry, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if len(repo.entries) == 0 {
+		return model.AuditEntry{}, model.NotFoundf("audit log is empty")
+	}
+	return repo.entries[len(repo.entries)-1], nil
+}
+
+func (repo *AuditInMemRepository) GetAll() ([]model.AuditEntry, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	entries := make([]model.AuditEntry, len(repo.entries))
+	copy(entries, repo.entries)
+	return entries, nil
+}
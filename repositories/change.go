@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"encoding/json"
+	"projekat/model"
+)
+
+// storedChange pravi model.Change za zapise koje backend čuva kao vrednosti, kodirane isto kao
+// u file i Consul backend-u. Nil before ili after znači da zapis nije postojao, odnosno da je obrisan.
+func storedChange(before, after interface{}, revision int64) model.Change {
+	return model.Change{
+		Before:   encodeStored(before),
+		After:    encodeStored(after),
+		Revision: revision,
+	}
+}
+
+func encodeStored(value interface{}) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
@@ -1,33 +1,351 @@
 package repositories
 
-/*
 import (
-	"errors"
+	"encoding/json"
+	"fmt"
+	"log"
 	"projekat/model"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
 )
 
+// Prefiks pod kojim se u Consul KV čuvaju konfiguracije
+const configsPrefix = "configs/"
+
 type ConfigConsulRepository struct {
+	kv        *api.KV
+	namespace string
+	// prefix je prefiks ključeva prostora imena; za podrazumevani je prazan
+	prefix string
 }
 
-//dodaj implementaciju metoda iz interfejsa ConfigRepo
+// NewConsulClient kreira Consul klijenta za zadatu adresu (npr. "localhost:8500").
+// Prazna adresa znači podrazumevanu vrednost iz CONSUL_HTTP_ADDR ili localhost:8500.
+func NewConsulClient(address string) (*api.Client, error) {
+	config := api.DefaultConfig()
+	if address != "" {
+		config.Address = address
+	}
+	return api.NewClient(config)
+}
 
-func NewConfigConsulRepository() model.ConfigRepository {
-	return ConfigConsulRepository{}
+func NewConfigConsulRepository(client *api.Client) model.ConfigRepository {
+	return &ConfigConsulRepository{
+		kv:        client.KV(),
+		namespace: model.DefaultNamespace,
+	}
 }
 
-func (repo *ConfigConsulRepository) Create(config model.Config) error {
-	return errors.New("not implemented")
+func (repo *ConfigConsulRepository) In(namespace string) model.ConfigRepository {
+	return &ConfigConsulRepository{
+		kv:        repo.kv,
+		namespace: namespace,
+		prefix:    model.NamespacePrefix(namespace),
+	}
 }
 
-func (repo *ConfigConsulRepository) ReadByName(name string) (model.Config, error) {
-	return model.Config{}, errors.New("not implemented")
+func (repo *ConfigConsulRepository) Create(config model.Config) (model.Change, error) {
+	config.Namespace = repo.namespace
+	value, err := json.Marshal(config)
+	if err != nil {
+		return model.Change{}, err
+	}
+
+	key := repo.prefix + configsPrefix + configKey(config.Name, config.Version)
+	counterKey := repo.prefix + configVersionsPrefix + config.Name
+	for attempt := 0; attempt < consulCASRetries; attempt++ {
+		pair, _, err := repo.kv.Get(key, nil)
+		if err != nil {
+			return model.Change{}, err
+		}
+		if pair != nil {
+			return model.Change{}, model.AlreadyExistsf("config %s/%d already exists", config.Name, config.Version)
+		}
+
+		highest, counterIndex, err := consulHighestVersion(repo.kv, counterKey, repo.prefix+configsPrefix+config.Name+"/")
+		if err != nil {
+			return model.Change{}, err
+		}
+		if err := checkVersion("config", config.Name, highest, config.Version); err != nil {
+			return model.Change{}, err
+		}
+
+		ok, err := consulCreateVersioned(repo.kv, counterKey, counterIndex, config.Version, key, value)
+		if err != nil {
+			return model.Change{}, err
+		}
+		if ok {
+			return model.Change{After: value, Revision: config.Revision}, nil
+		}
+	}
+	return model.Change{}, model.Conflictf("config %s was modified concurrently too many times", config.Name)
+}
+
+func (repo *ConfigConsulRepository) CreateNextVersion(config model.Config) (model.Config, model.Change, error) {
+	config.Namespace = repo.namespace
+	counterKey := repo.prefix + configVersionsPrefix + config.Name
+	for attempt := 0; attempt < consulCASRetries; attempt++ {
+		highest, counterIndex, err := consulHighestVersion(repo.kv, counterKey, repo.prefix+configsPrefix+config.Name+"/")
+		if err != nil {
+			return model.Config{}, model.Change{}, err
+		}
+
+		config.Version = highest + 1
+		value, err := json.Marshal(config)
+		if err != nil {
+			return model.Config{}, model.Change{}, err
+		}
+
+		ok, err := consulCreateVersioned(repo.kv, counterKey, counterIndex, config.Version, repo.prefix+configsPrefix+configKey(config.Name, config.Version), value)
+		if err != nil {
+			return model.Config{}, model.Change{}, err
+		}
+		if ok {
+			return config, model.Change{After: value, Revision: config.Revision}, nil
+		}
+	}
+	return model.Config{}, model.Change{}, model.Conflictf("config %s was modified concurrently too many times", config.Name)
+}
+
+func (repo *ConfigConsulRepository) Read(name string, version int) (model.Config, error) {
+	return repo.Get(name, version)
+}
+
+func (repo *ConfigConsulRepository) Update(config model.Config) (model.Change, error) {
+	config.Namespace = repo.namespace
+	key := repo.prefix + configsPrefix + configKey(config.Name, config.Version)
+	pair, _, err := repo.kv.Get(key, nil)
+	if err != nil {
+		return model.Change{}, err
+	}
+	if pair == nil {
+		return model.Change{}, model.NotFoundf("config not found")
+	}
+	var stored model.Config
+	if err := json.Unmarshal(pair.Value, &stored); err != nil {
+		return model.Change{}, fmt.Errorf("cannot decode config %s: %w", pair.Key, err)
+	}
+	if err := model.CheckRevision("config", config.Name, config.Version, stored.Revision, config.Revision); err != nil {
+		return model.Change{}, err
+	}
+
+	config.Revision++
+	value, err := json.Marshal(config)
+	if err != nil {
+		return model.Change{}, err
+	}
+
+	// Upis uspeva samo ako ključ u međuvremenu nije izmenjen ili obrisan
+	ok, _, err := repo.kv.CAS(&api.KVPair{Key: key, Value: value, ModifyIndex: pair.ModifyIndex}, nil)
+	if err != nil {
+		return model.Change{}, err
+	}
+	if !ok {
+		return model.Change{}, model.PreconditionFailedf("config %s/%d was modified concurrently", config.Name, config.Version)
+	}
+	return model.Change{Before: pair.Value, After: value, Revision: config.Revision}, nil
+}
+
+func (repo *ConfigConsulRepository) Delete(name string, version int, expectedRevision int64) (model.Change, error) {
+	return consulDeleteIf(repo.kv, repo.prefix+configsPrefix+configKey(name, version), "config", name, version, expectedRevision)
+}
+
+func (repo *ConfigConsulRepository) Add(config model.Config) (model.Change, error) {
+	config.Namespace = repo.namespace
+	value, err := json.Marshal(config)
+	if err != nil {
+		return model.Change{}, err
+	}
+
+	before, err := consulReplace(repo.kv, repo.prefix+configsPrefix+configKey(config.Name, config.Version), value)
+	if err != nil {
+		return model.Change{}, err
+	}
+	consulRaiseVersion(repo.kv, repo.prefix+configVersionsPrefix+config.Name, repo.prefix+configsPrefix+config.Name+"/", config.Version)
+	return model.Change{Before: before, After: value, Revision: config.Revision}, nil
+}
+
+func (repo *ConfigConsulRepository) Get(name string, version int) (model.Config, error) {
+	pair, _, err := repo.kv.Get(repo.prefix+configsPrefix+configKey(name, version), nil)
+	if err != nil {
+		return model.Config{}, err
+	}
+	if pair == nil {
+		return model.Config{}, model.NotFoundf("config not found")
+	}
+
+	var config model.Config
+	if err := json.Unmarshal(pair.Value, &config); err != nil {
+		return model.Config{}, fmt.Errorf("cannot decode config %s: %w", pair.Key, err)
+	}
+	config.Namespace = repo.namespace
+	return config, nil
+}
+
+// GetAll vraća sve konfiguracije
+func (repo *ConfigConsulRepository) GetAll() ([]model.Config, error) {
+	return repo.list(repo.prefix + configsPrefix)
+}
+
+// List čita iz Consul-a samo ključeve ispod prefiksa imena iz upita
+func (repo *ConfigConsulRepository) List(query model.ListQuery) (model.ConfigPage, error) {
+	if err := query.Validate(); err != nil {
+		return model.ConfigPage{}, err
+	}
+	configs, err := repo.list(repo.prefix + configsPrefix + query.NamePrefix)
+	if err != nil {
+		return model.ConfigPage{}, err
+	}
+	return model.PageConfigs(configs, query)
+}
+
+// ListByName čita iz Consul-a samo ključeve ispod prefiksa imena
+func (repo *ConfigConsulRepository) ListByName(name string) ([]model.Config, error) {
+	configs, err := repo.list(repo.prefix + configsPrefix + name + "/")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Version < configs[j].Version })
+	return configs, nil
+}
+
+func (repo *ConfigConsulRepository) list(prefix string) ([]model.Config, error) {
+	pairs, _, err := repo.kv.List(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make([]model.Config, 0, len(pairs))
+	for _, pair := range pairs {
+		// Preskačemo "direktorijume" koje Consul UI ume da napravi
+		if strings.HasSuffix(pair.Key, "/") {
+			continue
+		}
+		var config model.Config
+		if err := json.Unmarshal(pair.Value, &config); err != nil {
+			return nil, fmt.Errorf("cannot decode config %s: %w", pair.Key, err)
+		}
+		config.Namespace = repo.namespace
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// consulHighestVersion vraća najveću korišćenu verziju za ime i ModifyIndex brojača (0 ako brojač
+// još ne postoji). Postojeći ključevi se uzimaju u obzir zbog podataka upisanih pre uvođenja brojača.
+func consulHighestVersion(kv *api.KV, counterKey, itemsPrefix string) (int, uint64, error) {
+	keys, _, err := kv.Keys(itemsPrefix, "", nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	highest := highestVersion(keys, itemsPrefix)
+
+	pair, _, err := kv.Get(counterKey, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	if pair == nil {
+		return highest, 0, nil
+	}
+	counter, err := strconv.Atoi(string(pair.Value))
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot decode version counter %s: %w", counterKey, err)
+	}
+	if counter > highest {
+		highest = counter
+	}
+	return highest, pair.ModifyIndex, nil
+}
+
+// consulCreateVersioned u jednoj Consul transakciji pomera brojač verzija i upisuje novi ključ.
+// Vraća false ako je brojač u međuvremenu izmenjen ili ključ već postoji.
+func consulCreateVersioned(kv *api.KV, counterKey string, counterIndex uint64, version int, key string, value []byte) (bool, error) {
+	ops := api.KVTxnOps{
+		&api.KVTxnOp{Verb: api.KVCAS, Key: counterKey, Value: []byte(strconv.Itoa(version)), Index: counterIndex},
+		&api.KVTxnOp{Verb: api.KVCAS, Key: key, Value: value, Index: 0},
+	}
+	ok, _, _, err := kv.Txn(ops, nil)
+	return ok, err
+}
+
+// consulDeleteIf briše zapis ako je sačuvan u reviziji expectedRevision (0 briše bez uslova).
+// Brisanje ide preko DeleteCAS sa ModifyIndex-om pročitanog zapisa, pa izmena između provere
+// i brisanja ne može da bude obrisana neprimećeno; tada se provera ponavlja.
+func consulDeleteIf(kv *api.KV, key, kind, name string, version int, expectedRevision int64) (model.Change, error) {
+	for attempt := 0; attempt < consulCASRetries; attempt++ {
+		pair, _, err := kv.Get(key, nil)
+		if err != nil {
+			return model.Change{}, err
+		}
+		if pair == nil {
+			return model.Change{}, model.NotFoundf("%s not found", kind)
+		}
+		var stored struct {
+			Revision int64 `json:"revision"`
+		}
+		if err := json.Unmarshal(pair.Value, &stored); err != nil {
+			return model.Change{}, fmt.Errorf("cannot decode %s %s: %w", kind, pair.Key, err)
+		}
+		if err := checkDeleteRevision(kind, name, version, stored.Revision, expectedRevision); err != nil {
+			return model.Change{}, err
+		}
+
+		ok, _, err := kv.DeleteCAS(&api.KVPair{Key: key, ModifyIndex: pair.ModifyIndex}, nil)
+		if err != nil {
+			return model.Change{}, err
+		}
+		if ok {
+			return model.Change{Before: pair.Value}, nil
+		}
+	}
+	return model.Change{}, model.Conflictf("%s %s/%d was modified concurrently too many times", kind, name, version)
 }
 
-func (repo *ConfigConsulRepository) Update(config model.Config) error {
-	return errors.New("not implemented")
+// consulReplace upisuje vrednost ključa bez obzira na to da li postoji i vraća vrednost koju je
+// zamenila (nil za novi ključ). Upis ide preko CAS-a, pa vraćena vrednost je baš ona koja je zamenjena.
+func consulReplace(kv *api.KV, key string, value []byte) (json.RawMessage, error) {
+	for attempt := 0; attempt < consulCASRetries; attempt++ {
+		pair, _, err := kv.Get(key, nil)
+		if err != nil {
+			return nil, err
+		}
+		var before json.RawMessage
+		var index uint64
+		if pair != nil {
+			before, index = pair.Value, pair.ModifyIndex
+		}
+		ok, _, err := kv.CAS(&api.KVPair{Key: key, Value: value, ModifyIndex: index}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return before, nil
+		}
+	}
+	return nil, model.Conflictf("%s was modified concurrently too many times", key)
 }
 
-func (repo *ConfigConsulRepository) DeleteByName(name string) error {
-	return errors.New("not implemented")
+// consulRaiseVersion podiže brojač verzija na version ako je manji
+func consulRaiseVersion(kv *api.KV, counterKey, itemsPrefix string, version int) {
+	for attempt := 0; attempt < consulCASRetries; attempt++ {
+		highest, counterIndex, err := consulHighestVersion(kv, counterKey, itemsPrefix)
+		if err != nil {
+			log.Printf("consul: cannot read version counter %s: %v", counterKey, err)
+			return
+		}
+		if version < highest {
+			return
+		}
+		ok, _, err := kv.CAS(&api.KVPair{Key: counterKey, Value: []byte(strconv.Itoa(version)), ModifyIndex: counterIndex}, nil)
+		if err != nil {
+			log.Printf("consul: cannot store version counter %s: %v", counterKey, err)
+			return
+		}
+		if ok {
+			return
+		}
+	}
 }
-*/
@@ -0,0 +1,271 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"projekat/model"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// writeConfig upisuje konfiguraciju i brojač verzija u lažni Consul kao da ih je upisala druga instanca servera
+func writeConfig(t *testing.T, fake *fakeConsul, name string, version int) {
+	t.Helper()
+	value, err := json.Marshal(testConfig(name, version))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.write(configsPrefix+configKey(name, version), value)
+	fake.write(configVersionsPrefix+name, []byte(fmt.Sprint(version)))
+}
+
+func configVersions(configs []model.Config) []string {
+	versions := make([]string, 0, len(configs))
+	for _, config := range configs {
+		versions = append(versions, configKey(config.Name, config.Version))
+	}
+	return versions
+}
+
+func TestConfigConsulRepositoryCreateConflict(t *testing.T) {
+	client, fake := newFakeConsul(t)
+	repo := NewConfigConsulRepository(client)
+
+	if _, err := repo.Create(testConfig("app", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Create(testConfig("app", 1)); !errors.Is(err, model.ErrAlreadyExists) {
+		t.Errorf("creating an existing version: got %v, want ErrAlreadyExists", err)
+	}
+	if _, err := repo.Create(testConfig("app", 3)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Create(testConfig("app", 2)); !errors.Is(err, model.ErrConflict) {
+		t.Errorf("creating a version below the latest: got %v, want ErrConflict", err)
+	}
+	// Obrisana verzija se ne dodeljuje ponovo
+	if _, err := repo.Delete("app", 3, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Create(testConfig("app", 3)); !errors.Is(err, model.ErrConflict) {
+		t.Errorf("creating a deleted version: got %v, want ErrConflict", err)
+	}
+
+	// Druga instanca upiše istu verziju između provere i transakcije: transakcija ne uspe,
+	// a ponovna provera nalazi upisanu konfiguraciju
+	var once sync.Once
+	fake.beforeWrite = func() {
+		once.Do(func() { writeConfig(t, fake, "race", 1) })
+	}
+	if _, err := repo.Create(testConfig("race", 1)); !errors.Is(err, model.ErrAlreadyExists) {
+		t.Errorf("creating a version written concurrently: got %v, want ErrAlreadyExists", err)
+	}
+	fake.beforeWrite = nil
+
+	want := []string{"configVersions/app", "configVersions/race", "configs/app/1", "configs/race/1"}
+	if got := fake.stored(); !reflect.DeepEqual(got, want) {
+		t.Errorf("stored keys %v, want %v", got, want)
+	}
+}
+
+func TestConfigConsulRepositoryCreateNextVersionUnderContention(t *testing.T) {
+	client, fake := newFakeConsul(t)
+	repo := NewConfigConsulRepository(client)
+
+	// Svaki poziv gubi najviše jednom od svakog drugog, pa manje poziva od consulCASRetries uvek uspe
+	const calls = consulCASRetries - 1
+	parallel(calls, func(int) {
+		if _, _, err := repo.CreateNextVersion(testConfig("app", 0)); err != nil {
+			t.Error(err)
+		}
+	})
+	configs, err := repo.ListByName("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != calls {
+		t.Fatalf("got %d versions after %d parallel calls: %v", len(configs), calls, configVersions(configs))
+	}
+	for i, config := range configs {
+		if config.Version != i+1 {
+			t.Fatalf("versions %v are not consecutive", configVersions(configs))
+		}
+	}
+
+	// Druga instanca dodeli sledeću verziju između čitanja brojača i transakcije
+	var once sync.Once
+	fake.beforeWrite = func() {
+		once.Do(func() { writeConfig(t, fake, "app", calls+1) })
+	}
+	config, _, err := repo.CreateNextVersion(testConfig("app", 0))
+	fake.beforeWrite = nil
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Version != calls+2 {
+		t.Errorf("got version %d, want %d after a concurrent create", config.Version, calls+2)
+	}
+	if _, err := repo.Get("app", calls+1); err != nil {
+		t.Errorf("concurrently created version was lost: %v", err)
+	}
+}
+
+func TestConfigConsulRepositoryListByName(t *testing.T) {
+	client, fake := newFakeConsul(t)
+	repo := NewConfigConsulRepository(client)
+
+	for _, config := range []model.Config{testConfig("app", 1), testConfig("app", 2), testConfig("app", 10), testConfig("app_other", 1), testConfig("ap", 1)} {
+		if _, err := repo.Create(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// "Direktorijum" koji ume da napravi Consul UI
+	fake.write(configsPrefix+"app/", nil)
+
+	configs, err := repo.ListByName("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := configVersions(configs), []string{"app/1", "app/2", "app/10"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, config := range configs {
+		if config.Namespace != model.DefaultNamespace {
+			t.Errorf("config %s/%d has namespace %q", config.Name, config.Version, config.Namespace)
+		}
+	}
+
+	configs, err = repo.ListByName("missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 0 {
+		t.Errorf("got %v for a missing name", configVersions(configs))
+	}
+}
+
+func TestConfigConsulRepositoryListPaging(t *testing.T) {
+	client, _ := newFakeConsul(t)
+	repo := NewConfigConsulRepository(client)
+	for _, config := range []model.Config{testConfig("db", 1), testConfig("db", 2), testConfig("api", 1), testConfig("cache", 1), testConfig("db_replica", 1), testConfig("web", 1)} {
+		if _, err := repo.Create(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	query := model.ListQuery{Sort: model.SortByName, Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("paging does not end")
+		}
+		page, err := repo.List(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Items) > query.Limit {
+			t.Fatalf("page has %d items, limit is %d", len(page.Items), query.Limit)
+		}
+		got = append(got, configVersions(page.Items)...)
+		if page.NextCursor == "" {
+			break
+		}
+		if pages == 0 {
+			// Zapis dodat iza kursora se vidi na sledećim stranama, a zapis ispred kursora ne
+			if _, err := repo.Create(testConfig("aaa", 1)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repo.Create(testConfig("queue", 1)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		query.Cursor = page.NextCursor
+	}
+	want := []string{"api/1", "cache/1", "db/1", "db/2", "db_replica/1", "queue/1", "web/1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pages contain %v, want %v", got, want)
+	}
+
+	page, err := repo.List(model.ListQuery{NamePrefix: "db", Sort: model.SortByName, Descending: true, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := configVersions(page.Items), []string{"db_replica/1", "db/2", "db/1"}; !reflect.DeepEqual(got, want) || page.NextCursor != "" {
+		t.Errorf("namePrefix page is %v with cursor %q, want %v and no cursor", got, page.NextCursor, want)
+	}
+
+	if _, err := repo.List(model.ListQuery{Limit: model.MaxPageLimit + 1}); !errors.Is(err, model.ErrInvalid) {
+		t.Errorf("limit above the maximum: got %v, want ErrInvalid", err)
+	}
+}
+
+func TestConfigConsulRepositoryNamespaceIsolation(t *testing.T) {
+	client, fake := newFakeConsul(t)
+	repo := NewConfigConsulRepository(client)
+	team := repo.In("team")
+
+	for _, r := range []model.ConfigRepository{repo, team} {
+		config, _, err := r.CreateNextVersion(testConfig("app", 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Version != 1 {
+			t.Errorf("first version in a namespace is %d, want 1", config.Version)
+		}
+	}
+	if _, err := team.Create(testConfig("team_only", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"configVersions/app", "configs/app/1",
+		"namespaces/team/configVersions/app", "namespaces/team/configVersions/team_only",
+		"namespaces/team/configs/app/1", "namespaces/team/configs/team_only/1",
+	}
+	if got := fake.stored(); !reflect.DeepEqual(got, want) {
+		t.Errorf("stored keys %v, want %v", got, want)
+	}
+
+	if _, err := repo.Get("team_only", 1); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("default namespace sees a config from team: %v", err)
+	}
+	config, err := team.Get("team_only", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Namespace != "team" {
+		t.Errorf("config from team has namespace %q", config.Namespace)
+	}
+
+	all, err := repo.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := configVersions(all); !reflect.DeepEqual(got, []string{"app/1"}) {
+		t.Errorf("GetAll in the default namespace returned %v", got)
+	}
+	page, err := team.List(model.ListQuery{Sort: model.SortByName, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := configVersions(page.Items); !reflect.DeepEqual(got, []string{"app/1", "team_only/1"}) {
+		t.Errorf("List in team returned %v", got)
+	}
+
+	// Brisanje u jednom prostoru imena ne dira isto ime u drugom
+	if _, err := team.Delete("app", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get("app", 1); err != nil {
+		t.Errorf("deleting app/1 in team removed it from the default namespace: %v", err)
+	}
+	versions, err := team.ListByName("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 0 {
+		t.Errorf("team still has %v", configVersions(versions))
+	}
+}
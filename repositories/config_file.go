@@ -0,0 +1,227 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"projekat/model"
+	"sort"
+)
+
+// ConfigFileRepository čuva konfiguracije u fileStore-u na lokalnom disku
+type ConfigFileRepository struct {
+	store     *fileStore
+	namespace string
+	// prefix je prefiks ključeva prostora imena; za podrazumevani je prazan
+	prefix string
+}
+
+func NewConfigFileRepository(store *fileStore) model.ConfigRepository {
+	return &ConfigFileRepository{
+		store:     store,
+		namespace: model.DefaultNamespace,
+	}
+}
+
+func (repo *ConfigFileRepository) In(namespace string) model.ConfigRepository {
+	return &ConfigFileRepository{
+		store:     repo.store,
+		namespace: namespace,
+		prefix:    model.NamespacePrefix(namespace),
+	}
+}
+
+func (repo *ConfigFileRepository) Create(config model.Config) (model.Change, error) {
+	config.Namespace = repo.namespace
+	key := repo.prefix + configsPrefix + configKey(config.Name, config.Version)
+	var change model.Change
+	err := repo.store.update(func(tx *fileTx) error {
+		if _, exists := tx.get(key); exists {
+			return model.AlreadyExistsf("config %s/%d already exists", config.Name, config.Version)
+		}
+		highest, err := tx.highestVersion(repo.prefix+configVersionsPrefix+config.Name, repo.prefix+configsPrefix+config.Name+"/")
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("config", config.Name, highest, config.Version); err != nil {
+			return err
+		}
+		if err := tx.put(repo.prefix+configVersionsPrefix+config.Name, config.Version); err != nil {
+			return err
+		}
+		if err := tx.put(key, config); err != nil {
+			return err
+		}
+		change = tx.change(key, config.Revision)
+		return nil
+	})
+	return change, err
+}
+
+func (repo *ConfigFileRepository) CreateNextVersion(config model.Config) (model.Config, model.Change, error) {
+	config.Namespace = repo.namespace
+	var change model.Change
+	err := repo.store.update(func(tx *fileTx) error {
+		highest, err := tx.highestVersion(repo.prefix+configVersionsPrefix+config.Name, repo.prefix+configsPrefix+config.Name+"/")
+		if err != nil {
+			return err
+		}
+		config.Version = highest + 1
+		if err := tx.put(repo.prefix+configVersionsPrefix+config.Name, config.Version); err != nil {
+			return err
+		}
+		key := repo.prefix + configsPrefix + configKey(config.Name, config.Version)
+		if err := tx.put(key, config); err != nil {
+			return err
+		}
+		change = tx.change(key, config.Revision)
+		return nil
+	})
+	if err != nil {
+		return model.Config{}, model.Change{}, err
+	}
+	return config, change, nil
+}
+
+func (repo *ConfigFileRepository) Read(name string, version int) (model.Config, error) {
+	return repo.Get(name, version)
+}
+
+func (repo *ConfigFileRepository) Update(config model.Config) (model.Change, error) {
+	config.Namespace = repo.namespace
+	key := repo.prefix + configsPrefix + configKey(config.Name, config.Version)
+	var change model.Change
+	err := repo.store.update(func(tx *fileTx) error {
+		value, exists := tx.get(key)
+		if !exists {
+			return model.NotFoundf("config not found")
+		}
+		stored, err := decodeConfig(value)
+		if err != nil {
+			return err
+		}
+		if err := model.CheckRevision("config", config.Name, config.Version, stored.Revision, config.Revision); err != nil {
+			return err
+		}
+		config.Revision++
+		if err := tx.put(key, config); err != nil {
+			return err
+		}
+		change = tx.change(key, config.Revision)
+		return nil
+	})
+	return change, err
+}
+
+func (repo *ConfigFileRepository) Delete(name string, version int, expectedRevision int64) (model.Change, error) {
+	key := repo.prefix + configsPrefix + configKey(name, version)
+	var change model.Change
+	err := repo.store.update(func(tx *fileTx) error {
+		value, exists := tx.get(key)
+		if !exists {
+			return model.NotFoundf("config not found")
+		}
+		config, err := decodeConfig(value)
+		if err != nil {
+			return err
+		}
+		if err := checkDeleteRevision("config", name, version, config.Revision, expectedRevision); err != nil {
+			return err
+		}
+		tx.delete(key)
+		change = tx.change(key, 0)
+		return nil
+	})
+	return change, err
+}
+
+func (repo *ConfigFileRepository) Add(config model.Config) (model.Change, error) {
+	config.Namespace = repo.namespace
+	key := repo.prefix + configsPrefix + configKey(config.Name, config.Version)
+	var change model.Change
+	err := repo.store.update(func(tx *fileTx) error {
+		highest, err := tx.highestVersion(repo.prefix+configVersionsPrefix+config.Name, repo.prefix+configsPrefix+config.Name+"/")
+		if err != nil {
+			return err
+		}
+		if config.Version > highest {
+			if err := tx.put(repo.prefix+configVersionsPrefix+config.Name, config.Version); err != nil {
+				return err
+			}
+		}
+		if err := tx.put(key, config); err != nil {
+			return err
+		}
+		change = tx.change(key, config.Revision)
+		return nil
+	})
+	return change, err
+}
+
+func (repo *ConfigFileRepository) Get(name string, version int) (model.Config, error) {
+	value, ok := repo.store.get(repo.prefix + configsPrefix + configKey(name, version))
+	if !ok {
+		return model.Config{}, model.NotFoundf("config not found")
+	}
+	return repo.decode(value)
+}
+
+// GetAll vraća sve konfiguracije
+func (repo *ConfigFileRepository) GetAll() ([]model.Config, error) {
+	values := repo.store.list(repo.prefix + configsPrefix)
+	configs := make([]model.Config, 0, len(values))
+	for _, value := range values {
+		config, err := repo.decode(value)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// List čita samo ključeve ispod prefiksa imena iz upita
+func (repo *ConfigFileRepository) List(query model.ListQuery) (model.ConfigPage, error) {
+	if err := query.Validate(); err != nil {
+		return model.ConfigPage{}, err
+	}
+	values := repo.store.list(repo.prefix + configsPrefix + query.NamePrefix)
+	configs := make([]model.Config, 0, len(values))
+	for _, value := range values {
+		config, err := repo.decode(value)
+		if err != nil {
+			return model.ConfigPage{}, err
+		}
+		configs = append(configs, config)
+	}
+	return model.PageConfigs(configs, query)
+}
+
+// ListByName čita samo ključeve ispod prefiksa imena
+func (repo *ConfigFileRepository) ListByName(name string) ([]model.Config, error) {
+	values := repo.store.list(repo.prefix + configsPrefix + name + "/")
+	configs := make([]model.Config, 0, len(values))
+	for _, value := range values {
+		config, err := repo.decode(value)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Version < configs[j].Version })
+	return configs, nil
+}
+
+// decode čita sačuvanu konfiguraciju; zapisi upisani pre uvođenja prostora imena ga nemaju
+func (repo *ConfigFileRepository) decode(value json.RawMessage) (model.Config, error) {
+	config, err := decodeConfig(value)
+	config.Namespace = repo.namespace
+	return config, err
+}
+
+func decodeConfig(value json.RawMessage) (model.Config, error) {
+	var config model.Config
+	if err := json.Unmarshal(value, &config); err != nil {
+		return model.Config{}, fmt.Errorf("cannot decode config: %w", err)
+	}
+	return config, nil
+}
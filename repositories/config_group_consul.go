@@ -0,0 +1,271 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"projekat/model"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Prefiks pod kojim se u Consul KV čuvaju grupe konfiguracija
+const configGroupsPrefix = "configGroups/"
+
+// Koliko puta pokušavamo CAS upis pre nego što odustanemo
+const consulCASRetries = 16
+
+type ConfigGroupConsulRepository struct {
+	kv        *api.KV
+	namespace string
+	// prefix je prefiks ključeva prostora imena; za podrazumevani je prazan
+	prefix string
+}
+
+func NewConfigGroupConsulRepository(client *api.Client) model.ConfigGroupRepository {
+	return &ConfigGroupConsulRepository{
+		kv:        client.KV(),
+		namespace: model.DefaultNamespace,
+	}
+}
+
+func (repo *ConfigGroupConsulRepository) In(namespace string) model.ConfigGroupRepository {
+	return &ConfigGroupConsulRepository{
+		kv:        repo.kv,
+		namespace: namespace,
+		prefix:    model.NamespacePrefix(namespace),
+	}
+}
+
+func (repo *ConfigGroupConsulRepository) Create(configGroup model.ConfigGroup) (model.Change, error) {
+	configGroup = configGroup.InNamespace(repo.namespace)
+	value, err := json.Marshal(configGroup)
+	if err != nil {
+		return model.Change{}, err
+	}
+
+	key := repo.prefix + configGroupsPrefix + configGroupKey(configGroup.Name, configGroup.Version)
+	counterKey := repo.prefix + configGroupVersionsPrefix + configGroup.Name
+	for attempt := 0; attempt < consulCASRetries; attempt++ {
+		pair, _, err := repo.kv.Get(key, nil)
+		if err != nil {
+			return model.Change{}, err
+		}
+		if pair != nil {
+			return model.Change{}, model.AlreadyExistsf("config group %s/%d already exists", configGroup.Name, configGroup.Version)
+		}
+
+		highest, counterIndex, err := consulHighestVersion(repo.kv, counterKey, repo.prefix+configGroupsPrefix+configGroup.Name+"/")
+		if err != nil {
+			return model.Change{}, err
+		}
+		if err := checkVersion("config group", configGroup.Name, highest, configGroup.Version); err != nil {
+			return model.Change{}, err
+		}
+
+		ok, err := consulCreateVersioned(repo.kv, counterKey, counterIndex, configGroup.Version, key, value)
+		if err != nil {
+			return model.Change{}, err
+		}
+		if ok {
+			return model.Change{After: value, Revision: configGroup.Revision}, nil
+		}
+	}
+	return model.Change{}, model.Conflictf("config group %s was modified concurrently too many times", configGroup.Name)
+}
+
+func (repo *ConfigGroupConsulRepository) CreateNextVersion(configGroup model.ConfigGroup) (model.ConfigGroup, model.Change, error) {
+	configGroup = configGroup.InNamespace(repo.namespace)
+	counterKey := repo.prefix + configGroupVersionsPrefix + configGroup.Name
+	for attempt := 0; attempt < consulCASRetries; attempt++ {
+		highest, counterIndex, err := consulHighestVersion(repo.kv, counterKey, repo.prefix+configGroupsPrefix+configGroup.Name+"/")
+		if err != nil {
+			return model.ConfigGroup{}, model.Change{}, err
+		}
+
+		configGroup.Version = highest + 1
+		value, err := json.Marshal(configGroup)
+		if err != nil {
+			return model.ConfigGroup{}, model.Change{}, err
+		}
+
+		ok, err := consulCreateVersioned(repo.kv, counterKey, counterIndex, configGroup.Version, repo.prefix+configGroupsPrefix+configGroupKey(configGroup.Name, configGroup.Version), value)
+		if err != nil {
+			return model.ConfigGroup{}, model.Change{}, err
+		}
+		if ok {
+			return configGroup, model.Change{After: value, Revision: configGroup.Revision}, nil
+		}
+	}
+	return model.ConfigGroup{}, model.Change{}, model.Conflictf("config group %s was modified concurrently too many times", configGroup.Name)
+}
+
+func (repo *ConfigGroupConsulRepository) Read(name string, version int) (model.ConfigGroup, error) {
+	return repo.Get(name, version)
+}
+
+func (repo *ConfigGroupConsulRepository) Update(configGroup model.ConfigGroup) (model.Change, error) {
+	_, change, err := repo.mutate(configGroup.Name, configGroup.Version, func(stored *model.ConfigGroup) error {
+		if err := model.CheckRevision("config group", stored.Name, stored.Version, stored.Revision, configGroup.Revision); err != nil {
+			return err
+		}
+		*stored = configGroup
+		return nil
+	})
+	return change, err
+}
+
+func (repo *ConfigGroupConsulRepository) Delete(name string, version int, expectedRevision int64) (model.Change, error) {
+	return consulDeleteIf(repo.kv, repo.prefix+configGroupsPrefix+configGroupKey(name, version), "config group", name, version, expectedRevision)
+}
+
+// GetAll vraća sve grupe konfiguracija
+func (repo *ConfigGroupConsulRepository) GetAll() ([]model.ConfigGroup, error) {
+	return repo.list(repo.prefix + configGroupsPrefix)
+}
+
+// List čita iz Consul-a samo ključeve ispod prefiksa imena iz upita
+func (repo *ConfigGroupConsulRepository) List(query model.ListQuery) (model.ConfigGroupPage, error) {
+	if err := query.Validate(); err != nil {
+		return model.ConfigGroupPage{}, err
+	}
+	configGroups, err := repo.list(repo.prefix + configGroupsPrefix + query.NamePrefix)
+	if err != nil {
+		return model.ConfigGroupPage{}, err
+	}
+	return model.PageConfigGroups(configGroups, query)
+}
+
+// ListByName čita iz Consul-a samo ključeve ispod prefiksa imena
+func (repo *ConfigGroupConsulRepository) ListByName(name string) ([]model.ConfigGroup, error) {
+	configGroups, err := repo.list(repo.prefix + configGroupsPrefix + name + "/")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(configGroups, func(i, j int) bool { return configGroups[i].Version < configGroups[j].Version })
+	return configGroups, nil
+}
+
+func (repo *ConfigGroupConsulRepository) list(prefix string) ([]model.ConfigGroup, error) {
+	pairs, _, err := repo.kv.List(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	configGroups := make([]model.ConfigGroup, 0, len(pairs))
+	for _, pair := range pairs {
+		if strings.HasSuffix(pair.Key, "/") {
+			continue
+		}
+		var configGroup model.ConfigGroup
+		if err := json.Unmarshal(pair.Value, &configGroup); err != nil {
+			return nil, fmt.Errorf("cannot decode config group %s: %w", pair.Key, err)
+		}
+		configGroups = append(configGroups, configGroup.InNamespace(repo.namespace))
+	}
+	return configGroups, nil
+}
+
+func (repo *ConfigGroupConsulRepository) Add(configGroup model.ConfigGroup) (model.Change, error) {
+	configGroup = configGroup.InNamespace(repo.namespace)
+	value, err := json.Marshal(configGroup)
+	if err != nil {
+		return model.Change{}, err
+	}
+
+	before, err := consulReplace(repo.kv, repo.prefix+configGroupsPrefix+configGroupKey(configGroup.Name, configGroup.Version), value)
+	if err != nil {
+		return model.Change{}, err
+	}
+	consulRaiseVersion(repo.kv, repo.prefix+configGroupVersionsPrefix+configGroup.Name, repo.prefix+configGroupsPrefix+configGroup.Name+"/", configGroup.Version)
+	return model.Change{Before: before, After: value, Revision: configGroup.Revision}, nil
+}
+
+func (repo *ConfigGroupConsulRepository) Get(name string, version int) (model.ConfigGroup, error) {
+	configGroup, _, err := repo.get(name, version)
+	return configGroup, err
+}
+
+func (repo *ConfigGroupConsulRepository) RemoveConfig(groupName string, groupVersion int, configName string, configVersion int) (model.Change, error) {
+	_, change, err := repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		return configGroup.RemoveConfig(configName, configVersion)
+	})
+	return change, err
+}
+
+func (repo *ConfigGroupConsulRepository) AddConfig(groupName string, groupVersion int, config model.Config) (model.ConfigGroup, model.Change, error) {
+	return repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		return configGroup.AddConfig(config)
+	})
+}
+
+// get vraća grupu zajedno sa sačuvanim parom, čiji ModifyIndex služi za CAS upis
+func (repo *ConfigGroupConsulRepository) get(name string, version int) (model.ConfigGroup, *api.KVPair, error) {
+	pair, _, err := repo.kv.Get(repo.prefix+configGroupsPrefix+configGroupKey(name, version), nil)
+	if err != nil {
+		return model.ConfigGroup{}, nil, err
+	}
+	if pair == nil {
+		return model.ConfigGroup{}, nil, model.NotFoundf("config group not found")
+	}
+
+	var configGroup model.ConfigGroup
+	if err := json.Unmarshal(pair.Value, &configGroup); err != nil {
+		return model.ConfigGroup{}, nil, fmt.Errorf("cannot decode config group %s: %w", pair.Key, err)
+	}
+	return configGroup.InNamespace(repo.namespace), pair, nil
+}
+
+func (repo *ConfigGroupConsulRepository) AddReference(groupName string, groupVersion int, reference model.ConfigReference) (model.Change, error) {
+	_, change, err := repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		return configGroup.AddReference(reference)
+	})
+	return change, err
+}
+
+func (repo *ConfigGroupConsulRepository) RemoveReference(groupName string, groupVersion int, configName string, configVersion int) (model.Change, error) {
+	_, change, err := repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		return configGroup.RemoveReference(configName, configVersion)
+	})
+	return change, err
+}
+
+func (repo *ConfigGroupConsulRepository) RemoveConfigsByLabels(groupName string, groupVersion int, selector map[string]string) ([]model.Config, model.Change, error) {
+	var removed []model.Config
+	_, change, err := repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		removed = configGroup.RemoveConfigsByLabels(selector)
+		return nil
+	})
+	return removed, change, err
+}
+
+// mutate atomski menja grupu: čita je, primenjuje change i upisuje CAS-om,
+// ponavljajući postupak ako je neko drugi u međuvremenu izmenio isti ključ.
+func (repo *ConfigGroupConsulRepository) mutate(name string, version int, change func(*model.ConfigGroup) error) (model.ConfigGroup, model.Change, error) {
+	key := repo.prefix + configGroupsPrefix + configGroupKey(name, version)
+	for attempt := 0; attempt < consulCASRetries; attempt++ {
+		configGroup, pair, err := repo.get(name, version)
+		if err != nil {
+			return model.ConfigGroup{}, model.Change{}, err
+		}
+		if err := change(&configGroup); err != nil {
+			return model.ConfigGroup{}, model.Change{}, err
+		}
+		configGroup = configGroup.InNamespace(repo.namespace)
+		configGroup.Revision++
+
+		value, err := json.Marshal(configGroup)
+		if err != nil {
+			return model.ConfigGroup{}, model.Change{}, err
+		}
+		ok, _, err := repo.kv.CAS(&api.KVPair{Key: key, Value: value, ModifyIndex: pair.ModifyIndex}, nil)
+		if err != nil {
+			return model.ConfigGroup{}, model.Change{}, err
+		}
+		if ok {
+			return configGroup, model.Change{Before: pair.Value, After: value, Revision: configGroup.Revision}, nil
+		}
+	}
+	return model.ConfigGroup{}, model.Change{}, model.Conflictf("config group %s was modified concurrently too many times", key)
+}
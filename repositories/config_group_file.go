@@ -0,0 +1,279 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"projekat/model"
+	"sort"
+)
+
+// ConfigGroupFileRepository čuva grupe konfiguracija u fileStore-u na lokalnom disku
+type ConfigGroupFileRepository struct {
+	store     *fileStore
+	namespace string
+	// prefix je prefiks ključeva prostora imena; za podrazumevani je prazan
+	prefix string
+}
+
+func NewConfigGroupFileRepository(store *fileStore) model.ConfigGroupRepository {
+	return &ConfigGroupFileRepository{
+		store:     store,
+		namespace: model.DefaultNamespace,
+	}
+}
+
+func (repo *ConfigGroupFileRepository) In(namespace string) model.ConfigGroupRepository {
+	return &ConfigGroupFileRepository{
+		store:     repo.store,
+		namespace: namespace,
+		prefix:    model.NamespacePrefix(namespace),
+	}
+}
+
+func (repo *ConfigGroupFileRepository) Create(configGroup model.ConfigGroup) (model.Change, error) {
+	configGroup = configGroup.InNamespace(repo.namespace)
+	key := repo.prefix + configGroupsPrefix + configGroupKey(configGroup.Name, configGroup.Version)
+	var change model.Change
+	err := repo.store.update(func(tx *fileTx) error {
+		if _, exists := tx.get(key); exists {
+			return model.AlreadyExistsf("config group %s/%d already exists", configGroup.Name, configGroup.Version)
+		}
+		highest, err := tx.highestVersion(repo.prefix+configGroupVersionsPrefix+configGroup.Name, repo.prefix+configGroupsPrefix+configGroup.Name+"/")
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("config group", configGroup.Name, highest, configGroup.Version); err != nil {
+			return err
+		}
+		if err := tx.put(repo.prefix+configGroupVersionsPrefix+configGroup.Name, configGroup.Version); err != nil {
+			return err
+		}
+		if err := tx.put(key, configGroup); err != nil {
+			return err
+		}
+		change = tx.change(key, configGroup.Revision)
+		return nil
+	})
+	return change, err
+}
+
+func (repo *ConfigGroupFileRepository) CreateNextVersion(configGroup model.ConfigGroup) (model.ConfigGroup, model.Change, error) {
+	configGroup = configGroup.InNamespace(repo.namespace)
+	var change model.Change
+	err := repo.store.update(func(tx *fileTx) error {
+		highest, err := tx.highestVersion(repo.prefix+configGroupVersionsPrefix+configGroup.Name, repo.prefix+configGroupsPrefix+configGroup.Name+"/")
+		if err != nil {
+			return err
+		}
+		configGroup.Version = highest + 1
+		if err := tx.put(repo.prefix+configGroupVersionsPrefix+configGroup.Name, configGroup.Version); err != nil {
+			return err
+		}
+		key := repo.prefix + configGroupsPrefix + configGroupKey(configGroup.Name, configGroup.Version)
+		if err := tx.put(key, configGroup); err != nil {
+			return err
+		}
+		change = tx.change(key, configGroup.Revision)
+		return nil
+	})
+	if err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	return configGroup, change, nil
+}
+
+func (repo *ConfigGroupFileRepository) Read(name string, version int) (model.ConfigGroup, error) {
+	return repo.Get(name, version)
+}
+
+func (repo *ConfigGroupFileRepository) Update(configGroup model.ConfigGroup) (model.Change, error) {
+	_, change, err := repo.mutate(configGroup.Name, configGroup.Version, func(stored *model.ConfigGroup) error {
+		if err := model.CheckRevision("config group", stored.Name, stored.Version, stored.Revision, configGroup.Revision); err != nil {
+			return err
+		}
+		*stored = configGroup
+		return nil
+	})
+	return change, err
+}
+
+func (repo *ConfigGroupFileRepository) Delete(name string, version int, expectedRevision int64) (model.Change, error) {
+	key := repo.prefix + configGroupsPrefix + configGroupKey(name, version)
+	var change model.Change
+	err := repo.store.update(func(tx *fileTx) error {
+		value, exists := tx.get(key)
+		if !exists {
+			return model.NotFoundf("config group not found")
+		}
+		configGroup, err := decodeConfigGroup(value)
+		if err != nil {
+			return err
+		}
+		if err := checkDeleteRevision("config group", name, version, configGroup.Revision, expectedRevision); err != nil {
+			return err
+		}
+		tx.delete(key)
+		change = tx.change(key, 0)
+		return nil
+	})
+	return change, err
+}
+
+// GetAll vraća sve grupe konfiguracija
+func (repo *ConfigGroupFileRepository) GetAll() ([]model.ConfigGroup, error) {
+	values := repo.store.list(repo.prefix + configGroupsPrefix)
+	configGroups := make([]model.ConfigGroup, 0, len(values))
+	for _, value := range values {
+		configGroup, err := repo.decode(value)
+		if err != nil {
+			return nil, err
+		}
+		configGroups = append(configGroups, configGroup)
+	}
+	return configGroups, nil
+}
+
+// List čita samo ključeve ispod prefiksa imena iz upita
+func (repo *ConfigGroupFileRepository) List(query model.ListQuery) (model.ConfigGroupPage, error) {
+	if err := query.Validate(); err != nil {
+		return model.ConfigGroupPage{}, err
+	}
+	values := repo.store.list(repo.prefix + configGroupsPrefix + query.NamePrefix)
+	configGroups := make([]model.ConfigGroup, 0, len(values))
+	for _, value := range values {
+		configGroup, err := repo.decode(value)
+		if err != nil {
+			return model.ConfigGroupPage{}, err
+		}
+		configGroups = append(configGroups, configGroup)
+	}
+	return model.PageConfigGroups(configGroups, query)
+}
+
+// ListByName čita samo ključeve ispod prefiksa imena
+func (repo *ConfigGroupFileRepository) ListByName(name string) ([]model.ConfigGroup, error) {
+	values := repo.store.list(repo.prefix + configGroupsPrefix + name + "/")
+	configGroups := make([]model.ConfigGroup, 0, len(values))
+	for _, value := range values {
+		configGroup, err := repo.decode(value)
+		if err != nil {
+			return nil, err
+		}
+		configGroups = append(configGroups, configGroup)
+	}
+	sort.Slice(configGroups, func(i, j int) bool { return configGroups[i].Version < configGroups[j].Version })
+	return configGroups, nil
+}
+
+func (repo *ConfigGroupFileRepository) Add(configGroup model.ConfigGroup) (model.Change, error) {
+	configGroup = configGroup.InNamespace(repo.namespace)
+	key := repo.prefix + configGroupsPrefix + configGroupKey(configGroup.Name, configGroup.Version)
+	var change model.Change
+	err := repo.store.update(func(tx *fileTx) error {
+		highest, err := tx.highestVersion(repo.prefix+configGroupVersionsPrefix+configGroup.Name, repo.prefix+configGroupsPrefix+configGroup.Name+"/")
+		if err != nil {
+			return err
+		}
+		if configGroup.Version > highest {
+			if err := tx.put(repo.prefix+configGroupVersionsPrefix+configGroup.Name, configGroup.Version); err != nil {
+				return err
+			}
+		}
+		if err := tx.put(key, configGroup); err != nil {
+			return err
+		}
+		change = tx.change(key, configGroup.Revision)
+		return nil
+	})
+	return change, err
+}
+
+func (repo *ConfigGroupFileRepository) Get(name string, version int) (model.ConfigGroup, error) {
+	value, ok := repo.store.get(repo.prefix + configGroupsPrefix + configGroupKey(name, version))
+	if !ok {
+		return model.ConfigGroup{}, model.NotFoundf("config group not found")
+	}
+	return repo.decode(value)
+}
+
+func (repo *ConfigGroupFileRepository) RemoveConfig(groupName string, groupVersion int, configName string, configVersion int) (model.Change, error) {
+	_, change, err := repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		return configGroup.RemoveConfig(configName, configVersion)
+	})
+	return change, err
+}
+
+func (repo *ConfigGroupFileRepository) AddConfig(groupName string, groupVersion int, config model.Config) (model.ConfigGroup, model.Change, error) {
+	return repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		return configGroup.AddConfig(config)
+	})
+}
+
+func (repo *ConfigGroupFileRepository) AddReference(groupName string, groupVersion int, reference model.ConfigReference) (model.Change, error) {
+	_, change, err := repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		return configGroup.AddReference(reference)
+	})
+	return change, err
+}
+
+func (repo *ConfigGroupFileRepository) RemoveReference(groupName string, groupVersion int, configName string, configVersion int) (model.Change, error) {
+	_, change, err := repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		return configGroup.RemoveReference(configName, configVersion)
+	})
+	return change, err
+}
+
+func (repo *ConfigGroupFileRepository) RemoveConfigsByLabels(groupName string, groupVersion int, selector map[string]string) ([]model.Config, model.Change, error) {
+	var removed []model.Config
+	_, change, err := repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		removed = configGroup.RemoveConfigsByLabels(selector)
+		return nil
+	})
+	return removed, change, err
+}
+
+// mutate atomski čita grupu, primenjuje change i upisuje rezultat u log
+func (repo *ConfigGroupFileRepository) mutate(name string, version int, change func(*model.ConfigGroup) error) (model.ConfigGroup, model.Change, error) {
+	key := repo.prefix + configGroupsPrefix + configGroupKey(name, version)
+	var updated model.ConfigGroup
+	var stored model.Change
+	err := repo.store.update(func(tx *fileTx) error {
+		value, exists := tx.get(key)
+		if !exists {
+			return model.NotFoundf("config group not found")
+		}
+		configGroup, err := repo.decode(value)
+		if err != nil {
+			return err
+		}
+		if err := change(&configGroup); err != nil {
+			return err
+		}
+		configGroup = configGroup.InNamespace(repo.namespace)
+		configGroup.Revision++
+		updated = configGroup
+		if err := tx.put(key, configGroup); err != nil {
+			return err
+		}
+		stored = tx.change(key, configGroup.Revision)
+		return nil
+	})
+	if err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	return updated, stored, nil
+}
+
+// decode čita sačuvanu grupu; zapisi upisani pre uvođenja prostora imena ga nemaju
+func (repo *ConfigGroupFileRepository) decode(value json.RawMessage) (model.ConfigGroup, error) {
+	configGroup, err := decodeConfigGroup(value)
+	return configGroup.InNamespace(repo.namespace), err
+}
+
+func decodeConfigGroup(value json.RawMessage) (model.ConfigGroup, error) {
+	var configGroup model.ConfigGroup
+	if err := json.Unmarshal(value, &configGroup); err != nil {
+		return model.ConfigGroup{}, fmt.Errorf("cannot decode config group: %w", err)
+	}
+	return configGroup, nil
+}
@@ -1,75 +1,172 @@
 package repositories
 
 import (
-	"errors"
 	"fmt"
 	"projekat/model"
+	"sort"
+	"sync"
 )
 
+// ConfigGroupInMemRepository je bezbedan za istovremeno korišćenje iz više gorutina. Repozitorijumi
+// različitih prostora imena dele mape i bravu; ključevi imaju prefiks prostora imena.
 type ConfigGroupInMemRepository struct {
+	mu           *sync.RWMutex
+	namespace    string
 	configGroups map[string]model.ConfigGroup
+	// Najveća ikad korišćena verzija za svako ime
+	versions map[string]int
 }
 
 func NewConfigGroupInMemRepository() model.ConfigGroupRepository {
 	return &ConfigGroupInMemRepository{
+		mu:           &sync.RWMutex{},
+		namespace:    model.DefaultNamespace,
 		configGroups: make(map[string]model.ConfigGroup),
+		versions:     make(map[string]int),
 	}
 }
 
-func (repo *ConfigGroupInMemRepository) Create(configGroup model.ConfigGroup) error {
-	key := configGroupKey(configGroup.Name, configGroup.Version)
+func (repo *ConfigGroupInMemRepository) In(namespace string) model.ConfigGroupRepository {
+	return &ConfigGroupInMemRepository{
+		mu:           repo.mu,
+		namespace:    namespace,
+		configGroups: repo.configGroups,
+		versions:     repo.versions,
+	}
+}
+
+func (repo *ConfigGroupInMemRepository) key(name string, version int) string {
+	return model.NamespacePrefix(repo.namespace) + configGroupKey(name, version)
+}
+
+func (repo *ConfigGroupInMemRepository) versionKey(name string) string {
+	return model.NamespacePrefix(repo.namespace) + name
+}
+
+func (repo *ConfigGroupInMemRepository) Create(configGroup model.ConfigGroup) (model.Change, error) {
+	configGroup = configGroup.InNamespace(repo.namespace)
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := repo.key(configGroup.Name, configGroup.Version)
 	if _, exists := repo.configGroups[key]; exists {
-		return errors.New("config group with this name already exists")
+		return model.Change{}, model.AlreadyExistsf("config group %s/%d already exists", configGroup.Name, configGroup.Version)
+	}
+	if err := checkVersion("config group", configGroup.Name, repo.versions[repo.versionKey(configGroup.Name)], configGroup.Version); err != nil {
+		return model.Change{}, err
 	}
 
-	repo.configGroups[key] = configGroup
-	return nil
+	repo.configGroups[key] = configGroup.Clone()
+	repo.versions[repo.versionKey(configGroup.Name)] = configGroup.Version
+	return storedChange(nil, configGroup, configGroup.Revision), nil
+}
+
+func (repo *ConfigGroupInMemRepository) CreateNextVersion(configGroup model.ConfigGroup) (model.ConfigGroup, model.Change, error) {
+	configGroup = configGroup.InNamespace(repo.namespace)
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	configGroup.Version = repo.versions[repo.versionKey(configGroup.Name)] + 1
+	repo.configGroups[repo.key(configGroup.Name, configGroup.Version)] = configGroup.Clone()
+	repo.versions[repo.versionKey(configGroup.Name)] = configGroup.Version
+	return configGroup, storedChange(nil, configGroup, configGroup.Revision), nil
 }
 
 func (repo *ConfigGroupInMemRepository) Read(name string, version int) (model.ConfigGroup, error) {
-	key := fmt.Sprintf("%s/%d", name, version)
+	return repo.Get(name, version)
+}
+
+func (repo *ConfigGroupInMemRepository) Update(newConfigGroup model.ConfigGroup) (model.Change, error) {
+	_, change, err := repo.mutate(newConfigGroup.Name, newConfigGroup.Version, func(configGroup *model.ConfigGroup) error {
+		if err := model.CheckRevision("config group", configGroup.Name, configGroup.Version, configGroup.Revision, newConfigGroup.Revision); err != nil {
+			return err
+		}
+		*configGroup = newConfigGroup.Clone()
+		return nil
+	})
+	return change, err
+}
+
+func (repo *ConfigGroupInMemRepository) Delete(name string, version int, expectedRevision int64) (model.Change, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := repo.key(name, version)
 	configGroup, exists := repo.configGroups[key]
 	if !exists {
-		return model.ConfigGroup{}, errors.New("config group not found")
+		return model.Change{}, model.NotFoundf("config group not found")
+	}
+	if err := checkDeleteRevision("config group", configGroup.Name, configGroup.Version, configGroup.Revision, expectedRevision); err != nil {
+		return model.Change{}, err
 	}
-	return configGroup, nil
+	delete(repo.configGroups, key)
+	return storedChange(configGroup, nil, 0), nil
 }
 
-func (repo *ConfigGroupInMemRepository) Update(newConfigGroup model.ConfigGroup) error {
-	key := configGroupKey(newConfigGroup.Name, newConfigGroup.Version)
-	if _, exists := repo.configGroups[key]; !exists {
-		return errors.New("config group not found")
+// GetAll vraća sve konfiguracije
+func (repo *ConfigGroupInMemRepository) GetAll() ([]model.ConfigGroup, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	configGroups := make([]model.ConfigGroup, 0, len(repo.configGroups))
+	for _, configGroup := range repo.configGroups {
+		if configGroup.Namespace == repo.namespace {
+			configGroups = append(configGroups, configGroup.Clone())
+		}
 	}
-	repo.configGroups[key] = newConfigGroup
-	return nil
+	return configGroups, nil
 }
 
-func (repo *ConfigGroupInMemRepository) Delete(name string, version int) error {
-	found := false
-	for key, configGroup := range repo.configGroups {
-		if configGroup.Name == name && configGroup.Version == version {
-			delete(repo.configGroups, key)
-			found = true
+// List bira i sortira zapise bez kopiranja, pa kopira samo grupe sa tražene strane
+func (repo *ConfigGroupInMemRepository) List(query model.ListQuery) (model.ConfigGroupPage, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	configGroups := make([]model.ConfigGroup, 0)
+	for _, configGroup := range repo.configGroups {
+		if configGroup.Namespace == repo.namespace && query.MatchesConfigGroup(configGroup) {
+			configGroups = append(configGroups, configGroup)
 		}
 	}
-	if !found {
-		return errors.New("config group not found")
+	page, err := model.PageConfigGroups(configGroups, query)
+	if err != nil {
+		return model.ConfigGroupPage{}, err
+	}
+	for i := range page.Items {
+		page.Items[i] = page.Items[i].Clone()
 	}
-	return nil
+	return page, nil
 }
 
-// GetAll vraća sve konfiguracije
-func (repo *ConfigGroupInMemRepository) GetAll() ([]model.ConfigGroup, error) {
-	configGroups := make([]model.ConfigGroup, 0, len(repo.configGroups))
+func (repo *ConfigGroupInMemRepository) ListByName(name string) ([]model.ConfigGroup, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	configGroups := make([]model.ConfigGroup, 0)
 	for _, configGroup := range repo.configGroups {
-		configGroups = append(configGroups, configGroup)
+		if configGroup.Namespace == repo.namespace && configGroup.Name == name {
+			configGroups = append(configGroups, configGroup.Clone())
+		}
 	}
+	sort.Slice(configGroups, func(i, j int) bool { return configGroups[i].Version < configGroups[j].Version })
 	return configGroups, nil
 }
 
-func (c ConfigGroupInMemRepository) Add(configGroup model.ConfigGroup) {
-	key := fmt.Sprintf("%s/%d", configGroup.Name, configGroup.Version)
-	c.configGroups[key] = configGroup
+func (repo *ConfigGroupInMemRepository) Add(configGroup model.ConfigGroup) (model.Change, error) {
+	configGroup = configGroup.InNamespace(repo.namespace)
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := repo.key(configGroup.Name, configGroup.Version)
+	var before interface{}
+	if stored, exists := repo.configGroups[key]; exists {
+		before = stored
+	}
+	repo.configGroups[key] = configGroup.Clone()
+	if configGroup.Version > repo.versions[repo.versionKey(configGroup.Name)] {
+		repo.versions[repo.versionKey(configGroup.Name)] = configGroup.Version
+	}
+	return storedChange(before, configGroup, configGroup.Revision), nil
 }
 
 // configKey kreira ključ za konfiguraciju na osnovu imena i verzije
@@ -77,61 +174,72 @@ func configGroupKey(name string, version int) string {
 	return fmt.Sprintf("%s/%d", name, version)
 }
 
-func (c ConfigGroupInMemRepository) Get(name string, version int) (model.ConfigGroup, error) {
-	key := configGroupKey(name, version)
-	configGroup, ok := c.configGroups[key]
-	if !ok {
-		return model.ConfigGroup{}, errors.New("config group not found")
-	}
-	return configGroup, nil
-}
+func (repo *ConfigGroupInMemRepository) Get(name string, version int) (model.ConfigGroup, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
 
-func (repo *ConfigGroupInMemRepository) RemoveConfig(groupName string, groupVersion int, configName string, configVersion int) error {
-	key := configGroupKey(groupName, groupVersion)
+	key := repo.key(name, version)
 	configGroup, ok := repo.configGroups[key]
 	if !ok {
-		return errors.New("config group not found")
+		return model.ConfigGroup{}, model.NotFoundf("config group not found")
 	}
+	return configGroup.Clone(), nil
+}
 
-	// Pronađimo konfiguraciju koju želimo ukloniti iz grupe
-	var indexToRemove = -1
-	for i, config := range configGroup.Configuration {
-		if config.Name == configName && config.Version == configVersion {
-			indexToRemove = i
-			break
-		}
-	}
-	if indexToRemove == -1 {
-		return fmt.Errorf("config with name %s and version %d not found in group", configName, configVersion)
-	}
+func (repo *ConfigGroupInMemRepository) RemoveConfig(groupName string, groupVersion int, configName string, configVersion int) (model.Change, error) {
+	_, change, err := repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		return configGroup.RemoveConfig(configName, configVersion)
+	})
+	return change, err
+}
 
-	// Uklonimo konfiguraciju iz grupe
-	configGroup.Configuration = append(configGroup.Configuration[:indexToRemove], configGroup.Configuration[indexToRemove+1:]...)
+func (repo *ConfigGroupInMemRepository) AddConfig(groupName string, groupVersion int, config model.Config) (model.ConfigGroup, model.Change, error) {
+	return repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		return configGroup.AddConfig(config)
+	})
+}
 
-	// Ažurirajmo grupu konfiguracija u memoriji
-	repo.configGroups[key] = configGroup
+func (repo *ConfigGroupInMemRepository) AddReference(groupName string, groupVersion int, reference model.ConfigReference) (model.Change, error) {
+	_, change, err := repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		return configGroup.AddReference(reference)
+	})
+	return change, err
+}
 
-	return nil
+func (repo *ConfigGroupInMemRepository) RemoveReference(groupName string, groupVersion int, configName string, configVersion int) (model.Change, error) {
+	_, change, err := repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		return configGroup.RemoveReference(configName, configVersion)
+	})
+	return change, err
 }
 
-func (repo *ConfigGroupInMemRepository) AddConfig(groupName string, groupVersion int, config model.Config) error {
-	// Kreiramo ključ za grupu konfiguracija
-	key := configGroupKey(groupName, groupVersion)
+func (repo *ConfigGroupInMemRepository) RemoveConfigsByLabels(groupName string, groupVersion int, selector map[string]string) ([]model.Config, model.Change, error) {
+	var removed []model.Config
+	_, change, err := repo.mutate(groupName, groupVersion, func(configGroup *model.ConfigGroup) error {
+		removed = configGroup.RemoveConfigsByLabels(selector)
+		return nil
+	})
+	return removed, change, err
+}
 
-	// Proveravamo postoji li već grupa sa tim ključem
-	configGroup, ok := repo.configGroups[key]
+// mutate primenjuje change na kopiju grupe pod ključem i čuva rezultat samo ako change uspe.
+// Ceo postupak se odvija pod bravom, pa je čitanje-izmena-upis atomski.
+func (repo *ConfigGroupInMemRepository) mutate(name string, version int, change func(*model.ConfigGroup) error) (model.ConfigGroup, model.Change, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := repo.key(name, version)
+	stored, ok := repo.configGroups[key]
 	if !ok {
-		return errors.New("config group not found")
+		return model.ConfigGroup{}, model.Change{}, model.NotFoundf("config group not found")
 	}
 
-	// Kreiramo novu konfiguraciju
-	newConfig := model.NewConfig2(groupName, groupVersion) // Prilagoditi kreiranje nove konfiguracije prema potrebama
-
-	// Dodajemo novu konfiguraciju u grupu
-	configGroup.Configuration = append(configGroup.Configuration, newConfig)
-
-	// Ažuriramo grupu konfiguracija u memoriji
+	configGroup := stored.Clone()
+	if err := change(&configGroup); err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	configGroup = configGroup.InNamespace(repo.namespace)
+	configGroup.Revision++
 	repo.configGroups[key] = configGroup
-
-	return nil
+	return configGroup.Clone(), storedChange(stored, configGroup, configGroup.Revision), nil
 }
@@ -0,0 +1,181 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"projekat/model"
+	"sync"
+	"testing"
+)
+
+func TestConfigGroupInMemRepositoryConcurrentAddConfig(t *testing.T) {
+	repo := NewConfigGroupInMemRepository()
+	if _, err := repo.Create(model.NewConfigGroup2("group", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Svaki AddConfig čita, menja i upisuje grupu; nijedna izmena ne sme da se izgubi
+	parallel(parallelCalls, func(i int) {
+		if _, _, err := repo.AddConfig("group", 1, testConfig(fmt.Sprintf("config_%d", i), 1)); err != nil {
+			t.Error(err)
+		}
+	})
+	group, err := repo.Get("group", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(group.Configuration) != parallelCalls {
+		t.Fatalf("group has %d configs after %d parallel AddConfig calls", len(group.Configuration), parallelCalls)
+	}
+	if group.Revision != parallelCalls {
+		t.Errorf("revision = %d, want %d", group.Revision, parallelCalls)
+	}
+
+	parallel(parallelCalls, func(i int) {
+		if _, err := repo.RemoveConfig("group", 1, fmt.Sprintf("config_%d", i), 1); err != nil {
+			t.Error(err)
+		}
+	})
+	group, err = repo.Get("group", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(group.Configuration) != 0 {
+		t.Errorf("group has %d configs after removing all of them", len(group.Configuration))
+	}
+	if group.Revision != 2*parallelCalls {
+		t.Errorf("revision = %d, want %d", group.Revision, 2*parallelCalls)
+	}
+}
+
+func TestConfigGroupInMemRepositoryConcurrentAccess(t *testing.T) {
+	repo := NewConfigGroupInMemRepository()
+	other := repo.In("other")
+	if _, err := repo.Create(model.NewConfigGroup2("shared", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	parallel(parallelCalls, func(i int) {
+		name := fmt.Sprintf("group_%d", i)
+		config := testConfig(fmt.Sprintf("config_%d", i), 1)
+		if _, err := repo.Create(model.NewConfigGroup(name, 1, []model.Config{config})); err != nil {
+			t.Error(err)
+			return
+		}
+		if _, _, err := repo.CreateNextVersion(model.NewConfigGroup2("versioned", 0)); err != nil {
+			t.Error(err)
+		}
+		if _, _, err := other.CreateNextVersion(model.NewConfigGroup2("versioned", 0)); err != nil {
+			t.Error(err)
+		}
+
+		// Grupa koju dele sve gorutine: reference i konfiguracije se dodaju i uklanjaju
+		reference := model.ConfigReference{Name: config.Name, Version: 1}
+		if _, err := repo.AddReference("shared", 1, reference); err != nil {
+			t.Error(err)
+		}
+		if _, _, err := repo.AddConfig("shared", 1, config); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.RemoveReference("shared", 1, config.Name, 1); err != nil {
+			t.Error(err)
+		}
+		if _, _, err := repo.RemoveConfigsByLabels("shared", 1, map[string]string{"missing": "label"}); err != nil {
+			t.Error(err)
+		}
+
+		group, err := repo.Get(name, 1)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		// Izmena vraćene kopije ne sme da utiče na sačuvanu grupu
+		group.Configuration[0].Parameters["key"] = "changed"
+		if _, err := repo.Update(group); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.Add(model.NewConfigGroup(name, 2, []model.Config{config})); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.Read(name, 2); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.ListByName("versioned"); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.List(model.ListQuery{NamePrefix: "group_", Limit: 10}); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.GetAll(); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.Delete(name, 2, 0); err != nil {
+			t.Error(err)
+		}
+	})
+
+	shared, err := repo.Get("shared", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shared.Configuration) != parallelCalls || len(shared.References) != 0 {
+		t.Errorf("shared group has %d configs and %d references, want %d and 0", len(shared.Configuration), len(shared.References), parallelCalls)
+	}
+	if shared.Revision != 4*parallelCalls {
+		t.Errorf("shared group revision = %d, want %d", shared.Revision, 4*parallelCalls)
+	}
+	for _, r := range []model.ConfigGroupRepository{repo, other} {
+		versions, err := r.ListByName("versioned")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != parallelCalls {
+			t.Fatalf("got %d versions of versioned, want %d", len(versions), parallelCalls)
+		}
+		for i, group := range versions {
+			if group.Version != i+1 {
+				t.Fatalf("version %d of versioned is %d, want consecutive versions", i+1, group.Version)
+			}
+		}
+	}
+	for i := 0; i < parallelCalls; i++ {
+		group, err := repo.Get(fmt.Sprintf("group_%d", i), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if group.Configuration[0].Parameters["key"] != "changed" || group.Revision != 1 {
+			t.Errorf("group %s/1 has parameters %v and revision %d after one update", group.Name, group.Configuration[0].Parameters, group.Revision)
+		}
+	}
+}
+
+func TestConfigGroupInMemRepositoryConcurrentAddOfSameConfig(t *testing.T) {
+	repo := NewConfigGroupInMemRepository()
+	if _, err := repo.Create(model.NewConfigGroup2("group", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	added := 0
+	parallel(parallelCalls, func(int) {
+		_, _, err := repo.AddConfig("group", 1, testConfig("app", 1))
+		if err != nil && !errors.Is(err, model.ErrAlreadyExists) {
+			t.Error(err)
+		}
+		if err == nil {
+			mu.Lock()
+			added++
+			mu.Unlock()
+		}
+	})
+	if added != 1 {
+		t.Errorf("%d parallel AddConfig calls of the same config succeeded, want 1", added)
+	}
+	group, err := repo.Get("group", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(group.Configuration) != 1 {
+		t.Errorf("group has %d configs, want 1", len(group.Configuration))
+	}
+}
@@ -1,84 +1,192 @@
 package repositories
 
 import (
-	"errors"
 	"fmt"
 	"projekat/model"
+	"sort"
+	"sync"
 )
 
+// ConfigInMemRepository je bezbedan za istovremeno korišćenje iz više gorutina. Repozitorijumi
+// različitih prostora imena dele mape i bravu; ključevi imaju prefiks prostora imena.
 type ConfigInMemRepository struct {
-	configs map[string]model.Config
+	mu        *sync.RWMutex
+	namespace string
+	configs   map[string]model.Config
+	// Najveća ikad korišćena verzija za svako ime
+	versions map[string]int
 }
 
 func NewConfigInMemRepository() model.ConfigRepository {
 	return &ConfigInMemRepository{
-		configs: make(map[string]model.Config),
+		mu:        &sync.RWMutex{},
+		namespace: model.DefaultNamespace,
+		configs:   make(map[string]model.Config),
+		versions:  make(map[string]int),
 	}
 }
 
-func (repo *ConfigInMemRepository) Create(config model.Config) error {
-	key := configKey(config.Name, config.Version)
+func (repo *ConfigInMemRepository) In(namespace string) model.ConfigRepository {
+	return &ConfigInMemRepository{
+		mu:        repo.mu,
+		namespace: namespace,
+		configs:   repo.configs,
+		versions:  repo.versions,
+	}
+}
+
+func (repo *ConfigInMemRepository) key(name string, version int) string {
+	return model.NamespacePrefix(repo.namespace) + configKey(name, version)
+}
+
+func (repo *ConfigInMemRepository) versionKey(name string) string {
+	return model.NamespacePrefix(repo.namespace) + name
+}
+
+func (repo *ConfigInMemRepository) Create(config model.Config) (model.Change, error) {
+	config.Namespace = repo.namespace
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := repo.key(config.Name, config.Version)
 	if _, exists := repo.configs[key]; exists {
-		return errors.New("config with this name and version already exists")
+		return model.Change{}, model.AlreadyExistsf("config %s/%d already exists", config.Name, config.Version)
+	}
+	if err := checkVersion("config", config.Name, repo.versions[repo.versionKey(config.Name)], config.Version); err != nil {
+		return model.Change{}, err
 	}
 
-	repo.configs[key] = config
-	return nil
+	repo.configs[key] = config.Clone()
+	repo.versions[repo.versionKey(config.Name)] = config.Version
+	return storedChange(nil, config, config.Revision), nil
+}
+
+func (repo *ConfigInMemRepository) CreateNextVersion(config model.Config) (model.Config, model.Change, error) {
+	config.Namespace = repo.namespace
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	config.Version = repo.versions[repo.versionKey(config.Name)] + 1
+	repo.configs[repo.key(config.Name, config.Version)] = config.Clone()
+	repo.versions[repo.versionKey(config.Name)] = config.Version
+	return config, storedChange(nil, config, config.Revision), nil
 }
 
 func (repo *ConfigInMemRepository) Read(name string, version int) (model.Config, error) {
-	for _, config := range repo.configs {
-		if config.Name == name && config.Version == version {
-			return config, nil
-		}
-	}
-	return model.Config{}, errors.New("config not found")
+	return repo.Get(name, version)
 }
 
-func (repo *ConfigInMemRepository) Update(config model.Config) error {
-	key := configKey(config.Name, config.Version)
-	if _, exists := repo.configs[key]; !exists {
-		return errors.New("config not found")
+func (repo *ConfigInMemRepository) Update(config model.Config) (model.Change, error) {
+	config.Namespace = repo.namespace
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := repo.key(config.Name, config.Version)
+	stored, exists := repo.configs[key]
+	if !exists {
+		return model.Change{}, model.NotFoundf("config not found")
+	}
+	if err := model.CheckRevision("config", config.Name, config.Version, stored.Revision, config.Revision); err != nil {
+		return model.Change{}, err
 	}
 
+	config = config.Clone()
+	config.Revision++
 	repo.configs[key] = config
-	return nil
+	return storedChange(stored, config, config.Revision), nil
 }
 
-func (repo *ConfigInMemRepository) Delete(name string, version int) error {
-	found := false
-	for key, config := range repo.configs {
-		if config.Name == name && config.Version == version {
-			delete(repo.configs, key)
-			found = true
-		}
+func (repo *ConfigInMemRepository) Delete(name string, version int, expectedRevision int64) (model.Change, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := repo.key(name, version)
+	config, exists := repo.configs[key]
+	if !exists {
+		return model.Change{}, model.NotFoundf("config not found")
 	}
-	if !found {
-		return errors.New("config not found")
+	if err := checkDeleteRevision("config", config.Name, config.Version, config.Revision, expectedRevision); err != nil {
+		return model.Change{}, err
 	}
-	return nil
+	delete(repo.configs, key)
+	return storedChange(config, nil, 0), nil
 }
 
-func (c ConfigInMemRepository) Add(config model.Config) {
-	key := configKey(config.Name, config.Version)
-	c.configs[key] = config
+func (repo *ConfigInMemRepository) Add(config model.Config) (model.Change, error) {
+	config.Namespace = repo.namespace
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := repo.key(config.Name, config.Version)
+	var before interface{}
+	if stored, exists := repo.configs[key]; exists {
+		before = stored
+	}
+	repo.configs[key] = config.Clone()
+	if config.Version > repo.versions[repo.versionKey(config.Name)] {
+		repo.versions[repo.versionKey(config.Name)] = config.Version
+	}
+	return storedChange(before, config, config.Revision), nil
 }
 
-func (c ConfigInMemRepository) Get(name string, version int) (model.Config, error) {
-	key := configKey(name, version)
-	config, ok := c.configs[key]
+func (repo *ConfigInMemRepository) Get(name string, version int) (model.Config, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	key := repo.key(name, version)
+	config, ok := repo.configs[key]
 	if !ok {
-		return model.Config{}, errors.New("config not found")
+		return model.Config{}, model.NotFoundf("config not found")
 	}
-	return config, nil
+	return config.Clone(), nil
 }
 
 // GetAll vraća sve konfiguracije
 func (repo *ConfigInMemRepository) GetAll() ([]model.Config, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
 	configs := make([]model.Config, 0, len(repo.configs))
 	for _, config := range repo.configs {
-		configs = append(configs, config)
+		if config.Namespace == repo.namespace {
+			configs = append(configs, config.Clone())
+		}
+	}
+	return configs, nil
+}
+
+// List bira i sortira zapise bez kopiranja, pa kopira samo konfiguracije sa tražene strane
+func (repo *ConfigInMemRepository) List(query model.ListQuery) (model.ConfigPage, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	configs := make([]model.Config, 0)
+	for _, config := range repo.configs {
+		if config.Namespace == repo.namespace && query.MatchesConfig(config) {
+			configs = append(configs, config)
+		}
+	}
+	page, err := model.PageConfigs(configs, query)
+	if err != nil {
+		return model.ConfigPage{}, err
+	}
+	for i := range page.Items {
+		page.Items[i] = page.Items[i].Clone()
+	}
+	return page, nil
+}
+
+func (repo *ConfigInMemRepository) ListByName(name string) ([]model.Config, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	configs := make([]model.Config, 0)
+	for _, config := range repo.configs {
+		if config.Namespace == repo.namespace && config.Name == name {
+			configs = append(configs, config.Clone())
+		}
 	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Version < configs[j].Version })
 	return configs, nil
 }
 
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"projekat/model"
+	"sync"
+	"testing"
+)
+
+// Broj gorutina u testovima istovremenog pristupa; pokreću se sa -race
+const parallelCalls = 50
+
+// parallel poziva fn iz n gorutina odjednom i čeka da se sve završe
+func parallel(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+}
+
+func testConfig(name string, version int) model.Config {
+	config := model.NewConfig(name, version, model.Parameters{"key": "value"})
+	config.Labels = map[string]string{"team": "core"}
+	return config
+}
+
+func TestConfigInMemRepositoryConcurrentAccess(t *testing.T) {
+	repo := NewConfigInMemRepository()
+	other := repo.In("other")
+
+	parallel(parallelCalls, func(i int) {
+		name := fmt.Sprintf("config_%d", i)
+		if _, err := repo.Create(testConfig(name, 1)); err != nil {
+			t.Error(err)
+			return
+		}
+		if _, _, err := repo.CreateNextVersion(testConfig("shared", 0)); err != nil {
+			t.Error(err)
+		}
+		if _, _, err := other.CreateNextVersion(testConfig("shared", 0)); err != nil {
+			t.Error(err)
+		}
+
+		config, err := repo.Get(name, 1)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		// Izmena vraćene kopije ne sme da utiče na sačuvanu konfiguraciju
+		config.Parameters["key"] = "changed"
+		config.Labels["team"] = "changed"
+		if _, err := repo.Update(config); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.Add(testConfig(name, 2)); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.Read(name, 2); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.ListByName("shared"); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.List(model.ListQuery{NamePrefix: "config_", Limit: 10}); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.GetAll(); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.Delete(name, 2, 0); err != nil {
+			t.Error(err)
+		}
+	})
+
+	// Svaki CreateNextVersion je dobio svoju verziju, u svakom prostoru imena posebno
+	for _, r := range []model.ConfigRepository{repo, other} {
+		versions, err := r.ListByName("shared")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != parallelCalls {
+			t.Fatalf("got %d versions of shared, want %d", len(versions), parallelCalls)
+		}
+		for i, config := range versions {
+			if config.Version != i+1 {
+				t.Fatalf("version %d of shared is %d, want consecutive versions", i+1, config.Version)
+			}
+		}
+	}
+	all, err := repo.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2*parallelCalls {
+		t.Errorf("got %d configs, want %d", len(all), 2*parallelCalls)
+	}
+	for i := 0; i < parallelCalls; i++ {
+		config, err := repo.Get(fmt.Sprintf("config_%d", i), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Parameters["key"] != "changed" || config.Revision != 1 {
+			t.Errorf("config %s/1 has parameters %v and revision %d after one update", config.Name, config.Parameters, config.Revision)
+		}
+	}
+}
+
+func TestConfigInMemRepositoryConcurrentCreateOfSameVersion(t *testing.T) {
+	repo := NewConfigInMemRepository()
+	var mu sync.Mutex
+	created := 0
+	parallel(parallelCalls, func(int) {
+		_, err := repo.Create(testConfig("app", 1))
+		if err != nil && !errors.Is(err, model.ErrAlreadyExists) {
+			t.Error(err)
+		}
+		if err == nil {
+			mu.Lock()
+			created++
+			mu.Unlock()
+		}
+	})
+	if created != 1 {
+		t.Errorf("%d parallel creates of the same version succeeded, want 1", created)
+	}
+}
+
+func TestConfigInMemRepositoryConcurrentUpdateOfSameRevision(t *testing.T) {
+	repo := NewConfigInMemRepository()
+	if _, err := repo.Create(testConfig("app", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	updated := 0
+	parallel(parallelCalls, func(i int) {
+		config := testConfig("app", 1)
+		config.Parameters["writer"] = i
+		_, err := repo.Update(config)
+		if err != nil && !errors.Is(err, model.ErrPreconditionFailed) {
+			t.Error(err)
+		}
+		if err == nil {
+			mu.Lock()
+			updated++
+			mu.Unlock()
+		}
+	})
+	if updated != 1 {
+		t.Errorf("%d parallel updates of revision 0 succeeded, want 1", updated)
+	}
+	stored, err := repo.Get("app", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Revision != 1 {
+		t.Errorf("revision = %d, want 1", stored.Revision)
+	}
+}
@@ -0,0 +1,247 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// fakeConsul je Consul KV u memoriji iza httptest servera. Podržava deo HTTP API-ja koji koriste
+// repozitorijumi: čitanje ključa, listu i ključeve ispod prefiksa, upis i brisanje sa CAS-om i
+// transakcije sa cas operacijama. Indeksi se dodeljuju kao u Consul-u, iz jednog rastućeg brojača.
+type fakeConsul struct {
+	mu    sync.Mutex
+	index uint64
+	pairs map[string]api.KVPair
+	// beforeWrite se poziva pre svakog upisa (PUT, DELETE i transakcije), bez zaključane brave.
+	// Testovi njime ubacuju tuđu izmenu između čitanja i upisa repozitorijuma.
+	beforeWrite func()
+}
+
+// newFakeConsul pokreće lažni Consul i vraća klijenta povezanog sa njim
+func newFakeConsul(t *testing.T) (*api.Client, *fakeConsul) {
+	t.Helper()
+	fake := &fakeConsul{pairs: make(map[string]api.KVPair)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client, err := api.NewClient(&api.Config{Address: server.URL, HttpClient: server.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, fake
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && f.beforeWrite != nil {
+		f.beforeWrite()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+	w.Header().Set("X-Consul-LastContact", "0")
+	w.Header().Set("X-Consul-KnownLeader", "true")
+	switch {
+	case r.URL.Path == "/v1/txn" && r.Method == http.MethodPut:
+		f.txn(w, r)
+	case strings.HasPrefix(r.URL.Path, "/v1/kv/"):
+		key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		switch r.Method {
+		case http.MethodGet:
+			f.get(w, r, key)
+		case http.MethodPut:
+			f.put(w, r, key)
+		case http.MethodDelete:
+			f.delete(w, r, key)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+// keys vraća sortirane ključeve ispod prefiksa
+func (f *fakeConsul) keys(prefix string) []string {
+	keys := make([]string, 0)
+	for key := range f.pairs {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (f *fakeConsul) get(w http.ResponseWriter, r *http.Request, key string) {
+	query := r.URL.Query()
+	var body interface{}
+	switch {
+	case query.Has("keys"):
+		keys := f.keys(key)
+		if len(keys) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body = keys
+	case query.Has("recurse"):
+		pairs := make([]api.KVPair, 0)
+		for _, key := range f.keys(key) {
+			pairs = append(pairs, f.pairs[key])
+		}
+		if len(pairs) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body = pairs
+	default:
+		pair, ok := f.pairs[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body = []api.KVPair{pair}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// casIndex čita ?cas=; ok je false ako upit nema cas
+func casIndex(r *http.Request) (index uint64, ok bool, err error) {
+	if !r.URL.Query().Has("cas") {
+		return 0, false, nil
+	}
+	index, err = strconv.ParseUint(r.URL.Query().Get("cas"), 10, 64)
+	return index, true, err
+}
+
+// matches proverava CAS indeks kao Consul: 0 znači da ključ ne sme da postoji,
+// a ostale vrednosti moraju da budu jednake ModifyIndex-u ključa
+func (f *fakeConsul) matches(key string, index uint64) bool {
+	pair, exists := f.pairs[key]
+	if index == 0 {
+		return !exists
+	}
+	return exists && pair.ModifyIndex == index
+}
+
+// set upisuje vrednost ključa sa indeksom index
+func (f *fakeConsul) set(key string, value []byte, index uint64) api.KVPair {
+	pair, exists := f.pairs[key]
+	if !exists {
+		pair = api.KVPair{Key: key, CreateIndex: index}
+	}
+	pair.Value = append([]byte(nil), value...)
+	pair.ModifyIndex = index
+	f.pairs[key] = pair
+	f.index = index
+	return pair
+}
+
+func (f *fakeConsul) put(w http.ResponseWriter, r *http.Request, key string) {
+	value, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	index, cas, err := casIndex(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if cas && !f.matches(key, index) {
+		fmt.Fprint(w, "false")
+		return
+	}
+	f.set(key, value, f.index+1)
+	fmt.Fprint(w, "true")
+}
+
+func (f *fakeConsul) delete(w http.ResponseWriter, r *http.Request, key string) {
+	index, cas, err := casIndex(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Has("recurse") {
+		for _, key := range f.keys(key) {
+			delete(f.pairs, key)
+		}
+		f.index++
+		fmt.Fprint(w, "true")
+		return
+	}
+	if cas && index != 0 && !f.matches(key, index) {
+		fmt.Fprint(w, "false")
+		return
+	}
+	delete(f.pairs, key)
+	f.index++
+	fmt.Fprint(w, "true")
+}
+
+// txn izvršava sve operacije ili nijednu; neuspela provera vraća 409 sa greškom operacije
+func (f *fakeConsul) txn(w http.ResponseWriter, r *http.Request) {
+	var ops []struct {
+		KV *api.KVTxnOp
+	}
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var failures api.TxnErrors
+	seen := make(map[string]bool)
+	for i, op := range ops {
+		switch {
+		case op.KV == nil || op.KV.Verb != api.KVCAS:
+			http.Error(w, fmt.Sprintf("operation %d: only KV cas is supported", i), http.StatusBadRequest)
+			return
+		case seen[op.KV.Key]:
+			http.Error(w, fmt.Sprintf("operation %d: key %s is used twice", i, op.KV.Key), http.StatusBadRequest)
+			return
+		case !f.matches(op.KV.Key, op.KV.Index):
+			failures = append(failures, &api.TxnError{OpIndex: i, What: fmt.Sprintf("failed to set key %q, index is stale", op.KV.Key)})
+		}
+		seen[op.KV.Key] = true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if len(failures) > 0 {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(api.TxnResponse{Errors: failures})
+		return
+	}
+
+	// Sve operacije jedne transakcije dobijaju isti indeks
+	results := make(api.TxnResults, 0, len(ops))
+	index := f.index + 1
+	for _, op := range ops {
+		pair := f.set(op.KV.Key, op.KV.Value, index)
+		results = append(results, &api.TxnResult{KV: &api.KVPair{Key: pair.Key, CreateIndex: pair.CreateIndex, ModifyIndex: pair.ModifyIndex}})
+	}
+	json.NewEncoder(w).Encode(api.TxnResponse{Results: results})
+}
+
+// write upisuje ključ kao da ga je upisao drugi klijent
+func (f *fakeConsul) write(key string, value []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.set(key, value, f.index+1)
+}
+
+// stored vraća sve ključeve u skladištu
+func (f *fakeConsul) stored() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.keys("")
+}
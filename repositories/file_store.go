@@ -0,0 +1,413 @@
+package repositories
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"projekat/model"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	walFileName      = "wal.log"
+	snapshotFileName = "snapshot.json"
+
+	// Zapis duži od ovoga sigurno je posledica oštećenog zaglavlja
+	maxWALRecordSize = 64 << 20
+
+	defaultCompactEvery = 1000
+)
+
+var walChecksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// fileStore je trajno ključ/vrednost skladište na lokalnom disku.
+//
+// Svaka izmena se prvo dopisuje u write-ahead log (wal.log) i sinhronizuje na disk,
+// pa se tek onda primenjuje u memoriji. Kada log naraste preko compactEvery zapisa,
+// celo stanje se upisuje u snapshot.json, a log se prazni.
+//
+// Zapis u logu ima oblik: dužina (4 bajta, big-endian), CRC-32C sadržaja (4 bajta), JSON sadržaj.
+type fileStore struct {
+	mu           sync.RWMutex
+	dir          string
+	data         map[string]json.RawMessage
+	wal          walFile
+	walRecords   int
+	compactEvery int
+	// failed je greška zbog koje log više nije u poznatom stanju; posle nje se izmene odbijaju
+	failed error
+}
+
+// walFile je deo *os.File koji koristi log
+type walFile interface {
+	io.ReadWriteSeeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// walRecord je jedna atomska grupa izmena
+type walRecord struct {
+	Ops []walOp `json:"ops"`
+}
+
+type walOp struct {
+	Op    string          `json:"op"` // "put" ili "delete"
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type snapshot struct {
+	Entries map[string]json.RawMessage `json:"entries"`
+}
+
+// openFileStore učitava snapshot, ponavlja log i odseca oštećen ili nedovršen kraj loga
+func openFileStore(dir string, compactEvery int) (*fileStore, error) {
+	if compactEvery <= 0 {
+		compactEvery = defaultCompactEvery
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	store := &fileStore{
+		dir:          dir,
+		data:         make(map[string]json.RawMessage),
+		compactEvery: compactEvery,
+	}
+	if err := store.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	store.wal = wal
+
+	if err := store.replay(); err != nil {
+		wal.Close()
+		return nil, err
+	}
+	if store.walRecords > 0 {
+		if err := store.compact(); err != nil {
+			wal.Close()
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+func (s *fileStore) loadSnapshot() error {
+	content, err := os.ReadFile(filepath.Join(s.dir, snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(content, &snap); err != nil {
+		return fmt.Errorf("corrupt snapshot %s: %w", filepath.Join(s.dir, snapshotFileName), err)
+	}
+	if snap.Entries != nil {
+		s.data = snap.Entries
+	}
+	return nil
+}
+
+// replay primenjuje sve ispravne zapise iz loga. Prvi nedovršen ili oštećen zapis
+// označava kraj loga (npr. pad usred upisa), pa se log na tom mestu odseca.
+func (s *fileStore) replay() error {
+	reader := bufio.NewReader(s.wal)
+	var offset int64
+	for {
+		record, size, err := readWALRecord(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("file store: %s: %v at offset %d, truncating log", filepath.Join(s.dir, walFileName), err, offset)
+			if err := s.wal.Truncate(offset); err != nil {
+				return err
+			}
+			break
+		}
+		s.applyRecord(record)
+		s.walRecords++
+		offset += size
+	}
+
+	_, err := s.wal.Seek(offset, io.SeekStart)
+	return err
+}
+
+func readWALRecord(reader io.Reader) (walRecord, int64, error) {
+	var header [8]byte
+	n, err := io.ReadFull(reader, header[:])
+	if err == io.EOF {
+		return walRecord{}, 0, io.EOF
+	}
+	if err != nil {
+		return walRecord{}, 0, fmt.Errorf("truncated record header (%d bytes)", n)
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	checksum := binary.BigEndian.Uint32(header[4:8])
+	if length > maxWALRecordSize {
+		return walRecord{}, 0, fmt.Errorf("corrupt record header (length %d)", length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return walRecord{}, 0, errors.New("truncated record")
+	}
+	if crc32.Checksum(payload, walChecksumTable) != checksum {
+		return walRecord{}, 0, errors.New("record checksum mismatch")
+	}
+
+	var record walRecord
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return walRecord{}, 0, fmt.Errorf("corrupt record: %w", err)
+	}
+	return record, int64(len(header)) + int64(length), nil
+}
+
+func (s *fileStore) applyRecord(record walRecord) {
+	for _, op := range record.Ops {
+		switch op.Op {
+		case "put":
+			s.data[op.Key] = op.Value
+		case "delete":
+			delete(s.data, op.Key)
+		}
+	}
+}
+
+// appendRecord upisuje zapis u log i čeka da stigne na disk. Ako upis ne uspe, log se
+// vraća na dužinu pre zapisa, da delimičan zapis ne bi ostao ispred sledećih.
+func (s *fileStore) appendRecord(record walRecord) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(payload, walChecksumTable))
+	copy(buf[8:], payload)
+
+	offset, err := s.wal.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := s.wal.Write(buf); err != nil {
+		return s.rollback(offset, err)
+	}
+	if err := s.wal.Sync(); err != nil {
+		return s.rollback(offset, err)
+	}
+	return nil
+}
+
+// rollback odseca log na offset posle neuspelog upisa i vraća cause. Ako ni to ne uspe, kraj
+// loga više nije poznat, pa se skladište označava kao neispravno.
+func (s *fileStore) rollback(offset int64, cause error) error {
+	if err := s.wal.Truncate(offset); err != nil {
+		s.failed = fmt.Errorf("cannot truncate log after a failed write: %w", err)
+		return cause
+	}
+	if _, err := s.wal.Seek(offset, io.SeekStart); err != nil {
+		s.failed = fmt.Errorf("cannot seek log after a failed write: %w", err)
+	}
+	return cause
+}
+
+// compact upisuje celo stanje u snapshot i prazni log. Poziva se pod bravom.
+func (s *fileStore) compact() error {
+	content, err := json.Marshal(snapshot{Entries: s.data})
+	if err != nil {
+		return err
+	}
+
+	// Snapshot se prvo upisuje u privremeni fajl, pa atomski preimenuje
+	tmp := filepath.Join(s.dir, snapshotFileName+".tmp")
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, snapshotFileName)); err != nil {
+		return err
+	}
+	syncDir(s.dir)
+
+	// Ako padnemo pre pražnjenja loga, ponovno primenjivanje zapisa preko snapshot-a je bezopasno.
+	// Posle delimičnog pražnjenja položaj upisa više ne odgovara kraju loga.
+	if err := s.wal.Truncate(0); err != nil {
+		s.failed = fmt.Errorf("cannot truncate log after compaction: %w", err)
+		return err
+	}
+	if _, err := s.wal.Seek(0, io.SeekStart); err != nil {
+		s.failed = fmt.Errorf("cannot seek log after compaction: %w", err)
+		return err
+	}
+	s.walRecords = 0
+	return s.wal.Sync()
+}
+
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// get vraća sirovu vrednost za ključ
+func (s *fileStore) get(key string) (json.RawMessage, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.data[key]
+	return value, ok
+}
+
+// list vraća sve vrednosti čiji ključ počinje prefiksom, sortirane po ključu
+func (s *fileStore) list(prefix string) []json.RawMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0)
+	for key := range s.data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	values := make([]json.RawMessage, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, s.data[key])
+	}
+	return values
+}
+
+// fileTx skuplja izmene jedne update operacije
+type fileTx struct {
+	store *fileStore
+	ops   []walOp
+}
+
+func (tx *fileTx) get(key string) (json.RawMessage, bool) {
+	for i := len(tx.ops) - 1; i >= 0; i-- {
+		if tx.ops[i].Key == key {
+			return tx.ops[i].Value, tx.ops[i].Op == "put"
+		}
+	}
+	value, ok := tx.store.data[key]
+	return value, ok
+}
+
+// keys vraća ključeve ispod prefiksa iz sačuvanog stanja (bez izmena ove transakcije)
+func (tx *fileTx) keys(prefix string) []string {
+	keys := make([]string, 0)
+	for key := range tx.store.data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// highestVersion vraća najveću korišćenu verziju za ime: iz brojača, ili iz postojećih
+// ključeva ako brojač još ne postoji (podaci upisani pre uvođenja brojača)
+func (tx *fileTx) highestVersion(counterKey, itemsPrefix string) (int, error) {
+	highest := highestVersion(tx.keys(itemsPrefix), itemsPrefix)
+	if value, ok := tx.get(counterKey); ok {
+		var counter int
+		if err := json.Unmarshal(value, &counter); err != nil {
+			return 0, fmt.Errorf("cannot decode version counter %s: %w", counterKey, err)
+		}
+		if counter > highest {
+			highest = counter
+		}
+	}
+	return highest, nil
+}
+
+func (tx *fileTx) put(key string, value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	tx.ops = append(tx.ops, walOp{Op: "put", Key: key, Value: encoded})
+	return nil
+}
+
+func (tx *fileTx) delete(key string) {
+	tx.ops = append(tx.ops, walOp{Op: "delete", Key: key})
+}
+
+// change vraća izmenu ključa u ovoj transakciji: sačuvanu vrednost i vrednost posle izmena transakcije
+func (tx *fileTx) change(key string, revision int64) model.Change {
+	change := model.Change{Before: tx.store.data[key], Revision: revision}
+	if value, ok := tx.get(key); ok {
+		change.After = value
+	}
+	return change
+}
+
+// update izvršava fn pod ekskluzivnom bravom. Ako fn uspe, sve njene izmene
+// se upisuju u log kao jedan zapis, pa se ili primene sve ili nijedna.
+func (s *fileStore) update(fn func(tx *fileTx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failed != nil {
+		return fmt.Errorf("file store: log is unusable until restart: %w", s.failed)
+	}
+	tx := &fileTx{store: s}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.ops) == 0 {
+		return nil
+	}
+
+	record := walRecord{Ops: tx.ops}
+	if err := s.appendRecord(record); err != nil {
+		return fmt.Errorf("file store: cannot write log: %w", err)
+	}
+	s.applyRecord(record)
+	s.walRecords++
+
+	if s.walRecords >= s.compactEvery {
+		if err := s.compact(); err != nil {
+			// Izmena je već trajno u logu, pa neuspelo sažimanje samo beležimo
+			log.Printf("file store: compaction failed: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openTestStore(t *testing.T, dir string, compactEvery int) *fileStore {
+	t.Helper()
+	store, err := openFileStore(dir, compactEvery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.wal.Close() })
+	return store
+}
+
+// reopen zatvara log kao pri padu procesa (bez sažimanja) i ponovo otvara skladište
+func reopen(t *testing.T, store *fileStore) *fileStore {
+	t.Helper()
+	store.wal.Close()
+	return openTestStore(t, store.dir, store.compactEvery)
+}
+
+func putValue(t *testing.T, store *fileStore, key string, value interface{}) {
+	t.Helper()
+	err := store.update(func(tx *fileTx) error {
+		return tx.put(key, value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func deleteValue(t *testing.T, store *fileStore, key string) {
+	t.Helper()
+	err := store.update(func(tx *fileTx) error {
+		tx.delete(key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectData proverava celo stanje skladišta
+func expectData(t *testing.T, store *fileStore, want map[string]string) {
+	t.Helper()
+	if len(store.data) != len(want) {
+		t.Errorf("store has %d keys, want %d: %s", len(store.data), len(want), store.data)
+	}
+	for key, value := range want {
+		got, ok := store.get(key)
+		if !ok {
+			t.Errorf("key %s is missing", key)
+			continue
+		}
+		if string(got) != value {
+			t.Errorf("key %s = %s, want %s", key, got, value)
+		}
+	}
+}
+
+func walSize(t *testing.T, dir string) int64 {
+	t.Helper()
+	info, err := os.Stat(filepath.Join(dir, walFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestFileStoreReplaysLogAfterRestart(t *testing.T) {
+	store := openTestStore(t, t.TempDir(), 100)
+	putValue(t, store, "a", 1)
+	putValue(t, store, "b", 2)
+	putValue(t, store, "a", 3)
+	deleteValue(t, store, "b")
+	err := store.update(func(tx *fileTx) error {
+		if err := tx.put("c", 4); err != nil {
+			return err
+		}
+		return tx.put("d", 5)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(store.dir, snapshotFileName)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("snapshot was written before reaching compactEvery: %v", err)
+	}
+
+	store = reopen(t, store)
+	expectData(t, store, map[string]string{"a": "3", "c": "4", "d": "5"})
+	// Otvaranje sažima ponovljen log u snapshot
+	if size := walSize(t, store.dir); size != 0 {
+		t.Errorf("log has %d bytes after opening, want 0", size)
+	}
+
+	putValue(t, store, "e", 6)
+	store = reopen(t, store)
+	expectData(t, store, map[string]string{"a": "3", "c": "4", "d": "5", "e": "6"})
+}
+
+func TestFileStoreTruncatesTornTail(t *testing.T) {
+	cases := map[string]int{
+		"partial header":  3,
+		"partial payload": 12,
+	}
+	for name, tornBytes := range cases {
+		t.Run(name, func(t *testing.T) {
+			store := openTestStore(t, t.TempDir(), 100)
+			putValue(t, store, "a", 1)
+			putValue(t, store, "b", 2)
+			good := walSize(t, store.dir)
+
+			// Pad usred upisa trećeg zapisa ostavlja samo njegov početak
+			putValue(t, store, "c", "a value long enough to be torn")
+			store.wal.Close()
+			if err := os.Truncate(filepath.Join(store.dir, walFileName), good+int64(tornBytes)); err != nil {
+				t.Fatal(err)
+			}
+
+			store = openTestStore(t, store.dir, store.compactEvery)
+			expectData(t, store, map[string]string{"a": "1", "b": "2"})
+
+			// Zapisi posle odsečenog kraja se ponavljaju posle sledećeg restarta
+			putValue(t, store, "d", 4)
+			store = reopen(t, store)
+			expectData(t, store, map[string]string{"a": "1", "b": "2", "d": "4"})
+		})
+	}
+}
+
+func TestFileStoreStopsAtChecksumMismatch(t *testing.T) {
+	store := openTestStore(t, t.TempDir(), 100)
+	putValue(t, store, "a", 1)
+	first := walSize(t, store.dir)
+	putValue(t, store, "b", 2)
+	putValue(t, store, "c", 3)
+	store.wal.Close()
+
+	// Menja se jedan bajt sadržaja drugog zapisa, dužina i zaglavlje ostaju ispravni
+	path := filepath.Join(store.dir, walFileName)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content[first+8+2] ^= 0xff
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Oštećen zapis je kraj loga: ni on ni zapisi posle njega se ne primenjuju
+	store = openTestStore(t, store.dir, store.compactEvery)
+	expectData(t, store, map[string]string{"a": "1"})
+}
+
+func TestFileStoreCompaction(t *testing.T) {
+	store := openTestStore(t, t.TempDir(), 3)
+	putValue(t, store, "a", 1)
+	putValue(t, store, "b", 2)
+	if walSize(t, store.dir) == 0 {
+		t.Fatal("log is empty before compaction")
+	}
+	deleteValue(t, store, "a")
+	if size := walSize(t, store.dir); size != 0 || store.walRecords != 0 {
+		t.Fatalf("after compactEvery records the log has %d bytes and %d records, want an empty log", size, store.walRecords)
+	}
+
+	content, err := os.ReadFile(filepath.Join(store.dir, snapshotFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap snapshot
+	if err := json.Unmarshal(content, &snap); err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Entries) != 1 || string(snap.Entries["b"]) != "2" {
+		t.Errorf("snapshot has entries %s, want only b", snap.Entries)
+	}
+
+	// Posle restarta stanje je snapshot plus zapisi upisani posle sažimanja
+	putValue(t, store, "c", 3)
+	store = reopen(t, store)
+	expectData(t, store, map[string]string{"b": "2", "c": "3"})
+	if _, err := os.Stat(filepath.Join(store.dir, snapshotFileName+".tmp")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("temporary snapshot file was left behind: %v", err)
+	}
+}
+
+// faultyWAL prosleđuje pozive logu, a na zahtev upisuje samo pola bafera ili vraća grešku
+type faultyWAL struct {
+	walFile
+	failWrite    bool
+	failSync     bool
+	failTruncate bool
+}
+
+var errInjected = errors.New("injected failure")
+
+func (f *faultyWAL) Write(p []byte) (int, error) {
+	if f.failWrite {
+		n, _ := f.walFile.Write(p[:len(p)/2])
+		return n, errInjected
+	}
+	return f.walFile.Write(p)
+}
+
+func (f *faultyWAL) Sync() error {
+	if f.failSync {
+		return errInjected
+	}
+	return f.walFile.Sync()
+}
+
+func (f *faultyWAL) Truncate(size int64) error {
+	if f.failTruncate {
+		return errInjected
+	}
+	return f.walFile.Truncate(size)
+}
+
+func TestFileStoreRollsBackFailedWrite(t *testing.T) {
+	cases := map[string]faultyWAL{
+		"write": {failWrite: true},
+		"sync":  {failSync: true},
+	}
+	for name, fault := range cases {
+		t.Run(name, func(t *testing.T) {
+			store := openTestStore(t, t.TempDir(), 100)
+			putValue(t, store, "a", 1)
+			before := walSize(t, store.dir)
+
+			wal := fault
+			wal.walFile = store.wal
+			store.wal = &wal
+			err := store.update(func(tx *fileTx) error {
+				return tx.put("b", 2)
+			})
+			if !errors.Is(err, errInjected) {
+				t.Fatalf("got %v, want the write error", err)
+			}
+			if size := walSize(t, store.dir); size != before {
+				t.Errorf("log has %d bytes after a failed write, want %d", size, before)
+			}
+			expectData(t, store, map[string]string{"a": "1"})
+
+			// Sledeći upis ide odmah iza poslednjeg ispravnog zapisa
+			store.wal = wal.walFile
+			putValue(t, store, "c", 3)
+			store = reopen(t, store)
+			expectData(t, store, map[string]string{"a": "1", "c": "3"})
+		})
+	}
+}
+
+func TestFileStoreFailsWhenRollbackFails(t *testing.T) {
+	store := openTestStore(t, t.TempDir(), 100)
+	putValue(t, store, "a", 1)
+
+	wal := &faultyWAL{walFile: store.wal, failWrite: true, failTruncate: true}
+	store.wal = wal
+	err := store.update(func(tx *fileTx) error {
+		return tx.put("b", 2)
+	})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("got %v, want the write error", err)
+	}
+
+	// Kraj loga više nije poznat, pa se izmene odbijaju i kada disk ponovo radi
+	store.wal = wal.walFile
+	err = store.update(func(tx *fileTx) error {
+		return tx.put("c", 3)
+	})
+	if err == nil {
+		t.Fatal("update succeeded after the log could not be truncated")
+	}
+	expectData(t, store, map[string]string{"a": "1"})
+
+	// Restart odseca delimičan zapis i skladište ponovo prima izmene
+	store = reopen(t, store)
+	expectData(t, store, map[string]string{"a": "1"})
+	putValue(t, store, "c", 3)
+	expectData(t, store, map[string]string{"a": "1", "c": "3"})
+}
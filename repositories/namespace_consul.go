@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"projekat/model"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Prefiks pod kojim se čuvaju opisi prostora imena. Zapisi samih prostora imena su ispod
+// "namespaces/{ime}/", pa opisi ne smeju biti pod istim prefiksom.
+const namespaceRecordsPrefix = "namespaceRecords/"
+
+type NamespaceConsulRepository struct {
+	kv *api.KV
+}
+
+func NewNamespaceConsulRepository(client *api.Client) model.NamespaceRepository {
+	return &NamespaceConsulRepository{
+		kv: client.KV(),
+	}
+}
+
+func (repo *NamespaceConsulRepository) Create(namespace model.Namespace) error {
+	value, err := json.Marshal(namespace)
+	if err != nil {
+		return err
+	}
+
+	// ModifyIndex 0 upisuje ključ samo ako još ne postoji
+	ok, _, err := repo.kv.CAS(&api.KVPair{Key: namespaceRecordsPrefix + namespace.Name, Value: value, ModifyIndex: 0}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return model.AlreadyExistsf("namespace %s already exists", namespace.Name)
+	}
+	return nil
+}
+
+func (repo *NamespaceConsulRepository) Update(namespace model.Namespace) error {
+	key := namespaceRecordsPrefix + namespace.Name
+	pair, _, err := repo.kv.Get(key, nil)
+	if err != nil {
+		return err
+	}
+	if pair == nil {
+		return model.NotFoundf("namespace not found")
+	}
+
+	value, err := json.Marshal(namespace)
+	if err != nil {
+		return err
+	}
+	ok, _, err := repo.kv.CAS(&api.KVPair{Key: key, Value: value, ModifyIndex: pair.ModifyIndex}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return model.Conflictf("namespace %s was modified concurrently", namespace.Name)
+	}
+	return nil
+}
+
+func (repo *NamespaceConsulRepository) Get(name string) (model.Namespace, error) {
+	pair, _, err := repo.kv.Get(namespaceRecordsPrefix+name, nil)
+	if err != nil {
+		return model.Namespace{}, err
+	}
+	if pair == nil {
+		return model.Namespace{}, model.NotFoundf("namespace not found")
+	}
+	var namespace model.Namespace
+	if err := json.Unmarshal(pair.Value, &namespace); err != nil {
+		return model.Namespace{}, fmt.Errorf("cannot decode namespace %s: %w", pair.Key, err)
+	}
+	return namespace, nil
+}
+
+func (repo *NamespaceConsulRepository) Delete(name string) error {
+	key := namespaceRecordsPrefix + name
+	pair, _, err := repo.kv.Get(key, nil)
+	if err != nil {
+		return err
+	}
+	if pair == nil {
+		return model.NotFoundf("namespace not found")
+	}
+
+	_, err = repo.kv.Delete(key, nil)
+	return err
+}
+
+func (repo *NamespaceConsulRepository) GetAll() ([]model.Namespace, error) {
+	pairs, _, err := repo.kv.List(namespaceRecordsPrefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]model.Namespace, 0, len(pairs))
+	for _, pair := range pairs {
+		// Preskačemo "direktorijume" koje Consul UI ume da napravi
+		if strings.HasSuffix(pair.Key, "/") {
+			continue
+		}
+		var namespace model.Namespace
+		if err := json.Unmarshal(pair.Value, &namespace); err != nil {
+			return nil, fmt.Errorf("cannot decode namespace %s: %w", pair.Key, err)
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces, nil
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"projekat/model"
+)
+
+// NamespaceFileRepository čuva prostore imena u fileStore-u na lokalnom disku
+type NamespaceFileRepository struct {
+	store *fileStore
+}
+
+func NewNamespaceFileRepository(store *fileStore) model.NamespaceRepository {
+	return &NamespaceFileRepository{
+		store: store,
+	}
+}
+
+func (repo *NamespaceFileRepository) Create(namespace model.Namespace) error {
+	key := namespaceRecordsPrefix + namespace.Name
+	return repo.store.update(func(tx *fileTx) error {
+		if _, exists := tx.get(key); exists {
+			return model.AlreadyExistsf("namespace %s already exists", namespace.Name)
+		}
+		return tx.put(key, namespace)
+	})
+}
+
+func (repo *NamespaceFileRepository) Update(namespace model.Namespace) error {
+	key := namespaceRecordsPrefix + namespace.Name
+	return repo.store.update(func(tx *fileTx) error {
+		if _, exists := tx.get(key); !exists {
+			return model.NotFoundf("namespace not found")
+		}
+		return tx.put(key, namespace)
+	})
+}
+
+func (repo *NamespaceFileRepository) Get(name string) (model.Namespace, error) {
+	value, ok := repo.store.get(namespaceRecordsPrefix + name)
+	if !ok {
+		return model.Namespace{}, model.NotFoundf("namespace not found")
+	}
+	var namespace model.Namespace
+	if err := json.Unmarshal(value, &namespace); err != nil {
+		return model.Namespace{}, fmt.Errorf("cannot decode namespace: %w", err)
+	}
+	return namespace, nil
+}
+
+func (repo *NamespaceFileRepository) Delete(name string) error {
+	key := namespaceRecordsPrefix + name
+	return repo.store.update(func(tx *fileTx) error {
+		if _, exists := tx.get(key); !exists {
+			return model.NotFoundf("namespace not found")
+		}
+		tx.delete(key)
+		return nil
+	})
+}
+
+func (repo *NamespaceFileRepository) GetAll() ([]model.Namespace, error) {
+	values := repo.store.list(namespaceRecordsPrefix)
+	namespaces := make([]model.Namespace, 0, len(values))
+	for _, value := range values {
+		var namespace model.Namespace
+		if err := json.Unmarshal(value, &namespace); err != nil {
+			return nil, fmt.Errorf("cannot decode namespace: %w", err)
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces, nil
+}
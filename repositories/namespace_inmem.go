@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"projekat/model"
+	"sync"
+)
+
+// NamespaceInMemRepository je bezbedan za istovremeno korišćenje iz više gorutina
+type NamespaceInMemRepository struct {
+	mu         sync.RWMutex
+	namespaces map[string]model.Namespace
+}
+
+func NewNamespaceInMemRepository() model.NamespaceRepository {
+	return &NamespaceInMemRepository{
+		namespaces: make(map[string]model.Namespace),
+	}
+}
+
+func (repo *NamespaceInMemRepository) Create(namespace model.Namespace) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, exists := repo.namespaces[namespace.Name]; exists {
+		return model.AlreadyExistsf("namespace %s already exists", namespace.Name)
+	}
+	repo.namespaces[namespace.Name] = namespace.Clone()
+	return nil
+}
+
+func (repo *NamespaceInMemRepository) Update(namespace model.Namespace) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, exists := repo.namespaces[namespace.Name]; !exists {
+		return model.NotFoundf("namespace not found")
+	}
+	repo.namespaces[namespace.Name] = namespace.Clone()
+	return nil
+}
+
+func (repo *NamespaceInMemRepository) Get(name string) (model.Namespace, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	namespace, ok := repo.namespaces[name]
+	if !ok {
+		return model.Namespace{}, model.NotFoundf("namespace not found")
+	}
+	return namespace.Clone(), nil
+}
+
+func (repo *NamespaceInMemRepository) Delete(name string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, exists := repo.namespaces[name]; !exists {
+		return model.NotFoundf("namespace not found")
+	}
+	delete(repo.namespaces, name)
+	return nil
+}
+
+func (repo *NamespaceInMemRepository) GetAll() ([]model.Namespace, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	namespaces := make([]model.Namespace, 0, len(repo.namespaces))
+	for _, namespace := range repo.namespaces {
+		namespaces = append(namespaces, namespace.Clone())
+	}
+	return namespaces, nil
+}
@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"fmt"
+	"projekat/model"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Storage objedinjuje repozitorijume koje pravi jedan backend
+type Storage struct {
+	Configs      model.ConfigRepository
+	ConfigGroups model.ConfigGroupRepository
+	Schemas      model.SchemaRepository
+	Webhooks     model.WebhookRepository
+	Namespaces   model.NamespaceRepository
+	Audit        model.AuditRepository
+}
+
+// BackendFactory pravi repozitorijume backend-a na osnovu opcija (ključ=vrednost)
+type BackendFactory func(options map[string]string) (Storage, error)
+
+var backends = map[string]BackendFactory{}
+
+func init() {
+	RegisterBackend("memory", newMemoryStorage)
+	RegisterBackend("file", newFileStorage)
+	RegisterBackend("consul", newConsulStorage)
+}
+
+// RegisterBackend registruje backend pod datim imenom
+func RegisterBackend(name string, factory BackendFactory) {
+	if _, exists := backends[name]; exists {
+		panic(fmt.Sprintf("storage backend %q registered twice", name))
+	}
+	backends[name] = factory
+}
+
+// Backends vraća imena svih registrovanih backend-a
+func Backends() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewStorage pravi repozitorijume izabranog backend-a
+func NewStorage(backend string, options map[string]string) (Storage, error) {
+	factory, ok := backends[backend]
+	if !ok {
+		return Storage{}, fmt.Errorf("unknown storage backend %q (available: %s)", backend, strings.Join(Backends(), ", "))
+	}
+	storage, err := factory(options)
+	if err != nil {
+		return Storage{}, fmt.Errorf("storage backend %q: %w", backend, err)
+	}
+	return storage, nil
+}
+
+// checkOptions odbija opcije koje backend ne podržava, da greška u kucanju ne prođe neprimećeno
+func checkOptions(options map[string]string, allowed ...string) error {
+	for key := range options {
+		known := false
+		for _, name := range allowed {
+			if key == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			if len(allowed) == 0 {
+				return fmt.Errorf("unknown option %q (backend takes no options)", key)
+			}
+			return fmt.Errorf("unknown option %q (supported: %s)", key, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+func newMemoryStorage(options map[string]string) (Storage, error) {
+	if err := checkOptions(options); err != nil {
+		return Storage{}, err
+	}
+	return Storage{
+		Configs:      NewConfigInMemRepository(),
+		ConfigGroups: NewConfigGroupInMemRepository(),
+		Schemas:      NewSchemaInMemRepository(),
+		Webhooks:     NewWebhookInMemRepository(),
+		Namespaces:   NewNamespaceInMemRepository(),
+		Audit:        NewAuditInMemRepository(),
+	}, nil
+}
+
+func newFileStorage(options map[string]string) (Storage, error) {
+	if err := checkOptions(options, "dir", "compact_every"); err != nil {
+		return Storage{}, err
+	}
+	dir := options["dir"]
+	if dir == "" {
+		dir = "data"
+	}
+	compactEvery := defaultCompactEvery
+	if value, ok := options["compact_every"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return Storage{}, fmt.Errorf("compact_every must be a positive integer, got %q", value)
+		}
+		compactEvery = n
+	}
+
+	store, err := openFileStore(dir, compactEvery)
+	if err != nil {
+		return Storage{}, err
+	}
+	return Storage{
+		Configs:      NewConfigFileRepository(store),
+		ConfigGroups: NewConfigGroupFileRepository(store),
+		Schemas:      NewSchemaFileRepository(store),
+		Webhooks:     NewWebhookFileRepository(store),
+		Namespaces:   NewNamespaceFileRepository(store),
+		Audit:        NewAuditFileRepository(store),
+	}, nil
+}
+
+func newConsulStorage(options map[string]string) (Storage, error) {
+	if err := checkOptions(options, "address"); err != nil {
+		return Storage{}, err
+	}
+	client, err := NewConsulClient(options["address"])
+	if err != nil {
+		return Storage{}, err
+	}
+	// Proveravamo odmah da li je agent dostupan, umesto da prva greška stigne tek sa prvim zahtevom
+	if _, err := client.Status().Leader(); err != nil {
+		return Storage{}, fmt.Errorf("cannot reach consul agent: %w", err)
+	}
+	return Storage{
+		Configs:      NewConfigConsulRepository(client),
+		ConfigGroups: NewConfigGroupConsulRepository(client),
+		Schemas:      NewSchemaConsulRepository(client),
+		Webhooks:     NewWebhookConsulRepository(client),
+		Namespaces:   NewNamespaceConsulRepository(client),
+		Audit:        NewAuditConsulRepository(client),
+	}, nil
+}
@@ -0,0 +1,151 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"projekat/model"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Prefiks pod kojim se u Consul KV čuvaju šeme
+const schemasPrefix = "schemas/"
+
+type SchemaConsulRepository struct {
+	kv *api.KV
+}
+
+func NewSchemaConsulRepository(client *api.Client) model.SchemaRepository {
+	return &SchemaConsulRepository{
+		kv: client.KV(),
+	}
+}
+
+func (repo *SchemaConsulRepository) Create(schema model.Schema) error {
+	value, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+
+	key := schemasPrefix + configKey(schema.Name, schema.Version)
+	counterKey := schemaVersionsPrefix + schema.Name
+	for attempt := 0; attempt < consulCASRetries; attempt++ {
+		pair, _, err := repo.kv.Get(key, nil)
+		if err != nil {
+			return err
+		}
+		if pair != nil {
+			return model.AlreadyExistsf("schema %s/%d already exists", schema.Name, schema.Version)
+		}
+
+		highest, counterIndex, err := consulHighestVersion(repo.kv, counterKey, schemasPrefix+schema.Name+"/")
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("schema", schema.Name, highest, schema.Version); err != nil {
+			return err
+		}
+
+		ok, err := consulCreateVersioned(repo.kv, counterKey, counterIndex, schema.Version, key, value)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	return model.Conflictf("schema %s was modified concurrently too many times", schema.Name)
+}
+
+func (repo *SchemaConsulRepository) CreateNextVersion(schema model.Schema) (model.Schema, error) {
+	counterKey := schemaVersionsPrefix + schema.Name
+	for attempt := 0; attempt < consulCASRetries; attempt++ {
+		highest, counterIndex, err := consulHighestVersion(repo.kv, counterKey, schemasPrefix+schema.Name+"/")
+		if err != nil {
+			return model.Schema{}, err
+		}
+
+		schema.Version = highest + 1
+		value, err := json.Marshal(schema)
+		if err != nil {
+			return model.Schema{}, err
+		}
+
+		ok, err := consulCreateVersioned(repo.kv, counterKey, counterIndex, schema.Version, schemasPrefix+configKey(schema.Name, schema.Version), value)
+		if err != nil {
+			return model.Schema{}, err
+		}
+		if ok {
+			return schema, nil
+		}
+	}
+	return model.Schema{}, model.Conflictf("schema %s was modified concurrently too many times", schema.Name)
+}
+
+func (repo *SchemaConsulRepository) Get(name string, version int) (model.Schema, error) {
+	pair, _, err := repo.kv.Get(schemasPrefix+configKey(name, version), nil)
+	if err != nil {
+		return model.Schema{}, err
+	}
+	if pair == nil {
+		return model.Schema{}, model.NotFoundf("schema not found")
+	}
+
+	var schema model.Schema
+	if err := json.Unmarshal(pair.Value, &schema); err != nil {
+		return model.Schema{}, fmt.Errorf("cannot decode schema %s: %w", pair.Key, err)
+	}
+	return schema, nil
+}
+
+func (repo *SchemaConsulRepository) Delete(name string, version int) error {
+	key := schemasPrefix + configKey(name, version)
+	pair, _, err := repo.kv.Get(key, nil)
+	if err != nil {
+		return err
+	}
+	if pair == nil {
+		return model.NotFoundf("schema not found")
+	}
+
+	_, err = repo.kv.Delete(key, nil)
+	return err
+}
+
+// GetAll vraća sve šeme
+func (repo *SchemaConsulRepository) GetAll() ([]model.Schema, error) {
+	return repo.list(schemasPrefix)
+}
+
+// ListByName čita iz Consul-a samo ključeve ispod prefiksa imena
+func (repo *SchemaConsulRepository) ListByName(name string) ([]model.Schema, error) {
+	schemas, err := repo.list(schemasPrefix + name + "/")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Version < schemas[j].Version })
+	return schemas, nil
+}
+
+func (repo *SchemaConsulRepository) list(prefix string) ([]model.Schema, error) {
+	pairs, _, err := repo.kv.List(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make([]model.Schema, 0, len(pairs))
+	for _, pair := range pairs {
+		// Preskačemo "direktorijume" koje Consul UI ume da napravi
+		if strings.HasSuffix(pair.Key, "/") {
+			continue
+		}
+		var schema model.Schema
+		if err := json.Unmarshal(pair.Value, &schema); err != nil {
+			return nil, fmt.Errorf("cannot decode schema %s: %w", pair.Key, err)
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, nil
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"projekat/model"
+	"sort"
+)
+
+// SchemaFileRepository čuva šeme u fileStore-u na lokalnom disku
+type SchemaFileRepository struct {
+	store *fileStore
+}
+
+func NewSchemaFileRepository(store *fileStore) model.SchemaRepository {
+	return &SchemaFileRepository{
+		store: store,
+	}
+}
+
+func (repo *SchemaFileRepository) Create(schema model.Schema) error {
+	key := schemasPrefix + configKey(schema.Name, schema.Version)
+	return repo.store.update(func(tx *fileTx) error {
+		if _, exists := tx.get(key); exists {
+			return model.AlreadyExistsf("schema %s/%d already exists", schema.Name, schema.Version)
+		}
+		highest, err := tx.highestVersion(schemaVersionsPrefix+schema.Name, schemasPrefix+schema.Name+"/")
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("schema", schema.Name, highest, schema.Version); err != nil {
+			return err
+		}
+		if err := tx.put(schemaVersionsPrefix+schema.Name, schema.Version); err != nil {
+			return err
+		}
+		return tx.put(key, schema)
+	})
+}
+
+func (repo *SchemaFileRepository) CreateNextVersion(schema model.Schema) (model.Schema, error) {
+	err := repo.store.update(func(tx *fileTx) error {
+		highest, err := tx.highestVersion(schemaVersionsPrefix+schema.Name, schemasPrefix+schema.Name+"/")
+		if err != nil {
+			return err
+		}
+		schema.Version = highest + 1
+		if err := tx.put(schemaVersionsPrefix+schema.Name, schema.Version); err != nil {
+			return err
+		}
+		return tx.put(schemasPrefix+configKey(schema.Name, schema.Version), schema)
+	})
+	if err != nil {
+		return model.Schema{}, err
+	}
+	return schema, nil
+}
+
+func (repo *SchemaFileRepository) Get(name string, version int) (model.Schema, error) {
+	value, ok := repo.store.get(schemasPrefix + configKey(name, version))
+	if !ok {
+		return model.Schema{}, model.NotFoundf("schema not found")
+	}
+	return decodeSchema(value)
+}
+
+func (repo *SchemaFileRepository) Delete(name string, version int) error {
+	key := schemasPrefix + configKey(name, version)
+	return repo.store.update(func(tx *fileTx) error {
+		if _, exists := tx.get(key); !exists {
+			return model.NotFoundf("schema not found")
+		}
+		tx.delete(key)
+		return nil
+	})
+}
+
+// GetAll vraća sve šeme
+func (repo *SchemaFileRepository) GetAll() ([]model.Schema, error) {
+	return repo.list(schemasPrefix)
+}
+
+// ListByName čita samo ključeve ispod prefiksa imena
+func (repo *SchemaFileRepository) ListByName(name string) ([]model.Schema, error) {
+	schemas, err := repo.list(schemasPrefix + name + "/")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Version < schemas[j].Version })
+	return schemas, nil
+}
+
+func (repo *SchemaFileRepository) list(prefix string) ([]model.Schema, error) {
+	values := repo.store.list(prefix)
+	schemas := make([]model.Schema, 0, len(values))
+	for _, value := range values {
+		schema, err := decodeSchema(value)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, nil
+}
+
+func decodeSchema(value json.RawMessage) (model.Schema, error) {
+	var schema model.Schema
+	if err := json.Unmarshal(value, &schema); err != nil {
+		return model.Schema{}, fmt.Errorf("cannot decode schema: %w", err)
+	}
+	return schema, nil
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"projekat/model"
+	"sort"
+	"sync"
+)
+
+// SchemaInMemRepository je bezbedan za istovremeno korišćenje iz više gorutina
+type SchemaInMemRepository struct {
+	mu      sync.RWMutex
+	schemas map[string]model.Schema
+	// Najveća ikad korišćena verzija za svako ime
+	versions map[string]int
+}
+
+func NewSchemaInMemRepository() model.SchemaRepository {
+	return &SchemaInMemRepository{
+		schemas:  make(map[string]model.Schema),
+		versions: make(map[string]int),
+	}
+}
+
+func (repo *SchemaInMemRepository) Create(schema model.Schema) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := configKey(schema.Name, schema.Version)
+	if _, exists := repo.schemas[key]; exists {
+		return model.AlreadyExistsf("schema %s/%d already exists", schema.Name, schema.Version)
+	}
+	if err := checkVersion("schema", schema.Name, repo.versions[schema.Name], schema.Version); err != nil {
+		return err
+	}
+
+	repo.schemas[key] = schema.Clone()
+	repo.versions[schema.Name] = schema.Version
+	return nil
+}
+
+func (repo *SchemaInMemRepository) CreateNextVersion(schema model.Schema) (model.Schema, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	schema.Version = repo.versions[schema.Name] + 1
+	repo.schemas[configKey(schema.Name, schema.Version)] = schema.Clone()
+	repo.versions[schema.Name] = schema.Version
+	return schema, nil
+}
+
+func (repo *SchemaInMemRepository) Get(name string, version int) (model.Schema, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	schema, ok := repo.schemas[configKey(name, version)]
+	if !ok {
+		return model.Schema{}, model.NotFoundf("schema not found")
+	}
+	return schema.Clone(), nil
+}
+
+func (repo *SchemaInMemRepository) Delete(name string, version int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := configKey(name, version)
+	if _, exists := repo.schemas[key]; !exists {
+		return model.NotFoundf("schema not found")
+	}
+	delete(repo.schemas, key)
+	return nil
+}
+
+// GetAll vraća sve šeme
+func (repo *SchemaInMemRepository) GetAll() ([]model.Schema, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	schemas := make([]model.Schema, 0, len(repo.schemas))
+	for _, schema := range repo.schemas {
+		schemas = append(schemas, schema.Clone())
+	}
+	return schemas, nil
+}
+
+func (repo *SchemaInMemRepository) ListByName(name string) ([]model.Schema, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	schemas := make([]model.Schema, 0)
+	for _, schema := range repo.schemas {
+		if schema.Name == name {
+			schemas = append(schemas, schema.Clone())
+		}
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Version < schemas[j].Version })
+	return schemas, nil
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"projekat/model"
+	"strconv"
+	"strings"
+)
+
+// Prefiksi pod kojima backend-i čuvaju najveću ikad dodeljenu verziju za svako ime.
+// Brojač se ne smanjuje pri brisanju, pa se obrisana verzija nikad ne dodeljuje ponovo.
+const (
+	configVersionsPrefix      = "configVersions/"
+	configGroupVersionsPrefix = "configGroupVersions/"
+	schemaVersionsPrefix      = "schemaVersions/"
+)
+
+// checkVersion proverava verziju koju je klijent sam izabrao. Verzije su nepromenljive i ne smeju
+// se ponavljati, pa se prihvata samo verzija veća od svih do sada korišćenih za to ime.
+// Kada verziju dodeljuje server, nova verzija je uvek highest+1.
+func checkVersion(kind, name string, highest, requested int) error {
+	if requested <= highest {
+		return model.Conflictf("%s %s: version %d is not greater than the latest used version %d", kind, name, requested, highest)
+	}
+	return nil
+}
+
+// checkDeleteRevision proverava reviziju zapisa pre brisanja; expected 0 znači brisanje bez uslova
+func checkDeleteRevision(kind, name string, version int, stored, expected int64) error {
+	if expected == 0 {
+		return nil
+	}
+	return model.CheckRevision(kind, name, version, stored, expected)
+}
+
+// versionFromKey izdvaja verziju iz ključa oblika prefiks + ime + "/" + verzija
+func versionFromKey(key, prefix string) (int, bool) {
+	version, err := strconv.Atoi(strings.TrimPrefix(key, prefix))
+	if err != nil {
+		return 0, false
+	}
+	return version, true
+}
+
+// highestVersion vraća najveću verziju među ključevima ispod prefiksa (ime + "/")
+func highestVersion(keys []string, prefix string) int {
+	highest := 0
+	for _, key := range keys {
+		if version, ok := versionFromKey(key, prefix); ok && version > highest {
+			highest = version
+		}
+	}
+	return highest
+}
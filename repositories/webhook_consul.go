@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"projekat/model"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Prefiksi pod kojima se u Consul KV čuvaju pretplate i neuspele isporuke
+const (
+	webhooksPrefix    = "webhooks/"
+	deadLettersPrefix = "webhookDeadLetters/"
+)
+
+type WebhookConsulRepository struct {
+	kv *api.KV
+}
+
+func NewWebhookConsulRepository(client *api.Client) model.WebhookRepository {
+	return &WebhookConsulRepository{
+		kv: client.KV(),
+	}
+}
+
+func (repo *WebhookConsulRepository) Create(webhook model.Webhook) error {
+	value, err := json.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+
+	// ModifyIndex 0 upisuje ključ samo ako još ne postoji
+	ok, _, err := repo.kv.CAS(&api.KVPair{Key: webhooksPrefix + webhook.ID, Value: value, ModifyIndex: 0}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return model.AlreadyExistsf("webhook %s already exists", webhook.ID)
+	}
+	return nil
+}
+
+func (repo *WebhookConsulRepository) Update(webhook model.Webhook) error {
+	key := webhooksPrefix + webhook.ID
+	pair, _, err := repo.kv.Get(key, nil)
+	if err != nil {
+		return err
+	}
+	if pair == nil {
+		return model.NotFoundf("webhook not found")
+	}
+
+	value, err := json.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+	ok, _, err := repo.kv.CAS(&api.KVPair{Key: key, Value: value, ModifyIndex: pair.ModifyIndex}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return model.Conflictf("webhook %s was modified concurrently", webhook.ID)
+	}
+	return nil
+}
+
+func (repo *WebhookConsulRepository) Get(id string) (model.Webhook, error) {
+	var webhook model.Webhook
+	if err := repo.get(webhooksPrefix+id, "webhook not found", &webhook); err != nil {
+		return model.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (repo *WebhookConsulRepository) Delete(id string) error {
+	return repo.delete(webhooksPrefix+id, "webhook not found")
+}
+
+func (repo *WebhookConsulRepository) GetAll() ([]model.Webhook, error) {
+	webhooks := make([]model.Webhook, 0)
+	err := repo.list(webhooksPrefix, func(value []byte) error {
+		var webhook model.Webhook
+		if err := json.Unmarshal(value, &webhook); err != nil {
+			return err
+		}
+		webhooks = append(webhooks, webhook)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (repo *WebhookConsulRepository) AddDeadLetter(letter model.DeadLetter) error {
+	value, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	_, err = repo.kv.Put(&api.KVPair{Key: deadLettersPrefix + letter.ID, Value: value}, nil)
+	return err
+}
+
+func (repo *WebhookConsulRepository) GetDeadLetter(id string) (model.DeadLetter, error) {
+	var letter model.DeadLetter
+	if err := repo.get(deadLettersPrefix+id, "dead letter not found", &letter); err != nil {
+		return model.DeadLetter{}, err
+	}
+	return letter, nil
+}
+
+func (repo *WebhookConsulRepository) DeleteDeadLetter(id string) error {
+	return repo.delete(deadLettersPrefix+id, "dead letter not found")
+}
+
+func (repo *WebhookConsulRepository) GetAllDeadLetters() ([]model.DeadLetter, error) {
+	letters := make([]model.DeadLetter, 0)
+	err := repo.list(deadLettersPrefix, func(value []byte) error {
+		var letter model.DeadLetter
+		if err := json.Unmarshal(value, &letter); err != nil {
+			return err
+		}
+		letters = append(letters, letter)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return letters, nil
+}
+
+func (repo *WebhookConsulRepository) get(key, notFound string, target interface{}) error {
+	pair, _, err := repo.kv.Get(key, nil)
+	if err != nil {
+		return err
+	}
+	if pair == nil {
+		return model.NotFoundf(notFound)
+	}
+	if err := json.Unmarshal(pair.Value, target); err != nil {
+		return fmt.Errorf("cannot decode %s: %w", pair.Key, err)
+	}
+	return nil
+}
+
+func (repo *WebhookConsulRepository) delete(key, notFound string) error {
+	pair, _, err := repo.kv.Get(key, nil)
+	if err != nil {
+		return err
+	}
+	if pair == nil {
+		return model.NotFoundf(notFound)
+	}
+
+	_, err = repo.kv.Delete(key, nil)
+	return err
+}
+
+func (repo *WebhookConsulRepository) list(prefix string, decode func(value []byte) error) error {
+	pairs, _, err := repo.kv.List(prefix, nil)
+	if err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		// Preskačemo "direktorijume" koje Consul UI ume da napravi
+		if strings.HasSuffix(pair.Key, "/") {
+			continue
+		}
+		if err := decode(pair.Value); err != nil {
+			return fmt.Errorf("cannot decode %s: %w", pair.Key, err)
+		}
+	}
+	return nil
+}
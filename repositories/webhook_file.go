@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"projekat/model"
+)
+
+// WebhookFileRepository čuva pretplate i neuspele isporuke u fileStore-u na lokalnom disku
+type WebhookFileRepository struct {
+	store *fileStore
+}
+
+func NewWebhookFileRepository(store *fileStore) model.WebhookRepository {
+	return &WebhookFileRepository{
+		store: store,
+	}
+}
+
+func (repo *WebhookFileRepository) Create(webhook model.Webhook) error {
+	key := webhooksPrefix + webhook.ID
+	return repo.store.update(func(tx *fileTx) error {
+		if _, exists := tx.get(key); exists {
+			return model.AlreadyExistsf("webhook %s already exists", webhook.ID)
+		}
+		return tx.put(key, webhook)
+	})
+}
+
+func (repo *WebhookFileRepository) Update(webhook model.Webhook) error {
+	key := webhooksPrefix + webhook.ID
+	return repo.store.update(func(tx *fileTx) error {
+		if _, exists := tx.get(key); !exists {
+			return model.NotFoundf("webhook not found")
+		}
+		return tx.put(key, webhook)
+	})
+}
+
+func (repo *WebhookFileRepository) Get(id string) (model.Webhook, error) {
+	value, ok := repo.store.get(webhooksPrefix + id)
+	if !ok {
+		return model.Webhook{}, model.NotFoundf("webhook not found")
+	}
+	var webhook model.Webhook
+	if err := json.Unmarshal(value, &webhook); err != nil {
+		return model.Webhook{}, fmt.Errorf("cannot decode webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (repo *WebhookFileRepository) Delete(id string) error {
+	return repo.delete(webhooksPrefix+id, "webhook not found")
+}
+
+func (repo *WebhookFileRepository) GetAll() ([]model.Webhook, error) {
+	values := repo.store.list(webhooksPrefix)
+	webhooks := make([]model.Webhook, 0, len(values))
+	for _, value := range values {
+		var webhook model.Webhook
+		if err := json.Unmarshal(value, &webhook); err != nil {
+			return nil, fmt.Errorf("cannot decode webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+func (repo *WebhookFileRepository) AddDeadLetter(letter model.DeadLetter) error {
+	return repo.store.update(func(tx *fileTx) error {
+		return tx.put(deadLettersPrefix+letter.ID, letter)
+	})
+}
+
+func (repo *WebhookFileRepository) GetDeadLetter(id string) (model.DeadLetter, error) {
+	value, ok := repo.store.get(deadLettersPrefix + id)
+	if !ok {
+		return model.DeadLetter{}, model.NotFoundf("dead letter not found")
+	}
+	var letter model.DeadLetter
+	if err := json.Unmarshal(value, &letter); err != nil {
+		return model.DeadLetter{}, fmt.Errorf("cannot decode dead letter: %w", err)
+	}
+	return letter, nil
+}
+
+func (repo *WebhookFileRepository) DeleteDeadLetter(id string) error {
+	return repo.delete(deadLettersPrefix+id, "dead letter not found")
+}
+
+func (repo *WebhookFileRepository) GetAllDeadLetters() ([]model.DeadLetter, error) {
+	values := repo.store.list(deadLettersPrefix)
+	letters := make([]model.DeadLetter, 0, len(values))
+	for _, value := range values {
+		var letter model.DeadLetter
+		if err := json.Unmarshal(value, &letter); err != nil {
+			return nil, fmt.Errorf("cannot decode dead letter: %w", err)
+		}
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
+
+func (repo *WebhookFileRepository) delete(key, notFound string) error {
+	return repo.store.update(func(tx *fileTx) error {
+		if _, exists := tx.get(key); !exists {
+			return model.NotFoundf(notFound)
+		}
+		tx.delete(key)
+		return nil
+	})
+}
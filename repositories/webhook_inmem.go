@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"projekat/model"
+	"sync"
+)
+
+// WebhookInMemRepository je bezbedan za istovremeno korišćenje iz više gorutina
+type WebhookInMemRepository struct {
+	mu          sync.RWMutex
+	webhooks    map[string]model.Webhook
+	deadLetters map[string]model.DeadLetter
+}
+
+func NewWebhookInMemRepository() model.WebhookRepository {
+	return &WebhookInMemRepository{
+		webhooks:    make(map[string]model.Webhook),
+		deadLetters: make(map[string]model.DeadLetter),
+	}
+}
+
+func (repo *WebhookInMemRepository) Create(webhook model.Webhook) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, exists := repo.webhooks[webhook.ID]; exists {
+		return model.AlreadyExistsf("webhook %s already exists", webhook.ID)
+	}
+	repo.webhooks[webhook.ID] = webhook.Clone()
+	return nil
+}
+
+func (repo *WebhookInMemRepository) Update(webhook model.Webhook) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, exists := repo.webhooks[webhook.ID]; !exists {
+		return model.NotFoundf("webhook not found")
+	}
+	repo.webhooks[webhook.ID] = webhook.Clone()
+	return nil
+}
+
+func (repo *WebhookInMemRepository) Get(id string) (model.Webhook, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	webhook, ok := repo.webhooks[id]
+	if !ok {
+		return model.Webhook{}, model.NotFoundf("webhook not found")
+	}
+	return webhook.Clone(), nil
+}
+
+func (repo *WebhookInMemRepository) Delete(id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, exists := repo.webhooks[id]; !exists {
+		return model.NotFoundf("webhook not found")
+	}
+	delete(repo.webhooks, id)
+	return nil
+}
+
+func (repo *WebhookInMemRepository) GetAll() ([]model.Webhook, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	webhooks := make([]model.Webhook, 0, len(repo.webhooks))
+	for _, webhook := range repo.webhooks {
+		webhooks = append(webhooks, webhook.Clone())
+	}
+	return webhooks, nil
+}
+
+func (repo *WebhookInMemRepository) AddDeadLetter(letter model.DeadLetter) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.deadLetters[letter.ID] = letter.Clone()
+	return nil
+}
+
+func (repo *WebhookInMemRepository) GetDeadLetter(id string) (model.DeadLetter, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	letter, ok := repo.deadLetters[id]
+	if !ok {
+		return model.DeadLetter{}, model.NotFoundf("dead letter not found")
+	}
+	return letter.Clone(), nil
+}
+
+func (repo *WebhookInMemRepository) DeleteDeadLetter(id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, exists := repo.deadLetters[id]; !exists {
+		return model.NotFoundf("dead letter not found")
+	}
+	delete(repo.deadLetters, id)
+	return nil
+}
+
+func (repo *WebhookInMemRepository) GetAllDeadLetters() ([]model.DeadLetter, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	letters := make([]model.DeadLetter, 0, len(repo.deadLetters))
+	for _, letter := range repo.deadLetters {
+		letters = append(letters, letter.Clone())
+	}
+	return letters, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"projekat/audit"
+	"projekat/repositories"
+	"projekat/secrets"
+)
+
+// rotateKeys je komanda "rotate-keys": ponovo šifruje aktivnim glavnim ključem svaku tajnu
+// sačuvanu u backend-u. Prima iste opcije kao server; server za isti backend ne treba da radi
+// za vreme rotacije. Svaki ponovo šifrovan zapis se upisuje u audit log. Posle uspešne rotacije
+// stari ključevi se mogu ukloniti iz fajla.
+func rotateKeys(args []string) error {
+	opts, err := parseOptions(args)
+	if err != nil {
+		return err
+	}
+	if opts.masterKeyFile == "" {
+		return errors.New("rotate-keys requires -master-key-file (or MASTER_KEY_FILE)")
+	}
+	keyring, err := secrets.LoadKeyring(opts.masterKeyFile)
+	if err != nil {
+		return err
+	}
+
+	storage, err := repositories.NewStorage(opts.storage, opts.storageOptions)
+	if err != nil {
+		return err
+	}
+
+	auditLog, err := audit.NewLog(storage.Audit)
+	if err != nil {
+		return err
+	}
+
+	report, err := secrets.Rotate(storage.Configs, storage.ConfigGroups, storage.Webhooks, storage.Namespaces, keyring, auditLog)
+	if err != nil {
+		return err
+	}
+	log.Printf("Re-encrypted %d secrets in %d configs, %d config groups and %d webhooks with key %s",
+		report.Secrets, report.Configs, report.ConfigGroups, report.Webhooks, keyring.ActiveKeyID())
+	return nil
+}
+
+// loadKeyring učitava glavne ključeve servera. Privremeni ključ se koristi samo za backend u memoriji;
+// trajni backend bez fajla sa ključevima se odbija, jer tajne posle restarta ne bi mogle da se pročitaju.
+func loadKeyring(opts options) (*secrets.Keyring, error) {
+	if opts.masterKeyFile != "" {
+		return secrets.LoadKeyring(opts.masterKeyFile)
+	}
+	if opts.storage != "memory" {
+		return nil, fmt.Errorf("storage backend %q requires -master-key-file (or MASTER_KEY_FILE): secrets encrypted with an ephemeral key cannot be read after a restart", opts.storage)
+	}
+	log.Printf("No master key file configured; secret parameters are encrypted with an ephemeral key")
+	return secrets.EphemeralKeyring()
+}
@@ -0,0 +1,103 @@
+package secrets
+
+import (
+	"fmt"
+	"projekat/model"
+)
+
+// secretAAD je mesto tajne vrednosti: prostor imena, ime konfiguracije i ime parametra. Verzija
+// nije deo mesta jer je pri CreateNextVersion dodeljuje tek repozitorijum. Za podrazumevani
+// prostor imena mesto je isto kao pre uvođenja prostora imena, pa se stare vrednosti i dalje čitaju.
+func secretAAD(namespace, configName, parameter string) string {
+	return model.NamespacePrefix(namespace) + configName + "/" + parameter
+}
+
+// EncryptConfig vraća kopiju konfiguracije u kojoj su tajni parametri premešteni iz
+// Parameters u Encrypted. Eventualne šifrovane vrednosti koje je poslao klijent se odbacuju.
+func (k *Keyring) EncryptConfig(config model.Config) (model.Config, error) {
+	config = config.Clone()
+	config.Encrypted = nil
+	for _, name := range config.Secrets {
+		value, ok := config.Parameters[name]
+		if !ok {
+			continue
+		}
+		encrypted, err := k.Encrypt(value, secretAAD(config.Namespace, config.Name, name))
+		if err != nil {
+			return model.Config{}, fmt.Errorf("cannot encrypt secret parameter %s of config %s/%d: %w", name, config.Name, config.Version, err)
+		}
+		if config.Encrypted == nil {
+			config.Encrypted = make(map[string]model.EncryptedValue)
+		}
+		config.Encrypted[name] = encrypted
+		delete(config.Parameters, name)
+	}
+	return config, nil
+}
+
+// DecryptConfig vraća tajne parametre iz Encrypted u Parameters
+func (k *Keyring) DecryptConfig(config model.Config) (model.Config, error) {
+	if len(config.Encrypted) == 0 {
+		config.Encrypted = nil
+		return config, nil
+	}
+
+	config = config.Clone()
+	if config.Parameters == nil {
+		config.Parameters = make(model.Parameters, len(config.Encrypted))
+	}
+	for name, encrypted := range config.Encrypted {
+		value, err := k.Decrypt(encrypted, secretAAD(config.Namespace, config.Name, name))
+		if err != nil {
+			return model.Config{}, fmt.Errorf("cannot decrypt secret parameter %s of config %s/%d: %w", name, config.Name, config.Version, err)
+		}
+		config.Parameters[name] = value
+	}
+	config.Encrypted = nil
+	return config, nil
+}
+
+func (k *Keyring) decryptConfigs(configs []model.Config) ([]model.Config, error) {
+	for i, config := range configs {
+		decrypted, err := k.DecryptConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		configs[i] = decrypted
+	}
+	return configs, nil
+}
+
+// EncryptConfigGroup šifruje tajne parametre ugrađenih konfiguracija grupe
+func (k *Keyring) EncryptConfigGroup(configGroup model.ConfigGroup) (model.ConfigGroup, error) {
+	configGroup = configGroup.Clone()
+	for i, config := range configGroup.Configuration {
+		encrypted, err := k.EncryptConfig(config)
+		if err != nil {
+			return model.ConfigGroup{}, err
+		}
+		configGroup.Configuration[i] = encrypted
+	}
+	return configGroup, nil
+}
+
+// DecryptConfigGroup dešifruje tajne parametre ugrađenih konfiguracija grupe
+func (k *Keyring) DecryptConfigGroup(configGroup model.ConfigGroup) (model.ConfigGroup, error) {
+	configuration, err := k.decryptConfigs(configGroup.Configuration)
+	if err != nil {
+		return model.ConfigGroup{}, fmt.Errorf("config group %s/%d: %w", configGroup.Name, configGroup.Version, err)
+	}
+	configGroup.Configuration = configuration
+	return configGroup, nil
+}
+
+func (k *Keyring) decryptConfigGroups(configGroups []model.ConfigGroup) ([]model.ConfigGroup, error) {
+	for i, configGroup := range configGroups {
+		decrypted, err := k.DecryptConfigGroup(configGroup)
+		if err != nil {
+			return nil, err
+		}
+		configGroups[i] = decrypted
+	}
+	return configGroups, nil
+}
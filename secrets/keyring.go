@@ -0,0 +1,188 @@
+// Package secrets šifruje tajne parametre konfiguracija pre nego što stignu u repozitorijum.
+//
+// Koristi se envelope šifrovanje: svaka vrednost se šifruje novim slučajnim ključem podataka
+// (AES-256-GCM), a ključ podataka glavnim ključem iz lokalnog fajla. Pri rotaciji se glavni
+// ključ menja, a svaka sačuvana tajna ponovo šifruje.
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"projekat/model"
+	"strings"
+)
+
+const keySize = 32
+
+// Keyring sadrži glavne ključeve po oznaci. Aktivnim ključem se šifruju nove vrednosti,
+// a ostali ključevi služe samo za čitanje vrednosti šifrovanih pre rotacije.
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+// LoadKeyring čita fajl sa glavnim ključevima. Svaki red ima oblik "oznaka:ključ", gde je ključ
+// 32 bajta kodirana u base64; prvi ključ u fajlu je aktivni. Prazni redovi i redovi koji
+// počinju sa # se preskaču.
+func LoadKeyring(path string) (*Keyring, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	keyring := &Keyring{keys: make(map[string][]byte)}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		id, encoded, ok := strings.Cut(text, ":")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" {
+			return nil, fmt.Errorf("%s:%d: expected id:base64-key", path, line)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: key %s is not valid base64: %w", path, line, id, err)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("%s:%d: key %s must be %d bytes, got %d", path, line, id, keySize, len(key))
+		}
+		if _, exists := keyring.keys[id]; exists {
+			return nil, fmt.Errorf("%s:%d: duplicate key id %s", path, line, id)
+		}
+		keyring.keys[id] = key
+		if keyring.active == "" {
+			keyring.active = id
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if keyring.active == "" {
+		return nil, fmt.Errorf("%s: no master keys", path)
+	}
+	return keyring, nil
+}
+
+// EphemeralKeyring pravi privremeni glavni ključ koji postoji samo dok proces radi
+func EphemeralKeyring() (*Keyring, error) {
+	key, err := randomBytes(keySize)
+	if err != nil {
+		return nil, err
+	}
+	id, err := randomBytes(4)
+	if err != nil {
+		return nil, err
+	}
+	active := "ephemeral-" + hex.EncodeToString(id)
+	return &Keyring{active: active, keys: map[string][]byte{active: key}}, nil
+}
+
+// ActiveKeyID vraća oznaku ključa kojim se šifruju nove vrednosti
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// Encrypt šifruje JSON vrednost aktivnim ključem. aad vezuje šifrat za mesto na kojem se
+// čuva, pa se šifrovana vrednost ne može prepisati u drugi parametar.
+func (k *Keyring) Encrypt(value interface{}, aad string) (model.EncryptedValue, error) {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return model.EncryptedValue{}, err
+	}
+
+	dataKey, err := randomBytes(keySize)
+	if err != nil {
+		return model.EncryptedValue{}, err
+	}
+	ciphertext, err := seal(dataKey, plaintext, []byte(aad))
+	if err != nil {
+		return model.EncryptedValue{}, err
+	}
+	wrapped, err := seal(k.keys[k.active], dataKey, []byte(k.active+"\x00"+aad))
+	if err != nil {
+		return model.EncryptedValue{}, err
+	}
+	return model.EncryptedValue{KeyID: k.active, DataKey: wrapped, Ciphertext: ciphertext}, nil
+}
+
+// Decrypt vraća JSON vrednost (brojevi kao json.Number, kao i u model.Parameters)
+func (k *Keyring) Decrypt(encrypted model.EncryptedValue, aad string) (interface{}, error) {
+	masterKey, ok := k.keys[encrypted.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown master key %q", encrypted.KeyID)
+	}
+	dataKey, err := open(masterKey, encrypted.DataKey, []byte(encrypted.KeyID+"\x00"+aad))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(dataKey, encrypted.Ciphertext, []byte(aad))
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(plaintext))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// seal šifruje AES-GCM-om i vraća nonce ispred šifrata
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomBytes(aead.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(key, sealed, aad []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad)
+	if err != nil {
+		return nil, errors.New("message authentication failed")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
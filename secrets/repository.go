@@ -0,0 +1,272 @@
+package secrets
+
+import "projekat/model"
+
+// ConfigRepository šifruje tajne parametre pre upisa u repozitorijum i dešifruje ih pri čitanju
+type ConfigRepository struct {
+	repo      model.ConfigRepository
+	keyring   *Keyring
+	namespace string
+}
+
+func NewConfigRepository(repo model.ConfigRepository, keyring *Keyring) model.ConfigRepository {
+	return &ConfigRepository{
+		repo:      repo,
+		keyring:   keyring,
+		namespace: model.DefaultNamespace,
+	}
+}
+
+func (r *ConfigRepository) In(namespace string) model.ConfigRepository {
+	return &ConfigRepository{
+		repo:      r.repo.In(namespace),
+		keyring:   r.keyring,
+		namespace: namespace,
+	}
+}
+
+// encrypt šifruje tajne vezujući ih za prostor imena repozitorijuma, a ne za onaj iz zahteva
+func (r *ConfigRepository) encrypt(config model.Config) (model.Config, error) {
+	config.Namespace = r.namespace
+	return r.keyring.EncryptConfig(config)
+}
+
+// Izmene vraćaju Change repozitorijuma ispod, sa šifrovanim zapisima onakvim kakvi su sačuvani
+func (r *ConfigRepository) Create(config model.Config) (model.Change, error) {
+	encrypted, err := r.encrypt(config)
+	if err != nil {
+		return model.Change{}, err
+	}
+	return r.repo.Create(encrypted)
+}
+
+func (r *ConfigRepository) CreateNextVersion(config model.Config) (model.Config, model.Change, error) {
+	encrypted, err := r.encrypt(config)
+	if err != nil {
+		return model.Config{}, model.Change{}, err
+	}
+	created, change, err := r.repo.CreateNextVersion(encrypted)
+	if err != nil {
+		return model.Config{}, model.Change{}, err
+	}
+	decrypted, err := r.keyring.DecryptConfig(created)
+	if err != nil {
+		return model.Config{}, model.Change{}, err
+	}
+	return decrypted, change, nil
+}
+
+func (r *ConfigRepository) Read(name string, version int) (model.Config, error) {
+	return r.Get(name, version)
+}
+
+func (r *ConfigRepository) Update(config model.Config) (model.Change, error) {
+	encrypted, err := r.encrypt(config)
+	if err != nil {
+		return model.Change{}, err
+	}
+	return r.repo.Update(encrypted)
+}
+
+func (r *ConfigRepository) Delete(name string, version int, expectedRevision int64) (model.Change, error) {
+	return r.repo.Delete(name, version, expectedRevision)
+}
+
+func (r *ConfigRepository) Add(config model.Config) (model.Change, error) {
+	encrypted, err := r.encrypt(config)
+	if err != nil {
+		return model.Change{}, err
+	}
+	return r.repo.Add(encrypted)
+}
+
+func (r *ConfigRepository) Get(name string, version int) (model.Config, error) {
+	config, err := r.repo.Get(name, version)
+	if err != nil {
+		return model.Config{}, err
+	}
+	return r.keyring.DecryptConfig(config)
+}
+
+func (r *ConfigRepository) GetAll() ([]model.Config, error) {
+	configs, err := r.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return r.keyring.decryptConfigs(configs)
+}
+
+func (r *ConfigRepository) ListByName(name string) ([]model.Config, error) {
+	configs, err := r.repo.ListByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return r.keyring.decryptConfigs(configs)
+}
+
+// List dešifruje samo konfiguracije sa tražene strane
+func (r *ConfigRepository) List(query model.ListQuery) (model.ConfigPage, error) {
+	page, err := r.repo.List(query)
+	if err != nil {
+		return model.ConfigPage{}, err
+	}
+	page.Items, err = r.keyring.decryptConfigs(page.Items)
+	if err != nil {
+		return model.ConfigPage{}, err
+	}
+	return page, nil
+}
+
+// ConfigGroupRepository šifruje tajne parametre ugrađenih konfiguracija grupa.
+// Reference se čuvaju bez vrednosti, pa ih nije potrebno šifrovati.
+type ConfigGroupRepository struct {
+	repo      model.ConfigGroupRepository
+	keyring   *Keyring
+	namespace string
+}
+
+func NewConfigGroupRepository(repo model.ConfigGroupRepository, keyring *Keyring) model.ConfigGroupRepository {
+	return &ConfigGroupRepository{
+		repo:      repo,
+		keyring:   keyring,
+		namespace: model.DefaultNamespace,
+	}
+}
+
+func (r *ConfigGroupRepository) In(namespace string) model.ConfigGroupRepository {
+	return &ConfigGroupRepository{
+		repo:      r.repo.In(namespace),
+		keyring:   r.keyring,
+		namespace: namespace,
+	}
+}
+
+func (r *ConfigGroupRepository) encrypt(configGroup model.ConfigGroup) (model.ConfigGroup, error) {
+	return r.keyring.EncryptConfigGroup(configGroup.InNamespace(r.namespace))
+}
+
+func (r *ConfigGroupRepository) Create(configGroup model.ConfigGroup) (model.Change, error) {
+	encrypted, err := r.encrypt(configGroup)
+	if err != nil {
+		return model.Change{}, err
+	}
+	return r.repo.Create(encrypted)
+}
+
+func (r *ConfigGroupRepository) CreateNextVersion(configGroup model.ConfigGroup) (model.ConfigGroup, model.Change, error) {
+	encrypted, err := r.encrypt(configGroup)
+	if err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	created, change, err := r.repo.CreateNextVersion(encrypted)
+	if err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	decrypted, err := r.keyring.DecryptConfigGroup(created)
+	if err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	return decrypted, change, nil
+}
+
+func (r *ConfigGroupRepository) Read(name string, version int) (model.ConfigGroup, error) {
+	return r.Get(name, version)
+}
+
+func (r *ConfigGroupRepository) Update(configGroup model.ConfigGroup) (model.Change, error) {
+	encrypted, err := r.encrypt(configGroup)
+	if err != nil {
+		return model.Change{}, err
+	}
+	return r.repo.Update(encrypted)
+}
+
+func (r *ConfigGroupRepository) Delete(name string, version int, expectedRevision int64) (model.Change, error) {
+	return r.repo.Delete(name, version, expectedRevision)
+}
+
+func (r *ConfigGroupRepository) GetAll() ([]model.ConfigGroup, error) {
+	configGroups, err := r.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return r.keyring.decryptConfigGroups(configGroups)
+}
+
+func (r *ConfigGroupRepository) ListByName(name string) ([]model.ConfigGroup, error) {
+	configGroups, err := r.repo.ListByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return r.keyring.decryptConfigGroups(configGroups)
+}
+
+// List dešifruje samo grupe sa tražene strane
+func (r *ConfigGroupRepository) List(query model.ListQuery) (model.ConfigGroupPage, error) {
+	page, err := r.repo.List(query)
+	if err != nil {
+		return model.ConfigGroupPage{}, err
+	}
+	page.Items, err = r.keyring.decryptConfigGroups(page.Items)
+	if err != nil {
+		return model.ConfigGroupPage{}, err
+	}
+	return page, nil
+}
+
+func (r *ConfigGroupRepository) Add(configGroup model.ConfigGroup) (model.Change, error) {
+	encrypted, err := r.encrypt(configGroup)
+	if err != nil {
+		return model.Change{}, err
+	}
+	return r.repo.Add(encrypted)
+}
+
+func (r *ConfigGroupRepository) Get(name string, version int) (model.ConfigGroup, error) {
+	configGroup, err := r.repo.Get(name, version)
+	if err != nil {
+		return model.ConfigGroup{}, err
+	}
+	return r.keyring.DecryptConfigGroup(configGroup)
+}
+
+func (r *ConfigGroupRepository) RemoveConfig(groupName string, groupVersion int, configName string, configVersion int) (model.Change, error) {
+	return r.repo.RemoveConfig(groupName, groupVersion, configName, configVersion)
+}
+
+func (r *ConfigGroupRepository) AddConfig(groupName string, groupVersion int, config model.Config) (model.ConfigGroup, model.Change, error) {
+	config.Namespace = r.namespace
+	encrypted, err := r.keyring.EncryptConfig(config)
+	if err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	configGroup, change, err := r.repo.AddConfig(groupName, groupVersion, encrypted)
+	if err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	decrypted, err := r.keyring.DecryptConfigGroup(configGroup)
+	if err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	return decrypted, change, nil
+}
+
+func (r *ConfigGroupRepository) AddReference(groupName string, groupVersion int, reference model.ConfigReference) (model.Change, error) {
+	return r.repo.AddReference(groupName, groupVersion, reference)
+}
+
+func (r *ConfigGroupRepository) RemoveReference(groupName string, groupVersion int, configName string, configVersion int) (model.Change, error) {
+	return r.repo.RemoveReference(groupName, groupVersion, configName, configVersion)
+}
+
+func (r *ConfigGroupRepository) RemoveConfigsByLabels(groupName string, groupVersion int, selector map[string]string) ([]model.Config, model.Change, error) {
+	removed, change, err := r.repo.RemoveConfigsByLabels(groupName, groupVersion, selector)
+	if err != nil {
+		return nil, model.Change{}, err
+	}
+	decrypted, err := r.keyring.decryptConfigs(removed)
+	if err != nil {
+		return nil, model.Change{}, err
+	}
+	return decrypted, change, nil
+}
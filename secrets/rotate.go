@@ -0,0 +1,153 @@
+package secrets
+
+import (
+	"fmt"
+	"projekat/audit"
+	"projekat/events"
+	"projekat/model"
+)
+
+// RotationOperation je operacija kojom rotacija upisuje ponovo šifrovane zapise u audit log
+const RotationOperation = "rotateKeys"
+
+// RotationReport broji šta je rotacija ponovo šifrovala
+type RotationReport struct {
+	Configs      int
+	ConfigGroups int
+	Webhooks     int
+	Secrets      int
+}
+
+// Rotate ponovo šifruje aktivnim ključem svaku tajnu sačuvanu u repozitorijumima, u svim prostorima
+// imena. Prima repozitorijume backend-a, ne dekoratore iz ovog paketa, jer radi direktno sa šifrovanim
+// vrednostima. Vrednost šifrovana nepoznatim ključem prekida rotaciju sa greškom.
+// Ponovo šifrovan zapis dobija novu reviziju (i ETag) kao i svaka izmena, pa se svaka konfiguracija
+// i grupa upisuje u auditLog kao izmena sistema sa operacijom rotateKeys.
+func Rotate(configRepo model.ConfigRepository, groupRepo model.ConfigGroupRepository, webhookRepo model.WebhookRepository, namespaceRepo model.NamespaceRepository, keyring *Keyring, auditLog *audit.Log) (RotationReport, error) {
+	var report RotationReport
+
+	namespaces, err := namespaceRepo.GetAll()
+	if err != nil {
+		return report, err
+	}
+	names := []string{model.DefaultNamespace}
+	for _, namespace := range namespaces {
+		if namespace.Name != model.DefaultNamespace {
+			names = append(names, namespace.Name)
+		}
+	}
+	for _, namespace := range names {
+		if err := keyring.rotateNamespace(configRepo.In(namespace), groupRepo.In(namespace), rotationTrail{log: auditLog, namespace: namespace}, &report); err != nil {
+			return report, fmt.Errorf("namespace %s: %w", namespace, err)
+		}
+	}
+
+	webhooks, err := webhookRepo.GetAll()
+	if err != nil {
+		return report, err
+	}
+	for _, webhook := range webhooks {
+		if webhook.EncryptedSecret == nil {
+			continue
+		}
+		decrypted, err := keyring.DecryptWebhook(webhook)
+		if err != nil {
+			return report, err
+		}
+		rotated, err := keyring.EncryptWebhook(decrypted)
+		if err != nil {
+			return report, err
+		}
+		if err := webhookRepo.Update(rotated); err != nil {
+			return report, fmt.Errorf("cannot store webhook %s: %w", webhook.ID, err)
+		}
+		report.Webhooks++
+		report.Secrets++
+	}
+	return report, nil
+}
+
+// rotationTrail upisuje ponovo šifrovane zapise jednog prostora imena u audit log
+type rotationTrail struct {
+	log       *audit.Log
+	namespace string
+}
+
+// record upisuje sačuvanu izmenu; neuspeh prekida rotaciju, jer bi izmena ostala bez zapisa
+func (t rotationTrail) record(kind events.Kind, name string, version int, stored model.Change) error {
+	if t.log == nil {
+		return nil
+	}
+	change := audit.Change{Action: model.AuditUpdate, Operation: RotationOperation, Kind: kind, Namespace: t.namespace, Name: name, Version: version, Before: stored.Before, After: stored.After}
+	if _, err := t.log.Record(audit.System, change); err != nil {
+		return fmt.Errorf("%s/%d was re-encrypted but not recorded: %w", name, version, err)
+	}
+	return nil
+}
+
+// rotateNamespace ponovo šifruje tajne konfiguracija i grupa jednog prostora imena
+func (k *Keyring) rotateNamespace(configRepo model.ConfigRepository, groupRepo model.ConfigGroupRepository, trail rotationTrail, report *RotationReport) error {
+	configs, err := configRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	for _, config := range configs {
+		if len(config.Encrypted) == 0 {
+			continue
+		}
+		rotated, err := k.rotateConfig(config)
+		if err != nil {
+			return err
+		}
+		stored, err := configRepo.Update(rotated)
+		if err != nil {
+			return fmt.Errorf("cannot store config %s/%d: %w", config.Name, config.Version, err)
+		}
+		if err := trail.record(events.KindConfig, config.Name, config.Version, stored); err != nil {
+			return fmt.Errorf("config %w", err)
+		}
+		report.Configs++
+		report.Secrets += len(rotated.Encrypted)
+	}
+
+	configGroups, err := groupRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	for _, configGroup := range configGroups {
+		changed := false
+		for i, config := range configGroup.Configuration {
+			if len(config.Encrypted) == 0 {
+				continue
+			}
+			rotated, err := k.rotateConfig(config)
+			if err != nil {
+				return fmt.Errorf("config group %s/%d: %w", configGroup.Name, configGroup.Version, err)
+			}
+			configGroup.Configuration[i] = rotated
+			report.Secrets += len(rotated.Encrypted)
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+		stored, err := groupRepo.Update(configGroup)
+		if err != nil {
+			return fmt.Errorf("cannot store config group %s/%d: %w", configGroup.Name, configGroup.Version, err)
+		}
+		if err := trail.record(events.KindConfigGroup, configGroup.Name, configGroup.Version, stored); err != nil {
+			return fmt.Errorf("config group %w", err)
+		}
+		report.ConfigGroups++
+	}
+	return nil
+}
+
+// rotateConfig dešifruje tajne konfiguracije i šifruje ih ponovo, sa novim ključevima podataka
+func (k *Keyring) rotateConfig(config model.Config) (model.Config, error) {
+	decrypted, err := k.DecryptConfig(config)
+	if err != nil {
+		return model.Config{}, err
+	}
+	return k.EncryptConfig(decrypted)
+}
@@ -0,0 +1,100 @@
+package secrets
+
+import (
+	"projekat/audit"
+	"projekat/model"
+	"projekat/repositories"
+	"testing"
+	"time"
+)
+
+func testKeyring(t *testing.T, active string, ids ...string) *Keyring {
+	t.Helper()
+	keyring := &Keyring{active: active, keys: make(map[string][]byte)}
+	for _, id := range append([]string{active}, ids...) {
+		key := make([]byte, keySize)
+		copy(key, id)
+		keyring.keys[id] = key
+	}
+	return keyring
+}
+
+func TestRotateRecordsChanges(t *testing.T) {
+	configs := repositories.NewConfigInMemRepository()
+	groups := repositories.NewConfigGroupInMemRepository()
+	namespaces := repositories.NewNamespaceInMemRepository()
+	if err := namespaces.Create(model.Namespace{Name: "team", CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatal(err)
+	}
+
+	old := testKeyring(t, "old")
+	secret := model.Config{Name: "db", Version: 1, Parameters: model.Parameters{"password": "s3cret", "host": "db"}, Secrets: []string{"password"}, Revision: model.FirstRevision}
+	plain := model.Config{Name: "cache", Version: 1, Parameters: model.Parameters{"host": "cache"}, Revision: model.FirstRevision}
+	for _, namespace := range []string{model.DefaultNamespace, "team"} {
+		for _, config := range []model.Config{secret, plain} {
+			if _, err := NewConfigRepository(configs, old).In(namespace).Create(config); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	group := model.ConfigGroup{Name: "app", Version: 1, Configuration: []model.Config{secret}, Revision: model.FirstRevision}
+	if _, err := NewConfigGroupRepository(groups, old).Create(group); err != nil {
+		t.Fatal(err)
+	}
+
+	auditRepo := repositories.NewAuditInMemRepository()
+	auditLog, err := audit.NewLog(auditRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated := testKeyring(t, "new", "old")
+	report, err := Rotate(configs, groups, repositories.NewWebhookInMemRepository(), namespaces, rotated, auditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Configs != 2 || report.ConfigGroups != 1 || report.Secrets != 3 {
+		t.Errorf("got report %+v", report)
+	}
+
+	// Svaki ponovo šifrovan zapis ima zapis u audit logu sa hešom sačuvanog stanja
+	entries, err := auditLog.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := make(map[string]model.AuditEntry)
+	for _, entry := range entries {
+		if entry.Operation != RotationOperation || entry.Action != model.AuditUpdate || entry.Principal != audit.SystemPrincipal {
+			t.Errorf("unexpected entry %+v", entry)
+		}
+		recorded[entry.Resource] = entry
+	}
+	for _, resource := range []string{"configs/db/1", "namespaces/team/configs/db/1", "configGroups/app/1"} {
+		if _, ok := recorded[resource]; !ok {
+			t.Errorf("rotation of %s is not in the audit log: %v", resource, recorded)
+		}
+	}
+	if len(recorded) != 3 {
+		t.Errorf("audit log has %d entries, want 3", len(recorded))
+	}
+	if result, err := auditLog.Verify(); err != nil || !result.Valid {
+		t.Errorf("audit log after rotation: %+v, %v", result, err)
+	}
+
+	stored, err := configs.In("team").Get("db", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Encrypted["password"].KeyID != "new" || stored.Revision != model.FirstRevision+1 {
+		t.Errorf("rotated config has key %q and revision %d", stored.Encrypted["password"].KeyID, stored.Revision)
+	}
+	if after := recorded["namespaces/team/configs/db/1"].AfterHash; after == "" || after == recorded["namespaces/team/configs/db/1"].BeforeHash {
+		t.Errorf("audit entry does not describe the re-encrypted config: %+v", recorded["namespaces/team/configs/db/1"])
+	}
+	decrypted, err := NewConfigRepository(configs, testKeyring(t, "new")).In("team").Get("db", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted.Parameters["password"] != "s3cret" {
+		t.Errorf("rotated secret is %v", decrypted.Parameters["password"])
+	}
+}
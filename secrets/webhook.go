@@ -0,0 +1,116 @@
+package secrets
+
+import (
+	"fmt"
+	"projekat/model"
+)
+
+// webhookAAD vezuje šifrovan ključ za potpis za pretplatu kojoj pripada
+func webhookAAD(id string) string {
+	return "webhooks/" + id
+}
+
+// EncryptWebhook vraća kopiju pretplate u kojoj je ključ za potpis premešten u EncryptedSecret
+func (k *Keyring) EncryptWebhook(webhook model.Webhook) (model.Webhook, error) {
+	webhook = webhook.Clone()
+	webhook.EncryptedSecret = nil
+	if webhook.Secret == "" {
+		return webhook, nil
+	}
+	encrypted, err := k.Encrypt(webhook.Secret, webhookAAD(webhook.ID))
+	if err != nil {
+		return model.Webhook{}, fmt.Errorf("cannot encrypt secret of webhook %s: %w", webhook.ID, err)
+	}
+	webhook.EncryptedSecret = &encrypted
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// DecryptWebhook vraća ključ za potpis iz EncryptedSecret u Secret
+func (k *Keyring) DecryptWebhook(webhook model.Webhook) (model.Webhook, error) {
+	if webhook.EncryptedSecret == nil {
+		return webhook, nil
+	}
+	value, err := k.Decrypt(*webhook.EncryptedSecret, webhookAAD(webhook.ID))
+	if err != nil {
+		return model.Webhook{}, fmt.Errorf("cannot decrypt secret of webhook %s: %w", webhook.ID, err)
+	}
+	secret, ok := value.(string)
+	if !ok {
+		return model.Webhook{}, fmt.Errorf("secret of webhook %s is not a string", webhook.ID)
+	}
+	webhook.Secret = secret
+	webhook.EncryptedSecret = nil
+	return webhook, nil
+}
+
+// WebhookRepository šifruje ključeve za potpis pretplata. Neuspele isporuke ne sadrže tajne,
+// pa se prosleđuju bez izmena.
+type WebhookRepository struct {
+	repo    model.WebhookRepository
+	keyring *Keyring
+}
+
+func NewWebhookRepository(repo model.WebhookRepository, keyring *Keyring) model.WebhookRepository {
+	return &WebhookRepository{
+		repo:    repo,
+		keyring: keyring,
+	}
+}
+
+func (r *WebhookRepository) Create(webhook model.Webhook) error {
+	encrypted, err := r.keyring.EncryptWebhook(webhook)
+	if err != nil {
+		return err
+	}
+	return r.repo.Create(encrypted)
+}
+
+func (r *WebhookRepository) Update(webhook model.Webhook) error {
+	encrypted, err := r.keyring.EncryptWebhook(webhook)
+	if err != nil {
+		return err
+	}
+	return r.repo.Update(encrypted)
+}
+
+func (r *WebhookRepository) Get(id string) (model.Webhook, error) {
+	webhook, err := r.repo.Get(id)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	return r.keyring.DecryptWebhook(webhook)
+}
+
+func (r *WebhookRepository) GetAll() ([]model.Webhook, error) {
+	webhooks, err := r.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for i, webhook := range webhooks {
+		if webhooks[i], err = r.keyring.DecryptWebhook(webhook); err != nil {
+			return nil, err
+		}
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) Delete(id string) error {
+	return r.repo.Delete(id)
+}
+
+func (r *WebhookRepository) AddDeadLetter(letter model.DeadLetter) error {
+	return r.repo.AddDeadLetter(letter)
+}
+
+func (r *WebhookRepository) GetDeadLetter(id string) (model.DeadLetter, error) {
+	return r.repo.GetDeadLetter(id)
+}
+
+func (r *WebhookRepository) DeleteDeadLetter(id string) error {
+	return r.repo.DeleteDeadLetter(id)
+}
+
+func (r *WebhookRepository) GetAllDeadLetters() ([]model.DeadLetter, error) {
+	return r.repo.GetAllDeadLetters()
+}
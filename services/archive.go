@@ -0,0 +1,405 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"projekat/archive"
+	"projekat/audit"
+	"projekat/model"
+	"time"
+)
+
+// ImportMode određuje šta uvoz radi sa zapisom koji već postoji pod istim imenom i verzijom
+type ImportMode string
+
+const (
+	// SkipExisting zadržava postojeći zapis
+	SkipExisting ImportMode = "skip-existing"
+	// Overwrite zamenjuje postojeću grupu grupom iz arhive. Verzije konfiguracija se ne menjaju
+	// posle kreiranja, pa je konfiguracija drugačija od postojeće i ovde sukob.
+	Overwrite ImportMode = "overwrite"
+	// FailOnConflict odbija ceo uvoz ako se bilo koji postojeći zapis razlikuje od arhive
+	FailOnConflict ImportMode = "fail-on-conflict"
+)
+
+// ParseImportMode proverava ime načina uvoza
+func ParseImportMode(name string) (ImportMode, error) {
+	switch mode := ImportMode(name); mode {
+	case SkipExisting, Overwrite, FailOnConflict:
+		return mode, nil
+	}
+	return "", model.Invalidf("unknown import mode %q (expected %q, %q or %q)", name, SkipExisting, Overwrite, FailOnConflict)
+}
+
+// ImportAction je ishod uvoza jednog zapisa
+type ImportAction string
+
+const (
+	ImportCreate    ImportAction = "create"
+	ImportOverwrite ImportAction = "overwrite"
+	ImportSkip      ImportAction = "skip"
+	// ImportUnchanged znači da postojeći zapis već ima isti sadržaj, pa se ne upisuje ni u jednom načinu
+	ImportUnchanged ImportAction = "unchanged"
+	ImportConflict  ImportAction = "conflict"
+)
+
+// ImportItem opisuje ishod za jednu konfiguraciju ili grupu iz arhive
+type ImportItem struct {
+	Kind    string       `json:"kind"`
+	Name    string       `json:"name"`
+	Version int          `json:"version"`
+	Action  ImportAction `json:"action"`
+}
+
+// ImportSummary broji ishode po vrsti zapisa
+type ImportSummary struct {
+	Created     int `json:"created"`
+	Overwritten int `json:"overwritten"`
+	Skipped     int `json:"skipped"`
+	Unchanged   int `json:"unchanged"`
+	Conflicts   int `json:"conflicts"`
+}
+
+func (s *ImportSummary) count(action ImportAction) {
+	s.add(action, 1)
+}
+
+func (s *ImportSummary) add(action ImportAction, delta int) {
+	switch action {
+	case ImportCreate:
+		s.Created += delta
+	case ImportOverwrite:
+		s.Overwritten += delta
+	case ImportSkip:
+		s.Skipped += delta
+	case ImportUnchanged:
+		s.Unchanged += delta
+	case ImportConflict:
+		s.Conflicts += delta
+	}
+}
+
+// ImportReport je izveštaj uvoza; pri probnom uvozu (DryRun) opisuje šta bi uvoz uradio
+type ImportReport struct {
+	Mode         ImportMode    `json:"mode"`
+	DryRun       bool          `json:"dryRun"`
+	Configs      ImportSummary `json:"configs"`
+	ConfigGroups ImportSummary `json:"configGroups"`
+	Items        []ImportItem  `json:"items"`
+}
+
+// Conflicts vraća zapise koji se razlikuju od postojećih u načinu FailOnConflict, odnosno
+// konfiguracije koje se razlikuju od postojećih u načinu Overwrite
+func (r ImportReport) Conflicts() []ImportItem {
+	conflicts := make([]ImportItem, 0)
+	for _, item := range r.Items {
+		if item.Action == ImportConflict {
+			conflicts = append(conflicts, item)
+		}
+	}
+	return conflicts
+}
+
+// replan menja ishod zapisa iz plana kada se pri upisu pokaže da je drugačiji
+func (r *ImportReport) replan(item int, summary *ImportSummary, action ImportAction) {
+	summary.add(r.Items[item].Action, -1)
+	summary.add(action, 1)
+	r.Items[item].Action = action
+}
+
+// ArchiveService izvozi i uvozi celo skladište. Koristi samo metode ConfigRepository
+// i ConfigGroupRepository interfejsa, pa radi sa svakim backend-om.
+type ArchiveService struct {
+	repo      model.ConfigRepository
+	groupRepo model.ConfigGroupRepository
+	schemas   SchemaService
+	audit     auditTrail
+}
+
+func NewArchiveService(repo model.ConfigRepository, groupRepo model.ConfigGroupRepository, schemas SchemaService, auditLog *audit.Log) ArchiveService {
+	return ArchiveService{
+		repo:      repo,
+		groupRepo: groupRepo,
+		schemas:   schemas,
+		audit:     newAuditTrail(auditLog),
+	}
+}
+
+// In vraća servis koji izvozi i uvozi zapise prostora imena
+func (s ArchiveService) In(namespace string) ArchiveService {
+	s.repo = s.repo.In(namespace)
+	s.groupRepo = s.groupRepo.In(namespace)
+	s.audit.namespace = namespace
+	return s
+}
+
+// By vraća servis čiji se uvoz upisuje u audit log u ime actor-a
+func (s ArchiveService) By(actor audit.Actor) ArchiveService {
+	s.audit.actor = actor
+	return s
+}
+
+// exportPageLimit je broj zapisa koji izvoz čita iz skladišta odjednom
+const exportPageLimit = model.MaxPageLimit
+
+// Export zapisuje sve konfiguracije i grupe u arhivu, sortirane po imenu i verziji. Zapisi se čitaju
+// stranu po stranu i odmah zapisuju, pa se skladište ne učitava celo u memoriju.
+func (s ArchiveService) Export(out *archive.Writer) error {
+	query := model.ListQuery{Sort: model.SortByName, Limit: exportPageLimit}
+	for {
+		page, err := s.repo.List(query)
+		if err != nil {
+			return err
+		}
+		for _, config := range page.Items {
+			if err := out.Config(config); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	query = model.ListQuery{Sort: model.SortByName, Limit: exportPageLimit}
+	for {
+		page, err := s.groupRepo.List(query)
+		if err != nil {
+			return err
+		}
+		for _, configGroup := range page.Items {
+			if err := out.ConfigGroup(configGroup); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	return out.Close()
+}
+
+// Import upisuje arhivu u skladište. Pre prvog upisa proverava sve zapise, i po šemama, i pravi
+// plan, pa se neispravna arhiva ili sukob odbija bez ikakve izmene. Zapisi zadržavaju verzije iz
+// arhive, a postojeća verzija konfiguracije se nikad ne prepisuje. Novi zapisi se prave sa Create,
+// pa zapis koji je u međuvremenu napravljen, ili čija je verzija već korišćena (i obrisana), nije
+// pregažen: u načinu SkipExisting se preskače, a inače je sukob i uvoz se prekida.
+func (s ArchiveService) Import(a archive.Archive, mode ImportMode, dryRun bool) (ImportReport, error) {
+	report := ImportReport{Mode: mode, DryRun: dryRun, Items: make([]ImportItem, 0, len(a.Configs)+len(a.ConfigGroups))}
+	if err := s.checkArchive(a); err != nil {
+		return report, err
+	}
+
+	// Revizija iz arhive pripada izvornom skladištu: pri poređenju i zameni važi revizija
+	// postojećeg zapisa, pa Update zamenjuje samo grupu koja se nije menjala od plana.
+	// Arhiva nema prostor imena, pa se za poređenje uzima prostor imena postojećeg zapisa.
+	configActions := make([]ImportAction, len(a.Configs))
+	for i, config := range a.Configs {
+		existing, err := s.repo.Get(config.Name, config.Version)
+		if err == nil {
+			config.Namespace = existing.Namespace
+			config.Revision = existing.Revision
+		}
+		action, err := planImport(mode, config, existing, err)
+		if err != nil {
+			return report, err
+		}
+		if action == ImportOverwrite {
+			action = ImportConflict
+		}
+		configActions[i] = action
+		report.Configs.count(action)
+		report.Items = append(report.Items, ImportItem{Kind: "config", Name: config.Name, Version: config.Version, Action: action})
+	}
+	groupActions := make([]ImportAction, len(a.ConfigGroups))
+	groupRevisions := make([]int64, len(a.ConfigGroups))
+	for i, configGroup := range a.ConfigGroups {
+		existing, err := s.groupRepo.Get(configGroup.Name, configGroup.Version)
+		if err == nil {
+			configGroup = configGroup.InNamespace(existing.Namespace)
+			configGroup.Revision = existing.Revision
+			groupRevisions[i] = existing.Revision
+		}
+		action, err := planImport(mode, configGroup, existing, err)
+		if err != nil {
+			return report, err
+		}
+		groupActions[i] = action
+		report.ConfigGroups.count(action)
+		report.Items = append(report.Items, ImportItem{Kind: "configGroup", Name: configGroup.Name, Version: configGroup.Version, Action: action})
+	}
+
+	if conflicts := report.Configs.Conflicts + report.ConfigGroups.Conflicts; conflicts > 0 {
+		if mode == Overwrite {
+			return report, model.AlreadyExistsf("%d configs in the archive differ from existing versions, which cannot be overwritten; nothing was imported", conflicts)
+		}
+		return report, model.AlreadyExistsf("%d records in the archive differ from existing ones; nothing was imported", conflicts)
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	// Konfiguracije se upisuju pre grupa, da bi reference grupa uvek imale na šta da upućuju
+	now := time.Now().UTC()
+	for i, config := range a.Configs {
+		if config.CreatedAt.IsZero() {
+			config.CreatedAt = now
+		}
+		switch configActions[i] {
+		case ImportCreate:
+			if config.Revision == 0 {
+				config.Revision = model.FirstRevision
+			}
+			stored, err := s.repo.Create(config)
+			if err != nil {
+				if action, ok := createConflict(mode, err); ok {
+					report.replan(i, &report.Configs, action)
+					if action == ImportSkip {
+						continue
+					}
+				}
+				return report, fmt.Errorf("cannot import config %s/%d: %w", config.Name, config.Version, err)
+			}
+			s.audit.record(configChange(model.AuditCreate, "import", config.Name, config.Version, stored))
+		}
+	}
+	for i, configGroup := range a.ConfigGroups {
+		if configGroup.CreatedAt.IsZero() {
+			stampCreated(&configGroup, now)
+		}
+		switch groupActions[i] {
+		case ImportCreate:
+			if configGroup.Revision == 0 {
+				configGroup.Revision = model.FirstRevision
+			}
+			stored, err := s.groupRepo.Create(configGroup)
+			if err != nil {
+				if action, ok := createConflict(mode, err); ok {
+					report.replan(len(a.Configs)+i, &report.ConfigGroups, action)
+					if action == ImportSkip {
+						continue
+					}
+				}
+				return report, fmt.Errorf("cannot import config group %s/%d: %w", configGroup.Name, configGroup.Version, err)
+			}
+			s.audit.record(configGroupChange(model.AuditCreate, "import", configGroup.Name, configGroup.Version, stored))
+		case ImportOverwrite:
+			configGroup.Revision = groupRevisions[i]
+			stored, err := s.groupRepo.Update(configGroup)
+			if err != nil {
+				return report, fmt.Errorf("cannot import config group %s/%d: %w", configGroup.Name, configGroup.Version, err)
+			}
+			s.audit.record(configGroupChange(model.AuditUpdate, "import", configGroup.Name, configGroup.Version, stored))
+		}
+	}
+	return report, nil
+}
+
+// planImport bira ishod za zapis iz arhive na osnovu postojećeg zapisa (ili greške pri čitanju)
+func planImport(mode ImportMode, record, existing interface{}, getErr error) (ImportAction, error) {
+	if errors.Is(getErr, model.ErrNotFound) {
+		return ImportCreate, nil
+	}
+	if getErr != nil {
+		return "", getErr
+	}
+	if sameRecord(record, existing) {
+		return ImportUnchanged, nil
+	}
+	switch mode {
+	case SkipExisting:
+		return ImportSkip, nil
+	case Overwrite:
+		return ImportOverwrite, nil
+	}
+	return ImportConflict, nil
+}
+
+// createConflict odlučuje o zapisu koji Create odbija jer postoji (napravljen je posle plana) ili
+// jer mu je verzija već korišćena: u načinu SkipExisting se preskače, a inače je sukob
+func createConflict(mode ImportMode, err error) (ImportAction, bool) {
+	if !errors.Is(err, model.ErrAlreadyExists) && !errors.Is(err, model.ErrConflict) {
+		return "", false
+	}
+	if mode == SkipExisting {
+		return ImportSkip, true
+	}
+	return ImportConflict, true
+}
+
+// sameRecord poredi zapise po JSON obliku, kakav se i izvozi
+func sameRecord(a, b interface{}) bool {
+	first, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	second, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(first) == string(second)
+}
+
+// checkArchive proverava zapise arhive pre uvoza: ispravnost, ponovljene ključeve,
+// maskirane tajne vrednosti i reference grupa
+func (s ArchiveService) checkArchive(a archive.Archive) error {
+	configs := make(map[string]bool, len(a.Configs))
+	for _, config := range a.Configs {
+		if err := s.checkImportedConfig(config); err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s/%d", config.Name, config.Version)
+		if configs[key] {
+			return model.Invalidf("config %s appears more than once in the archive", key)
+		}
+		configs[key] = true
+	}
+
+	configGroups := make(map[string]bool, len(a.ConfigGroups))
+	for _, configGroup := range a.ConfigGroups {
+		if err := configGroup.Validate(); err != nil {
+			return err
+		}
+		for _, config := range configGroup.Configuration {
+			if err := s.checkImportedConfig(config); err != nil {
+				return fmt.Errorf("config group %s/%d: %w", configGroup.Name, configGroup.Version, err)
+			}
+		}
+		key := fmt.Sprintf("%s/%d", configGroup.Name, configGroup.Version)
+		if configGroups[key] {
+			return model.Invalidf("config group %s appears more than once in the archive", key)
+		}
+		configGroups[key] = true
+
+		for _, reference := range configGroup.References {
+			if configs[fmt.Sprintf("%s/%d", reference.Name, reference.Version)] {
+				continue
+			}
+			if _, err := s.repo.Get(reference.Name, reference.Version); err != nil {
+				if errors.Is(err, model.ErrNotFound) {
+					return model.Invalidf("config group %s references config %s/%d, which is neither in the archive nor in the store", key, reference.Name, reference.Version)
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkImportedConfig proverava konfiguraciju kao pri kreiranju, uključujući šemu, i odbija
+// konfiguraciju izvezenu bez otkrivanja tajni, jer bi se maska upisala kao vrednost tajnog parametra
+func (s ArchiveService) checkImportedConfig(config model.Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	for _, name := range config.Secrets {
+		if value, ok := config.Parameters[name].(string); ok && value == model.MaskedValue {
+			return model.Invalidf("secret parameter %q of config %s/%d is masked; export with ?reveal=true to include secret values", name, config.Name, config.Version)
+		}
+	}
+	_, err := s.schemas.ValidateConfig(config)
+	return err
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"errors"
+	"projekat/archive"
+	"projekat/model"
+	"projekat/repositories"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var importTime = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func importConfig(name string, version int, host string) model.Config {
+	return model.Config{Name: name, Version: version, Parameters: model.Parameters{"host": host}, CreatedAt: importTime}
+}
+
+func importGroup(name string, version int, env string) model.ConfigGroup {
+	return model.ConfigGroup{
+		Name:          name,
+		Version:       version,
+		Configuration: []model.Config{{Name: "embedded", Version: 1, Parameters: model.Parameters{"env": env}, Labels: map[string]string{"env": env}, CreatedAt: importTime}},
+		References:    []model.ConfigReference{{Name: "db", Version: 1}},
+		CreatedAt:     importTime,
+	}
+}
+
+// newArchiveTestService pravi skladište sa konfiguracijom db/1 i grupom app/1 koja upućuje na nju
+func newArchiveTestService(t *testing.T) (ArchiveService, model.ConfigRepository, model.ConfigGroupRepository, SchemaService) {
+	t.Helper()
+	repo := repositories.NewConfigInMemRepository()
+	groupRepo := repositories.NewConfigGroupInMemRepository()
+	schemas := NewSchemaService(repositories.NewSchemaInMemRepository())
+	for _, config := range []model.Config{importConfig("db", 1, "db.local")} {
+		config.Revision = model.FirstRevision
+		if _, err := repo.Create(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	group := importGroup("app", 1, "prod")
+	group.Revision = model.FirstRevision
+	if _, err := groupRepo.Create(group); err != nil {
+		t.Fatal(err)
+	}
+	return NewArchiveService(repo, groupRepo, schemas, nil), repo, groupRepo, schemas
+}
+
+func itemActions(report ImportReport) map[string]ImportAction {
+	actions := make(map[string]ImportAction, len(report.Items))
+	for _, item := range report.Items {
+		actions[item.Kind+" "+item.Name] = item.Action
+	}
+	return actions
+}
+
+func TestImportModes(t *testing.T) {
+	cases := []struct {
+		name    string
+		mode    ImportMode
+		dryRun  bool
+		configs []model.Config
+		groups  []model.ConfigGroup
+		want    map[string]ImportAction
+		wantErr error
+		// Stanje posle uvoza: host konfiguracije db/1, env grupe app/1 i da li postoji cache/1
+		host, env string
+		cache     bool
+	}{
+		{
+			name:    "skip existing",
+			mode:    SkipExisting,
+			configs: []model.Config{importConfig("db", 1, "other.local"), importConfig("cache", 1, "cache.local")},
+			groups:  []model.ConfigGroup{importGroup("app", 1, "staging")},
+			want:    map[string]ImportAction{"config db": ImportSkip, "config cache": ImportCreate, "configGroup app": ImportSkip},
+			host:    "db.local", env: "prod", cache: true,
+		},
+		{
+			name:    "overwrite replaces groups",
+			mode:    Overwrite,
+			configs: []model.Config{importConfig("db", 1, "db.local"), importConfig("cache", 1, "cache.local")},
+			groups:  []model.ConfigGroup{importGroup("app", 1, "staging")},
+			want:    map[string]ImportAction{"config db": ImportUnchanged, "config cache": ImportCreate, "configGroup app": ImportOverwrite},
+			host:    "db.local", env: "staging", cache: true,
+		},
+		{
+			name:    "overwrite does not rewrite config versions",
+			mode:    Overwrite,
+			configs: []model.Config{importConfig("db", 1, "other.local"), importConfig("cache", 1, "cache.local")},
+			groups:  []model.ConfigGroup{importGroup("app", 1, "staging")},
+			want:    map[string]ImportAction{"config db": ImportConflict, "config cache": ImportCreate, "configGroup app": ImportOverwrite},
+			wantErr: model.ErrAlreadyExists,
+			host:    "db.local", env: "prod",
+		},
+		{
+			name:    "fail on conflict",
+			mode:    FailOnConflict,
+			configs: []model.Config{importConfig("db", 1, "db.local"), importConfig("cache", 1, "cache.local")},
+			groups:  []model.ConfigGroup{importGroup("app", 1, "staging")},
+			want:    map[string]ImportAction{"config db": ImportUnchanged, "config cache": ImportCreate, "configGroup app": ImportConflict},
+			wantErr: model.ErrAlreadyExists,
+			host:    "db.local", env: "prod",
+		},
+		{
+			name:    "fail on conflict without differences",
+			mode:    FailOnConflict,
+			configs: []model.Config{importConfig("db", 1, "db.local"), importConfig("cache", 1, "cache.local")},
+			groups:  []model.ConfigGroup{importGroup("app", 1, "prod")},
+			want:    map[string]ImportAction{"config db": ImportUnchanged, "config cache": ImportCreate, "configGroup app": ImportUnchanged},
+			host:    "db.local", env: "prod", cache: true,
+		},
+		{
+			name:    "dry run",
+			mode:    Overwrite,
+			dryRun:  true,
+			configs: []model.Config{importConfig("cache", 1, "cache.local")},
+			groups:  []model.ConfigGroup{importGroup("app", 1, "staging")},
+			want:    map[string]ImportAction{"config cache": ImportCreate, "configGroup app": ImportOverwrite},
+			host:    "db.local", env: "prod",
+		},
+		{
+			name:    "reference outside the archive and the store",
+			mode:    SkipExisting,
+			configs: []model.Config{importConfig("cache", 1, "cache.local")},
+			groups: []model.ConfigGroup{func() model.ConfigGroup {
+				group := importGroup("web", 1, "prod")
+				group.References = []model.ConfigReference{{Name: "missing", Version: 1}}
+				return group
+			}()},
+			wantErr: model.ErrInvalid,
+			host:    "db.local", env: "prod",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			service, repo, groupRepo, _ := newArchiveTestService(t)
+			report, err := service.Import(archive.New(c.configs, c.groups, importTime), c.mode, c.dryRun)
+			if !errors.Is(err, c.wantErr) || (c.wantErr == nil) != (err == nil) {
+				t.Fatalf("got error %v, want %v", err, c.wantErr)
+			}
+			if c.want != nil && !reflect.DeepEqual(itemActions(report), c.want) {
+				t.Errorf("got actions %v, want %v", itemActions(report), c.want)
+			}
+
+			db, err := repo.Get("db", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if db.Parameters["host"] != c.host || db.Revision != model.FirstRevision {
+				t.Errorf("db/1 has host %v and revision %d, want %s and %d", db.Parameters["host"], db.Revision, c.host, model.FirstRevision)
+			}
+			app, err := groupRepo.Get("app", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if env := app.Configuration[0].Parameters["env"]; env != c.env {
+				t.Errorf("app/1 has env %v, want %s", env, c.env)
+			}
+			if _, err := repo.Get("cache", 1); (err == nil) != c.cache {
+				t.Errorf("cache/1 exists: %v, want %v", err == nil, c.cache)
+			}
+		})
+	}
+}
+
+func TestImportValidatesSchemas(t *testing.T) {
+	schema := []byte(`{"type":"object","properties":{"port":{"type":"integer"}},"required":["port"]}`)
+	cases := []struct {
+		name   string
+		config model.Config
+		group  model.ConfigGroup
+	}{
+		{name: "config", config: model.Config{Name: "cache", Version: 1, Parameters: model.Parameters{"port": "x"}, CreatedAt: importTime}},
+		{name: "config in a group", config: model.Config{Name: "other", Version: 1, CreatedAt: importTime}, group: model.ConfigGroup{
+			Name: "web", Version: 1, CreatedAt: importTime,
+			Configuration: []model.Config{{Name: "cache", Version: 1, Parameters: model.Parameters{"host": "cache.local"}, CreatedAt: importTime}},
+		}},
+	}
+	for _, c := range cases {
+		for _, mode := range []ImportMode{SkipExisting, Overwrite, FailOnConflict} {
+			t.Run(c.name+" "+string(mode), func(t *testing.T) {
+				service, repo, _, schemas := newArchiveTestService(t)
+				if err := schemas.Create(model.Schema{Name: "cache", Version: 1, Schema: schema}); err != nil {
+					t.Fatal(err)
+				}
+				var groups []model.ConfigGroup
+				if c.group.Name != "" {
+					groups = append(groups, c.group)
+				}
+				_, err := service.Import(archive.New([]model.Config{c.config}, groups, importTime), mode, false)
+				var violation *model.SchemaViolationError
+				if !errors.As(err, &violation) {
+					t.Fatalf("got %v, want a schema violation", err)
+				}
+				if _, err := repo.Get(c.config.Name, 1); !errors.Is(err, model.ErrNotFound) {
+					t.Errorf("config from a rejected archive was imported: %v", err)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"log"
+	"projekat/audit"
+	"projekat/events"
+	"projekat/model"
+)
+
+// auditTrail upisuje izmene servisa u audit log u ime actor-a, za prostor imena servisa
+type auditTrail struct {
+	log       *audit.Log
+	actor     audit.Actor
+	namespace string
+}
+
+func newAuditTrail(auditLog *audit.Log) auditTrail {
+	return auditTrail{
+		log:       auditLog,
+		actor:     audit.System,
+		namespace: model.DefaultNamespace,
+	}
+}
+
+// record upisuje izmenu koja je već sačuvana. Izmena se ne poništava ako upis u log ne uspe:
+// greška se beleži u log servera, a broj neupisanih izmena prijavljuje GET /audit/verify.
+func (t auditTrail) record(change audit.Change) {
+	if t.log == nil {
+		return
+	}
+	change.Namespace = t.namespace
+	if _, err := t.log.Record(t.actor, change); err != nil {
+		log.Printf("audit: %s %s/%d: %v", change.Operation, change.Name, change.Version, err)
+		t.log.Unrecorded(change, err)
+	}
+}
+
+// configChange opisuje izmenu konfiguracije; stanja pre i posle izmene uzima iz izmene repozitorijuma
+func configChange(action, operation, name string, version int, stored model.Change) audit.Change {
+	return audit.Change{Action: action, Operation: operation, Kind: events.KindConfig, Name: name, Version: version, Before: stored.Before, After: stored.After}
+}
+
+func configGroupChange(action, operation, name string, version int, stored model.Change) audit.Change {
+	return audit.Change{Action: action, Operation: operation, Kind: events.KindConfigGroup, Name: name, Version: version, Before: stored.Before, After: stored.After}
+}
+
+// storedAction vraća create za zapis koji pre izmene nije postojao, inače update
+func storedAction(stored model.Change) string {
+	if stored.Before == nil {
+		return model.AuditCreate
+	}
+	return model.AuditUpdate
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"projekat/audit"
+	"projekat/model"
+	"projekat/repositories"
+	"testing"
+)
+
+// failingAuditRepository odbija upise dok je fail postavljen
+type failingAuditRepository struct {
+	model.AuditRepository
+	fail bool
+}
+
+func (r *failingAuditRepository) Append(entry model.AuditEntry) error {
+	if r.fail {
+		return errors.New("audit storage is unavailable")
+	}
+	return r.AuditRepository.Append(entry)
+}
+
+// Izmena se čuva i kada upis u audit log ne uspe, ali je Verify prijavljuje
+func TestAuditFailureIsReported(t *testing.T) {
+	auditRepo := &failingAuditRepository{AuditRepository: repositories.NewAuditInMemRepository()}
+	auditLog, err := audit.NewLog(auditRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewConfigService(repositories.NewConfigInMemRepository(), repositories.NewConfigGroupInMemRepository(), NewSchemaService(repositories.NewSchemaInMemRepository()), RestrictReferences, auditLog)
+
+	if err := service.CreateConfig(model.Config{Name: "db", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	auditRepo.fail = true
+	if err := service.CreateConfig(model.Config{Name: "db", Version: 2}); err != nil {
+		t.Fatalf("change failed because of the audit log: %v", err)
+	}
+	auditRepo.fail = false
+
+	result, err := auditLog.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.Unrecorded != 1 || result.Entries != 1 || result.LastFailure == "" {
+		t.Errorf("got %+v, want one unrecorded change", result)
+	}
+}
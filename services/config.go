@@ -1,42 +1,267 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"projekat/audit"
 	"projekat/model"
+	"strings"
+	"time"
 )
 
+// ReferencePolicy određuje šta se dešava pri brisanju konfiguracije na koju upućuju grupe
+type ReferencePolicy string
+
+const (
+	// RestrictReferences odbija brisanje dok god neka grupa upućuje na konfiguraciju
+	RestrictReferences ReferencePolicy = "restrict"
+	// CascadeReferences briše konfiguraciju i uklanja sve reference na nju
+	CascadeReferences ReferencePolicy = "cascade"
+)
+
+// ParseReferencePolicy proverava ime politike zadato u podešavanjima
+func ParseReferencePolicy(name string) (ReferencePolicy, error) {
+	switch policy := ReferencePolicy(name); policy {
+	case RestrictReferences, CascadeReferences:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown reference policy %q (expected %q or %q)", name, RestrictReferences, CascadeReferences)
+}
+
 type ConfigService struct {
-	repo model.ConfigRepository
+	repo      model.ConfigRepository
+	groupRepo model.ConfigGroupRepository
+	schemas   SchemaService
+	policy    ReferencePolicy
+	audit     auditTrail
 }
 
-func NewConfigService(repo model.ConfigRepository) ConfigService {
+func NewConfigService(repo model.ConfigRepository, groupRepo model.ConfigGroupRepository, schemas SchemaService, policy ReferencePolicy, auditLog *audit.Log) ConfigService {
 	return ConfigService{
-		repo: repo,
+		repo:      repo,
+		groupRepo: groupRepo,
+		schemas:   schemas,
+		policy:    policy,
+		audit:     newAuditTrail(auditLog),
 	}
 }
 
+// In vraća servis koji radi sa konfiguracijama i grupama iz prostora imena
+func (s ConfigService) In(namespace string) ConfigService {
+	s.repo = s.repo.In(namespace)
+	s.groupRepo = s.groupRepo.In(namespace)
+	s.audit.namespace = namespace
+	return s
+}
+
+// By vraća servis čije se izmene upisuju u audit log u ime actor-a
+func (s ConfigService) By(actor audit.Actor) ConfigService {
+	s.audit.actor = actor
+	return s
+}
+
 func (s ConfigService) Hello() {
 	fmt.Println("hello from config service")
 }
 
 func (s ConfigService) CreateConfig(config model.Config) error {
-	return s.repo.Create(config)
+	if _, err := s.Validate(config); err != nil {
+		return err
+	}
+	config.CreatedAt = time.Now().UTC()
+	config.Revision = model.FirstRevision
+	stored, err := s.repo.Create(config)
+	if err != nil {
+		return err
+	}
+	s.audit.record(configChange(model.AuditCreate, "create", config.Name, config.Version, stored))
+	return nil
+}
+
+// Validate proverava konfiguraciju kao pri kreiranju, bez čuvanja, i vraća verziju šeme
+// po kojoj su provereni parametri (0 ako za ime ne postoji šema)
+func (s ConfigService) Validate(config model.Config) (int, error) {
+	if err := config.Validate(); err != nil {
+		return 0, err
+	}
+	return s.schemas.ValidateConfig(config)
 }
 
 func (s ConfigService) Read(name string, version int) (model.Config, error) {
 	return s.repo.Read(name, version)
 }
 
+// UpdateConfig se odbija: verzije su nepromenljive, izmena se pravi kao nova verzija
 func (s ConfigService) UpdateConfig(config model.Config) error {
-	return s.repo.Update(config)
+	return model.Conflictf("config %s/%d is immutable; create a new version instead", config.Name, config.Version)
+}
+
+// CreateVersion čuva konfiguraciju pod sledećom slobodnom verzijom koju dodeljuje server
+func (s ConfigService) CreateVersion(name string, config model.Config) (model.Config, error) {
+	if config.Name != "" && config.Name != name {
+		return model.Config{}, model.Invalidf("config name %q does not match %q from the path", config.Name, name)
+	}
+	if err := model.ValidateName("config", name); err != nil {
+		return model.Config{}, err
+	}
+	config.Name = name
+	if _, err := s.schemas.ValidateConfig(config); err != nil {
+		return model.Config{}, err
+	}
+	config.CreatedAt = time.Now().UTC()
+	config.Revision = model.FirstRevision
+	return s.createNextVersion("createVersion", config)
+}
+
+// createNextVersion čuva konfiguraciju pod sledećom slobodnom verzijom i upisuje izmenu u audit log
+func (s ConfigService) createNextVersion(operation string, config model.Config) (model.Config, error) {
+	created, stored, err := s.repo.CreateNextVersion(config)
+	if err != nil {
+		return model.Config{}, err
+	}
+	s.audit.record(configChange(model.AuditCreate, operation, created.Name, created.Version, stored))
+	return created, nil
+}
+
+// Latest vraća najnoviju verziju konfiguracije
+func (s ConfigService) Latest(name string) (model.Config, error) {
+	configs, err := s.repo.ListByName(name)
+	if err != nil {
+		return model.Config{}, err
+	}
+	if len(configs) == 0 {
+		return model.Config{}, model.NotFoundf("config %s not found", name)
+	}
+	return configs[len(configs)-1], nil
+}
+
+// Versions vraća metapodatke o svim verzijama konfiguracije
+func (s ConfigService) Versions(name string) ([]model.ConfigVersion, error) {
+	configs, err := s.repo.ListByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(configs) == 0 {
+		return nil, model.NotFoundf("config %s not found", name)
+	}
+
+	versions := make([]model.ConfigVersion, 0, len(configs))
+	for _, config := range configs {
+		versions = append(versions, config.VersionInfo())
+	}
+	return versions, nil
+}
+
+// Rollback pravi novu verziju konfiguracije kao kopiju ranije verzije
+func (s ConfigService) Rollback(name string, version int) (model.Config, error) {
+	config, err := s.repo.Get(name, version)
+	if err != nil {
+		return model.Config{}, err
+	}
+	// Nova verzija mora da odgovara trenutnoj šemi, i kada je kopija starije verzije
+	if _, err := s.schemas.ValidateConfig(config); err != nil {
+		return model.Config{}, err
+	}
+	config.CreatedAt = time.Now().UTC()
+	config.Revision = model.FirstRevision
+	return s.createNextVersion("rollback", config)
 }
 
-func (s ConfigService) Delete(name string, version int) error {
-	return s.repo.Delete(name, version)
+// Delete briše konfiguraciju. Uslov iz If-Match se proverava na konfiguraciji pročitanoj pre brisanja,
+// a brisanje uspeva samo ako je konfiguracija i dalje u proverenoj reviziji.
+//
+// Grupa može da doda referencu između provere referenci i brisanja, pa se reference proveravaju
+// ponovo posle brisanja: politika restrict tada vraća konfiguraciju, a cascade uklanja i te reference.
+// ConfigGroupService.AddReference proverava konfiguraciju posle upisa reference, pa bar jedna
+// strana uvek vidi drugu i referenca ne ostaje da upućuje na obrisanu konfiguraciju.
+func (s ConfigService) Delete(name string, version int, precondition model.Precondition) error {
+	config, err := s.repo.Get(name, version)
+	if err != nil {
+		return err
+	}
+	if err := precondition.Check("config", name, version, config.Revision); err != nil {
+		return err
+	}
+	var expectedRevision int64
+	if precondition.Set {
+		expectedRevision = config.Revision
+	}
+
+	groups, err := s.referencingGroups(name, version)
+	if err != nil {
+		return err
+	}
+	if len(groups) > 0 {
+		if s.policy != CascadeReferences {
+			return referencedConflict(name, version, groups)
+		}
+		// Kaskadno brisanje: prvo uklanjamo reference, pa tek onda samu konfiguraciju
+		if err := s.removeReferences(groups, name, version); err != nil {
+			return err
+		}
+	}
+
+	stored, err := s.repo.Delete(name, version, expectedRevision)
+	if err != nil {
+		return err
+	}
+	s.audit.record(configChange(model.AuditDelete, "delete", name, version, stored))
+
+	groups, err = s.referencingGroups(name, version)
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	if s.policy == CascadeReferences {
+		return s.removeReferences(groups, name, version)
+	}
+	restored, err := s.repo.Add(config)
+	if err != nil {
+		return fmt.Errorf("config %s/%d was deleted while config groups added references to it and cannot be restored: %w", name, version, err)
+	}
+	s.audit.record(configChange(model.AuditCreate, "restore", name, version, restored))
+	return referencedConflict(name, version, groups)
 }
 
-func (s ConfigService) Add(config model.Config) {
-	s.repo.Add(config)
+// removeReferences uklanja iz grupa reference na konfiguraciju
+func (s ConfigService) removeReferences(groups []model.ConfigGroup, name string, version int) error {
+	for _, group := range groups {
+		stored, err := s.groupRepo.RemoveReference(group.Name, group.Version, name, version)
+		if err != nil && !errors.Is(err, model.ErrNotFound) {
+			return err
+		}
+		if err == nil {
+			s.audit.record(configGroupChange(model.AuditUpdate, "removeReference", group.Name, group.Version, stored))
+		}
+	}
+	return nil
+}
+
+// referencedConflict je greška za brisanje konfiguracije na koju upućuju grupe
+func referencedConflict(name string, version int, groups []model.ConfigGroup) error {
+	keys := make([]string, 0, len(groups))
+	for _, group := range groups {
+		keys = append(keys, fmt.Sprintf("%s/%d", group.Name, group.Version))
+	}
+	return model.Conflictf("config %s/%d is referenced by config groups: %s", name, version, strings.Join(keys, ", "))
+}
+
+// Add čuva konfiguraciju i zamenjuje postojeću sa istim imenom i verzijom
+func (s ConfigService) Add(config model.Config) error {
+	if config.CreatedAt.IsZero() {
+		config.CreatedAt = time.Now().UTC()
+	}
+	if config.Revision == 0 {
+		config.Revision = model.FirstRevision
+	}
+	stored, err := s.repo.Add(config)
+	if err != nil {
+		return err
+	}
+	s.audit.record(configChange(storedAction(stored), "add", config.Name, config.Version, stored))
+	return nil
 }
 
 func (s ConfigService) Get(name string, version int) (model.Config, error) {
@@ -46,3 +271,37 @@ func (s ConfigService) Get(name string, version int) (model.Config, error) {
 func (s ConfigService) GetAll() ([]model.Config, error) {
 	return s.repo.GetAll()
 }
+
+// List vraća jednu stranu konfiguracija; filtere i sortiranje primenjuje repozitorijum
+func (s ConfigService) List(query model.ListQuery) (model.ConfigPage, error) {
+	return s.repo.List(query)
+}
+
+// referencingGroups vraća grupe koje upućuju na datu konfiguraciju
+func (s ConfigService) referencingGroups(name string, version int) ([]model.ConfigGroup, error) {
+	configGroups, err := s.groupRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	referencing := make([]model.ConfigGroup, 0)
+	for _, configGroup := range configGroups {
+		if configGroup.HasReference(name, version) {
+			referencing = append(referencing, configGroup)
+		}
+	}
+	return referencing, nil
+}
+
+// Diff poredi dve verzije konfiguracije
+func (s ConfigService) Diff(name string, from, to int) (model.ConfigDiff, error) {
+	fromConfig, err := s.repo.Get(name, from)
+	if err != nil {
+		return model.ConfigDiff{}, err
+	}
+	toConfig, err := s.repo.Get(name, to)
+	if err != nil {
+		return model.ConfigDiff{}, err
+	}
+	return model.DiffConfigs(fromConfig, toConfig), nil
+}
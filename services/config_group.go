@@ -1,98 +1,494 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"projekat/audit"
 	"projekat/model"
+	"time"
 )
 
+// Koliko puta se izmena bez If-Match ponavlja kada je grupa izmenjena između čitanja i upisa
+const updateAttempts = 16
+
+// errUnchanged prekida updateIf kada izmena ne menja grupu, pa nema ni upisa
+var errUnchanged = errors.New("config group unchanged")
+
 type ConfigGroupService struct {
-	repo model.ConfigGroupRepository
+	repo       model.ConfigGroupRepository
+	configRepo model.ConfigRepository
+	schemas    SchemaService
+	audit      auditTrail
 }
 
-func NewConfigGroupService(repo model.ConfigGroupRepository) ConfigGroupService {
+func NewConfigGroupService(repo model.ConfigGroupRepository, configRepo model.ConfigRepository, schemas SchemaService, auditLog *audit.Log) ConfigGroupService {
 	return ConfigGroupService{
-		repo: repo,
+		repo:       repo,
+		configRepo: configRepo,
+		schemas:    schemas,
+		audit:      newAuditTrail(auditLog),
 	}
 }
 
+// In vraća servis koji radi sa grupama iz prostora imena; reference upućuju na konfiguracije
+// iz istog prostora imena
+func (s ConfigGroupService) In(namespace string) ConfigGroupService {
+	s.repo = s.repo.In(namespace)
+	s.configRepo = s.configRepo.In(namespace)
+	s.audit.namespace = namespace
+	return s
+}
+
+// By vraća servis čije se izmene upisuju u audit log u ime actor-a
+func (s ConfigGroupService) By(actor audit.Actor) ConfigGroupService {
+	s.audit.actor = actor
+	return s
+}
+
 func (s ConfigGroupService) Hello() {
 	fmt.Println("hello from config group service")
 }
 
 func (s ConfigGroupService) Create(configGroup model.ConfigGroup) error {
-	return s.repo.Create(configGroup)
+	if err := configGroup.Validate(); err != nil {
+		return err
+	}
+	if err := s.checkSchemas(configGroup); err != nil {
+		return err
+	}
+	if err := s.checkReferences(&configGroup); err != nil {
+		return err
+	}
+	stampCreated(&configGroup, time.Now().UTC())
+	stored, err := s.repo.Create(configGroup)
+	if err != nil {
+		return err
+	}
+	s.audit.record(configGroupChange(model.AuditCreate, "create", configGroup.Name, configGroup.Version, stored))
+	return nil
 }
 
 func (s ConfigGroupService) Read(name string, version int) (model.ConfigGroup, error) {
-	return s.repo.Read(name, version)
+	return s.Get(name, version)
 }
 
 func (s ConfigGroupService) Update(configGroup model.ConfigGroup) error {
-	return s.repo.Update(configGroup)
+	if err := s.checkReferences(&configGroup); err != nil {
+		return err
+	}
+	return s.audited("update", configGroup.Name, configGroup.Version, func() (model.Change, error) {
+		return s.repo.Update(configGroup)
+	})
 }
 
-func (s ConfigGroupService) Delete(name string, version int) error {
-	return s.repo.Delete(name, version)
+// CreateVersion čuva grupu pod sledećom slobodnom verzijom koju dodeljuje server
+func (s ConfigGroupService) CreateVersion(name string, configGroup model.ConfigGroup) (model.ConfigGroup, error) {
+	if configGroup.Name != "" && configGroup.Name != name {
+		return model.ConfigGroup{}, model.Invalidf("config group name %q does not match %q from the path", configGroup.Name, name)
+	}
+	if err := model.ValidateName("config group", name); err != nil {
+		return model.ConfigGroup{}, err
+	}
+	for _, config := range configGroup.Configuration {
+		if err := config.Validate(); err != nil {
+			return model.ConfigGroup{}, err
+		}
+	}
+	if err := s.checkSchemas(configGroup); err != nil {
+		return model.ConfigGroup{}, err
+	}
+	configGroup.Name = name
+	if err := s.checkReferences(&configGroup); err != nil {
+		return model.ConfigGroup{}, err
+	}
+	stampCreated(&configGroup, time.Now().UTC())
+	return s.createNextVersion("createVersion", configGroup)
 }
 
-func (s ConfigGroupService) GetAll() ([]model.ConfigGroup, error) {
-	return s.repo.GetAll()
+// createNextVersion čuva grupu pod sledećom slobodnom verzijom, upisuje izmenu u audit log
+// i vraća grupu sa razrešenim referencama
+func (s ConfigGroupService) createNextVersion(operation string, configGroup model.ConfigGroup) (model.ConfigGroup, error) {
+	created, stored, err := s.repo.CreateNextVersion(configGroup)
+	if err != nil {
+		return model.ConfigGroup{}, err
+	}
+	s.audit.record(configGroupChange(model.AuditCreate, operation, created.Name, created.Version, stored))
+	s.resolve(&created)
+	return created, nil
 }
 
-func (s ConfigGroupService) Add(configGroup model.ConfigGroup) {
-	s.repo.Add(configGroup)
+// Latest vraća najnoviju verziju grupe
+func (s ConfigGroupService) Latest(name string) (model.ConfigGroup, error) {
+	configGroups, err := s.repo.ListByName(name)
+	if err != nil {
+		return model.ConfigGroup{}, err
+	}
+	if len(configGroups) == 0 {
+		return model.ConfigGroup{}, model.NotFoundf("config group %s not found", name)
+	}
+	latest := configGroups[len(configGroups)-1]
+	s.resolve(&latest)
+	return latest, nil
 }
 
-func (s ConfigGroupService) Get(name string, version int) (model.ConfigGroup, error) {
-	return s.repo.Get(name, version)
+// Versions vraća metapodatke o svim verzijama grupe
+func (s ConfigGroupService) Versions(name string) ([]model.ConfigGroupVersion, error) {
+	configGroups, err := s.repo.ListByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(configGroups) == 0 {
+		return nil, model.NotFoundf("config group %s not found", name)
+	}
+
+	versions := make([]model.ConfigGroupVersion, 0, len(configGroups))
+	for _, configGroup := range configGroups {
+		versions = append(versions, configGroup.VersionInfo())
+	}
+	return versions, nil
 }
 
-func (s ConfigGroupService) RemoveConfig(groupName string, groupVersion int, configName string, configVersion int) error {
-	// Prvo dohvatimo grupu konfiguracija
-	configGroup, err := s.repo.Get(groupName, groupVersion)
+// Rollback pravi novu verziju grupe kao kopiju ranije verzije. Reference se kopiraju
+// kao reference, pa rollback ne uspeva ako neka od referenciranih konfiguracija više ne postoji.
+func (s ConfigGroupService) Rollback(name string, version int) (model.ConfigGroup, error) {
+	configGroup, err := s.repo.Get(name, version)
 	if err != nil {
-		return err
+		return model.ConfigGroup{}, err
+	}
+	if err := s.checkSchemas(configGroup); err != nil {
+		return model.ConfigGroup{}, err
+	}
+	if err := s.checkReferences(&configGroup); err != nil {
+		return model.ConfigGroup{}, err
 	}
+	configGroup.CreatedAt = time.Now().UTC()
+	configGroup.Revision = model.FirstRevision
+	return s.createNextVersion("rollback", configGroup)
+}
 
-	// Pronađimo konfiguraciju koju želimo ukloniti iz grupe
-	var indexToRemove = -1
-	for i, config := range configGroup.Configuration {
-		if config.Name == configName && config.Version == configVersion {
-			indexToRemove = i
-			break
+// Delete briše grupu. Uslov iz If-Match se proverava na grupi pročitanoj neposredno pre brisanja,
+// a repozitorijum briše grupu samo ako je i dalje u proverenoj reviziji (compare-and-swap).
+func (s ConfigGroupService) Delete(name string, version int, precondition model.Precondition) error {
+	var expectedRevision int64
+	if precondition.Set {
+		configGroup, err := s.repo.Get(name, version)
+		if err != nil {
+			return err
+		}
+		if err := precondition.Check("config group", name, version, configGroup.Revision); err != nil {
+			return err
 		}
+		expectedRevision = configGroup.Revision
 	}
-	if indexToRemove == -1 {
-		return fmt.Errorf("config with name %s and version %d not found in group", configName, configVersion)
+	stored, err := s.repo.Delete(name, version, expectedRevision)
+	if err != nil {
+		return err
 	}
+	s.audit.record(configGroupChange(model.AuditDelete, "delete", name, version, stored))
+	return nil
+}
 
-	// Uklonimo konfiguraciju iz grupe
-	configGroup.Configuration = append(configGroup.Configuration[:indexToRemove], configGroup.Configuration[indexToRemove+1:]...)
+func (s ConfigGroupService) GetAll() ([]model.ConfigGroup, error) {
+	configGroups, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for i := range configGroups {
+		s.resolve(&configGroups[i])
+	}
+	return configGroups, nil
+}
 
-	// Ažurirajmo grupu konfiguracija u repozitoriju
-	err = s.repo.Update(configGroup)
+// List vraća jednu stranu grupa sa razrešenim referencama
+func (s ConfigGroupService) List(query model.ListQuery) (model.ConfigGroupPage, error) {
+	page, err := s.repo.List(query)
 	if err != nil {
-		return err
+		return model.ConfigGroupPage{}, err
 	}
+	for i := range page.Items {
+		s.resolve(&page.Items[i])
+	}
+	return page, nil
+}
 
+// Add čuva grupu i zamenjuje postojeću sa istim imenom i verzijom
+func (s ConfigGroupService) Add(configGroup model.ConfigGroup) error {
+	if configGroup.CreatedAt.IsZero() {
+		stampCreated(&configGroup, time.Now().UTC())
+	}
+	stored, err := s.repo.Add(configGroup)
+	if err != nil {
+		return err
+	}
+	s.audit.record(configGroupChange(storedAction(stored), "add", configGroup.Name, configGroup.Version, stored))
 	return nil
 }
 
-func (s ConfigGroupService) AddConfigs(groupName string, groupVersion int, config model.Config) error {
-	// Prvo dohvatimo grupu konfiguracija
-	configGroup, err := s.repo.Get(groupName, groupVersion)
+func (s ConfigGroupService) Get(name string, version int) (model.ConfigGroup, error) {
+	configGroup, err := s.repo.Get(name, version)
+	if err != nil {
+		return model.ConfigGroup{}, err
+	}
+	s.resolve(&configGroup)
+	return configGroup, nil
+}
+
+func (s ConfigGroupService) RemoveConfig(groupName string, groupVersion int, configName string, configVersion int, precondition model.Precondition) error {
+	return s.audited("removeConfig", groupName, groupVersion, func() (model.Change, error) {
+		if precondition.Set {
+			_, stored, err := s.updateIf(groupName, groupVersion, precondition, func(configGroup *model.ConfigGroup) error {
+				return configGroup.RemoveConfig(configName, configVersion)
+			})
+			return stored, err
+		}
+		// Repozitorijum uklanja konfiguraciju atomski, pa nema potrebe za čitanjem i ponovnim upisom grupe
+		return s.repo.RemoveConfig(groupName, groupVersion, configName, configVersion)
+	})
+}
+
+// AddConfigs dodaje konfiguraciju u grupu i vraća izmenjenu grupu
+func (s ConfigGroupService) AddConfigs(groupName string, groupVersion int, config model.Config, precondition model.Precondition) (model.ConfigGroup, error) {
+	if err := config.Validate(); err != nil {
+		return model.ConfigGroup{}, err
+	}
+	if _, err := s.schemas.ValidateConfig(config); err != nil {
+		return model.ConfigGroup{}, err
+	}
+	if config.CreatedAt.IsZero() {
+		config.CreatedAt = time.Now().UTC()
+	}
+	// Ugrađena konfiguracija nema svoju reviziju, menja se zajedno sa grupom
+	config.Revision = 0
+
+	var configGroup model.ConfigGroup
+	err := s.audited("addConfig", groupName, groupVersion, func() (model.Change, error) {
+		var stored model.Change
+		var err error
+		if precondition.Set {
+			configGroup, stored, err = s.updateIf(groupName, groupVersion, precondition, func(configGroup *model.ConfigGroup) error {
+				return configGroup.AddConfig(config)
+			})
+		} else {
+			configGroup, stored, err = s.repo.AddConfig(groupName, groupVersion, config)
+		}
+		return stored, err
+	})
+	if err != nil {
+		return model.ConfigGroup{}, err
+	}
+	s.resolve(&configGroup)
+	return configGroup, nil
+}
+
+// AddReference dodaje u grupu referencu na postojeću konfiguraciju. Konfiguracija se proverava
+// pre i posle upisa reference, da referenca ne bi ostala ako je konfiguracija u međuvremenu obrisana.
+func (s ConfigGroupService) AddReference(groupName string, groupVersion int, reference model.ConfigReference, precondition model.Precondition) error {
+	if _, err := s.configRepo.Get(reference.Name, reference.Version); err != nil {
+		return model.Conflictf("referenced config %s/%d not found", reference.Name, reference.Version)
+	}
+	err := s.audited("addReference", groupName, groupVersion, func() (model.Change, error) {
+		if precondition.Set {
+			_, stored, err := s.updateIf(groupName, groupVersion, precondition, func(configGroup *model.ConfigGroup) error {
+				return configGroup.AddReference(reference)
+			})
+			return stored, err
+		}
+		return s.repo.AddReference(groupName, groupVersion, reference)
+	})
 	if err != nil {
 		return err
 	}
 
-	// Dodajemo nove konfiguracije u grupu
-	configGroup.Configuration = append(configGroup.Configuration, config)
+	// Konfiguracija je mogla da bude obrisana posle provere, a pre upisa reference. Tada se
+	// referenca uklanja; ConfigService.Delete posle brisanja isto proverava reference.
+	if _, err := s.configRepo.Get(reference.Name, reference.Version); !errors.Is(err, model.ErrNotFound) {
+		return nil
+	}
+	err = s.audited("removeReference", groupName, groupVersion, func() (model.Change, error) {
+		return s.repo.RemoveReference(groupName, groupVersion, reference.Name, reference.Version)
+	})
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		return fmt.Errorf("referenced config %s/%d was deleted and the reference cannot be removed: %w", reference.Name, reference.Version, err)
+	}
+	return model.Conflictf("referenced config %s/%d was deleted", reference.Name, reference.Version)
+}
+
+func (s ConfigGroupService) RemoveReference(groupName string, groupVersion int, configName string, configVersion int, precondition model.Precondition) error {
+	return s.audited("removeReference", groupName, groupVersion, func() (model.Change, error) {
+		if precondition.Set {
+			_, stored, err := s.updateIf(groupName, groupVersion, precondition, func(configGroup *model.ConfigGroup) error {
+				return configGroup.RemoveReference(configName, configVersion)
+			})
+			return stored, err
+		}
+		return s.repo.RemoveReference(groupName, groupVersion, configName, configVersion)
+	})
+}
 
-	// Ažurirajmo grupu konfiguracija u repozitoriju
-	err = s.repo.Update(configGroup)
+// audited izvršava izmenu postojeće grupe i upisuje je u audit log sa stanjem grupe pre i posle
+// izmene, kako ga je vratio repozitorijum. Izmena koja nije promenila sačuvanu grupu se ne upisuje.
+func (s ConfigGroupService) audited(operation, name string, version int, change func() (model.Change, error)) error {
+	stored, err := change()
 	if err != nil {
 		return err
 	}
+	if !stored.Changed() {
+		return nil
+	}
+	s.audit.record(configGroupChange(model.AuditUpdate, operation, name, version, stored))
+	return nil
+}
+
+// updateIf menja grupu pod uslovom iz If-Match: čita je, proverava reviziju, primenjuje change
+// i upisuje compare-and-swap Update-om, pa izmena ne prolazi ni ako je grupa izmenjena posle
+// čitanja. Nulta vrednost uslova ne proverava reviziju, ali upis i dalje ne prolazi ako je grupa
+// izmenjena posle čitanja.
+func (s ConfigGroupService) updateIf(name string, version int, precondition model.Precondition, change func(*model.ConfigGroup) error) (model.ConfigGroup, model.Change, error) {
+	configGroup, err := s.repo.Get(name, version)
+	if err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	if err := precondition.Check("config group", name, version, configGroup.Revision); err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	if err := change(&configGroup); err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	stored, err := s.repo.Update(configGroup)
+	if err != nil {
+		return model.ConfigGroup{}, model.Change{}, err
+	}
+	configGroup.Revision = stored.Revision
+	return configGroup, stored, nil
+}
 
+// checkSchemas proverava ugrađene konfiguracije po šemama. Referencirane konfiguracije
+// su proverene kada su sačuvane, pa se ovde ne proveravaju ponovo.
+func (s ConfigGroupService) checkSchemas(configGroup model.ConfigGroup) error {
+	for _, config := range configGroup.Configuration {
+		if _, err := s.schemas.ValidateConfig(config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkReferences proverava da sve reference upućuju na postojeće konfiguracije
+// i briše razrešene kopije, jer se one nikad ne čuvaju u repozitorijumu
+func (s ConfigGroupService) checkReferences(configGroup *model.ConfigGroup) error {
+	for i, reference := range configGroup.References {
+		if _, err := s.configRepo.Get(reference.Name, reference.Version); err != nil {
+			return model.Conflictf("referenced config %s/%d not found", reference.Name, reference.Version)
+		}
+		configGroup.References[i].Config = nil
+	}
 	return nil
 }
+
+// resolve popunjava reference grupe trenutnim sadržajem konfiguracija iz ConfigRepository-ja
+func (s ConfigGroupService) resolve(configGroup *model.ConfigGroup) {
+	for i, reference := range configGroup.References {
+		config, err := s.configRepo.Get(reference.Name, reference.Version)
+		if err != nil {
+			log.Printf("config group %s/%d references missing config %s/%d", configGroup.Name, configGroup.Version, reference.Name, reference.Version)
+			continue
+		}
+		configGroup.References[i].Config = &config
+	}
+}
+
+// GetConfigsByLabels vraća konfiguracije grupe (ugrađene i referencirane) koje imaju sve labele iz selektora
+func (s ConfigGroupService) GetConfigsByLabels(name string, version int, selector map[string]string) ([]model.Config, error) {
+	configGroup, err := s.Get(name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := make([]model.Config, 0)
+	for _, config := range configGroup.Configs() {
+		if config.MatchesLabels(selector) {
+			matching = append(matching, config)
+		}
+	}
+	return matching, nil
+}
+
+// RemoveConfigsByLabels uklanja iz grupe ugrađene konfiguracije i reference koje odgovaraju selektoru.
+// Sve se uklanja jednim compare-and-swap upisom, pa grupa nikad ne ostaje delimično filtrirana.
+// Bez If-Match se upis ponavlja ako je grupa u međuvremenu izmenjena.
+func (s ConfigGroupService) RemoveConfigsByLabels(name string, version int, selector map[string]string, precondition model.Precondition) ([]model.Config, error) {
+	var removed []model.Config
+	err := s.audited("removeByLabels", name, version, func() (model.Change, error) {
+		var stored model.Change
+		var err error
+		for attempt := 1; ; attempt++ {
+			removed, stored, err = s.removeByLabels(name, version, selector, precondition)
+			if precondition.Set || !errors.Is(err, model.ErrPreconditionFailed) || attempt == updateAttempts {
+				return stored, err
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// removeByLabels čita grupu, uklanja ugrađene konfiguracije i razrešene reference po selektoru
+// i upisuje grupu compare-and-swap-om, pod uslovom iz If-Match ako je zadat
+func (s ConfigGroupService) removeByLabels(name string, version int, selector map[string]string, precondition model.Precondition) ([]model.Config, model.Change, error) {
+	var removed []model.Config
+	_, stored, err := s.updateIf(name, version, precondition, func(configGroup *model.ConfigGroup) error {
+		removed = configGroup.RemoveConfigsByLabels(selector)
+
+		s.resolve(configGroup)
+		kept := make([]model.ConfigReference, 0, len(configGroup.References))
+		for _, reference := range configGroup.References {
+			if reference.Config != nil && reference.Config.MatchesLabels(selector) {
+				removed = append(removed, *reference.Config)
+				continue
+			}
+			reference.Config = nil
+			kept = append(kept, reference)
+		}
+		configGroup.References = kept
+		if len(removed) == 0 {
+			return errUnchanged
+		}
+		return nil
+	})
+	if errors.Is(err, errUnchanged) {
+		return removed, model.Change{}, nil
+	}
+	if err != nil {
+		return nil, model.Change{}, err
+	}
+	return removed, stored, nil
+}
+
+// stampCreated postavlja vreme kreiranja i početnu reviziju nove grupe, a ugrađenim
+// konfiguracijama vreme kreiranja ako ga nemaju. Ugrađene konfiguracije nemaju svoju reviziju.
+func stampCreated(configGroup *model.ConfigGroup, now time.Time) {
+	configGroup.CreatedAt = now
+	configGroup.Revision = model.FirstRevision
+	for i := range configGroup.Configuration {
+		if configGroup.Configuration[i].CreatedAt.IsZero() {
+			configGroup.Configuration[i].CreatedAt = now
+		}
+		configGroup.Configuration[i].Revision = 0
+	}
+}
+
+// Diff poredi dve verzije grupe, uključujući konfiguracije na koje grupe upućuju
+func (s ConfigGroupService) Diff(name string, from, to int) (model.ConfigGroupDiff, error) {
+	fromGroup, err := s.Get(name, from)
+	if err != nil {
+		return model.ConfigGroupDiff{}, err
+	}
+	toGroup, err := s.Get(name, to)
+	if err != nil {
+		return model.ConfigGroupDiff{}, err
+	}
+	return model.DiffConfigGroups(fromGroup, toGroup), nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"projekat/audit"
+	"projekat/model"
+	"projekat/repositories"
+	"reflect"
+	"testing"
+)
+
+func newDiffTestServices(t *testing.T) (ConfigService, ConfigGroupService) {
+	t.Helper()
+	auditLog, err := audit.NewLog(repositories.NewAuditInMemRepository())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := repositories.NewConfigInMemRepository()
+	groupRepo := repositories.NewConfigGroupInMemRepository()
+	schemas := NewSchemaService(repositories.NewSchemaInMemRepository())
+	return NewConfigService(repo, groupRepo, schemas, RestrictReferences, auditLog),
+		NewConfigGroupService(groupRepo, repo, schemas, auditLog)
+}
+
+func TestConfigDiff(t *testing.T) {
+	configs, _ := newDiffTestServices(t)
+	for _, config := range []model.Config{
+		{Name: "db", Version: 1,
+			Parameters: model.Parameters{"host": "a", "port": "5432", "pool": map[string]interface{}{"max": "10"}, "debug": "true"},
+			Labels:     map[string]string{"env": "dev", "team": "core"}},
+		{Name: "db", Version: 2,
+			Parameters: model.Parameters{"host": "b", "port": "5432", "pool": map[string]interface{}{"max": "20"}, "tls": "on"},
+			Labels:     map[string]string{"env": "prod", "tier": "1"}},
+	} {
+		if err := configs.CreateConfig(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diff, err := configs.Diff("db", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := model.ConfigDiff{
+		Name:        "db",
+		FromVersion: 1,
+		ToVersion:   2,
+		Parameters: model.MapDiff{
+			Added:   map[string]interface{}{"tls": "on"},
+			Removed: map[string]interface{}{"debug": "true"},
+			Modified: map[string]model.ValueChange{
+				"host": {From: "a", To: "b"},
+				"pool": {From: map[string]interface{}{"max": "10"}, To: map[string]interface{}{"max": "20"}},
+			},
+		},
+		Labels: model.MapDiff{
+			Added:    map[string]interface{}{"tier": "1"},
+			Removed:  map[string]interface{}{"team": "core"},
+			Modified: map[string]model.ValueChange{"env": {From: "dev", To: "prod"}},
+		},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("got %+v, want %+v", diff, want)
+	}
+
+	// Obrnut redosled verzija daje obrnutu razliku
+	reverse, err := configs.Diff("db", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reverse.Parameters.Added, want.Parameters.Removed) || !reflect.DeepEqual(reverse.Labels.Removed, want.Labels.Added) {
+		t.Errorf("reverse diff is %+v", reverse)
+	}
+
+	same, err := configs.Diff("db", 1, 1)
+	if err != nil || !same.Empty() {
+		t.Errorf("diff of a version with itself is %+v, %v", same, err)
+	}
+
+	for _, missing := range [][2]int{{1, 3}, {3, 1}} {
+		if _, err := configs.Diff("db", missing[0], missing[1]); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("diff %d..%d: got %v, want ErrNotFound", missing[0], missing[1], err)
+		}
+	}
+	if _, err := configs.Diff("cache", 1, 2); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("diff of a missing config: got %v, want ErrNotFound", err)
+	}
+}
+
+// Reference se porede kao konfiguracije na koje upućuju, uparene po imenu kao i ugrađene
+func TestConfigGroupDiff(t *testing.T) {
+	configs, groups := newDiffTestServices(t)
+	for _, config := range []model.Config{
+		{Name: "db", Version: 1, Parameters: model.Parameters{"host": "a"}},
+		{Name: "db", Version: 2, Parameters: model.Parameters{"host": "b"}},
+		{Name: "cache", Version: 1, Parameters: model.Parameters{"size": "1"}},
+		{Name: "queue", Version: 1, Parameters: model.Parameters{"url": "q"}},
+	} {
+		if err := configs.CreateConfig(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, configGroup := range []model.ConfigGroup{
+		{Name: "app", Version: 1,
+			Configuration: []model.Config{
+				{Name: "web", Version: 1, Parameters: model.Parameters{"port": "80"}},
+				{Name: "old", Version: 1, Parameters: model.Parameters{"x": "1"}},
+				{Name: "same", Version: 1, Parameters: model.Parameters{"x": "1"}},
+			},
+			References: []model.ConfigReference{{Name: "db", Version: 1}, {Name: "cache", Version: 1}}},
+		{Name: "app", Version: 2,
+			Configuration: []model.Config{
+				{Name: "web", Version: 1, Parameters: model.Parameters{"port": "8080"}, Labels: map[string]string{"env": "prod"}},
+				{Name: "same", Version: 1, Parameters: model.Parameters{"x": "1"}},
+				{Name: "new", Version: 1, Parameters: model.Parameters{"y": "2"}},
+			},
+			References: []model.ConfigReference{{Name: "db", Version: 2}, {Name: "queue", Version: 1}}},
+	} {
+		if err := groups.Create(configGroup); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diff, err := groups.Diff("app", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Name != "app" || diff.FromVersion != 1 || diff.ToVersion != 2 {
+		t.Errorf("diff is of %s %d..%d", diff.Name, diff.FromVersion, diff.ToVersion)
+	}
+	if got := diffConfigKeys(diff.Added); !reflect.DeepEqual(got, []string{"new/1", "queue/1"}) {
+		t.Errorf("added %v", got)
+	}
+	if got := diffConfigKeys(diff.Removed); !reflect.DeepEqual(got, []string{"cache/1", "old/1"}) {
+		t.Errorf("removed %v", got)
+	}
+	if len(diff.Added) == 2 && diff.Added[1].Parameters["url"] != "q" {
+		t.Errorf("added reference is not resolved: %+v", diff.Added[1])
+	}
+
+	if len(diff.Changed) != 2 {
+		t.Fatalf("changed %+v", diff.Changed)
+	}
+	// Nova verzija konfiguracije na koju grupa upućuje je izmena, a ne brisanje i dodavanje
+	db := diff.Changed[0]
+	if db.Name != "db" || db.FromVersion != 1 || db.ToVersion != 2 ||
+		!reflect.DeepEqual(db.Parameters.Modified, map[string]model.ValueChange{"host": {From: "a", To: "b"}}) {
+		t.Errorf("db changed as %+v", db)
+	}
+	web := diff.Changed[1]
+	if web.Name != "web" || web.FromVersion != 1 || web.ToVersion != 1 ||
+		!reflect.DeepEqual(web.Parameters.Modified, map[string]model.ValueChange{"port": {From: "80", To: "8080"}}) ||
+		!reflect.DeepEqual(web.Labels.Added, map[string]interface{}{"env": "prod"}) {
+		t.Errorf("web changed as %+v", web)
+	}
+
+	for _, missing := range [][2]int{{1, 3}, {3, 1}} {
+		if _, err := groups.Diff("app", missing[0], missing[1]); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("diff %d..%d: got %v, want ErrNotFound", missing[0], missing[1], err)
+		}
+	}
+}
+
+func diffConfigKeys(configs []model.Config) []string {
+	keys := make([]string, 0, len(configs))
+	for _, config := range configs {
+		keys = append(keys, fmt.Sprintf("%s/%d", config.Name, config.Version))
+	}
+	return keys
+}
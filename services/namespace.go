@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"projekat/model"
+	"sort"
+	"time"
+)
+
+type NamespaceService struct {
+	repo       model.NamespaceRepository
+	configRepo model.ConfigRepository
+	groupRepo  model.ConfigGroupRepository
+}
+
+func NewNamespaceService(repo model.NamespaceRepository, configRepo model.ConfigRepository, groupRepo model.ConfigGroupRepository) NamespaceService {
+	return NamespaceService{
+		repo:       repo,
+		configRepo: configRepo,
+		groupRepo:  groupRepo,
+	}
+}
+
+// EnsureDefault pravi podrazumevani prostor imena ako još ne postoji. Poziva se pri pokretanju,
+// pa postojeće skladište posle nadogradnje dobija prostor imena za zapise bez njega.
+func (s NamespaceService) EnsureDefault() error {
+	_, err := s.repo.Get(model.DefaultNamespace)
+	if !errors.Is(err, model.ErrNotFound) {
+		return err
+	}
+	err = s.repo.Create(model.Namespace{
+		Name:        model.DefaultNamespace,
+		Description: "Configs and groups created without a namespace",
+		CreatedAt:   time.Now().UTC(),
+	})
+	if errors.Is(err, model.ErrAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+func (s NamespaceService) Create(namespace model.Namespace) (model.Namespace, error) {
+	if err := namespace.Validate(); err != nil {
+		return model.Namespace{}, err
+	}
+	namespace.CreatedAt = time.Now().UTC()
+	if err := s.repo.Create(namespace); err != nil {
+		return model.Namespace{}, err
+	}
+	return namespace, nil
+}
+
+func (s NamespaceService) Get(name string) (model.Namespace, error) {
+	return s.repo.Get(name)
+}
+
+// GetAll vraća prostore imena sortirane po imenu
+func (s NamespaceService) GetAll() ([]model.Namespace, error) {
+	namespaces, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Name < namespaces[j].Name })
+	return namespaces, nil
+}
+
+// Update menja opis i labele prostora imena; ime i vreme kreiranja se ne menjaju
+func (s NamespaceService) Update(namespace model.Namespace) (model.Namespace, error) {
+	stored, err := s.repo.Get(namespace.Name)
+	if err != nil {
+		return model.Namespace{}, err
+	}
+	stored.Description = namespace.Description
+	stored.Labels = namespace.Labels
+	if err := s.repo.Update(stored); err != nil {
+		return model.Namespace{}, err
+	}
+	return stored, nil
+}
+
+// Delete briše prazan prostor imena. Podrazumevani prostor imena se ne briše, a prostor imena
+// sa konfiguracijama ili grupama se odbija, da zapisi ne bi ostali nedostupni.
+func (s NamespaceService) Delete(name string) error {
+	if name == model.DefaultNamespace {
+		return model.Conflictf("the %s namespace cannot be deleted", model.DefaultNamespace)
+	}
+	if _, err := s.repo.Get(name); err != nil {
+		return err
+	}
+	configs, err := s.configRepo.In(name).GetAll()
+	if err != nil {
+		return err
+	}
+	configGroups, err := s.groupRepo.In(name).GetAll()
+	if err != nil {
+		return err
+	}
+	if len(configs) > 0 || len(configGroups) > 0 {
+		return model.Conflictf("namespace %s still has %d configs and %d config groups; delete them first", name, len(configs), len(configGroups))
+	}
+	return s.repo.Delete(name)
+}
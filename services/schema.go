@@ -0,0 +1,193 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"projekat/model"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+type SchemaService struct {
+	repo     model.SchemaRepository
+	compiled *compiledSchemas
+}
+
+// compiledSchemas čuva već prevedene šeme. Verzija šeme se nikad ne menja niti ponovo
+// dodeljuje, pa je ime/verzija dovoljan ključ.
+type compiledSchemas struct {
+	mu      sync.Mutex
+	schemas map[string]*jsonschema.Schema
+}
+
+func NewSchemaService(repo model.SchemaRepository) SchemaService {
+	return SchemaService{
+		repo:     repo,
+		compiled: &compiledSchemas{schemas: make(map[string]*jsonschema.Schema)},
+	}
+}
+
+func (s SchemaService) Create(schema model.Schema) error {
+	if err := schema.Validate(); err != nil {
+		return err
+	}
+	if _, err := compileSchema(schema); err != nil {
+		return err
+	}
+	schema.CreatedAt = time.Now().UTC()
+	return s.repo.Create(schema)
+}
+
+// CreateVersion čuva šemu pod sledećom slobodnom verzijom koju dodeljuje server
+func (s SchemaService) CreateVersion(name string, schema model.Schema) (model.Schema, error) {
+	if schema.Name != "" && schema.Name != name {
+		return model.Schema{}, model.Invalidf("schema name %q does not match %q from the path", schema.Name, name)
+	}
+	if err := model.ValidateName("schema", name); err != nil {
+		return model.Schema{}, err
+	}
+	if len(schema.Schema) == 0 {
+		return model.Schema{}, model.Invalidf("schema %s has no schema document", name)
+	}
+	schema.Name = name
+	if _, err := compileSchema(schema); err != nil {
+		return model.Schema{}, err
+	}
+	schema.CreatedAt = time.Now().UTC()
+	return s.repo.CreateNextVersion(schema)
+}
+
+func (s SchemaService) Get(name string, version int) (model.Schema, error) {
+	return s.repo.Get(name, version)
+}
+
+// Latest vraća najnoviju verziju šeme
+func (s SchemaService) Latest(name string) (model.Schema, error) {
+	schemas, err := s.repo.ListByName(name)
+	if err != nil {
+		return model.Schema{}, err
+	}
+	if len(schemas) == 0 {
+		return model.Schema{}, model.NotFoundf("schema %s not found", name)
+	}
+	return schemas[len(schemas)-1], nil
+}
+
+// Versions vraća sve verzije šeme
+func (s SchemaService) Versions(name string) ([]model.Schema, error) {
+	schemas, err := s.repo.ListByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(schemas) == 0 {
+		return nil, model.NotFoundf("schema %s not found", name)
+	}
+	return schemas, nil
+}
+
+func (s SchemaService) GetAll() ([]model.Schema, error) {
+	return s.repo.GetAll()
+}
+
+// Delete briše verziju šeme; konfiguracije se od tada proveravaju po prethodnoj verziji, ako postoji
+func (s SchemaService) Delete(name string, version int) error {
+	return s.repo.Delete(name, version)
+}
+
+// ValidateConfig proverava parametre konfiguracije po najnovijoj šemi za njeno ime i vraća
+// verziju korišćene šeme (0 ako šema ne postoji, kada se svaka konfiguracija prihvata).
+// Odstupanja se vraćaju kao *model.SchemaViolationError.
+func (s SchemaService) ValidateConfig(config model.Config) (int, error) {
+	schemas, err := s.repo.ListByName(config.Name)
+	if err != nil {
+		return 0, err
+	}
+	if len(schemas) == 0 {
+		return 0, nil
+	}
+	schema := schemas[len(schemas)-1]
+
+	compiled, err := s.compiled.get(schema)
+	if err != nil {
+		return 0, err
+	}
+
+	// Validator očekuje vrednosti kakve daje JSON dekoder, a parametri mogu biti zadati i iz koda
+	var instance interface{} = map[string]interface{}{}
+	if config.Parameters != nil {
+		instance = model.NormalizeValue(map[string]interface{}(config.Parameters))
+	}
+
+	err = compiled.Validate(instance)
+	var validationErr *jsonschema.ValidationError
+	if errors.As(err, &validationErr) {
+		found := violations(validationErr, nil)
+		sort.SliceStable(found, func(i, j int) bool { return found[i].Path < found[j].Path })
+		return schema.Version, &model.SchemaViolationError{
+			Config:        config.Name,
+			SchemaVersion: schema.Version,
+			Violations:    found,
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("cannot validate config %s against schema %s/%d: %w", config.Name, schema.Name, schema.Version, err)
+	}
+	return schema.Version, nil
+}
+
+func (c *compiledSchemas) get(schema model.Schema) (*jsonschema.Schema, error) {
+	key := fmt.Sprintf("%s/%d", schema.Name, schema.Version)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if compiled, ok := c.schemas[key]; ok {
+		return compiled, nil
+	}
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	c.schemas[key] = compiled
+	return compiled, nil
+}
+
+// compileSchema prevodi JSON Schema dokument (podrazumevano draft 2020-12, ili draft zadat sa $schema)
+func compileSchema(schema model.Schema) (*jsonschema.Schema, error) {
+	url := fmt.Sprintf("mem:///schemas/%s/%d.json", schema.Name, schema.Version)
+
+	compiler := jsonschema.NewCompiler()
+	// Šema ne sme da učitava spoljne dokumente: $ref na fajl ili URL bi čitao sa servera ili mreže
+	compiler.LoadURL = func(ref string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("external reference %s is not allowed", ref)
+	}
+	if err := compiler.AddResource(url, bytes.NewReader(schema.Schema)); err != nil {
+		return nil, model.Invalidf("schema %s is not valid JSON: %v", schema.Name, err)
+	}
+	compiled, err := compiler.Compile(url)
+	if err != nil {
+		// Interni URL dokumenta nije od koristi korisniku, pa se prikazuje samo uzrok
+		var schemaErr *jsonschema.SchemaError
+		if errors.As(err, &schemaErr) && schemaErr.Err != nil {
+			err = schemaErr.Err
+		}
+		return nil, model.Invalidf("schema %s is not a valid JSON Schema: %v", schema.Name, err)
+	}
+	return compiled, nil
+}
+
+// violations skuplja konkretna odstupanja; greške sa uzrocima samo grupišu druge greške
+func violations(err *jsonschema.ValidationError, found []model.Violation) []model.Violation {
+	if len(err.Causes) == 0 {
+		return append(found, model.Violation{Path: err.InstanceLocation, Message: err.Message})
+	}
+	for _, cause := range err.Causes {
+		found = violations(cause, found)
+	}
+	return found
+}
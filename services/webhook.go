@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"projekat/model"
+	"projekat/webhooks"
+	"sort"
+	"time"
+)
+
+type WebhookService struct {
+	repo       model.WebhookRepository
+	dispatcher *webhooks.Dispatcher
+	// Da li pretplata sme da šalje na loopback, privatne i link-local adrese
+	allowPrivateTargets bool
+}
+
+func NewWebhookService(repo model.WebhookRepository, dispatcher *webhooks.Dispatcher, allowPrivateTargets bool) WebhookService {
+	return WebhookService{
+		repo:                repo,
+		dispatcher:          dispatcher,
+		allowPrivateTargets: allowPrivateTargets,
+	}
+}
+
+// Create čuva pretplatu i vraća je zajedno sa ključem za potpis, jedini put kada se ključ vidi.
+// Oznaku dodeljuje server, a ključ i on ako ga klijent nije poslao. URL koji upućuje na
+// loopback, privatnu ili link-local adresu se odbija, osim ako su takve adrese dozvoljene.
+func (s WebhookService) Create(webhook model.Webhook) (model.Webhook, error) {
+	webhook.EncryptedSecret = nil
+	if err := webhook.Validate(); err != nil {
+		return model.Webhook{}, err
+	}
+	if !s.allowPrivateTargets {
+		if err := webhooks.CheckTarget(webhook.URL); err != nil {
+			return model.Webhook{}, err
+		}
+	}
+
+	id, err := webhooks.NewID()
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	webhook.ID = id
+	if webhook.Secret == "" {
+		if webhook.Secret, err = webhooks.NewSecret(); err != nil {
+			return model.Webhook{}, err
+		}
+	}
+	webhook.CreatedAt = time.Now().UTC()
+
+	if err := s.repo.Create(webhook); err != nil {
+		return model.Webhook{}, err
+	}
+	s.dispatcher.SubscriptionsChanged()
+	return webhook, nil
+}
+
+func (s WebhookService) Get(id string) (model.Webhook, error) {
+	webhook, err := s.repo.Get(id)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	return webhook.WithoutSecret(), nil
+}
+
+// GetAll vraća pretplate bez ključeva, po redosledu kreiranja
+func (s WebhookService) GetAll() ([]model.Webhook, error) {
+	all, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for i, webhook := range all {
+		all[i] = webhook.WithoutSecret()
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].CreatedAt.Before(all[j].CreatedAt)
+		}
+		return all[i].ID < all[j].ID
+	})
+	return all, nil
+}
+
+// Delete briše pretplatu; njene neuspele isporuke ostaju u dead-letter listi dok se ne obrišu
+func (s WebhookService) Delete(id string) error {
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	s.dispatcher.SubscriptionsChanged()
+	return nil
+}
+
+// DeadLetters vraća neuspele isporuke, od najstarije; webhookID ih ograničava na jednu pretplatu
+func (s WebhookService) DeadLetters(webhookID string) ([]model.DeadLetter, error) {
+	all, err := s.repo.GetAllDeadLetters()
+	if err != nil {
+		return nil, err
+	}
+	letters := make([]model.DeadLetter, 0, len(all))
+	for _, letter := range all {
+		if webhookID == "" || letter.WebhookID == webhookID {
+			letters = append(letters, letter)
+		}
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		if letters[i].EventRevision != letters[j].EventRevision {
+			return letters[i].EventRevision < letters[j].EventRevision
+		}
+		return letters[i].FailedAt.Before(letters[j].FailedAt)
+	})
+	return letters, nil
+}
+
+func (s WebhookService) DeadLetter(id string) (model.DeadLetter, error) {
+	return s.repo.GetDeadLetter(id)
+}
+
+func (s WebhookService) DeleteDeadLetter(id string) error {
+	return s.repo.DeleteDeadLetter(id)
+}
+
+// Replay ponovo šalje neuspelu isporuku na trenutni URL pretplate, jednim pokušajem. Uspešna
+// isporuka se uklanja iz dead-letter liste, a neuspela ostaje sa uvećanim brojem pokušaja i
+// vraća *webhooks.DeliveryError.
+func (s WebhookService) Replay(ctx context.Context, id string) error {
+	letter, err := s.repo.GetDeadLetter(id)
+	if err != nil {
+		return err
+	}
+	webhook, err := s.repo.Get(letter.WebhookID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.Conflictf("webhook %s of dead letter %s no longer exists", letter.WebhookID, id)
+		}
+		return err
+	}
+
+	sendErr := s.dispatcher.Send(ctx, webhook, letter.ID, letter.Payload)
+	if sendErr == nil {
+		return s.repo.DeleteDeadLetter(id)
+	}
+
+	letter.URL = webhook.URL
+	letter.Attempts++
+	letter.LastError = sendErr.Error()
+	letter.FailedAt = time.Now().UTC()
+	if err := s.repo.AddDeadLetter(letter); err != nil {
+		return err
+	}
+	return sendErr
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"projekat/model"
+	"projekat/repositories"
+	"projekat/webhooks"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReplayDeadLetter(t *testing.T) {
+	var mu sync.Mutex
+	status := http.StatusInternalServerError
+	var deliveries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		deliveries = append(deliveries, r.Header.Get(webhooks.DeliveryHeader))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	repo := repositories.NewWebhookInMemRepository()
+	dispatcher := webhooks.NewDispatcher(repo, server.Client(), webhooks.RetryPolicy{Attempts: 1})
+	service := NewWebhookService(repo, dispatcher, true)
+
+	webhook, err := service.Create(model.Webhook{URL: server.URL, Prefix: "configs/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Isporuka je ranije išla na stari URL; ponovno slanje koristi trenutni URL pretplate
+	letter := model.DeadLetter{
+		ID:            "delivery-1",
+		WebhookID:     webhook.ID,
+		URL:           "http://127.0.0.1:1/old",
+		EventRevision: 4,
+		Payload:       []byte(`{"delivery":"delivery-1"}`),
+		Attempts:      5,
+		LastError:     "webhook responded with 500 Internal Server Error",
+		FailedAt:      time.Now().UTC(),
+	}
+	if err := repo.AddDeadLetter(letter); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neuspelo ponovno slanje ostavlja isporuku u listi, sa uvećanim brojem pokušaja
+	err = service.Replay(context.Background(), letter.ID)
+	var deliveryErr *webhooks.DeliveryError
+	if !errors.As(err, &deliveryErr) || deliveryErr.Status != http.StatusInternalServerError {
+		t.Fatalf("got %v, want a delivery error with status 500", err)
+	}
+	stored, err := service.DeadLetter(letter.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Attempts != 6 || stored.URL != server.URL {
+		t.Errorf("after a failed replay got attempts %d and URL %s, want 6 and %s", stored.Attempts, stored.URL, server.URL)
+	}
+
+	mu.Lock()
+	status = http.StatusNoContent
+	mu.Unlock()
+	if err := service.Replay(context.Background(), letter.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.DeadLetter(letter.ID); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("dead letter is still stored after a successful replay: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(deliveries) != 2 || deliveries[0] != letter.ID || deliveries[1] != letter.ID {
+		t.Errorf("replays were sent as deliveries %v, want the original delivery %s twice", deliveries, letter.ID)
+	}
+}
+
+func TestReplayWithoutWebhook(t *testing.T) {
+	repo := repositories.NewWebhookInMemRepository()
+	service := NewWebhookService(repo, webhooks.NewDispatcher(repo, http.DefaultClient, webhooks.RetryPolicy{Attempts: 1}), true)
+	if err := repo.AddDeadLetter(model.DeadLetter{ID: "delivery-1", WebhookID: "deleted", Payload: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Replay(context.Background(), "delivery-1"); !errors.Is(err, model.ErrConflict) {
+		t.Errorf("got %v, want ErrConflict", err)
+	}
+	if err := service.Replay(context.Background(), "missing"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestCreateWebhookChecksTarget(t *testing.T) {
+	repo := repositories.NewWebhookInMemRepository()
+	dispatcher := webhooks.NewDispatcher(repo, http.DefaultClient, webhooks.RetryPolicy{Attempts: 1})
+
+	service := NewWebhookService(repo, dispatcher, false)
+	for _, url := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest/meta-data/", "http://localhost/hook", "http://192.168.0.10/hook"} {
+		if _, err := service.Create(model.Webhook{URL: url}); !errors.Is(err, model.ErrInvalid) {
+			t.Errorf("%s: got %v, want ErrInvalid", url, err)
+		}
+	}
+	if _, err := service.Create(model.Webhook{URL: "https://93.184.216.34/hook"}); err != nil {
+		t.Errorf("public target: %v", err)
+	}
+
+	allowing := NewWebhookService(repo, dispatcher, true)
+	if _, err := allowing.Create(model.Webhook{URL: "http://127.0.0.1:8080/hook"}); err != nil {
+		t.Errorf("loopback target with private targets allowed: %v", err)
+	}
+}
@@ -0,0 +1,311 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"projekat/events"
+	"projekat/model"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Koliko zahteva isporuke može da bude u toku istovremeno; kada su svi slotovi zauzeti, novi događaji
+// čekaju. Isporuka drži slot samo dok traje zahtev, a ne i dok čeka na sledeći pokušaj.
+const maxConcurrentDeliveries = 16
+
+// Koliko dugo se koristi pročitana lista pretplata. Izmene kroz ovaj server je odmah osvežavaju
+// (SubscriptionsChanged), pa rok važi samo za izmene koje backend dobije mimo njega.
+const subscriptionsTTL = 30 * time.Second
+
+// Payload je telo isporuke. Delivery je isti za sve pokušaje i za ponovno slanje iz
+// dead-letter liste, pa primalac po njemu prepoznaje duplikate.
+type Payload struct {
+	Delivery  string       `json:"delivery"`
+	WebhookID string       `json:"webhookId"`
+	Event     events.Event `json:"event"`
+}
+
+// RetryPolicy određuje ponovne pokušaje: posle n-tog neuspeha čeka se Backoff·2^(n-1), najviše MaxBackoff
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func (p RetryPolicy) delay(failed int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < failed && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// DeliveryError je neuspeo pokušaj isporuke: greška veze ili odgovor van 2xx
+type DeliveryError struct {
+	Status int
+	Err    error
+}
+
+func (e *DeliveryError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("webhook delivery failed: %v", e.Err)
+	}
+	return fmt.Sprintf("webhook responded with %d %s", e.Status, http.StatusText(e.Status))
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// Dispatcher šalje događaje iz loga izmena pretplatama čiji prefiks i vrste odgovaraju.
+// Neuspele isporuke ponavlja po RetryPolicy, a posle poslednjeg pokušaja ih upisuje u dead-letter listu.
+// Isporuka primaocu koji vraća grešku ne zauzima slot dok čeka na ponovni pokušaj, pa takav
+// primalac ne usporava isporuke ostalim pretplatama.
+//
+// Događaji dolaze iz events.Log ove instance, pa isporuke imaju iste granice kao /watch: redosled je
+// redosled upisa kroz ovu instancu, a izmene upisane preko druge instance se ne šalju. Ako više
+// instanci deli backend, svaka šalje samo svoje izmene.
+type Dispatcher struct {
+	repo   model.WebhookRepository
+	client *http.Client
+	retry  RetryPolicy
+	slots  chan struct{}
+
+	mu            sync.Mutex
+	subscriptions []model.Webhook
+	loadedAt      time.Time
+}
+
+func NewDispatcher(repo model.WebhookRepository, client *http.Client, retry RetryPolicy) *Dispatcher {
+	if retry.Attempts < 1 {
+		retry.Attempts = 1
+	}
+	if retry.MaxBackoff < retry.Backoff {
+		retry.MaxBackoff = retry.Backoff
+	}
+	return &Dispatcher{
+		repo:   repo,
+		client: client,
+		retry:  retry,
+		slots:  make(chan struct{}, maxConcurrentDeliveries),
+	}
+}
+
+// Run prati log izmena od trenutne revizije i šalje događaje dok ctx ne istekne
+func (d *Dispatcher) Run(ctx context.Context, changes *events.Log) {
+	since := changes.Revision()
+	for {
+		found, revision, err := changes.Wait(ctx, since, "")
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			// Isporuke su zaostale više od istorije loga; preskočeni događaji se ne mogu poslati
+			log.Printf("webhooks: %v; resuming from revision %d", err, revision)
+		}
+		for _, event := range found {
+			d.Dispatch(ctx, event)
+		}
+		since = revision
+	}
+}
+
+// Dispatch pokreće isporuku događaja svakoj pretplati koja ga prati
+func (d *Dispatcher) Dispatch(ctx context.Context, event events.Event) {
+	webhooks, err := d.webhooks()
+	if err != nil {
+		log.Printf("webhooks: cannot read subscriptions for revision %d: %v", event.Revision, err)
+		return
+	}
+	for _, webhook := range webhooks {
+		if !Matches(webhook, event) {
+			continue
+		}
+		id, err := NewID()
+		if err != nil {
+			log.Printf("webhooks: %v", err)
+			return
+		}
+		payload, err := json.Marshal(Payload{Delivery: id, WebhookID: webhook.ID, Event: event})
+		if err != nil {
+			log.Printf("webhooks: %v", err)
+			return
+		}
+
+		if !d.acquire(ctx) {
+			return
+		}
+		go d.deliver(ctx, &delivery{webhook: webhook, id: id, revision: event.Revision, payload: payload})
+	}
+}
+
+// webhooks vraća pretplate iz keša, a čita ih iz repozitorijuma kada rok istekne. Ako čitanje ne
+// uspe, koristi se poslednja pročitana lista.
+func (d *Dispatcher) webhooks() ([]model.Webhook, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.subscriptions != nil && time.Since(d.loadedAt) < subscriptionsTTL {
+		return d.subscriptions, nil
+	}
+
+	webhooks, err := d.repo.GetAll()
+	if err != nil {
+		if d.subscriptions != nil {
+			log.Printf("webhooks: cannot refresh subscriptions, using the ones read at %s: %v", d.loadedAt.Format(time.RFC3339), err)
+			return d.subscriptions, nil
+		}
+		return nil, err
+	}
+	if webhooks == nil {
+		webhooks = []model.Webhook{}
+	}
+	d.subscriptions, d.loadedAt = webhooks, time.Now()
+	return webhooks, nil
+}
+
+// SubscriptionsChanged odbacuje keš pretplata, pa sledeći događaj ponovo čita listu
+func (d *Dispatcher) SubscriptionsChanged() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subscriptions = nil
+}
+
+// Matches proverava da li pretplata prati događaj
+func Matches(webhook model.Webhook, event events.Event) bool {
+	if !event.Matches(webhook.Prefix) {
+		return false
+	}
+	if len(webhook.Types) == 0 {
+		return true
+	}
+	for _, eventType := range webhook.Types {
+		if eventType == string(event.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// delivery je isporuka jednog događaja jednoj pretplati
+type delivery struct {
+	webhook  model.Webhook
+	id       string
+	revision int64
+	payload  []byte
+	attempts int
+}
+
+// acquire zauzima slot za jedan zahtev isporuke; vraća false ako ctx istekne pre toga
+func (d *Dispatcher) acquire(ctx context.Context) bool {
+	select {
+	case d.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (d *Dispatcher) release() {
+	<-d.slots
+}
+
+// deliver šalje isporuku uz ponovne pokušaje, a posle poslednjeg neuspeha je upisuje u dead-letter
+// listu. Pozivalac je zauzeo slot za prvi pokušaj; slot se oslobađa posle svakog zahteva, a za
+// sledeći pokušaj se zauzima ponovo kada tajmer istekne.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *delivery) {
+	for {
+		delivery.attempts++
+		err := d.Send(ctx, delivery.webhook, delivery.id, delivery.payload)
+		d.release()
+		if err == nil {
+			return
+		}
+		if delivery.attempts >= d.retry.Attempts {
+			d.deadLetter(delivery, err)
+			return
+		}
+
+		timer := time.NewTimer(d.retry.delay(delivery.attempts))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+		if ctx.Err() != nil || !d.acquire(ctx) {
+			d.deadLetter(delivery, fmt.Errorf("%w (delivery interrupted by shutdown)", err))
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deadLetter(delivery *delivery, err error) {
+	letter := model.DeadLetter{
+		ID:            delivery.id,
+		WebhookID:     delivery.webhook.ID,
+		URL:           delivery.webhook.URL,
+		EventRevision: delivery.revision,
+		Payload:       delivery.payload,
+		Attempts:      delivery.attempts,
+		LastError:     err.Error(),
+		FailedAt:      time.Now().UTC(),
+	}
+	if err := d.repo.AddDeadLetter(letter); err != nil {
+		log.Printf("webhooks: cannot store dead letter %s for webhook %s: %v", delivery.id, delivery.webhook.ID, err)
+	}
+}
+
+// Send šalje jednu potpisanu isporuku, bez ponovnih pokušaja. Uspeh je svaki 2xx odgovor.
+func (d *Dispatcher) Send(ctx context.Context, webhook model.Webhook, id string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return &DeliveryError{Err: err}
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeader, webhook.ID)
+	req.Header.Set(DeliveryHeader, id)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return &DeliveryError{Err: err}
+	}
+	// Telo se pročita do kraja da bi veza mogla ponovo da se koristi
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &DeliveryError{Status: resp.StatusCode}
+	}
+	return nil
+}
+
+// NewID vraća nasumičnu oznaku pretplate ili isporuke
+func NewID() (string, error) {
+	return randomHex(16)
+}
+
+// NewSecret vraća nasumičan ključ za potpis, za pretplate za koje ga klijent nije zadao
+func NewSecret() (string, error) {
+	return randomHex(32)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate random value: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
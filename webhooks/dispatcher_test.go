@@ -0,0 +1,327 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"projekat/events"
+	"projekat/model"
+	"projekat/repositories"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123"
+
+// receiver je primalac isporuka koji pamti zahteve i odgovara statusom koji vrati respond
+type receiver struct {
+	mu       sync.Mutex
+	requests []receivedRequest
+	respond  func(n int) int
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+	at     time.Time
+}
+
+func newReceiver(t *testing.T, respond func(n int) int) (*receiver, *httptest.Server) {
+	rec := &receiver{respond: respond}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		rec.requests = append(rec.requests, receivedRequest{header: r.Header.Clone(), body: body, at: time.Now()})
+		n := len(rec.requests)
+		rec.mu.Unlock()
+		w.WriteHeader(rec.respond(n))
+	}))
+	t.Cleanup(server.Close)
+	return rec, server
+}
+
+func (r *receiver) received() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedRequest(nil), r.requests...)
+}
+
+// eventually ponavlja proveru dok ne uspe ili ne istekne rok
+func eventually(t *testing.T, what string, check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func addWebhook(t *testing.T, repo model.WebhookRepository, id, url, prefix string) model.Webhook {
+	t.Helper()
+	webhook := model.Webhook{ID: id, URL: url, Prefix: prefix, Secret: testSecret, CreatedAt: time.Now().UTC()}
+	if err := repo.Create(webhook); err != nil {
+		t.Fatal(err)
+	}
+	return webhook
+}
+
+func testEvent(revision int64, name string) events.Event {
+	return events.Event{
+		Revision:  revision,
+		Type:      events.Updated,
+		Kind:      events.KindConfig,
+		Key:       events.Key(events.KindConfig, model.DefaultNamespace, name, 1),
+		Namespace: model.DefaultNamespace,
+		Name:      name,
+		Version:   1,
+		Time:      time.Now().UTC(),
+	}
+}
+
+func TestSendSignsDelivery(t *testing.T) {
+	rec, server := newReceiver(t, func(int) int { return http.StatusNoContent })
+	repo := repositories.NewWebhookInMemRepository()
+	webhook := addWebhook(t, repo, "hook", server.URL, "configs/")
+	d := NewDispatcher(repo, server.Client(), RetryPolicy{Attempts: 1})
+
+	payload := []byte(`{"delivery":"d1"}`)
+	if err := d.Send(context.Background(), webhook, "d1", payload); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := rec.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	if got := req.header.Get(WebhookHeader); got != "hook" {
+		t.Errorf("%s = %q, want hook", WebhookHeader, got)
+	}
+	if got := req.header.Get(DeliveryHeader); got != "d1" {
+		t.Errorf("%s = %q, want d1", DeliveryHeader, got)
+	}
+	timestamp, signature := req.header.Get(TimestampHeader), req.header.Get(SignatureHeader)
+	if err := Verify(testSecret, timestamp, signature, req.body, time.Minute, time.Now()); err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+
+	if err := Verify("another-secret-value", timestamp, signature, req.body, time.Minute, time.Now()); err == nil {
+		t.Error("signature verified with a different secret")
+	}
+	if err := Verify(testSecret, timestamp, signature, []byte(`{"delivery":"d2"}`), time.Minute, time.Now()); err == nil {
+		t.Error("signature verified for a modified body")
+	}
+	if err := Verify(testSecret, timestamp, signature, req.body, time.Minute, time.Now().Add(time.Hour)); err == nil {
+		t.Error("signature verified outside the tolerance window")
+	}
+}
+
+func TestSendReportsNon2xx(t *testing.T) {
+	_, server := newReceiver(t, func(int) int { return http.StatusBadGateway })
+	repo := repositories.NewWebhookInMemRepository()
+	webhook := addWebhook(t, repo, "hook", server.URL, "configs/")
+	d := NewDispatcher(repo, server.Client(), RetryPolicy{Attempts: 1})
+
+	err := d.Send(context.Background(), webhook, "d1", []byte(`{}`))
+	deliveryErr, ok := err.(*DeliveryError)
+	if !ok {
+		t.Fatalf("got %v, want *DeliveryError", err)
+	}
+	if deliveryErr.Status != http.StatusBadGateway {
+		t.Errorf("status = %d, want %d", deliveryErr.Status, http.StatusBadGateway)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Attempts: 10, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, expected := range want {
+		if got := policy.delay(i + 1); got != expected {
+			t.Errorf("delay(%d) = %s, want %s", i+1, got, expected)
+		}
+	}
+}
+
+func TestDispatchRetriesWithBackoff(t *testing.T) {
+	rec, server := newReceiver(t, func(n int) int {
+		if n < 3 {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	})
+	repo := repositories.NewWebhookInMemRepository()
+	addWebhook(t, repo, "hook", server.URL, "configs/")
+	backoff := 20 * time.Millisecond
+	d := NewDispatcher(repo, server.Client(), RetryPolicy{Attempts: 5, Backoff: backoff, MaxBackoff: time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.Dispatch(ctx, testEvent(7, "db_config"))
+	eventually(t, "three attempts", func() bool { return len(rec.received()) == 3 })
+
+	requests := rec.received()
+	delivery := requests[0].header.Get(DeliveryHeader)
+	for i, req := range requests {
+		if got := req.header.Get(DeliveryHeader); got != delivery {
+			t.Errorf("attempt %d has delivery %q, want %q", i+1, got, delivery)
+		}
+	}
+	if gap := requests[1].at.Sub(requests[0].at); gap < backoff {
+		t.Errorf("second attempt after %s, want at least %s", gap, backoff)
+	}
+	if gap := requests[2].at.Sub(requests[1].at); gap < 2*backoff {
+		t.Errorf("third attempt after %s, want at least %s", gap, 2*backoff)
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(requests[2].body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Delivery != delivery || payload.WebhookID != "hook" || payload.Event.Revision != 7 {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+
+	time.Sleep(5 * backoff)
+	if n := len(rec.received()); n != 3 {
+		t.Errorf("got %d attempts after a successful delivery, want 3", n)
+	}
+	letters, err := repo.GetAllDeadLetters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 0 {
+		t.Errorf("successful delivery was dead-lettered: %+v", letters)
+	}
+}
+
+func TestDispatchDeadLettersAfterLastAttempt(t *testing.T) {
+	rec, server := newReceiver(t, func(int) int { return http.StatusServiceUnavailable })
+	repo := repositories.NewWebhookInMemRepository()
+	addWebhook(t, repo, "hook", server.URL, "configs/")
+	addWebhook(t, repo, "other", server.URL, "configGroups/")
+	d := NewDispatcher(repo, server.Client(), RetryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.Dispatch(ctx, testEvent(3, "db_config"))
+
+	var letters []model.DeadLetter
+	eventually(t, "a dead letter", func() bool {
+		var err error
+		letters, err = repo.GetAllDeadLetters()
+		return err == nil && len(letters) == 1
+	})
+	letter := letters[0]
+	if letter.WebhookID != "hook" || letter.URL != server.URL || letter.EventRevision != 3 {
+		t.Errorf("unexpected dead letter %+v", letter)
+	}
+	if letter.Attempts != 3 {
+		t.Errorf("attempts = %d, want 3", letter.Attempts)
+	}
+	if !strings.Contains(letter.LastError, "503") {
+		t.Errorf("last error %q does not mention the response status", letter.LastError)
+	}
+	if n := len(rec.received()); n != 3 {
+		t.Errorf("receiver got %d requests, want 3 (the configGroups/ webhook must not match)", n)
+	}
+	if got := rec.received()[0].header.Get(DeliveryHeader); got != letter.ID {
+		t.Errorf("dead letter id %q differs from delivery %q", letter.ID, got)
+	}
+	var payload Payload
+	if err := json.Unmarshal(letter.Payload, &payload); err != nil || payload.Delivery != letter.ID {
+		t.Errorf("dead letter payload %s does not match the delivery (%v)", letter.Payload, err)
+	}
+}
+
+// Isporuka koja čeka na ponovni pokušaj ne sme da drži slot, inače bi jedan primalac koji ne
+// radi zaustavio isporuke svim ostalim pretplatama
+func TestDispatchReleasesSlotWhileWaitingForRetry(t *testing.T) {
+	failing, failingServer := newReceiver(t, func(int) int { return http.StatusInternalServerError })
+	working, workingServer := newReceiver(t, func(int) int { return http.StatusOK })
+	repo := repositories.NewWebhookInMemRepository()
+	addWebhook(t, repo, "failing", failingServer.URL, "configs/failing")
+	addWebhook(t, repo, "working", workingServer.URL, "configs/working")
+	d := NewDispatcher(repo, http.DefaultClient, RetryPolicy{Attempts: 2, Backoff: time.Hour, MaxBackoff: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	waiting := 2 * maxConcurrentDeliveries
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < waiting; i++ {
+			d.Dispatch(ctx, testEvent(int64(i+1), "failing"))
+		}
+		d.Dispatch(ctx, testEvent(int64(waiting+1), "working"))
+	}()
+	eventually(t, "first attempts of failing deliveries", func() bool { return len(failing.received()) == waiting })
+	eventually(t, "delivery to the working webhook", func() bool { return len(working.received()) == 1 })
+	<-done
+
+	// Pri gašenju se isporuke koje čekaju upisuju u dead-letter listu
+	cancel()
+	eventually(t, "dead letters for interrupted deliveries", func() bool {
+		letters, err := repo.GetAllDeadLetters()
+		return err == nil && len(letters) == waiting
+	})
+	letters, _ := repo.GetAllDeadLetters()
+	for _, letter := range letters {
+		if letter.Attempts != 1 || !strings.Contains(letter.LastError, "interrupted by shutdown") {
+			t.Fatalf("unexpected dead letter %+v", letter)
+		}
+	}
+}
+
+// countingRepository broji čitanja liste pretplata
+type countingRepository struct {
+	model.WebhookRepository
+	mu    sync.Mutex
+	reads int
+}
+
+func (r *countingRepository) GetAll() ([]model.Webhook, error) {
+	r.mu.Lock()
+	r.reads++
+	r.mu.Unlock()
+	return r.WebhookRepository.GetAll()
+}
+
+func (r *countingRepository) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reads
+}
+
+func TestDispatchCachesSubscriptions(t *testing.T) {
+	rec, server := newReceiver(t, func(int) int { return http.StatusOK })
+	repo := &countingRepository{WebhookRepository: repositories.NewWebhookInMemRepository()}
+	addWebhook(t, repo, "hook", server.URL, "configs/")
+	d := NewDispatcher(repo, server.Client(), RetryPolicy{Attempts: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := 1; i <= 3; i++ {
+		d.Dispatch(ctx, testEvent(int64(i), "db_config"))
+	}
+	eventually(t, "three deliveries", func() bool { return len(rec.received()) == 3 })
+	if n := repo.count(); n != 1 {
+		t.Errorf("subscriptions were read %d times for three events, want once", n)
+	}
+
+	// Pretplata dodata mimo servisa se vidi tek kada se keš odbaci
+	addWebhook(t, repo, "added", server.URL, "configs/")
+	d.Dispatch(ctx, testEvent(4, "db_config"))
+	eventually(t, "fourth delivery", func() bool { return len(rec.received()) == 4 })
+	d.SubscriptionsChanged()
+	d.Dispatch(ctx, testEvent(5, "db_config"))
+	eventually(t, "deliveries to both webhooks", func() bool { return len(rec.received()) == 6 })
+	if n := repo.count(); n != 2 {
+		t.Errorf("subscriptions were read %d times, want 2", n)
+	}
+}
@@ -0,0 +1,50 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Zaglavlja svake isporuke
+const (
+	WebhookHeader   = "X-Webhook-Id"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+// Sign potpisuje isporuku: HMAC-SHA256 ključem pretplate nad "{timestamp}.{telo}", heksadecimalno.
+// Vreme je deo potpisa, pa primalac može da odbije stare, ponovo poslate zahteve.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify proverava potpis primljene isporuke i da vreme potpisa nije starije od tolerance.
+// Namenjen je primaocima napisanim u Go-u i testovima.
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid webhook timestamp")
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return errors.New("webhook timestamp is outside the allowed window")
+	}
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return errors.New("unsupported webhook signature")
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, seconds, body))) {
+		return errors.New("webhook signature does not match")
+	}
+	return nil
+}
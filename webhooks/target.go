@@ -0,0 +1,112 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"projekat/model"
+	"syscall"
+	"time"
+)
+
+// Koliko dugo se pri kreiranju pretplate čeka na razrešavanje imena iz URL-a
+const resolveTimeout = 5 * time.Second
+
+// Opsezi koje net.IP ne prepoznaje kao privatne, a nisu javne adrese: "ova mreža", deljeni opseg
+// operatera (CGNAT), IETF protokoli, testiranje performansi i rezervisani opseg sa broadcast-om
+var reservedNetworks = parseNetworks("0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4")
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// blocked proverava da li je adresa loopback, privatna, link-local (uključujući metadata servis
+// cloud-a na 169.254.169.254), multicast ili rezervisana, pa isporuka na nju ne sme da izađe sa servera
+func blocked(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckTarget odbija URL pretplate čija adresa je blokirana. Ime se razrešava, pa se odbija i ime
+// koje upućuje na takvu adresu; ime koje trenutno ne može da se razreši se prihvata, jer klijent
+// za isporuke (NewClient) svakako proverava adresu pri svakom povezivanju.
+func CheckTarget(rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return model.Invalidf("webhook url must be an absolute http or https URL, got %q", rawURL)
+	}
+	host := target.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return checkAddress(rawURL, ip)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, address := range addresses {
+		if err := checkAddress(rawURL, address.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkAddress(rawURL string, ip net.IP) error {
+	if blocked(ip) {
+		return model.Invalidf("webhook url %q points to %s, a loopback, private, link-local or reserved address; "+
+			"start the server with -webhook-allow-private to allow such targets", rawURL, ip)
+	}
+	return nil
+}
+
+// NewClient vraća klijent za isporuke sa zadatim trajanjem zahteva. Ako allowPrivate nije
+// uključen, klijent odbija povezivanje na blokirane adrese. Provera se radi nad adresom na koju se
+// zaista povezuje, pa važi i za preusmerenja i za ime koje se posle kreiranja pretplate razrešava
+// drugačije; zato klijent tada ne koristi proxy iz okruženja, čija bi se adresa proveravala umesto odredišta.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		transport.Proxy = nil
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   refuseBlocked,
+		}
+		transport.DialContext = dialer.DialContext
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// refuseBlocked se poziva posle razrešavanja imena, neposredno pre povezivanja na adresu
+func refuseBlocked(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || blocked(ip) {
+		return fmt.Errorf("webhook target %s is a loopback, private, link-local or reserved address", host)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"projekat/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBlocked(t *testing.T) {
+	cases := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"127.8.9.10", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00:ec2::254", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"100.64.0.1", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"93.184.216.34", false},
+		{"8.8.8.8", false},
+		{"2606:4700:4700::1111", false},
+		{"172.32.0.1", false},
+	}
+	for _, c := range cases {
+		if got := blocked(net.ParseIP(c.ip)); got != c.blocked {
+			t.Errorf("blocked(%s) = %v, want %v", c.ip, got, c.blocked)
+		}
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	cases := []struct {
+		url     string
+		allowed bool
+	}{
+		{"http://127.0.0.1:8080/hook", false},
+		{"http://localhost/hook", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"https://[::1]/hook", false},
+		{"http://[fe80::1%25eth0]/hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"https://93.184.216.34/hook", true},
+		{"https://[2606:4700:4700::1111]/hook", true},
+	}
+	for _, c := range cases {
+		err := CheckTarget(c.url)
+		if c.allowed && err != nil {
+			t.Errorf("%s: %v", c.url, err)
+		}
+		if !c.allowed && !errors.Is(err, model.ErrInvalid) {
+			t.Errorf("%s: got %v, want ErrInvalid", c.url, err)
+		}
+	}
+}
+
+// Klijent proverava adresu na koju se povezuje, pa odbija i odredišta koja CheckTarget nije video
+func TestNewClientRefusesBlockedTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := NewClient(time.Second, false).Get(server.URL)
+	if err == nil || !strings.Contains(err.Error(), "loopback") {
+		t.Errorf("got %v, want the loopback target to be refused", err)
+	}
+
+	resp, err := NewClient(time.Second, true).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("got %d, want 204", resp.StatusCode)
+	}
+}